@@ -2,18 +2,37 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/goodleby/golang-app/appmode"
+	"github.com/goodleby/golang-app/articlefeed"
+	"github.com/goodleby/golang-app/buildinfo"
+	"github.com/goodleby/golang-app/certs"
 	"github.com/goodleby/golang-app/client/auth"
 	"github.com/goodleby/golang-app/client/database"
 	"github.com/goodleby/golang-app/client/example"
 	"github.com/goodleby/golang-app/client/pubsub"
+	"github.com/goodleby/golang-app/client/webhook"
+	"github.com/goodleby/golang-app/clientip"
+	"github.com/goodleby/golang-app/dispatcher"
 	"github.com/goodleby/golang-app/env"
+	"github.com/goodleby/golang-app/featureflag"
+	"github.com/goodleby/golang-app/health"
+	"github.com/goodleby/golang-app/idempotency"
+	"github.com/goodleby/golang-app/logger"
 	"github.com/goodleby/golang-app/processor"
+	"github.com/goodleby/golang-app/ratelimit"
+	"github.com/goodleby/golang-app/rpc"
 	"github.com/goodleby/golang-app/server"
+	"github.com/goodleby/golang-app/server/admin"
+	"github.com/goodleby/golang-app/server/graphql"
+	"github.com/goodleby/golang-app/server/middleware"
+	"github.com/goodleby/golang-app/server/web"
+	"github.com/goodleby/golang-app/tenancy"
 )
 
 type App struct {
@@ -52,6 +71,9 @@ func (app *App) Launch(ctx context.Context) {
 		slog.Error(fmt.Sprintf("Critical service error: %v", err))
 	}
 
+	// Stop receiving traffic before the services stop
+	app.Clients.Health.Shutdown()
+
 	var errs []error
 
 	// New context for graceful shutdown
@@ -85,26 +107,213 @@ type Service interface {
 func setupServices(ctx context.Context, env *env.Config, clients *Clients) ([]Service, error) {
 	var services []Service
 
-	server, err := server.New(ctx, env.Host, env.Port, env.AllowedOrigins, server.Clients{
-		DB:      clients.DB,
-		Auth:    clients.Auth,
-		PubSub:  clients.PubSub,
-		Example: clients.Example,
+	rateLimits, err := parseRateLimits(env)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing rate limits: %v", err)
+	}
+
+	var rateLimitStore middleware.RateLimitStore
+	switch env.RateLimitStore {
+	case "memory":
+		rateLimitStore = ratelimit.NewMemoryStore()
+	case "postgres":
+		rateLimitStore = clients.DB
+		services = append(services, ratelimit.NewSweeper(clients.DB, rateLimits))
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", env.RateLimitStore)
+	}
+
+	trustedProxies, err := clientip.ParseProxies(env.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing trusted proxies: %v", err)
+	}
+
+	modes, err := appmode.New(ctx, clients.DB)
+	if err != nil {
+		return nil, fmt.Errorf("error creating app mode switch: %v", err)
+	}
+	services = append(services, modes)
+
+	var flagSource featureflag.Source
+	switch env.FeatureFlagsStore {
+	case "none":
+		flagSource = featureflag.StaticSource(nil)
+	case "file":
+		flagSource = featureflag.FileSource{Path: env.FeatureFlagsFile}
+	case "postgres":
+		flagSource = clients.DB
+	default:
+		return nil, fmt.Errorf("unknown feature flags store %q", env.FeatureFlagsStore)
+	}
+
+	flags, err := featureflag.New(ctx, flagSource, env.FeatureFlagsReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("error loading feature flags: %v", err)
+	}
+	services = append(services, flags)
+
+	tenants, err := tenancy.New(ctx, clients.DB, tenancy.Config{
+		Fallback:       env.TenantFallback,
+		ReloadInterval: env.TenantReloadInterval,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading tenants: %v", err)
+	}
+	services = append(services, tenants)
+	clients.Auth.SetupTenants(tenants)
+
+	articleFeed := articlefeed.New(clients.DB, env.ArticleEventsBacklog, env.ArticleEventsRetention)
+	services = append(services, articleFeed)
+
+	var idempotencyStore middleware.IdempotencyStore
+	switch env.IdempotencyStore {
+	case "memory":
+		idempotencyStore = idempotency.NewMemoryStore()
+	case "postgres":
+		idempotencyStore = clients.DB
+		services = append(services, idempotency.NewSweeper(clients.DB))
+	default:
+		return nil, fmt.Errorf("unknown idempotency store %q", env.IdempotencyStore)
+	}
+
+	graphQLConfig := graphql.Config{
+		MaxDepth:      env.GraphQLMaxDepth,
+		MaxComplexity: env.GraphQLMaxComplexity,
+		PersistedOnly: env.GraphQLPersistedOnly,
+	}
+	if env.GraphQLPersistedQueries != "" {
+		graphQLConfig.PersistedQueries, err = graphql.LoadPersistedQueries(env.GraphQLPersistedQueries)
+		if err != nil {
+			return nil, fmt.Errorf("error loading graphql persisted queries: %v", err)
+		}
+	} else if env.GraphQLPersistedOnly {
+		return nil, errors.New("graphql persisted only mode needs persisted queries")
+	}
+
+	var tlsConfig *tls.Config
+	if env.TLSCertFile != "" {
+		certificates, err := certs.NewReloader(env.TLSCertFile, env.TLSKeyFile, certReloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("error loading tls certificate: %v", err)
+		}
+		services = append(services, certificates)
+
+		tlsConfig, err = certs.ServerConfig(certificates, certs.Config{
+			ClientAuth:   env.TLSClientAuth,
+			ClientCAFile: env.TLSClientCAFile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error creating tls config: %v", err)
+		}
+	} else if env.TLSClientAuth != certs.ClientAuthNone {
+		return nil, errors.New("client certificate verification needs tls")
+	}
+
+	server, err := server.New(ctx, server.Config{
+		Host:              env.Host,
+		Port:              env.Port,
+		TLS:               tlsConfig,
+		H2C:               env.H2C,
+		ReadHeaderTimeout: env.HTTPReadHeaderTimeout,
+		ReadTimeout:       env.HTTPReadTimeout,
+		WriteTimeout:      env.HTTPWriteTimeout,
+		IdleTimeout:       env.HTTPIdleTimeout,
+		MaxHeaderBytes:    env.HTTPMaxHeaderBytes,
+		RouteLimits: server.RouteLimits{
+			Timeout:      env.RequestTimeout,
+			MaxBodySize:  env.MaxBodySize,
+			Timeouts:     env.RequestTimeouts,
+			MaxBodySizes: env.MaxBodySizes,
+		},
+		AllowedOrigins:   env.AllowedOrigins,
+		TrustedProxies:   trustedProxies,
+		OIDCPostLoginURL: env.OIDCPostLoginURL,
+		AccessLog: middleware.AccessLogConfig{
+			SampleRate:   env.AccessLogSampleRate,
+			ExcludePaths: env.AccessLogExcludePaths,
+		},
+		RateLimits:         rateLimits,
+		IdempotencyTTL:     env.IdempotencyTTL,
+		CompressionMinSize: env.CompressionMinSize,
+		GraphQL:            graphQLConfig,
+		ModeRetryAfter:     env.ModeRetryAfter,
+		TenantHeader:       env.TenantHeader,
+		FlaggedGroups:      env.FeatureFlagGroups,
+		Web: web.Config{
+			BaseURL:     env.WebBaseURL,
+			PageSize:    env.WebPageSize,
+			CacheMaxAge: env.WebCacheMaxAge,
+		},
+	}, server.Clients{
+		DB:          clients.DB,
+		Auth:        clients.Auth,
+		PubSub:      clients.PubSub,
+		Example:     clients.Example,
+		RateLimit:   rateLimitStore,
+		Idempotency: idempotencyStore,
+		ArticleFeed: articleFeed,
+		Health:      clients.Health,
+		Mode:        modes,
+		Flags:       flags,
+		Tenants:     tenants,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error creating new server: %v", err)
 	}
 	services = append(services, server)
 
+	rpcServer, err := rpc.New(ctx, rpc.Config{
+		Host:           env.GRPCHost,
+		Port:           env.GRPCPort,
+		TrustedProxies: trustedProxies,
+	}, rpc.Clients{
+		DB:      clients.DB,
+		Auth:    clients.Auth,
+		Mode:    modes,
+		Tenants: tenants,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating new grpc server: %v", err)
+	}
+	services = append(services, rpcServer)
+
 	processor, err := processor.New(ctx, processor.Clients{
-		PubSub: clients.PubSub,
-		DB:     clients.DB,
+		PubSub:  clients.PubSub,
+		DB:      clients.DB,
+		Mode:    modes,
+		Tenants: tenants,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error creating new processor: %v", err)
 	}
 	services = append(services, processor)
 
+	adminServer, err := admin.New(ctx, admin.Config{
+		Host:      env.AdminHost,
+		Port:      env.AdminPort,
+		BuildInfo: buildinfo.Read(env.ServiceName, env.Environment),
+		Settings:  env,
+	}, admin.Clients{
+		Health:   clients.Health,
+		LogLevel: logger.Level,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating new admin server: %v", err)
+	}
+	services = append(services, adminServer)
+
+	webhookDispatcher := dispatcher.New(dispatcher.Config{
+		Concurrency:  env.WebhookConcurrency,
+		PollInterval: webhookPollInterval,
+		// Leaves the sender time to record the outcome after a timeout.
+		Lease:        2 * env.WebhookTimeout,
+		MaxAttempts:  env.WebhookMaxAttempts,
+		BackoffBase:  env.WebhookBackoffBase,
+		BackoffMax:   env.WebhookBackoffMax,
+		DisableAfter: env.WebhookDisableAfter,
+	}, clients.DB, clients.Webhook)
+	services = append(services, webhookDispatcher)
+
 	return services, nil
 }
 
@@ -113,6 +322,8 @@ type Clients struct {
 	Auth    *auth.Client
 	PubSub  *pubsub.Client
 	Example *example.Client
+	Webhook *webhook.Client
+	Health  *health.Checker
 }
 
 func setupClients(ctx context.Context, env *env.Config) (*Clients, error) {
@@ -130,12 +341,39 @@ func setupClients(ctx context.Context, env *env.Config) (*Clients, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error creating database client: %v", err)
 	}
+	c.DB.RowLevelSecurity = env.TenantRowLevelSecurity
 
 	c.Auth = auth.New(ctx, env.AuthSecret, env.AuthTokenTTL, auth.Keys{
 		Admin:  env.AuthAdminKey,
 		Editor: env.AuthEditorKey,
 		Viewer: env.AuthViewerKey,
+	}, auth.Stores{
+		APIKeys: c.DB,
+		TOTP:    c.DB,
 	})
+	c.Auth.SetupTOTP(env.ServiceName, auth.AccessLevel(env.AuthMFAAccessLevel))
+
+	if env.TLSClientAuth != certs.ClientAuthNone {
+		err = c.Auth.SetupClientCertificates(env.TLSClientRoles)
+		if err != nil {
+			return nil, fmt.Errorf("error setting up client certificates: %v", err)
+		}
+	}
+
+	if env.OIDCIssuerURL != "" {
+		err = c.Auth.SetupOIDC(ctx, auth.OIDCConfig{
+			IssuerURL:    env.OIDCIssuerURL,
+			ClientID:     env.OIDCClientID,
+			ClientSecret: env.OIDCClientSecret,
+			RedirectURL:  env.OIDCRedirectURL,
+			Scopes:       env.OIDCScopes,
+			GroupsClaim:  env.OIDCGroupsClaim,
+			RoleMapping:  env.OIDCRoleMapping,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error setting up oidc login: %v", err)
+		}
+	}
 
 	c.PubSub, err = pubsub.New(ctx, env.PubSubProjectID)
 	if err != nil {
@@ -144,6 +382,13 @@ func setupClients(ctx context.Context, env *env.Config) (*Clients, error) {
 
 	c.Example = example.New(env.ExampleEndpoint)
 
+	c.Webhook = webhook.New(env.WebhookTimeout, env.ServiceName)
+
+	c.Health = health.NewChecker(env.HealthCheckTimeout, env.HealthCheckCacheTTL)
+	c.DB.RegisterHealthChecks(c.Health)
+	c.PubSub.RegisterHealthChecks(c.Health)
+	c.Example.RegisterHealthChecks(c.Health)
+
 	return &c, nil
 }
 
@@ -161,3 +406,25 @@ func (c *Clients) Close() error {
 
 	return nil
 }
+
+func parseRateLimits(env *env.Config) (ratelimit.Limits, error) {
+	var limits ratelimit.Limits
+	var err error
+
+	limits.Groups, err = ratelimit.ParseLimits(env.RateLimitGroups)
+	if err != nil {
+		return ratelimit.Limits{}, fmt.Errorf("error parsing route group limits: %v", err)
+	}
+
+	limits.Roles, err = ratelimit.ParseLimits(env.RateLimitRoles)
+	if err != nil {
+		return ratelimit.Limits{}, fmt.Errorf("error parsing role limits: %v", err)
+	}
+
+	return limits, nil
+}
+
+const (
+	webhookPollInterval time.Duration = time.Second
+	certReloadInterval  time.Duration = 10 * time.Second
+)
@@ -0,0 +1,111 @@
+package appmode
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/goodleby/golang-app/metrics"
+	"github.com/goodleby/golang-app/model/mode"
+)
+
+// Store persists the mode, so all replicas run in the same one.
+type Store interface {
+	SelectMode(ctx context.Context) (*mode.State, error)
+	UpdateMode(ctx context.Context, payload mode.Payload) (*mode.State, error)
+	WatchMode(ctx context.Context, fn func(mode.State)) error
+}
+
+// Switch holds the mode of the app, following the changes made by any
+// replica. Switch is an app service.
+type Switch struct {
+	store Store
+
+	mu    sync.RWMutex
+	state mode.State
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New loads the current mode, so the app never starts accepting what it
+// shouldn't.
+func New(ctx context.Context, store Store) (*Switch, error) {
+	state, err := store.SelectMode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error loading app mode: %v", err)
+	}
+
+	s := &Switch{
+		store: store,
+		done:  make(chan struct{}),
+	}
+	s.apply(*state)
+
+	return s, nil
+}
+
+func (s *Switch) Start(ctx context.Context, errc chan<- error) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	defer close(s.done)
+
+	err := s.store.WatchMode(ctx, s.apply)
+	if err != nil {
+		errc <- fmt.Errorf("error watching app mode: %v", err)
+	}
+}
+
+func (s *Switch) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("error stopping app mode switch: %v", ctx.Err())
+	}
+}
+
+// CurrentMode returns the mode the app is in.
+func (s *Switch) CurrentMode() mode.State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.state
+}
+
+// SetMode persists the mode and switches to it straight away, the other
+// replicas follow once notified.
+func (s *Switch) SetMode(ctx context.Context, payload mode.Payload) (*mode.State, error) {
+	state, err := s.store.UpdateMode(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("error updating app mode: %v", err)
+	}
+
+	s.apply(*state)
+
+	return state, nil
+}
+
+// apply switches to the state unless it's older than the current one, e.g.
+// read by the watcher before an update of this replica.
+func (s *Switch) apply(state mode.State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if state.UpdatedAt.Before(s.state.UpdatedAt) {
+		return
+	}
+
+	previous := s.state.Mode
+	s.state = state
+
+	if previous != state.Mode {
+		slog.Warn(fmt.Sprintf("App mode switched to %s", state.Mode), "reason", state.Reason)
+		metrics.SetAppMode(string(state.Mode))
+	}
+}
@@ -0,0 +1,81 @@
+package appmode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/model/mode"
+)
+
+// fakeStore hands the states sent to it to the watcher, like the notifications
+// of another replica.
+type fakeStore struct {
+	state   mode.State
+	changes chan mode.State
+}
+
+func (f *fakeStore) SelectMode(ctx context.Context) (*mode.State, error) {
+	return &f.state, nil
+}
+
+func (f *fakeStore) UpdateMode(ctx context.Context, payload mode.Payload) (*mode.State, error) {
+	f.state = mode.State{Mode: payload.Mode, Reason: payload.Reason, UpdatedAt: time.Now()}
+	return &f.state, nil
+}
+
+func (f *fakeStore) WatchMode(ctx context.Context, fn func(mode.State)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case state := <-f.changes:
+			fn(state)
+		}
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	start := time.Now()
+	store := &fakeStore{
+		state:   mode.State{Mode: mode.ReadOnly, UpdatedAt: start},
+		changes: make(chan mode.State),
+	}
+
+	s, err := New(context.Background(), store)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if got := s.CurrentMode().Mode; got != mode.ReadOnly {
+		t.Errorf("CurrentMode() after New() = %v, want %v", got, mode.ReadOnly)
+	}
+
+	_, err = s.SetMode(context.Background(), mode.Payload{Mode: mode.Maintenance})
+	if err != nil {
+		t.Fatalf("SetMode() error = %v", err)
+	}
+
+	if got := s.CurrentMode().Mode; got != mode.Maintenance {
+		t.Errorf("CurrentMode() after SetMode() = %v, want %v", got, mode.Maintenance)
+	}
+
+	go s.Start(context.Background(), make(chan error, 1))
+	defer s.Stop(context.Background())
+
+	// Each change is applied once the watcher takes the next one.
+	store.changes <- mode.State{Mode: mode.ReadOnly, UpdatedAt: start}
+	store.changes <- store.state
+
+	if got := s.CurrentMode().Mode; got != mode.Maintenance {
+		t.Errorf("CurrentMode() after outdated change = %v, want %v", got, mode.Maintenance)
+	}
+
+	changed := mode.State{Mode: mode.Normal, UpdatedAt: time.Now()}
+	store.changes <- changed
+	store.changes <- changed
+
+	if got := s.CurrentMode().Mode; got != mode.Normal {
+		t.Errorf("CurrentMode() after watched change = %v, want %v", got, mode.Normal)
+	}
+}
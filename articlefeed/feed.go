@@ -0,0 +1,202 @@
+package articlefeed
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/goodleby/golang-app/model/article"
+)
+
+// Source is the durable log of article events.
+type Source interface {
+	SelectRecentArticleEvents(ctx context.Context, limit int) ([]article.Event, error)
+	WatchArticleEvents(ctx context.Context, afterID int64, fn func(article.Event)) error
+	PruneArticleEvents(ctx context.Context, before time.Time) error
+}
+
+// Feed fans the article events of the source out to subscribers. It keeps a
+// bounded backlog of the latest events, so subscribers can resume after a
+// reconnect. Feed is an app service.
+type Feed struct {
+	source      Source
+	backlogSize int
+	retention   time.Duration
+
+	mu          sync.Mutex
+	backlog     []article.Event
+	lastID      int64
+	subscribers map[*Subscription]struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Subscription receives the events following the ones it was created with.
+// Events is closed when the subscriber falls too far behind or the feed stops,
+// the subscriber is then expected to resume with a new subscription.
+type Subscription struct {
+	Events <-chan article.Event
+	events chan article.Event
+}
+
+func New(source Source, backlogSize int, retention time.Duration) *Feed {
+	return &Feed{
+		source:      source,
+		backlogSize: backlogSize,
+		retention:   retention,
+		subscribers: map[*Subscription]struct{}{},
+		done:        make(chan struct{}),
+	}
+}
+
+func (f *Feed) Start(ctx context.Context, errc chan<- error) {
+	ctx, f.cancel = context.WithCancel(ctx)
+	defer close(f.done)
+
+	recent, err := f.source.SelectRecentArticleEvents(ctx, f.backlogSize)
+	if err != nil {
+		errc <- fmt.Errorf("error loading article events backlog: %v", err)
+		return
+	}
+
+	f.mu.Lock()
+	f.backlog = recent
+	if len(recent) > 0 {
+		f.lastID = recent[len(recent)-1].ID
+	}
+	lastID := f.lastID
+	f.mu.Unlock()
+
+	go f.prune(ctx)
+
+	slog.Info(fmt.Sprintf("Article feed is watching events after %d", lastID))
+
+	err = f.source.WatchArticleEvents(ctx, lastID, f.Publish)
+	if err != nil {
+		errc <- fmt.Errorf("error watching article events: %v", err)
+	}
+}
+
+func (f *Feed) Stop(ctx context.Context) error {
+	if f.cancel == nil {
+		return nil
+	}
+	f.cancel()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return fmt.Errorf("error stopping article feed: %v", ctx.Err())
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for subscription := range f.subscribers {
+		f.unsubscribe(subscription)
+	}
+
+	return nil
+}
+
+// Publish appends the event to the backlog and sends it to the subscribers.
+// Events that aren't newer than the last one are ignored.
+func (f *Feed) Publish(event article.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if event.ID <= f.lastID {
+		return
+	}
+	f.lastID = event.ID
+
+	f.backlog = append(f.backlog, event)
+	if len(f.backlog) > f.backlogSize {
+		f.backlog = f.backlog[len(f.backlog)-f.backlogSize:]
+	}
+
+	for subscription := range f.subscribers {
+		select {
+		case subscription.events <- event:
+		default:
+			// Too slow, it resumes from the backlog after reconnecting.
+			f.unsubscribe(subscription)
+		}
+	}
+}
+
+// Subscribe subscribes to the events following the event with the ID, or to
+// new events only if the ID is 0. It returns the missed events from the
+// backlog, and reports false if events between the ID and the backlog were
+// already dropped.
+func (f *Feed) Subscribe(lastEventID int64) (*Subscription, []article.Event, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	events := make(chan article.Event, subscriptionBuffer)
+	subscription := &Subscription{Events: events, events: events}
+	f.subscribers[subscription] = struct{}{}
+
+	if lastEventID == 0 || lastEventID >= f.lastID {
+		return subscription, nil, true
+	}
+
+	// Event IDs have gaps, e.g. from rolled back transactions, so only the
+	// position relative to the backlog tells whether events were missed.
+	if len(f.backlog) == 0 || lastEventID < f.backlog[0].ID-1 {
+		return subscription, nil, false
+	}
+
+	var missed []article.Event
+	for _, event := range f.backlog {
+		if event.ID > lastEventID {
+			missed = append(missed, event)
+		}
+	}
+
+	return subscription, missed, true
+}
+
+// Unsubscribe stops sending events to the subscription.
+func (f *Feed) Unsubscribe(subscription *Subscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.unsubscribe(subscription)
+}
+
+func (f *Feed) unsubscribe(subscription *Subscription) {
+	if _, ok := f.subscribers[subscription]; !ok {
+		return
+	}
+
+	delete(f.subscribers, subscription)
+	close(subscription.events)
+}
+
+// prune regularly deletes the events older than the retention from the
+// source.
+func (f *Feed) prune(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := f.source.PruneArticleEvents(ctx, time.Now().Add(-f.retention))
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error pruning article events: %v", err))
+			}
+		}
+	}
+}
+
+const (
+	subscriptionBuffer int           = 64
+	pruneInterval      time.Duration = time.Hour
+)
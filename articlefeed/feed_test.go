@@ -0,0 +1,160 @@
+package articlefeed
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/model/article"
+)
+
+func events(ids ...int64) []article.Event {
+	var events []article.Event
+	for _, id := range ids {
+		events = append(events, article.Event{ID: id, Type: article.EventUpdated})
+	}
+
+	return events
+}
+
+func ids(events []article.Event) []int64 {
+	var ids []int64
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+
+	return ids
+}
+
+func TestFeedSubscribe(t *testing.T) {
+	tests := []struct {
+		name        string
+		published   []article.Event
+		lastEventID int64
+		wantMissed  []int64
+		wantOk      bool
+	}{
+		{
+			name:        "new subscriber gets no backlog",
+			published:   events(1, 2, 3),
+			lastEventID: 0,
+			wantOk:      true,
+		},
+		{
+			name:        "resumes from the backlog",
+			published:   events(1, 2, 3, 4, 5),
+			lastEventID: 3,
+			wantMissed:  []int64{4, 5},
+			wantOk:      true,
+		},
+		{
+			name:        "resumes across gaps in the ids",
+			published:   events(3, 7, 8),
+			lastEventID: 2,
+			wantMissed:  []int64{3, 7, 8},
+			wantOk:      true,
+		},
+		{
+			name:        "up to date subscriber misses nothing",
+			published:   events(4, 5),
+			lastEventID: 5,
+			wantOk:      true,
+		},
+		{
+			name:        "can't resume from before the backlog",
+			published:   events(1, 2, 3, 4, 5),
+			lastEventID: 1,
+			wantOk:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(nil, 3, time.Hour)
+			for _, event := range tt.published {
+				f.Publish(event)
+			}
+
+			_, missed, ok := f.Subscribe(tt.lastEventID)
+			if ok != tt.wantOk {
+				t.Fatalf("Subscribe() ok = %v, want %v", ok, tt.wantOk)
+			}
+
+			if got := ids(missed); !reflect.DeepEqual(got, tt.wantMissed) {
+				t.Errorf("Subscribe() missed = %v, want %v", got, tt.wantMissed)
+			}
+		})
+	}
+}
+
+func TestFeedPublish(t *testing.T) {
+	f := New(nil, 10, time.Hour)
+
+	subscription, _, _ := f.Subscribe(0)
+
+	f.Publish(article.Event{ID: 1})
+	f.Publish(article.Event{ID: 1})
+	f.Publish(article.Event{ID: 2})
+
+	if got := ids([]article.Event{<-subscription.Events, <-subscription.Events}); !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Errorf("Publish() sent = %v, want [1 2] without duplicates", got)
+	}
+
+	// A subscriber that doesn't keep up is dropped.
+	for i := range subscriptionBuffer + 1 {
+		f.Publish(article.Event{ID: int64(i + 3)})
+	}
+
+	received := 0
+	for range subscription.Events {
+		received++
+	}
+	if received != subscriptionBuffer {
+		t.Errorf("Publish() sent %v events to a slow subscriber, want %v before dropping it", received, subscriptionBuffer)
+	}
+}
+
+type fakeSource struct {
+	recent  []article.Event
+	watched chan int64
+	live    []article.Event
+}
+
+func (s *fakeSource) SelectRecentArticleEvents(ctx context.Context, limit int) ([]article.Event, error) {
+	return s.recent, nil
+}
+
+func (s *fakeSource) WatchArticleEvents(ctx context.Context, afterID int64, fn func(article.Event)) error {
+	s.watched <- afterID
+	for _, event := range s.live {
+		fn(event)
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (s *fakeSource) PruneArticleEvents(ctx context.Context, before time.Time) error {
+	return nil
+}
+
+func TestFeedStart(t *testing.T) {
+	source := &fakeSource{recent: events(1, 2), watched: make(chan int64), live: events(3)}
+	f := New(source, 10, time.Hour)
+
+	errc := make(chan error, 1)
+	go f.Start(context.Background(), errc)
+
+	if afterID := <-source.watched; afterID != 2 {
+		t.Errorf("Start() watched after = %v, want 2", afterID)
+	}
+
+	err := f.Stop(context.Background())
+	if err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	_, missed, ok := f.Subscribe(1)
+	if got := ids(missed); !ok || !reflect.DeepEqual(got, []int64{2, 3}) {
+		t.Errorf("Subscribe() after start = %v, %v, want [2 3], true", got, ok)
+	}
+}
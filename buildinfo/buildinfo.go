@@ -0,0 +1,51 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+type Info struct {
+	Service     string    `json:"service"`
+	Environment string    `json:"environment"`
+	GoVersion   string    `json:"go_version"`
+	Module      string    `json:"module"`
+	Version     string    `json:"version"`
+	Revision    string    `json:"revision,omitempty"`
+	CommitTime  string    `json:"commit_time,omitempty"`
+	Modified    bool      `json:"modified"`
+	StartedAt   time.Time `json:"started_at"`
+}
+
+// Read reads the build info embedded in the binary. The VCS settings are only
+// there when the binary was built from a repository checkout.
+func Read(service, environment string) *Info {
+	info := Info{
+		Service:     service,
+		Environment: environment,
+		GoVersion:   runtime.Version(),
+		StartedAt:   time.Now(),
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return &info
+	}
+
+	info.Module = build.Main.Path
+	info.Version = build.Main.Version
+
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.time":
+			info.CommitTime = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+
+	return &info
+}
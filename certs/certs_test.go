@@ -0,0 +1,186 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a self-signed certificate for the common name and its
+// key to the directory.
+func writeKeyPair(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              []string{commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error creating certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("error encoding key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, "tls.crt")
+	keyFile = filepath.Join(dir, "tls.key")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, file string, data []byte) {
+	t.Helper()
+
+	err := os.WriteFile(file, data, 0o600)
+	if err != nil {
+		t.Fatalf("error writing %s: %v", file, err)
+	}
+}
+
+func commonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("error parsing certificate: %v", err)
+	}
+
+	return leaf.Subject.CommonName
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "old.example.com")
+
+	r, err := NewReloader(certFile, keyFile, time.Minute)
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	if got := commonName(t, r); got != "old.example.com" {
+		t.Errorf("common name = %q, want %q", got, "old.example.com")
+	}
+
+	reloaded, err := r.Reload()
+	if err != nil || reloaded {
+		t.Errorf("Reload() of unchanged files = %v, %v, want false, nil", reloaded, err)
+	}
+
+	// A half written key pair keeps the previous certificate.
+	writeFile(t, keyFile, []byte("garbage"))
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(keyFile, later, later)
+
+	_, err = r.Reload()
+	if err == nil {
+		t.Errorf("Reload() of invalid files error = nil, want error")
+	}
+	if got := commonName(t, r); got != "old.example.com" {
+		t.Errorf("common name after failed reload = %q, want %q", got, "old.example.com")
+	}
+
+	writeKeyPair(t, dir, "new.example.com")
+	later = later.Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+
+	reloaded, err = r.Reload()
+	if err != nil || !reloaded {
+		t.Errorf("Reload() of changed files = %v, %v, want true, nil", reloaded, err)
+	}
+	if got := commonName(t, r); got != "new.example.com" {
+		t.Errorf("common name after reload = %q, want %q", got, "new.example.com")
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "server.example.com")
+
+	r, err := NewReloader(certFile, keyFile, time.Minute)
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		config         Config
+		wantClientAuth tls.ClientAuthType
+		wantErr        bool
+	}{
+		{
+			name:           "no client auth",
+			config:         Config{ClientAuth: ClientAuthNone},
+			wantClientAuth: tls.NoClientCert,
+		},
+		{
+			name:           "optional client auth",
+			config:         Config{ClientAuth: ClientAuthOptional, ClientCAFile: certFile},
+			wantClientAuth: tls.VerifyClientCertIfGiven,
+		},
+		{
+			name:           "required client auth",
+			config:         Config{ClientAuth: ClientAuthRequire, ClientCAFile: certFile},
+			wantClientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name:    "client auth without cas",
+			config:  Config{ClientAuth: ClientAuthRequire},
+			wantErr: true,
+		},
+		{
+			name:    "cas without certificates",
+			config:  Config{ClientAuth: ClientAuthRequire, ClientCAFile: keyFile},
+			wantErr: true,
+		},
+		{
+			name:    "unknown client auth",
+			config:  Config{ClientAuth: "sometimes"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ServerConfig(r, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ServerConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got.ClientAuth != tt.wantClientAuth {
+				t.Errorf("ServerConfig().ClientAuth = %v, want %v", got.ClientAuth, tt.wantClientAuth)
+			}
+		})
+	}
+}
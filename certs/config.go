@@ -0,0 +1,71 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Client certificate verification modes.
+const (
+	ClientAuthNone     string = "none"
+	ClientAuthOptional string = "optional"
+	ClientAuthRequire  string = "require"
+)
+
+type Config struct {
+	// ClientAuth is one of the ClientAuth modes, with optional only the
+	// certificates that are presented are verified.
+	ClientAuth string
+	// ClientCAFile is the PEM bundle of the CAs issuing client certificates.
+	ClientCAFile string
+}
+
+// ServerConfig returns the TLS config of a server presenting the certificate
+// of the reloader and verifying client certificates as configured.
+func ServerConfig(reloader *Reloader, config Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	switch config.ClientAuth {
+	case ClientAuthNone, "":
+		return tlsConfig, nil
+	case ClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth mode %q", config.ClientAuth)
+	}
+
+	if config.ClientCAFile == "" {
+		return nil, errors.New("client certificate verification needs a client ca file")
+	}
+
+	var err error
+	tlsConfig.ClientCAs, err = LoadCertPool(config.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading client cas: %v", err)
+	}
+
+	return tlsConfig, nil
+}
+
+// LoadCertPool loads the certificates of a PEM bundle.
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in %s", file)
+	}
+
+	return pool, nil
+}
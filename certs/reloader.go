@@ -0,0 +1,142 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves the certificate of a key pair and reloads it when the files
+// change, so renewed certificates are picked up without a restart. Files are
+// compared by their modification time and size, which also catches the
+// symlink swap of mounted Kubernetes secrets. Reloader is an app service.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	version fileVersion
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type fileVersion [2]struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader loads the key pair, which must be valid from the start.
+func NewReloader(certFile, keyFile string, interval time.Duration) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+
+	_, err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, it is meant for
+// tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+func (r *Reloader) Start(ctx context.Context, errc chan<- error) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				// The previous certificate is served until the files are fixed.
+				slog.Error(fmt.Sprintf("Error reloading tls certificate: %v", err))
+				continue
+			}
+			if reloaded {
+				slog.Info(fmt.Sprintf("Reloaded tls certificate from %s", r.certFile))
+			}
+		}
+	}
+}
+
+func (r *Reloader) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("error stopping tls certificate reloader: %v", ctx.Err())
+	}
+}
+
+// Reload loads the key pair if the files changed since the last load and
+// reports whether they did.
+func (r *Reloader) Reload() (bool, error) {
+	version, err := r.stat()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	unchanged := r.cert != nil && version == r.version
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("error loading key pair: %v", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.version = version
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+func (r *Reloader) stat() (fileVersion, error) {
+	var version fileVersion
+
+	for i, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fileVersion{}, fmt.Errorf("error reading file info: %v", err)
+		}
+
+		version[i].modTime = info.ModTime()
+		version[i].size = info.Size()
+	}
+
+	return version, nil
+}
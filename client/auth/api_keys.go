@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/apikey"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type APIKeyStore interface {
+	SelectAllAPIKeys(ctx context.Context) ([]apikey.APIKey, error)
+	SelectAPIKeyByHash(ctx context.Context, hash string) (*apikey.APIKey, error)
+	InsertAPIKey(ctx context.Context, payload apikey.Payload, prefix, hash string) (*apikey.APIKey, error)
+	UpdateAPIKeyHash(ctx context.Context, id int, prefix, hash string) (*apikey.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int) error
+	TouchAPIKey(ctx context.Context, id int) error
+}
+
+// CreateAPIKey stores a new API key and returns its plaintext value. Only the
+// hash is persisted, so the plaintext can't be recovered afterwards.
+func (c *Client) CreateAPIKey(ctx context.Context, payload apikey.Payload) (string, *apikey.APIKey, error) {
+	ctx, span := tracing.StartSpan(ctx, "CreateAPIKey")
+	defer span.End()
+
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating api key: %v", err)
+	}
+
+	apiKey, err := c.apiKeys.InsertAPIKey(ctx, payload, prefix, hashAPIKey(key))
+	if err != nil {
+		return "", nil, fmt.Errorf("error inserting api key: %v", err)
+	}
+
+	return key, apiKey, nil
+}
+
+func (c *Client) ListAPIKeys(ctx context.Context) ([]apikey.APIKey, error) {
+	ctx, span := tracing.StartSpan(ctx, "ListAPIKeys")
+	defer span.End()
+
+	apiKeys, err := c.apiKeys.SelectAllAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting api keys: %v", err)
+	}
+
+	return apiKeys, nil
+}
+
+// RotateAPIKey replaces the secret of an existing API key, keeping its name,
+// scopes and expiry. The previous secret stops working immediately.
+func (c *Client) RotateAPIKey(ctx context.Context, id int) (string, *apikey.APIKey, error) {
+	ctx, span := tracing.StartSpan(ctx, "RotateAPIKey")
+	defer span.End()
+
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating api key: %v", err)
+	}
+
+	apiKey, err := c.apiKeys.UpdateAPIKeyHash(ctx, id, prefix, hashAPIKey(key))
+	if err != nil {
+		switch err.(type) {
+		case *client.ErrNotFound:
+			return "", nil, err
+		default:
+			return "", nil, fmt.Errorf("error updating api key hash: %v", err)
+		}
+	}
+
+	return key, apiKey, nil
+}
+
+func (c *Client) RevokeAPIKey(ctx context.Context, id int) error {
+	ctx, span := tracing.StartSpan(ctx, "RevokeAPIKey")
+	defer span.End()
+
+	err := c.apiKeys.RevokeAPIKey(ctx, id)
+	if err != nil {
+		switch err.(type) {
+		case *client.ErrNotFound:
+			return err
+		default:
+			return fmt.Errorf("error revoking api key: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ReadAPIKeyClaims validates the API key and returns claims equivalent to the
+// ones of a session token with the access granted by the key scopes, capped
+// below the 2FA policy level.
+func (c *Client) ReadAPIKeyClaims(ctx context.Context, key string) (*Claims, error) {
+	ctx, span := tracing.StartSpan(ctx, "ReadAPIKeyClaims")
+	defer span.End()
+
+	// API keys are managed by the admins of the deployment.
+	err := defaultTenantOnly(ctx)
+	if err != nil {
+		return nil, &client.ErrUnauthorized{Err: fmt.Errorf("api keys are %v", err)}
+	}
+
+	apiKey, err := c.apiKeys.SelectAPIKeyByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		switch err.(type) {
+		case *client.ErrNotFound:
+			return nil, &client.ErrUnauthorized{Err: errors.New("invalid api key")}
+		default:
+			return nil, fmt.Errorf("error selecting api key: %v", err)
+		}
+	}
+
+	now := time.Now()
+	if !apiKey.Usable(now) {
+		return nil, &client.ErrUnauthorized{Err: fmt.Errorf("api key %q is revoked or expired", apiKey.Name)}
+	}
+
+	// Avoid writing on every request, last used precision of a minute is enough.
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > time.Minute {
+		err = c.apiKeys.TouchAPIKey(ctx, apiKey.ID)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error touching api key: %v", err))
+		}
+	}
+
+	// API keys can't pass 2FA, so they never grant the access it guards.
+	accessLevel := scopesAccess(apiKey.Scopes)
+	if c.totp.required(accessLevel) {
+		accessLevel = c.totp.requiredAccess - 1
+	}
+
+	var roleName string
+	for _, role := range c.roles {
+		if role.AccessLevel == accessLevel {
+			roleName = role.Name
+		}
+	}
+
+	return &Claims{
+		RoleName:    roleName,
+		AccessLevel: accessLevel,
+		Scopes:      apiKey.Scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        strconv.Itoa(apiKey.ID),
+			Subject:   APIKeySubject(apiKey.Name),
+			IssuedAt:  jwt.NewNumericDate(apiKey.CreatedAt),
+			ExpiresAt: jwt.NewNumericDate(apiKey.ExpiresAt),
+		},
+	}, nil
+}
+
+// APIKeySubject is the claims subject of requests authenticated by API key.
+func APIKeySubject(name string) string {
+	return "apikey:" + name
+}
+
+// scopesAccess returns the highest access level granted by the given scopes.
+func scopesAccess(scopes []string) AccessLevel {
+	var access AccessLevel
+	for _, scope := range scopes {
+		if level := scopeAccessLevels[scope]; level > access {
+			access = level
+		}
+	}
+
+	return access
+}
+
+var scopeAccessLevels = map[string]AccessLevel{
+	apikey.ScopeArticlesRead:  ViewerAccess,
+	apikey.ScopeArticlesWrite: EditorAccess,
+	apikey.ScopeAdmin:         AdminAccess,
+}
+
+func generateAPIKey() (key, prefix string, err error) {
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(b)
+
+	return apiKeyPrefix + secret, apiKeyPrefix + secret[:8], nil
+}
+
+// hashAPIKey uses a plain SHA-256 since API keys are long random strings, so
+// there is nothing to gain from a slow password hash.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+const apiKeyPrefix = "gak_"
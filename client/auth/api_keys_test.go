@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/apikey"
+)
+
+type fakeAPIKeyStore struct {
+	APIKeyStore
+	keys    map[string]apikey.APIKey
+	touched []int
+}
+
+func (s *fakeAPIKeyStore) SelectAPIKeyByHash(ctx context.Context, hash string) (*apikey.APIKey, error) {
+	apiKey, ok := s.keys[hash]
+	if !ok {
+		return nil, &client.ErrNotFound{Err: errors.New("api key not found")}
+	}
+
+	return &apiKey, nil
+}
+
+func (s *fakeAPIKeyStore) TouchAPIKey(ctx context.Context, id int) error {
+	s.touched = append(s.touched, id)
+	return nil
+}
+
+func TestClient_ReadAPIKeyClaims(t *testing.T) {
+	now := time.Now()
+	recently := now.Add(-10 * time.Second)
+	store := &fakeAPIKeyStore{
+		keys: map[string]apikey.APIKey{
+			hashAPIKey("gak_reader"): {
+				ID:        1,
+				Scopes:    []string{apikey.ScopeArticlesRead},
+				ExpiresAt: now.Add(time.Hour),
+			},
+			hashAPIKey("gak_writer"): {
+				ID:         2,
+				Scopes:     []string{apikey.ScopeArticlesRead, apikey.ScopeArticlesWrite},
+				ExpiresAt:  now.Add(time.Hour),
+				LastUsedAt: &recently,
+			},
+			hashAPIKey("gak_admin"): {
+				ID:        5,
+				Scopes:    []string{apikey.ScopeAdmin},
+				ExpiresAt: now.Add(time.Hour),
+			},
+			hashAPIKey("gak_expired"): {
+				ID:        3,
+				Scopes:    []string{apikey.ScopeAdmin},
+				ExpiresAt: now.Add(-time.Hour),
+			},
+			hashAPIKey("gak_revoked"): {
+				ID:        4,
+				Scopes:    []string{apikey.ScopeAdmin},
+				ExpiresAt: now.Add(time.Hour),
+				RevokedAt: &recently,
+			},
+		},
+	}
+	c := &Client{apiKeys: store}
+
+	tests := []struct {
+		name        string
+		key         string
+		mfaAccess   AccessLevel
+		want        AccessLevel
+		wantErr     bool
+		wantTouched bool
+	}{
+		{
+			name:        "read scope grants viewer access",
+			key:         "gak_reader",
+			want:        ViewerAccess,
+			wantTouched: true,
+		},
+		{
+			name:        "highest scope wins and recent use is not touched",
+			key:         "gak_writer",
+			want:        EditorAccess,
+			wantTouched: false,
+		},
+		{
+			name:        "admin scope without 2FA policy",
+			key:         "gak_admin",
+			want:        AdminAccess,
+			wantTouched: true,
+		},
+		{
+			name:        "admin scope is capped by 2FA policy",
+			key:         "gak_admin",
+			mfaAccess:   EditorAccess,
+			want:        EditorAccess - 1,
+			wantTouched: true,
+		},
+		{
+			name:    "expired key",
+			key:     "gak_expired",
+			wantErr: true,
+		},
+		{
+			name:    "revoked key",
+			key:     "gak_revoked",
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			key:     "gak_unknown",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.touched = nil
+			c.totp = &totpPolicy{requiredAccess: tt.mfaAccess}
+
+			got, err := c.ReadAPIKeyClaims(context.Background(), tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Client.ReadAPIKeyClaims() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if _, ok := err.(*client.ErrUnauthorized); !ok {
+					t.Fatalf("Client.ReadAPIKeyClaims() error = %T, want *client.ErrUnauthorized", err)
+				}
+				return
+			}
+			if got.AccessLevel != tt.want {
+				t.Errorf("Client.ReadAPIKeyClaims() access = %v, want %v", got.AccessLevel, tt.want)
+			}
+			if (len(store.touched) > 0) != tt.wantTouched {
+				t.Errorf("Client.ReadAPIKeyClaims() touched = %v, want touched %v", store.touched, tt.wantTouched)
+			}
+		})
+	}
+}
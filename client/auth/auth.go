@@ -8,6 +8,8 @@ import (
 
 	jwt "github.com/golang-jwt/jwt/v5"
 	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/model/tenant"
 	"github.com/goodleby/golang-app/tracing"
 )
 
@@ -16,9 +18,15 @@ type Client struct {
 	roles         []Role
 	TokenTTL      time.Duration
 	SigningMethod jwt.SigningMethod
+
+	apiKeys          APIKeyStore
+	oidc             *oidcProvider
+	totp             *totpPolicy
+	certificateRoles map[string]Role
+	tenants          TenantKeyring
 }
 
-func New(ctx context.Context, secret string, tokenTTL time.Duration, keys Keys) *Client {
+func New(ctx context.Context, secret string, tokenTTL time.Duration, keys Keys, stores Stores) *Client {
 	var c Client
 
 	c.authSecret = []byte(secret)
@@ -42,10 +50,17 @@ func New(ctx context.Context, secret string, tokenTTL time.Duration, keys Keys)
 
 	c.TokenTTL = tokenTTL
 	c.SigningMethod = jwt.SigningMethodHS256
+	c.apiKeys = stores.APIKeys
+	c.totp = &totpPolicy{store: stores.TOTP}
 
 	return &c
 }
 
+type Stores struct {
+	APIKeys APIKeyStore
+	TOTP    TOTPStore
+}
+
 type Keys struct {
 	Admin  string
 	Editor string
@@ -56,6 +71,8 @@ type Role struct {
 	Name        string
 	AccessLevel AccessLevel
 	Key         string
+	// KeyHash is set instead of Key for the roles of tenants, see HashRoleKey.
+	KeyHash string
 }
 
 type AccessLevel int
@@ -64,17 +81,54 @@ func (c *Client) CreateRoleToken(ctx context.Context, roleName, roleKey string)
 	ctx, span := tracing.StartSpan(ctx, "CreateRoleToken")
 	defer span.End()
 
-	role, err := c.findRole(roleName, roleKey)
+	role, err := c.findRole(ctx, roleName, roleKey)
 	if err != nil {
 		return "", time.Time{}, &client.ErrUnauthorized{Err: err}
 	}
 
+	token, expires, err := c.issueRoleToken(ctx, role, tenantSubject(ctx, role.Name), false)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("error issuing role token: %v", err)
+	}
+
+	return token, expires, nil
+}
+
+// issueRoleToken creates a session token granting the access of the given
+// role in the tenant of the context. Subject identifies who the token was
+// issued to. Unless the second factor is verified, the access is capped below
+// the 2FA policy level.
+func (c *Client) issueRoleToken(ctx context.Context, role Role, subject string, mfaVerified bool) (string, time.Time, error) {
 	expires := time.Now().Add(c.TokenTTL)
 
+	accessLevel := role.AccessLevel
+	mfaPending := !mfaVerified && c.totp.required(accessLevel)
+	if mfaPending {
+		accessLevel = c.totp.requiredAccess - 1
+	}
+
+	sessionID, err := randomString()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("error generating session id: %v", err)
+	}
+
+	// Tokens of the default tenant don't claim it, so the ones issued before
+	// tenants existed stay valid.
+	var tenantClaim string
+	if id := contextTenant(ctx); id != tenant.DefaultID {
+		tenantClaim = id
+	}
+
 	claims := Claims{
+		Tenant:      tenantClaim,
 		RoleName:    role.Name,
-		AccessLevel: role.AccessLevel,
+		AccessLevel: accessLevel,
+		MFA:         mfaVerified,
+		MFAPending:  mfaPending,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(expires),
 		},
 	}
@@ -84,19 +138,23 @@ func (c *Client) CreateRoleToken(ctx context.Context, roleName, roleKey string)
 		return "", time.Time{}, fmt.Errorf("error creating token with claims: %v", err)
 	}
 
+	audit.EventFromContext(ctx).SetActor(claims.Subject, claims.RoleName)
+
 	return token, expires, nil
 }
 
-func (c *Client) ReadTokenAccess(ctx context.Context, tokenString string) (AccessLevel, error) {
-	ctx, span := tracing.StartSpan(ctx, "CheckTokenAccess")
+// ReadTokenClaims validates the token and returns its claims. Any token that
+// fails validation is reported as unauthorized.
+func (c *Client) ReadTokenClaims(ctx context.Context, tokenString string) (*Claims, error) {
+	ctx, span := tracing.StartSpan(ctx, "ReadTokenClaims")
 	defer span.End()
 
 	claims, err := c.parseTokenClaims(ctx, tokenString)
 	if err != nil {
-		return 0, fmt.Errorf("error parsing token claims: %v", err)
+		return nil, &client.ErrUnauthorized{Err: fmt.Errorf("error parsing token claims: %v", err)}
 	}
 
-	return claims.AccessLevel, nil
+	return &claims, nil
 }
 
 func (c *Client) RefreshToken(ctx context.Context, tokenString string) (string, time.Time, error) {
@@ -105,11 +163,13 @@ func (c *Client) RefreshToken(ctx context.Context, tokenString string) (string,
 
 	claims, err := c.parseTokenClaims(ctx, tokenString)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("error parsing token claims: %v", err)
+		return "", time.Time{}, &client.ErrUnauthorized{Err: fmt.Errorf("error parsing token claims: %v", err)}
 	}
 
 	expires := time.Now().Add(c.TokenTTL)
 
+	// The refreshed token keeps the session ID, so the session stays the same.
+	claims.IssuedAt = jwt.NewNumericDate(time.Now())
 	claims.ExpiresAt = jwt.NewNumericDate(expires)
 
 	token, err := c.createTokenWithClaims(ctx, claims)
@@ -117,12 +177,19 @@ func (c *Client) RefreshToken(ctx context.Context, tokenString string) (string,
 		return "", time.Time{}, fmt.Errorf("error creating token with claims: %v", err)
 	}
 
+	audit.EventFromContext(ctx).SetActor(claims.Subject, claims.RoleName)
+
 	return token, expires, nil
 }
 
-func (c *Client) findRole(roleName, roleKey string) (Role, error) {
-	for _, role := range c.roles {
-		if role.Name == roleName && role.Key == roleKey {
+func (c *Client) findRole(ctx context.Context, roleName, roleKey string) (Role, error) {
+	roles, err := c.tenantRoles(ctx)
+	if err != nil {
+		return Role{}, err
+	}
+
+	for _, role := range roles {
+		if role.Name == roleName && role.matchesKey(roleKey) {
 			return role, nil
 		}
 	}
@@ -130,13 +197,28 @@ func (c *Client) findRole(roleName, roleKey string) (Role, error) {
 	return Role{}, errors.New("invalid role name or role key")
 }
 
+func (c *Client) findRoleByName(roleName string) (Role, error) {
+	for _, role := range c.roles {
+		if role.Name == roleName {
+			return role, nil
+		}
+	}
+
+	return Role{}, fmt.Errorf("unknown role %q", roleName)
+}
+
 func (c *Client) createTokenWithClaims(ctx context.Context, claims jwt.Claims) (string, error) {
 	_, span := tracing.StartSpan(ctx, "createTokenWithClaims")
 	defer span.End()
 
+	secret, err := c.signingSecret(ctx)
+	if err != nil {
+		return "", err
+	}
+
 	token := jwt.NewWithClaims(c.SigningMethod, claims)
 
-	signedToken, err := token.SignedString(c.authSecret)
+	signedToken, err := token.SignedString(secret)
 	if err != nil {
 		return "", fmt.Errorf("error signing auth token: %v", err)
 	}
@@ -150,28 +232,54 @@ func (c *Client) parseTokenClaims(ctx context.Context, tokenString string) (Clai
 
 	var claims Claims
 
+	err := c.parseToken(ctx, tokenString, &claims)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	err = checkTenant(ctx, &claims)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// parseToken verifies the token with the secret of the tenant of the context.
+func (c *Client) parseToken(ctx context.Context, tokenString string, claims jwt.Claims, options ...jwt.ParserOption) error {
+	options = append(options, jwt.WithValidMethods([]string{c.SigningMethod.Alg()}))
+
 	token, err := jwt.ParseWithClaims(
 		tokenString,
-		&claims,
+		claims,
 		func(t *jwt.Token) (interface{}, error) {
-			return c.authSecret, nil
+			return c.signingSecret(ctx)
 		},
-		jwt.WithValidMethods([]string{c.SigningMethod.Alg()}),
+		options...,
 	)
 	if err != nil {
-		return Claims{}, fmt.Errorf("error parsing auth token: %v", err)
+		return fmt.Errorf("error parsing auth token: %v", err)
 	}
 
 	if !token.Valid {
-		return Claims{}, &client.ErrUnauthorized{Err: errors.New("invalid auth token")}
+		return &client.ErrUnauthorized{Err: errors.New("invalid auth token")}
 	}
 
-	return claims, nil
+	return nil
 }
 
 type Claims struct {
+	// Tenant is empty for the default tenant, see TenantID.
+	Tenant      string      `json:"tenant,omitempty"`
 	RoleName    string      `json:"roleName"`
 	AccessLevel AccessLevel `json:"accessLevel"`
+	// MFA is set once the second factor has been verified for the session.
+	MFA bool `json:"mfa,omitempty"`
+	// MFAPending is set when the role requires 2FA and AccessLevel is capped
+	// until the second factor is verified.
+	MFAPending bool `json:"mfaPending,omitempty"`
+	// Scopes are only set for claims read from API keys.
+	Scopes []string `json:"scopes,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"reflect"
 	"testing"
 	"time"
@@ -76,7 +77,7 @@ func TestClient_findRole(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := c.findRole(tt.args.roleName, tt.args.roleKey)
+			got, err := c.findRole(context.Background(), tt.args.roleName, tt.args.roleKey)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Client.findRole() error = %v, wantErr %v", err, tt.wantErr)
 				return
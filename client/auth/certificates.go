@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+// SetupClientCertificates enables authentication by client certificates, the
+// role mapping maps certificate subject common names to our role names. The
+// certificates are expected to be verified by the TLS handshake.
+func (c *Client) SetupClientCertificates(roleMapping map[string]string) error {
+	c.certificateRoles = make(map[string]Role, len(roleMapping))
+
+	for commonName, roleName := range roleMapping {
+		role, err := c.findRoleByName(roleName)
+		if err != nil {
+			return fmt.Errorf("error mapping certificate subject %q: %v", commonName, err)
+		}
+
+		c.certificateRoles[commonName] = role
+	}
+
+	return nil
+}
+
+// ReadCertificateClaims returns the claims of the role mapped to the subject
+// of a verified client certificate, capped below the 2FA policy level.
+// Unmapped subjects are unauthorized.
+func (c *Client) ReadCertificateClaims(ctx context.Context, cert *x509.Certificate) (*Claims, error) {
+	_, span := tracing.StartSpan(ctx, "ReadCertificateClaims")
+	defer span.End()
+
+	if c.certificateRoles == nil {
+		return nil, &client.ErrUnauthorized{Err: errors.New("client certificates aren't accepted")}
+	}
+
+	err := defaultTenantOnly(ctx)
+	if err != nil {
+		return nil, &client.ErrUnauthorized{Err: fmt.Errorf("client certificates are %v", err)}
+	}
+
+	commonName := cert.Subject.CommonName
+	role, ok := c.certificateRoles[commonName]
+	if !ok {
+		return nil, &client.ErrUnauthorized{Err: fmt.Errorf("certificate subject %q has no role", commonName)}
+	}
+
+	// Certificates can't pass 2FA, so they never grant the access it guards.
+	accessLevel := role.AccessLevel
+	if c.totp.required(accessLevel) {
+		accessLevel = c.totp.requiredAccess - 1
+	}
+
+	return &Claims{
+		RoleName:    role.Name,
+		AccessLevel: accessLevel,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        cert.SerialNumber.String(),
+			Subject:   CertificateSubject(commonName),
+			IssuedAt:  jwt.NewNumericDate(cert.NotBefore),
+			ExpiresAt: jwt.NewNumericDate(cert.NotAfter),
+		},
+	}, nil
+}
+
+// CertificateSubject is the claims subject of requests authenticated by client
+// certificate.
+func CertificateSubject(commonName string) string {
+	return "cert:" + commonName
+}
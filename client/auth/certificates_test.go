@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+)
+
+func TestClient_SetupClientCertificates(t *testing.T) {
+	c := New(context.Background(), "secret", time.Minute, Keys{}, Stores{})
+
+	err := c.SetupClientCertificates(map[string]string{"billing": "superuser"})
+	if err == nil {
+		t.Errorf("Client.SetupClientCertificates() with unknown role error = nil, want error")
+	}
+}
+
+func TestClient_ReadCertificateClaims(t *testing.T) {
+	c := New(context.Background(), "secret", time.Minute, Keys{}, Stores{})
+
+	err := c.SetupClientCertificates(map[string]string{
+		"billing": EditorRole,
+		"reports": ViewerRole,
+		"ops":     AdminRole,
+	})
+	if err != nil {
+		t.Fatalf("Client.SetupClientCertificates() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		commonName  string
+		mfaAccess   AccessLevel
+		want        AccessLevel
+		wantSubject string
+		wantErr     bool
+	}{
+		{
+			name:        "mapped subject",
+			commonName:  "billing",
+			want:        EditorAccess,
+			wantSubject: "cert:billing",
+		},
+		{
+			name:        "another mapped subject",
+			commonName:  "reports",
+			want:        ViewerAccess,
+			wantSubject: "cert:reports",
+		},
+		{
+			name:        "admin subject without 2FA policy",
+			commonName:  "ops",
+			want:        AdminAccess,
+			wantSubject: "cert:ops",
+		},
+		{
+			name:        "admin subject is capped by 2FA policy",
+			commonName:  "ops",
+			mfaAccess:   AdminAccess,
+			want:        AdminAccess - 1,
+			wantSubject: "cert:ops",
+		},
+		{
+			name:       "unmapped subject",
+			commonName: "intruder",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.totp.requiredAccess = tt.mfaAccess
+
+			cert := &x509.Certificate{
+				SerialNumber: big.NewInt(1),
+				Subject:      pkix.Name{CommonName: tt.commonName},
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(time.Hour),
+			}
+
+			got, err := c.ReadCertificateClaims(context.Background(), cert)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Client.ReadCertificateClaims() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if _, ok := err.(*client.ErrUnauthorized); !ok {
+					t.Fatalf("Client.ReadCertificateClaims() error = %T, want *client.ErrUnauthorized", err)
+				}
+				return
+			}
+			if got.AccessLevel != tt.want {
+				t.Errorf("Client.ReadCertificateClaims() access = %v, want %v", got.AccessLevel, tt.want)
+			}
+			if got.Subject != tt.wantSubject {
+				t.Errorf("Client.ReadCertificateClaims() subject = %q, want %q", got.Subject, tt.wantSubject)
+			}
+		})
+	}
+}
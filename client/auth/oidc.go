@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/tracing"
+	"golang.org/x/oauth2"
+)
+
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// GroupsClaim is the ID token claim that holds the IdP groups of the user.
+	GroupsClaim string
+	// RoleMapping maps IdP group names to our role names.
+	RoleMapping map[string]string
+}
+
+type oidcProvider struct {
+	config      oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+	httpClient  *http.Client
+	groupsClaim string
+	roleMapping map[string]string
+}
+
+// SetupOIDC runs the discovery against the identity provider and enables the
+// OpenID Connect login flow.
+func (c *Client) SetupOIDC(ctx context.Context, config OIDCConfig) error {
+	httpClient := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: tracing.NewTracedTransport(http.DefaultTransport),
+	}
+
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, httpClient), config.IssuerURL)
+	if err != nil {
+		return fmt.Errorf("error running oidc discovery: %v", err)
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID}
+	}
+
+	c.oidc = &oidcProvider{
+		config: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier:    provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+		httpClient:  httpClient,
+		groupsClaim: config.GroupsClaim,
+		roleMapping: config.RoleMapping,
+	}
+
+	return nil
+}
+
+// BeginOIDCLogin returns the identity provider URL to redirect the user to and
+// a signed state token that has to be presented back on callback.
+func (c *Client) BeginOIDCLogin(ctx context.Context) (authURL, stateToken string, expires time.Time, err error) {
+	ctx, span := tracing.StartSpan(ctx, "BeginOIDCLogin")
+	defer span.End()
+
+	if c.oidc == nil {
+		return "", "", time.Time{}, &client.ErrNotFound{Err: errors.New("oidc login is not configured")}
+	}
+
+	// The role mapping of the IdP is for the deployment, not for tenants.
+	err = defaultTenantOnly(ctx)
+	if err != nil {
+		return "", "", time.Time{}, &client.ErrNotFound{Err: fmt.Errorf("oidc login is %v", err)}
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("error generating state: %v", err)
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	verifier := oauth2.GenerateVerifier()
+	expires = time.Now().Add(oidcStateTTL)
+
+	stateToken, err = c.createTokenWithClaims(ctx, oidcStateClaims{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oidcStateAudience},
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	})
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("error creating state token: %v", err)
+	}
+
+	authURL = c.oidc.config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+
+	return authURL, stateToken, expires, nil
+}
+
+// CompleteOIDCLogin exchanges the authorization code, verifies the ID token
+// and issues our own session token for the role mapped from the IdP groups.
+func (c *Client) CompleteOIDCLogin(ctx context.Context, stateToken, state, code string) (string, time.Time, error) {
+	ctx, span := tracing.StartSpan(ctx, "CompleteOIDCLogin")
+	defer span.End()
+
+	if c.oidc == nil {
+		return "", time.Time{}, &client.ErrNotFound{Err: errors.New("oidc login is not configured")}
+	}
+
+	err := defaultTenantOnly(ctx)
+	if err != nil {
+		return "", time.Time{}, &client.ErrNotFound{Err: fmt.Errorf("oidc login is %v", err)}
+	}
+
+	var stateClaims oidcStateClaims
+	err = c.parseToken(ctx, stateToken, &stateClaims, jwt.WithAudience(oidcStateAudience))
+	if err != nil {
+		return "", time.Time{}, &client.ErrUnauthorized{Err: fmt.Errorf("error parsing state token: %v", err)}
+	}
+
+	if state == "" || stateClaims.State != state {
+		return "", time.Time{}, &client.ErrUnauthorized{Err: errors.New("oidc state mismatch")}
+	}
+
+	exchangeCtx := context.WithValue(ctx, oauth2.HTTPClient, c.oidc.httpClient)
+	oauth2Token, err := c.oidc.config.Exchange(exchangeCtx, code, oauth2.VerifierOption(stateClaims.Verifier))
+	if err != nil {
+		return "", time.Time{}, &client.ErrUnauthorized{Err: fmt.Errorf("error exchanging authorization code: %v", err)}
+	}
+
+	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok {
+		return "", time.Time{}, &client.ErrUnauthorized{Err: errors.New("token response has no id_token")}
+	}
+
+	idToken, err := c.oidc.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return "", time.Time{}, &client.ErrUnauthorized{Err: fmt.Errorf("error verifying id token: %v", err)}
+	}
+
+	if idToken.Nonce != stateClaims.Nonce {
+		return "", time.Time{}, &client.ErrUnauthorized{Err: errors.New("oidc nonce mismatch")}
+	}
+
+	var idClaims map[string]any
+	err = idToken.Claims(&idClaims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("error decoding id token claims: %v", err)
+	}
+
+	role, err := c.mapOIDCRole(idClaims)
+	if err != nil {
+		return "", time.Time{}, &client.ErrUnauthorized{Err: err}
+	}
+
+	token, expires, err := c.issueRoleToken(ctx, role, tenantSubject(ctx, idToken.Subject), false)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("error issuing role token: %v", err)
+	}
+
+	return token, expires, nil
+}
+
+// mapOIDCRole picks the role with the highest access level among the ones
+// mapped from the groups present in the ID token claims.
+func (c *Client) mapOIDCRole(idClaims map[string]any) (Role, error) {
+	var groups []string
+	switch v := idClaims[c.oidc.groupsClaim].(type) {
+	case string:
+		groups = append(groups, v)
+	case []any:
+		for _, group := range v {
+			if s, ok := group.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	var found bool
+	var best Role
+	for _, group := range groups {
+		roleName, ok := c.oidc.roleMapping[group]
+		if !ok {
+			continue
+		}
+
+		role, err := c.findRoleByName(roleName)
+		if err != nil {
+			return Role{}, fmt.Errorf("error mapping group %q: %v", group, err)
+		}
+
+		if !found || role.AccessLevel > best.AccessLevel {
+			best = role
+			found = true
+		}
+	}
+
+	if !found {
+		return Role{}, errors.New("none of the idp groups map to a role")
+	}
+
+	return best, nil
+}
+
+type oidcStateClaims struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+const oidcStateTTL = 10 * time.Minute
+const oidcStateAudience = "oidc_state"
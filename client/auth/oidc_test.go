@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/goodleby/golang-app/client"
+)
+
+// fakeIdP is a minimal in-process OpenID Connect provider that supports
+// discovery, JWKS and the authorization code grant with PKCE.
+type fakeIdP struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]fakeAuthorization
+}
+
+type fakeAuthorization struct {
+	nonce         string
+	codeChallenge string
+	subject       string
+	groups        []string
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating rsa key: %v", err)
+	}
+
+	idp := &fakeIdP{key: key, codes: map[string]fakeAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]any{
+			"issuer":                                idp.URL,
+			"authorization_endpoint":                idp.URL + "/authorize",
+			"token_endpoint":                        idp.URL + "/token",
+			"jwks_uri":                              idp.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		authz, ok := idp.codes[r.PostFormValue("code")]
+		delete(idp.codes, r.PostFormValue("code"))
+		idp.mu.Unlock()
+
+		challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != authz.codeChallenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":    idp.URL,
+			"aud":    "client-id",
+			"sub":    authz.subject,
+			"nonce":  authz.nonce,
+			"groups": authz.groups,
+			"iat":    time.Now().Unix(),
+			"exp":    time.Now().Add(time.Minute).Unix(),
+		})
+		token.Header["kid"] = "test"
+
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			t.Errorf("error signing id token: %v", err)
+		}
+
+		writeJSON(t, w, map[string]any{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   60,
+			"id_token":     idToken,
+		})
+	})
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+// authorize simulates the user signing in at the IdP and returns the code the
+// IdP would redirect back with.
+func (idp *fakeIdP) authorize(t *testing.T, authURL, subject string, groups []string) (state, code string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("error parsing auth url: %v", err)
+	}
+
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("auth url code_challenge_method = %q, want S256", q.Get("code_challenge_method"))
+	}
+
+	code = q.Get("state") + "-code"
+
+	idp.mu.Lock()
+	idp.codes[code] = fakeAuthorization{
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		subject:       subject,
+		groups:        groups,
+	}
+	idp.mu.Unlock()
+
+	return q.Get("state"), code
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		t.Errorf("error encoding json: %v", err)
+	}
+}
+
+func TestClient_OIDCLogin(t *testing.T) {
+	idp := newFakeIdP(t)
+
+	c := New(context.Background(), "secret", time.Hour, Keys{Admin: "a", Editor: "e", Viewer: "v"}, Stores{})
+	err := c.SetupOIDC(context.Background(), OIDCConfig{
+		IssuerURL:   idp.URL,
+		ClientID:    "client-id",
+		RedirectURL: "http://localhost/callback",
+		GroupsClaim: "groups",
+		RoleMapping: map[string]string{"staff": ViewerRole, "writers": EditorRole},
+	})
+	if err != nil {
+		t.Fatalf("Client.SetupOIDC() error = %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		groups           []string
+		tamperState      bool
+		want             AccessLevel
+		wantUnauthorized bool
+	}{
+		{
+			name:   "highest mapped role wins",
+			groups: []string{"staff", "writers", "unknown"},
+			want:   EditorAccess,
+		},
+		{
+			name:   "single mapped group",
+			groups: []string{"staff"},
+			want:   ViewerAccess,
+		},
+		{
+			name:             "no mapped groups",
+			groups:           []string{"unknown"},
+			wantUnauthorized: true,
+		},
+		{
+			name:             "state mismatch",
+			groups:           []string{"writers"},
+			tamperState:      true,
+			wantUnauthorized: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			authURL, stateToken, _, err := c.BeginOIDCLogin(ctx)
+			if err != nil {
+				t.Fatalf("Client.BeginOIDCLogin() error = %v", err)
+			}
+
+			state, code := idp.authorize(t, authURL, "user-1", tt.groups)
+			if tt.tamperState {
+				state = "tampered"
+			}
+
+			token, _, err := c.CompleteOIDCLogin(ctx, stateToken, state, code)
+			if tt.wantUnauthorized {
+				var errUnauthorized *client.ErrUnauthorized
+				if !errors.As(err, &errUnauthorized) {
+					t.Fatalf("Client.CompleteOIDCLogin() error = %v, want unauthorized", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Client.CompleteOIDCLogin() error = %v", err)
+			}
+
+			claims, err := c.ReadTokenClaims(ctx, token)
+			if err != nil {
+				t.Fatalf("Client.ReadTokenClaims() error = %v", err)
+			}
+			if claims.AccessLevel != tt.want {
+				t.Errorf("Client.ReadTokenClaims() access = %v, want %v", claims.AccessLevel, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/apikey"
+	"github.com/goodleby/golang-app/model/session"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+// ReadSession returns the session described by the token.
+func (c *Client) ReadSession(ctx context.Context, tokenString string) (*session.Session, error) {
+	ctx, span := tracing.StartSpan(ctx, "ReadSession")
+	defer span.End()
+
+	claims, err := c.ReadTokenClaims(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	s := session.Session{
+		ID:          claims.ID,
+		Subject:     claims.Subject,
+		Role:        claims.RoleName,
+		AccessLevel: int(claims.AccessLevel),
+		Permissions: claims.Permissions(),
+		MFA:         claims.MFA,
+		MFAPending:  claims.MFAPending,
+	}
+	if claims.ExpiresAt != nil {
+		s.ExpiresAt = claims.ExpiresAt.Time
+	}
+
+	return &s, nil
+}
+
+// IntrospectToken reports whether the session token or API key is active and
+// what it grants. Invalid tokens aren't an error, they're reported inactive.
+func (c *Client) IntrospectToken(ctx context.Context, token string) (*session.Introspection, error) {
+	ctx, span := tracing.StartSpan(ctx, "IntrospectToken")
+	defer span.End()
+
+	var claims *Claims
+	var err error
+	tokenType := session.TokenTypeSession
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		tokenType = session.TokenTypeAPIKey
+		claims, err = c.ReadAPIKeyClaims(ctx, token)
+	} else {
+		claims, err = c.ReadTokenClaims(ctx, token)
+	}
+	if err != nil {
+		switch err.(type) {
+		case *client.ErrUnauthorized:
+			return &session.Introspection{Active: false}, nil
+		default:
+			return nil, fmt.Errorf("error reading %s claims: %v", tokenType, err)
+		}
+	}
+
+	introspection := session.Introspection{
+		Active:      true,
+		Scope:       strings.Join(claims.Permissions(), " "),
+		TokenType:   tokenType,
+		Subject:     claims.Subject,
+		ID:          claims.ID,
+		Role:        claims.RoleName,
+		AccessLevel: int(claims.AccessLevel),
+	}
+	if claims.ExpiresAt != nil {
+		introspection.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		introspection.IssuedAt = claims.IssuedAt.Unix()
+	}
+
+	return &introspection, nil
+}
+
+// Permissions returns the scopes granted by the claims. API keys are limited to
+// their own scopes, while session tokens get every scope up to their access
+// level.
+func (c *Claims) Permissions() []string {
+	if c.Scopes != nil {
+		return c.Scopes
+	}
+
+	permissions := []string{}
+	for _, scope := range apikey.Scopes {
+		level, ok := scopeAccessLevels[scope]
+		if ok && level <= c.AccessLevel {
+			permissions = append(permissions, scope)
+		}
+	}
+
+	return permissions
+}
+
+// HasPermission reports whether the claims grant the scope. The admin scope
+// grants every other scope.
+func (c *Claims) HasPermission(scope string) bool {
+	permissions := c.Permissions()
+	return slices.Contains(permissions, scope) || slices.Contains(permissions, apikey.ScopeAdmin)
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/model/apikey"
+	"github.com/goodleby/golang-app/model/session"
+)
+
+func TestClient_IntrospectToken(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeAPIKeyStore{
+		keys: map[string]apikey.APIKey{
+			hashAPIKey("gak_introspect"): {
+				ID:        1,
+				Name:      "gateway",
+				Scopes:    []string{apikey.ScopeIntrospect},
+				ExpiresAt: time.Now().Add(time.Hour),
+			},
+		},
+	}
+	c := New(ctx, "secret", time.Hour, Keys{Admin: "a", Editor: "e", Viewer: "v"}, Stores{APIKeys: store})
+
+	editorToken, _, err := c.CreateRoleToken(ctx, EditorRole, "e")
+	if err != nil {
+		t.Fatalf("Client.CreateRoleToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		wantActive    bool
+		wantTokenType string
+		wantScope     string
+	}{
+		{
+			name:          "session token",
+			token:         editorToken,
+			wantActive:    true,
+			wantTokenType: session.TokenTypeSession,
+			wantScope:     "articles:read articles:write",
+		},
+		{
+			name:          "api key",
+			token:         "gak_introspect",
+			wantActive:    true,
+			wantTokenType: session.TokenTypeAPIKey,
+			wantScope:     "auth:introspect",
+		},
+		{
+			name:  "unknown api key",
+			token: "gak_unknown",
+		},
+		{
+			name:  "malformed token",
+			token: "not-a-token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.IntrospectToken(ctx, tt.token)
+			if err != nil {
+				t.Fatalf("Client.IntrospectToken() error = %v", err)
+			}
+			if got.Active != tt.wantActive {
+				t.Errorf("Client.IntrospectToken() active = %v, want %v", got.Active, tt.wantActive)
+			}
+			if got.TokenType != tt.wantTokenType {
+				t.Errorf("Client.IntrospectToken() token type = %v, want %v", got.TokenType, tt.wantTokenType)
+			}
+			if got.Scope != tt.wantScope {
+				t.Errorf("Client.IntrospectToken() scope = %v, want %v", got.Scope, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestClient_ReadSession(t *testing.T) {
+	ctx := context.Background()
+	c := New(ctx, "secret", time.Hour, Keys{Admin: "a", Editor: "e", Viewer: "v"}, Stores{})
+
+	token, expires, err := c.CreateRoleToken(ctx, ViewerRole, "v")
+	if err != nil {
+		t.Fatalf("Client.CreateRoleToken() error = %v", err)
+	}
+
+	s, err := c.ReadSession(ctx, token)
+	if err != nil {
+		t.Fatalf("Client.ReadSession() error = %v", err)
+	}
+	if s.ID == "" {
+		t.Errorf("Client.ReadSession() id is empty")
+	}
+	if !slices.Equal(s.Permissions, []string{apikey.ScopeArticlesRead}) {
+		t.Errorf("Client.ReadSession() permissions = %v, want %v", s.Permissions, []string{apikey.ScopeArticlesRead})
+	}
+	if !s.ExpiresAt.Equal(expires.Truncate(time.Second)) {
+		t.Errorf("Client.ReadSession() expires = %v, want %v", s.ExpiresAt, expires.Truncate(time.Second))
+	}
+
+	refreshed, _, err := c.RefreshToken(ctx, token)
+	if err != nil {
+		t.Fatalf("Client.RefreshToken() error = %v", err)
+	}
+
+	refreshedSession, err := c.ReadSession(ctx, refreshed)
+	if err != nil {
+		t.Fatalf("Client.ReadSession() error = %v", err)
+	}
+	if refreshedSession.ID != s.ID {
+		t.Errorf("Client.ReadSession() refreshed id = %v, want %v", refreshedSession.ID, s.ID)
+	}
+}
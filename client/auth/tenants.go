@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/goodleby/golang-app/model/tenant"
+)
+
+// TenantKeyring looks up the keys of the tenants other than the default one,
+// failing for the unknown and suspended ones.
+type TenantKeyring interface {
+	TenantKeys(ctx context.Context, id string) (*tenant.Keys, error)
+}
+
+// SetupTenants lets the callers of the other tenants log in with the role keys
+// of their tenant. Their tokens are signed with the secret of the tenant, so
+// they are only valid for it. The default tenant keeps the keys and secret the
+// client was created with.
+func (c *Client) SetupTenants(keyring TenantKeyring) {
+	c.tenants = keyring
+}
+
+// TenantID returns the tenant the claims were issued for.
+func (c *Claims) TenantID() string {
+	if c.Tenant == "" {
+		return tenant.DefaultID
+	}
+
+	return c.Tenant
+}
+
+// Caller identifies who the claims were issued to, e.g. for the rollouts of
+// feature flags. Role key logins have the role as subject, see tenantSubject,
+// which is shared by everyone with the key, so each of their sessions is a
+// caller of its own.
+func (c *Claims) Caller() string {
+	if c.Subject == c.RoleName || c.Subject == c.RoleName+"@"+c.Tenant {
+		return "session:" + c.ID
+	}
+
+	return c.Subject
+}
+
+// TokenTenant returns the tenant claimed by the token without verifying it,
+// for resolving the tenant of a request before its token can be verified. It
+// returns an empty string if the token doesn't claim one.
+func TokenTenant(tokenString string) string {
+	var claims Claims
+	_, _, err := jwt.NewParser().ParseUnverified(tokenString, &claims)
+	if err != nil {
+		return ""
+	}
+
+	return claims.Tenant
+}
+
+// HashRoleKey returns the hash the role keys of tenants are stored as.
+func HashRoleKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// contextTenant returns the tenant of the context, the default one unless
+// set.
+func contextTenant(ctx context.Context) string {
+	id := tenant.IDFromContext(ctx)
+	if id == "" {
+		return tenant.DefaultID
+	}
+
+	return id
+}
+
+// tenantKeys returns the keys of the tenant of the context, or nil for the
+// default tenant.
+func (c *Client) tenantKeys(ctx context.Context) (*tenant.Keys, error) {
+	id := contextTenant(ctx)
+	if id == tenant.DefaultID {
+		return nil, nil
+	}
+
+	if c.tenants == nil {
+		return nil, fmt.Errorf("tenant %q has no keys, tenants aren't set up", id)
+	}
+
+	keys, err := c.tenants.TenantKeys(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting keys of tenant %q: %v", id, err)
+	}
+
+	return keys, nil
+}
+
+// signingSecret returns the secret the tokens of the tenant of the context
+// are signed with.
+func (c *Client) signingSecret(ctx context.Context) ([]byte, error) {
+	keys, err := c.tenantKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if keys == nil {
+		return c.authSecret, nil
+	}
+
+	if len(keys.SigningSecret) == 0 {
+		return nil, fmt.Errorf("tenant %q has no signing secret", contextTenant(ctx))
+	}
+
+	return keys.SigningSecret, nil
+}
+
+// tenantRoles returns the roles of the tenant of the context. The roles of the
+// other tenants have the access levels of the default ones and the keys of the
+// tenant, roles without a key can't be logged in to.
+func (c *Client) tenantRoles(ctx context.Context) ([]Role, error) {
+	keys, err := c.tenantKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if keys == nil {
+		return c.roles, nil
+	}
+
+	hashes := map[string]*string{
+		AdminRole:  keys.AdminKeyHash,
+		EditorRole: keys.EditorKeyHash,
+		ViewerRole: keys.ViewerKeyHash,
+	}
+
+	var roles []Role
+	for _, role := range c.roles {
+		hash := hashes[role.Name]
+		if hash == nil {
+			continue
+		}
+
+		roles = append(roles, Role{Name: role.Name, AccessLevel: role.AccessLevel, KeyHash: *hash})
+	}
+
+	return roles, nil
+}
+
+// matchesKey reports whether the key is the key of the role, comparing hashes
+// in constant time for the roles of tenants.
+func (r *Role) matchesKey(key string) bool {
+	if r.KeyHash != "" {
+		return subtle.ConstantTimeCompare([]byte(HashRoleKey(key)), []byte(r.KeyHash)) == 1
+	}
+
+	return r.Key == key
+}
+
+// tenantSubject qualifies the subject with the tenant of the context, so the
+// callers of different tenants, e.g. their admins, are never the same subject
+// in TOTP enrollments, rate limits and the audit log.
+func tenantSubject(ctx context.Context, subject string) string {
+	id := contextTenant(ctx)
+	if id == tenant.DefaultID {
+		return subject
+	}
+
+	return subject + "@" + id
+}
+
+// checkTenant fails unless the claims were issued for the tenant of the
+// context.
+func checkTenant(ctx context.Context, claims *Claims) error {
+	if claims.TenantID() != contextTenant(ctx) {
+		return errors.New("auth token is for another tenant")
+	}
+
+	return nil
+}
+
+// defaultTenantOnly fails for the other tenants, for the logins backed by the
+// deployment, e.g. API keys, client certificates and OIDC, which must not
+// grant access to the sites of tenants.
+func defaultTenantOnly(ctx context.Context) error {
+	if id := contextTenant(ctx); id != tenant.DefaultID {
+		return fmt.Errorf("not available to tenant %q", id)
+	}
+
+	return nil
+}
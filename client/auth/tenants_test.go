@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/tenant"
+)
+
+type fakeKeyring map[string]*tenant.Keys
+
+func (k fakeKeyring) TenantKeys(ctx context.Context, id string) (*tenant.Keys, error) {
+	keys, ok := k[id]
+	if !ok {
+		return nil, &tenant.ErrUnknown{ID: id}
+	}
+
+	return keys, nil
+}
+
+func newTenantTestClient() *Client {
+	c := New(context.Background(), "default_secret", time.Hour, Keys{
+		Admin:  "admin_key",
+		Editor: "editor_key",
+		Viewer: "viewer_key",
+	}, Stores{})
+
+	editorKeyHash := HashRoleKey("acme_editor_key")
+	c.SetupTenants(fakeKeyring{
+		"acme": {
+			SigningSecret: []byte("acme_secret"),
+			EditorKeyHash: &editorKeyHash,
+		},
+	})
+
+	return c
+}
+
+func TestClient_CreateRoleToken_tenants(t *testing.T) {
+	c := newTenantTestClient()
+
+	tests := []struct {
+		name     string
+		tenantID string
+		roleName string
+		roleKey  string
+		wantErr  bool
+	}{
+		{
+			name:     "default tenant",
+			roleName: EditorRole,
+			roleKey:  "editor_key",
+		},
+		{
+			name:     "tenant key",
+			tenantID: "acme",
+			roleName: EditorRole,
+			roleKey:  "acme_editor_key",
+		},
+		{
+			name:     "default key in tenant",
+			tenantID: "acme",
+			roleName: EditorRole,
+			roleKey:  "editor_key",
+			wantErr:  true,
+		},
+		{
+			name:     "tenant key in default tenant",
+			roleName: EditorRole,
+			roleKey:  "acme_editor_key",
+			wantErr:  true,
+		},
+		{
+			name:     "tenant role without key",
+			tenantID: "acme",
+			roleName: AdminRole,
+			roleKey:  "",
+			wantErr:  true,
+		},
+		{
+			name:     "unknown tenant",
+			tenantID: "globex",
+			roleName: EditorRole,
+			roleKey:  "acme_editor_key",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.tenantID != "" {
+				ctx = tenant.NewContext(ctx, tt.tenantID)
+			}
+
+			_, _, err := c.CreateRoleToken(ctx, tt.roleName, tt.roleKey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Client.CreateRoleToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if _, ok := err.(*client.ErrUnauthorized); err != nil && !ok {
+				t.Errorf("Client.CreateRoleToken() error = %T, want *client.ErrUnauthorized", err)
+			}
+		})
+	}
+}
+
+func TestClient_ReadTokenClaims_tenants(t *testing.T) {
+	c := newTenantTestClient()
+
+	acmeCtx := tenant.NewContext(context.Background(), "acme")
+	acmeToken, _, err := c.CreateRoleToken(acmeCtx, EditorRole, "acme_editor_key")
+	if err != nil {
+		t.Fatalf("Client.CreateRoleToken() error = %v", err)
+	}
+
+	defaultCtx := tenant.NewContext(context.Background(), tenant.DefaultID)
+	defaultToken, _, err := c.CreateRoleToken(defaultCtx, EditorRole, "editor_key")
+	if err != nil {
+		t.Fatalf("Client.CreateRoleToken() error = %v", err)
+	}
+
+	claims, err := c.ReadTokenClaims(acmeCtx, acmeToken)
+	if err != nil {
+		t.Fatalf("Client.ReadTokenClaims() error = %v", err)
+	}
+	if claims.TenantID() != "acme" || claims.Subject != "editor@acme" {
+		t.Errorf("Client.ReadTokenClaims() tenant = %q, subject = %q, want %q, %q", claims.TenantID(), claims.Subject, "acme", "editor@acme")
+	}
+
+	if got := TokenTenant(acmeToken); got != "acme" {
+		t.Errorf("TokenTenant() = %q, want %q", got, "acme")
+	}
+	if got := TokenTenant(defaultToken); got != "" {
+		t.Errorf("TokenTenant() = %q, want empty", got)
+	}
+
+	_, err = c.ReadTokenClaims(defaultCtx, acmeToken)
+	if err == nil {
+		t.Errorf("Client.ReadTokenClaims() of a tenant token in the default tenant error = nil, want error")
+	}
+
+	_, err = c.ReadTokenClaims(acmeCtx, defaultToken)
+	if err == nil {
+		t.Errorf("Client.ReadTokenClaims() of a default token in a tenant error = nil, want error")
+	}
+}
+
+func TestClaims_Caller(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims Claims
+		want   string
+	}{
+		{
+			name:   "role key login",
+			claims: Claims{RoleName: EditorRole, RegisteredClaims: jwt.RegisteredClaims{ID: "s1", Subject: EditorRole}},
+			want:   "session:s1",
+		},
+		{
+			name:   "tenant role key login",
+			claims: Claims{Tenant: "acme", RoleName: EditorRole, RegisteredClaims: jwt.RegisteredClaims{ID: "s2", Subject: "editor@acme"}},
+			want:   "session:s2",
+		},
+		{
+			name:   "oidc login",
+			claims: Claims{RoleName: EditorRole, RegisteredClaims: jwt.RegisteredClaims{ID: "s3", Subject: "alice"}},
+			want:   "alice",
+		},
+		{
+			name:   "api key",
+			claims: Claims{RoleName: AdminRole, RegisteredClaims: jwt.RegisteredClaims{ID: "7", Subject: APIKeySubject("ci")}},
+			want:   "apikey:ci",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.claims.Caller(); got != tt.want {
+				t.Errorf("Claims.Caller() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/totp"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type TOTPStore interface {
+	SelectTOTPEnrollment(ctx context.Context, subject string) (*totp.Enrollment, error)
+	UpsertTOTPEnrollment(ctx context.Context, subject, secret string, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, subject string, step int64) error
+	UseTOTPRecoveryCode(ctx context.Context, subject, codeHash string) error
+}
+
+type totpPolicy struct {
+	store          TOTPStore
+	issuer         string
+	requiredAccess AccessLevel
+}
+
+// required reports whether tokens with the given access level need a verified
+// second factor.
+func (p *totpPolicy) required(access AccessLevel) bool {
+	return p != nil && p.requiredAccess > 0 && access >= p.requiredAccess
+}
+
+// SetupTOTP enables the 2FA policy: tokens for roles at or above
+// requiredAccess don't grant that access until a TOTP code is verified.
+func (c *Client) SetupTOTP(issuer string, requiredAccess AccessLevel) {
+	c.totp.issuer = issuer
+	c.totp.requiredAccess = requiredAccess
+}
+
+// EnrollTOTP generates a new TOTP secret and recovery codes for the subject of
+// the token. A confirmed enrollment can't be replaced. Role key logins have the
+// role as their subject, so everyone logging in with a role key shares the
+// role's enrollment, while OIDC users enroll individually.
+func (c *Client) EnrollTOTP(ctx context.Context, tokenString string) (*totp.Setup, error) {
+	ctx, span := tracing.StartSpan(ctx, "EnrollTOTP")
+	defer span.End()
+
+	claims, err := c.parseTokenClaims(ctx, tokenString)
+	if err != nil {
+		return nil, &client.ErrUnauthorized{Err: fmt.Errorf("error parsing token claims: %v", err)}
+	}
+
+	if claims.Subject == "" {
+		return nil, &client.ErrUnauthorized{Err: errors.New("token has no subject to enroll")}
+	}
+
+	secret := make([]byte, totpSecretSize)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return nil, fmt.Errorf("error generating totp secret: %v", err)
+	}
+	encodedSecret := totpEncoding.EncodeToString(secret)
+
+	recoveryCodes := make([]string, totpRecoveryCodes)
+	recoveryCodeHashes := make([]string, totpRecoveryCodes)
+	for i := range recoveryCodes {
+		recoveryCodes[i], err = generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("error generating recovery code: %v", err)
+		}
+		recoveryCodeHashes[i] = hashAPIKey(recoveryCodes[i])
+	}
+
+	err = c.totp.store.UpsertTOTPEnrollment(ctx, claims.Subject, encodedSecret, recoveryCodeHashes)
+	if err != nil {
+		switch err.(type) {
+		case *client.ErrConflict:
+			return nil, err
+		default:
+			return nil, fmt.Errorf("error upserting totp enrollment: %v", err)
+		}
+	}
+
+	return &totp.Setup{
+		Secret:          encodedSecret,
+		ProvisioningURI: totpProvisioningURI(c.totp.issuer, claims.Subject, encodedSecret),
+		RecoveryCodes:   recoveryCodes,
+	}, nil
+}
+
+// VerifyTOTP checks a TOTP or recovery code for the subject of the token and
+// issues a new token with the full access of the role. The first valid TOTP
+// code confirms a pending enrollment.
+func (c *Client) VerifyTOTP(ctx context.Context, tokenString, code string) (string, time.Time, error) {
+	ctx, span := tracing.StartSpan(ctx, "VerifyTOTP")
+	defer span.End()
+
+	claims, err := c.parseTokenClaims(ctx, tokenString)
+	if err != nil {
+		return "", time.Time{}, &client.ErrUnauthorized{Err: fmt.Errorf("error parsing token claims: %v", err)}
+	}
+
+	enrollment, err := c.totp.store.SelectTOTPEnrollment(ctx, claims.Subject)
+	if err != nil {
+		switch err.(type) {
+		case *client.ErrNotFound:
+			return "", time.Time{}, &client.ErrUnauthorized{Err: errors.New("no totp enrollment for token subject")}
+		default:
+			return "", time.Time{}, fmt.Errorf("error selecting totp enrollment: %v", err)
+		}
+	}
+
+	err = c.checkSecondFactor(ctx, enrollment, code)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	role, err := c.findRoleByName(claims.RoleName)
+	if err != nil {
+		return "", time.Time{}, &client.ErrUnauthorized{Err: err}
+	}
+
+	token, expires, err := c.issueRoleToken(ctx, role, claims.Subject, true)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("error issuing role token: %v", err)
+	}
+
+	return token, expires, nil
+}
+
+func (c *Client) checkSecondFactor(ctx context.Context, enrollment *totp.Enrollment, code string) error {
+	code = strings.TrimSpace(code)
+
+	if len(code) != totpDigits {
+		if !enrollment.Confirmed() {
+			return &client.ErrUnauthorized{Err: errors.New("recovery codes can't confirm an enrollment")}
+		}
+
+		err := c.totp.store.UseTOTPRecoveryCode(ctx, enrollment.Subject, hashAPIKey(strings.ToLower(code)))
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrNotFound:
+				return &client.ErrUnauthorized{Err: errors.New("invalid recovery code")}
+			default:
+				return fmt.Errorf("error using recovery code: %v", err)
+			}
+		}
+
+		return nil
+	}
+
+	secret, err := totpEncoding.DecodeString(enrollment.Secret)
+	if err != nil {
+		return fmt.Errorf("error decoding totp secret: %v", err)
+	}
+
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		return &client.ErrUnauthorized{Err: errors.New("invalid totp code")}
+	}
+
+	// Using the step also prevents replaying a code within its validity window.
+	err = c.totp.store.UseTOTPStep(ctx, enrollment.Subject, step)
+	if err != nil {
+		switch err.(type) {
+		case *client.ErrNotFound:
+			return &client.ErrUnauthorized{Err: errors.New("totp code has already been used")}
+		default:
+			return fmt.Errorf("error using totp step: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// validateTOTP checks the code against the current time step and one step of
+// clock skew either side, returning the matched step.
+func validateTOTP(secret []byte, code string, now time.Time) (int64, bool) {
+	current := now.Unix() / int64(totpPeriod.Seconds())
+
+	for _, step := range []int64{current - 1, current, current + 1} {
+		if subtle.ConstantTimeCompare([]byte(hotp(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// hotp implements RFC 4226 with HMAC-SHA1, which is what authenticator apps
+// support universally.
+func hotp(secret []byte, counter int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulus := uint32(1)
+	for range totpDigits {
+		modulus *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, value%modulus)
+}
+
+func totpProvisioningURI(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod.Seconds()))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
+
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 5)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(totpEncoding.EncodeToString(b))
+
+	return code[:4] + "-" + code[4:], nil
+}
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+const totpDigits = 6
+const totpPeriod = 30 * time.Second
+const totpSecretSize = 20
+const totpRecoveryCodes = 10
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/totp"
+)
+
+func Test_validateTOTP(t *testing.T) {
+	// Secret and expected codes come from RFC 6238 appendix B (SHA1), truncated
+	// to 6 digits.
+	secret := []byte("12345678901234567890")
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		code   string
+		wantOk bool
+	}{
+		{
+			name:   "rfc vector at 59s",
+			now:    time.Unix(59, 0),
+			code:   "287082",
+			wantOk: true,
+		},
+		{
+			name:   "rfc vector at 1111111109s",
+			now:    time.Unix(1111111109, 0),
+			code:   "081804",
+			wantOk: true,
+		},
+		{
+			name:   "rfc vector at 1234567890s",
+			now:    time.Unix(1234567890, 0),
+			code:   "005924",
+			wantOk: true,
+		},
+		{
+			name:   "one step of clock skew is accepted",
+			now:    time.Unix(59+30, 0),
+			code:   "287082",
+			wantOk: true,
+		},
+		{
+			name:   "two steps of clock skew are rejected",
+			now:    time.Unix(59+60, 0),
+			code:   "287082",
+			wantOk: false,
+		},
+		{
+			name:   "wrong code",
+			now:    time.Unix(59, 0),
+			code:   "000000",
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := validateTOTP(secret, tt.code, tt.now)
+			if ok != tt.wantOk {
+				t.Errorf("validateTOTP() ok = %v, want %v", ok, tt.wantOk)
+			}
+		})
+	}
+}
+
+type fakeTOTPStore struct {
+	enrollment totp.Enrollment
+}
+
+func (s *fakeTOTPStore) SelectTOTPEnrollment(ctx context.Context, subject string) (*totp.Enrollment, error) {
+	if subject != s.enrollment.Subject {
+		return nil, &client.ErrNotFound{Err: errors.New("not found")}
+	}
+
+	return &s.enrollment, nil
+}
+
+func (s *fakeTOTPStore) UpsertTOTPEnrollment(ctx context.Context, subject, secret string, recoveryCodeHashes []string) error {
+	return errors.New("not implemented")
+}
+
+func (s *fakeTOTPStore) UseTOTPStep(ctx context.Context, subject string, step int64) error {
+	if s.enrollment.LastUsedStep != nil && *s.enrollment.LastUsedStep >= step {
+		return &client.ErrNotFound{Err: errors.New("step already used")}
+	}
+	s.enrollment.LastUsedStep = &step
+
+	return nil
+}
+
+func (s *fakeTOTPStore) UseTOTPRecoveryCode(ctx context.Context, subject, codeHash string) error {
+	return &client.ErrNotFound{Err: errors.New("not found")}
+}
+
+func TestClient_VerifyTOTP(t *testing.T) {
+	ctx := context.Background()
+	secret := []byte("12345678901234567890")
+	store := &fakeTOTPStore{enrollment: totp.Enrollment{
+		Subject: AdminRole,
+		Secret:  totpEncoding.EncodeToString(secret),
+	}}
+
+	c := New(ctx, "secret", time.Hour, Keys{Admin: "admin_key", Editor: "editor_key", Viewer: "viewer_key"}, Stores{TOTP: store})
+	c.SetupTOTP("test", AdminAccess)
+
+	token, _, err := c.CreateRoleToken(ctx, AdminRole, "admin_key")
+	if err != nil {
+		t.Fatalf("Client.CreateRoleToken() error = %v", err)
+	}
+
+	claims, err := c.ReadTokenClaims(ctx, token)
+	if err != nil {
+		t.Fatalf("Client.ReadTokenClaims() error = %v", err)
+	}
+	if claims.AccessLevel >= AdminAccess {
+		t.Fatalf("Client.ReadTokenClaims() access before 2fa = %v, want below %v", claims.AccessLevel, AdminAccess)
+	}
+
+	code := hotp(secret, time.Now().Unix()/int64(totpPeriod.Seconds()))
+
+	verifiedToken, _, err := c.VerifyTOTP(ctx, token, code)
+	if err != nil {
+		t.Fatalf("Client.VerifyTOTP() error = %v", err)
+	}
+
+	claims, err = c.ReadTokenClaims(ctx, verifiedToken)
+	if err != nil {
+		t.Fatalf("Client.ReadTokenClaims() error = %v", err)
+	}
+	if claims.AccessLevel != AdminAccess {
+		t.Fatalf("Client.ReadTokenClaims() access after 2fa = %v, want %v", claims.AccessLevel, AdminAccess)
+	}
+
+	_, _, err = c.VerifyTOTP(ctx, token, code)
+	if _, ok := err.(*client.ErrUnauthorized); !ok {
+		t.Fatalf("Client.VerifyTOTP() replayed code error = %v, want unauthorized", err)
+	}
+}
@@ -0,0 +1,275 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/apikey"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type APIKeyStmt struct {
+	SelectAll    *sqlx.Stmt
+	SelectByHash *sqlx.NamedStmt
+	Insert       *sqlx.NamedStmt
+	UpdateHash   *sqlx.NamedStmt
+	Revoke       *sqlx.NamedStmt
+	Touch        *sqlx.NamedStmt
+}
+
+func (apiKeyStmt *APIKeyStmt) Close() error {
+	errs := []error{}
+
+	err := apiKeyStmt.SelectAll.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select all api keys statement: %v", err))
+	}
+
+	err = apiKeyStmt.SelectByHash.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select api key by hash statement: %v", err))
+	}
+
+	err = apiKeyStmt.Insert.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing insert api key statement: %v", err))
+	}
+
+	err = apiKeyStmt.UpdateHash.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing update api key hash statement: %v", err))
+	}
+
+	err = apiKeyStmt.Revoke.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing revoke api key statement: %v", err))
+	}
+
+	err = apiKeyStmt.Touch.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing touch api key statement: %v", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareAPIKeyStatements(ctx context.Context) (*APIKeyStmt, error) {
+	var apiKeyStmt APIKeyStmt
+	var err error
+
+	apiKeyStmt.SelectAll, err = c.prepareSelectAllAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select all api keys statement: %v", err)
+	}
+
+	apiKeyStmt.SelectByHash, err = c.prepareSelectAPIKeyByHash(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select api key by hash statement: %v", err)
+	}
+
+	apiKeyStmt.Insert, err = c.prepareInsertAPIKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing insert api key statement: %v", err)
+	}
+
+	apiKeyStmt.UpdateHash, err = c.prepareUpdateAPIKeyHash(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing update api key hash statement: %v", err)
+	}
+
+	apiKeyStmt.Revoke, err = c.prepareRevokeAPIKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing revoke api key statement: %v", err)
+	}
+
+	apiKeyStmt.Touch, err = c.prepareTouchAPIKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing touch api key statement: %v", err)
+	}
+
+	return &apiKeyStmt, nil
+}
+
+const apiKeyColumns = "id, name, prefix, scopes, expires_at, last_used_at, created_at, revoked_at"
+
+func (c *Client) prepareSelectAllAPIKeys(ctx context.Context) (*sqlx.Stmt, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys ORDER BY id"
+	return c.DB.PreparexContext(ctx, query)
+}
+
+func (c *Client) SelectAllAPIKeys(ctx context.Context) ([]apikey.APIKey, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectAllAPIKeys")
+	defer span.End()
+
+	apiKeys := []apikey.APIKey{}
+	err := c.APIKeyStmt.SelectAll.SelectContext(ctx, &apiKeys)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting api keys: %v", err)
+	}
+
+	return apiKeys, nil
+}
+
+func (c *Client) prepareSelectAPIKeyByHash(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE key_hash = :key_hash"
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) SelectAPIKeyByHash(ctx context.Context, hash string) (*apikey.APIKey, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectAPIKeyByHash")
+	defer span.End()
+
+	args := struct {
+		Hash string `db:"key_hash"`
+	}{
+		Hash: hash,
+	}
+
+	var apiKey apikey.APIKey
+	err := c.APIKeyStmt.SelectByHash.GetContext(ctx, &apiKey, args)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, &client.ErrNotFound{Err: fmt.Errorf("api key not found: %v", err)}
+		default:
+			return nil, fmt.Errorf("error selecting api key by hash: %v", err)
+		}
+	}
+
+	return &apiKey, nil
+}
+
+func (c *Client) prepareInsertAPIKey(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at)
+						VALUES (:name, :prefix, :key_hash, :scopes, :expires_at)
+						RETURNING ` + apiKeyColumns
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) InsertAPIKey(ctx context.Context, payload apikey.Payload, prefix, hash string) (*apikey.APIKey, error) {
+	ctx, span := tracing.StartSpan(ctx, "InsertAPIKey")
+	defer span.End()
+
+	args := struct {
+		Name      string         `db:"name"`
+		Scopes    pq.StringArray `db:"scopes"`
+		ExpiresAt time.Time      `db:"expires_at"`
+		Prefix    string         `db:"prefix"`
+		Hash      string         `db:"key_hash"`
+	}{
+		Name:      payload.Name,
+		Scopes:    payload.Scopes,
+		ExpiresAt: payload.ExpiresAt,
+		Prefix:    prefix,
+		Hash:      hash,
+	}
+
+	var apiKey apikey.APIKey
+	err := c.APIKeyStmt.Insert.GetContext(ctx, &apiKey, args)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting an api key: %v", err)
+	}
+
+	return &apiKey, nil
+}
+
+func (c *Client) prepareUpdateAPIKeyHash(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `UPDATE api_keys
+						SET prefix = :prefix, key_hash = :key_hash, last_used_at = NULL
+						WHERE id = :id AND revoked_at IS NULL
+						RETURNING ` + apiKeyColumns
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) UpdateAPIKeyHash(ctx context.Context, id int, prefix, hash string) (*apikey.APIKey, error) {
+	ctx, span := tracing.StartSpan(ctx, "UpdateAPIKeyHash")
+	defer span.End()
+
+	args := struct {
+		ID     int    `db:"id"`
+		Prefix string `db:"prefix"`
+		Hash   string `db:"key_hash"`
+	}{
+		ID:     id,
+		Prefix: prefix,
+		Hash:   hash,
+	}
+
+	var apiKey apikey.APIKey
+	err := c.APIKeyStmt.UpdateHash.GetContext(ctx, &apiKey, args)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, &client.ErrNotFound{Err: fmt.Errorf("no active api key with id %d to update: %v", id, err)}
+		default:
+			return nil, fmt.Errorf("error updating api key with id %d: %v", id, err)
+		}
+	}
+
+	return &apiKey, nil
+}
+
+func (c *Client) prepareRevokeAPIKey(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `UPDATE api_keys SET revoked_at = now() WHERE id = :id AND revoked_at IS NULL`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) RevokeAPIKey(ctx context.Context, id int) error {
+	ctx, span := tracing.StartSpan(ctx, "RevokeAPIKey")
+	defer span.End()
+
+	args := struct {
+		ID int `db:"id"`
+	}{
+		ID: id,
+	}
+
+	result, err := c.APIKeyStmt.Revoke.ExecContext(ctx, args)
+	if err != nil {
+		return fmt.Errorf("error revoking api key with id %d: %v", id, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting affected rows: %v", err)
+	}
+
+	if rows == 0 {
+		return &client.ErrNotFound{Err: fmt.Errorf("no active api key with id %d to revoke", id)}
+	}
+
+	return nil
+}
+
+func (c *Client) prepareTouchAPIKey(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `UPDATE api_keys SET last_used_at = now() WHERE id = :id`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) TouchAPIKey(ctx context.Context, id int) error {
+	ctx, span := tracing.StartSpan(ctx, "TouchAPIKey")
+	defer span.End()
+
+	args := struct {
+		ID int `db:"id"`
+	}{
+		ID: id,
+	}
+
+	_, err := c.APIKeyStmt.Touch.ExecContext(ctx, args)
+	if err != nil {
+		return fmt.Errorf("error touching api key with id %d: %v", id, err)
+	}
+
+	return nil
+}
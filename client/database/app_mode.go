@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/model/mode"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type ModeStmt struct {
+	Select *sqlx.NamedStmt
+	Update *sqlx.NamedStmt
+}
+
+func (modeStmt *ModeStmt) Close() error {
+	errs := []error{}
+
+	err := modeStmt.Select.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select app mode statement: %v", err))
+	}
+
+	err = modeStmt.Update.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing update app mode statement: %v", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareModeStatements(ctx context.Context) (*ModeStmt, error) {
+	var modeStmt ModeStmt
+	var err error
+
+	modeStmt.Select, err = c.prepareSelectMode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select app mode statement: %v", err)
+	}
+
+	modeStmt.Update, err = c.prepareUpdateMode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing update app mode statement: %v", err)
+	}
+
+	return &modeStmt, nil
+}
+
+func (c *Client) prepareSelectMode(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT mode, reason, updated_at FROM app_mode`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) SelectMode(ctx context.Context) (*mode.State, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectMode")
+	defer span.End()
+
+	var state mode.State
+	err := c.ModeStmt.Select.GetContext(ctx, &state, struct{}{})
+	if err != nil {
+		return nil, fmt.Errorf("error selecting app mode: %v", err)
+	}
+
+	return &state, nil
+}
+
+func (c *Client) prepareUpdateMode(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `UPDATE app_mode SET mode = :mode, reason = :reason, updated_at = now()
+						FROM (SELECT id, mode, reason, updated_at FROM app_mode FOR UPDATE) AS old
+						WHERE app_mode.id = old.id
+						RETURNING app_mode.mode, app_mode.reason, app_mode.updated_at,
+							old.mode AS "old.mode", old.reason AS "old.reason", old.updated_at AS "old.updated_at"`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// UpdateMode switches the mode of all replicas, they are notified on the
+// app_mode channel.
+func (c *Client) UpdateMode(ctx context.Context, payload mode.Payload) (*mode.State, error) {
+	ctx, span := tracing.StartSpan(ctx, "UpdateMode")
+	defer span.End()
+
+	args := struct {
+		Mode   string `db:"mode"`
+		Reason string `db:"reason"`
+	}{
+		Mode:   string(payload.Mode),
+		Reason: payload.Reason,
+	}
+
+	var updated struct {
+		mode.State
+		Old mode.State `db:"old"`
+	}
+	err := c.ModeStmt.Update.GetContext(ctx, &updated, args)
+	if err != nil {
+		return nil, fmt.Errorf("error updating app mode: %v", err)
+	}
+
+	audit.EventFromContext(ctx).SetChange("app_mode", updated.Old, updated.State)
+
+	return &updated.State, nil
+}
+
+// WatchMode calls fn with the mode whenever Postgres notifies about a change
+// on the app_mode channel, and regularly in case a notification was lost
+// while reconnecting, until the context is done.
+func (c *Client) WatchMode(ctx context.Context, fn func(mode.State)) error {
+	listener := pq.NewListener(c.connectionString, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn(fmt.Sprintf("App mode listener: %v", err))
+		}
+	})
+	defer listener.Close()
+
+	err := listener.Listen(modeChannel)
+	if err != nil {
+		return fmt.Errorf("error listening to %s: %v", modeChannel, err)
+	}
+
+	ticker := time.NewTicker(modePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-listener.Notify:
+		case <-ticker.C:
+		}
+
+		state, err := c.SelectMode(ctx)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error reading app mode: %v", err))
+			continue
+		}
+
+		fn(*state)
+	}
+}
+
+const (
+	modeChannel      string        = "app_mode"
+	modePollInterval time.Duration = 10 * time.Second
+)
@@ -0,0 +1,291 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/model/tenant"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type ArticleEventStmt struct {
+	SelectAfter  *sqlx.NamedStmt
+	SelectRecent *sqlx.NamedStmt
+	Prune        *sqlx.NamedStmt
+}
+
+func (articleEventStmt *ArticleEventStmt) Close() error {
+	errs := []error{}
+
+	err := articleEventStmt.SelectAfter.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select article events after statement: %v", err))
+	}
+
+	err = articleEventStmt.SelectRecent.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select recent article events statement: %v", err))
+	}
+
+	err = articleEventStmt.Prune.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing prune article events statement: %v", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareArticleEventStatements(ctx context.Context) (*ArticleEventStmt, error) {
+	var articleEventStmt ArticleEventStmt
+	var err error
+
+	articleEventStmt.SelectAfter, err = c.prepareSelectArticleEventsAfter(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select article events after statement: %v", err)
+	}
+
+	articleEventStmt.SelectRecent, err = c.prepareSelectRecentArticleEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select recent article events statement: %v", err)
+	}
+
+	articleEventStmt.Prune, err = c.preparePruneArticleEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing prune article events statement: %v", err)
+	}
+
+	return &articleEventStmt, nil
+}
+
+// articleEventRow is an article event as stored, the article is the JSON of
+// the row.
+type articleEventRow struct {
+	ID         int64          `db:"id"`
+	Type       string         `db:"type"`
+	TenantID   sql.NullString `db:"tenant_id"`
+	Article    []byte         `db:"article"`
+	OccurredAt time.Time      `db:"occurred_at"`
+}
+
+func (row *articleEventRow) toEvent() (article.Event, error) {
+	event := article.Event{
+		ID:         row.ID,
+		Type:       row.Type,
+		TenantID:   row.TenantID.String,
+		OccurredAt: row.OccurredAt,
+	}
+
+	// The events recorded before tenants existed are of the default one.
+	if !row.TenantID.Valid {
+		event.TenantID = tenant.DefaultID
+	}
+
+	err := json.Unmarshal(row.Article, &event.Article)
+	if err != nil {
+		return article.Event{}, fmt.Errorf("error decoding article of event %d: %v", row.ID, err)
+	}
+
+	return event, nil
+}
+
+func toArticleEvents(rows []articleEventRow) ([]article.Event, error) {
+	events := make([]article.Event, 0, len(rows))
+	for _, row := range rows {
+		event, err := row.toEvent()
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (c *Client) prepareSelectArticleEventsAfter(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT id, type, tenant_id, article, occurred_at FROM article_events
+						WHERE id > :after
+						ORDER BY id
+						LIMIT :limit`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// SelectArticleEventsAfter selects up to limit events following the event with
+// the ID, oldest first.
+func (c *Client) SelectArticleEventsAfter(ctx context.Context, afterID int64, limit int) ([]article.Event, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectArticleEventsAfter")
+	defer span.End()
+
+	args := struct {
+		After int64 `db:"after"`
+		Limit int   `db:"limit"`
+	}{
+		After: afterID,
+		Limit: limit,
+	}
+
+	rows := []articleEventRow{}
+	err := c.ArticleEventStmt.SelectAfter.SelectContext(ctx, &rows, args)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting article events after %d: %v", afterID, err)
+	}
+
+	return toArticleEvents(rows)
+}
+
+func (c *Client) prepareSelectRecentArticleEvents(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT id, type, tenant_id, article, occurred_at FROM (
+							SELECT id, type, tenant_id, article, occurred_at FROM article_events ORDER BY id DESC LIMIT :limit
+						) AS recent
+						ORDER BY id`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// SelectRecentArticleEvents selects the last limit events, oldest first.
+func (c *Client) SelectRecentArticleEvents(ctx context.Context, limit int) ([]article.Event, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectRecentArticleEvents")
+	defer span.End()
+
+	args := struct {
+		Limit int `db:"limit"`
+	}{
+		Limit: limit,
+	}
+
+	rows := []articleEventRow{}
+	err := c.ArticleEventStmt.SelectRecent.SelectContext(ctx, &rows, args)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting recent article events: %v", err)
+	}
+
+	return toArticleEvents(rows)
+}
+
+func (c *Client) preparePruneArticleEvents(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `DELETE FROM article_events WHERE occurred_at < :before`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// PruneArticleEvents deletes the events that occurred before the time.
+func (c *Client) PruneArticleEvents(ctx context.Context, before time.Time) error {
+	ctx, span := tracing.StartSpan(ctx, "PruneArticleEvents")
+	defer span.End()
+
+	args := struct {
+		Before time.Time `db:"before"`
+	}{
+		Before: before,
+	}
+
+	_, err := c.ArticleEventStmt.Prune.ExecContext(ctx, args)
+	if err != nil {
+		return fmt.Errorf("error pruning article events: %v", err)
+	}
+
+	return nil
+}
+
+// WatchArticleEvents calls fn with every event following the event with the
+// ID, in order, until the context is done. New events are read when Postgres
+// notifies about them on the article_events channel, and regularly in case a
+// notification was lost while reconnecting.
+func (c *Client) WatchArticleEvents(ctx context.Context, afterID int64, fn func(article.Event)) error {
+	listener := pq.NewListener(c.connectionString, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Article events listener: %v", err))
+		}
+	})
+	defer listener.Close()
+
+	err := listener.Listen(articleEventsChannel)
+	if err != nil {
+		return fmt.Errorf("error listening to %s: %v", articleEventsChannel, err)
+	}
+
+	ticker := time.NewTicker(articleEventsPollInterval)
+	defer ticker.Stop()
+
+	var gap *articleEventsGap
+	for {
+		afterID, gap, err = c.readArticleEvents(ctx, afterID, gap, fn)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error reading article events: %v", err))
+		}
+
+		// Gaps of rolled back transactions are never filled or notified about.
+		var gapTimeout <-chan time.Time
+		if gap != nil {
+			gapTimeout = time.After(time.Until(gap.since.Add(articleEventsGapTimeout)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-listener.Notify:
+		case <-ticker.C:
+		case <-gapTimeout:
+		}
+	}
+}
+
+// articleEventsGap is the first missing ID after the last read event. Event
+// IDs are taken in insert order but become visible in commit order, so the
+// event may still be committed, or never be if its transaction rolled back.
+type articleEventsGap struct {
+	id    int64
+	since time.Time
+}
+
+// readArticleEvents calls fn with the events after the ID and returns the ID
+// of the last one. It stops at a gap in the IDs until the gap is filled or
+// older than articleEventsGapTimeout, so events are read in order.
+func (c *Client) readArticleEvents(ctx context.Context, afterID int64, gap *articleEventsGap, fn func(article.Event)) (int64, *articleEventsGap, error) {
+	for {
+		events, err := c.SelectArticleEventsAfter(ctx, afterID, articleEventsBatchSize)
+		if err != nil {
+			return afterID, gap, err
+		}
+
+		for _, event := range events {
+			// The events before the first one watched are unknown.
+			if afterID > 0 && event.ID > afterID+1 {
+				if gap == nil || gap.id != afterID+1 {
+					gap = &articleEventsGap{id: afterID + 1, since: time.Now()}
+				}
+				if time.Since(gap.since) < articleEventsGapTimeout {
+					return afterID, gap, nil
+				}
+
+				slog.Warn(fmt.Sprintf("Skipping article events %d to %d, they weren't committed within %v", afterID+1, event.ID-1, articleEventsGapTimeout))
+			}
+
+			gap = nil
+			fn(event)
+			afterID = event.ID
+		}
+
+		if len(events) < articleEventsBatchSize {
+			return afterID, gap, nil
+		}
+	}
+}
+
+const (
+	articleEventsChannel      string        = "article_events"
+	articleEventsBatchSize    int           = 100
+	articleEventsPollInterval time.Duration = 30 * time.Second
+	// articleEventsGapTimeout bounds the wait for the events of concurrent
+	// transactions, it must outlast the article transactions.
+	articleEventsGapTimeout time.Duration = 10 * time.Second
+)
@@ -8,16 +8,21 @@ import (
 
 	"github.com/goodleby/golang-app/client"
 	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/model/audit"
 	"github.com/goodleby/golang-app/tracing"
 	"github.com/jmoiron/sqlx"
 )
 
 type ArticleStmt struct {
-	SelectAll *sqlx.Stmt
-	Select    *sqlx.NamedStmt
-	Insert    *sqlx.NamedStmt
-	Delete    *sqlx.NamedStmt
-	Update    *sqlx.NamedStmt
+	SelectAll   *sqlx.NamedStmt
+	Select      *sqlx.NamedStmt
+	Insert      *sqlx.NamedStmt
+	Delete      *sqlx.NamedStmt
+	Update      *sqlx.NamedStmt
+	SelectPage  *sqlx.NamedStmt
+	SelectTags  *sqlx.NamedStmt
+	SelectAfter *sqlx.NamedStmt
+	Count       *sqlx.NamedStmt
 }
 
 func (articleStmt *ArticleStmt) Close() error {
@@ -48,6 +53,26 @@ func (articleStmt *ArticleStmt) Close() error {
 		errs = append(errs, fmt.Errorf("error closing update article statement: %v", err))
 	}
 
+	err = articleStmt.SelectPage.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select articles page statement: %v", err))
+	}
+
+	err = articleStmt.SelectTags.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select article tags statement: %v", err))
+	}
+
+	err = articleStmt.SelectAfter.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select articles after statement: %v", err))
+	}
+
+	err = articleStmt.Count.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing count articles statement: %v", err))
+	}
+
 	if len(errs) > 0 {
 		return errors.Join(errs...)
 	}
@@ -84,20 +109,53 @@ func (c *Client) prepareArticleStatements(ctx context.Context) (*ArticleStmt, er
 		return nil, fmt.Errorf("error preparing update article statement: %v", err)
 	}
 
+	articleStmt.SelectPage, err = c.prepareSelectArticlesPage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select articles page statement: %v", err)
+	}
+
+	articleStmt.SelectTags, err = c.prepareSelectArticleTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select article tags statement: %v", err)
+	}
+
+	articleStmt.SelectAfter, err = c.prepareSelectArticlesAfter(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select articles after statement: %v", err)
+	}
+
+	articleStmt.Count, err = c.prepareCountArticles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing count articles statement: %v", err)
+	}
+
 	return &articleStmt, nil
 }
 
-func (c *Client) prepareSelectAllArticles(ctx context.Context) (*sqlx.Stmt, error) {
-	query := "SELECT id, title, description, body FROM articles"
-	return c.DB.PreparexContext(ctx, query)
+func (c *Client) prepareSelectAllArticles(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := "SELECT id, title, description, body, tags FROM articles WHERE tenant_id = :tenant_id"
+	return c.DB.PrepareNamedContext(ctx, query)
 }
 
 func (c *Client) SelectAllArticles(ctx context.Context) ([]article.Article, error) {
 	ctx, span := tracing.StartSpan(ctx, "SelectAllArticles")
 	defer span.End()
 
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := struct {
+		TenantID string `db:"tenant_id"`
+	}{
+		TenantID: tenantID,
+	}
+
 	articles := []article.Article{}
-	err := c.ArticleStmt.SelectAll.SelectContext(ctx, &articles)
+	err = c.scoped(ctx, tenantID, c.ArticleStmt.SelectAll, func(stmt *sqlx.NamedStmt) error {
+		return stmt.SelectContext(ctx, &articles, args)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error selecting articles: %v", err)
 	}
@@ -106,7 +164,7 @@ func (c *Client) SelectAllArticles(ctx context.Context) ([]article.Article, erro
 }
 
 func (c *Client) prepareSelectArticle(ctx context.Context) (*sqlx.NamedStmt, error) {
-	query := "SELECT id, title, description, body FROM articles WHERE id = :id"
+	query := "SELECT id, title, description, body, tags FROM articles WHERE tenant_id = :tenant_id AND id = :id"
 	return c.DB.PrepareNamedContext(ctx, query)
 }
 
@@ -114,14 +172,23 @@ func (c *Client) SelectArticle(ctx context.Context, id int) (*article.Article, e
 	ctx, span := tracing.StartSpan(ctx, "SelectArticle")
 	defer span.End()
 
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	args := struct {
-		ID int `db:"id"`
+		TenantID string `db:"tenant_id"`
+		ID       int    `db:"id"`
 	}{
-		ID: id,
+		TenantID: tenantID,
+		ID:       id,
 	}
 
 	var article article.Article
-	err := c.ArticleStmt.Select.GetContext(ctx, &article, args)
+	err = c.scoped(ctx, tenantID, c.ArticleStmt.Select, func(stmt *sqlx.NamedStmt) error {
+		return stmt.GetContext(ctx, &article, args)
+	})
 	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
@@ -135,9 +202,9 @@ func (c *Client) SelectArticle(ctx context.Context, id int) (*article.Article, e
 }
 
 func (c *Client) prepareInsertArticle(ctx context.Context) (*sqlx.NamedStmt, error) {
-	query := `INSERT INTO articles (title, description, body)
-	        	VALUES (:title, :description, :body)
-						RETURNING id, title, description, body`
+	query := `INSERT INTO articles (tenant_id, title, description, body, tags)
+	        	VALUES (:tenant_id, :title, :description, :body, COALESCE(CAST(:tags AS TEXT[]), '{}'))
+						RETURNING id, title, description, body, tags`
 	return c.DB.PrepareNamedContext(ctx, query)
 }
 
@@ -145,23 +212,34 @@ func (c *Client) InsertArticle(ctx context.Context, payload article.Payload) (*a
 	ctx, span := tracing.StartSpan(ctx, "InsertArticle")
 	defer span.End()
 
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	args := struct {
 		article.Payload
+		TenantID string `db:"tenant_id"`
 	}{
-		Payload: payload,
+		Payload:  payload,
+		TenantID: tenantID,
 	}
 
 	var article article.Article
-	err := c.ArticleStmt.Insert.GetContext(ctx, &article, args)
+	err = c.scoped(ctx, tenantID, c.ArticleStmt.Insert, func(stmt *sqlx.NamedStmt) error {
+		return stmt.GetContext(ctx, &article, args)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error inserting an article: %v", err)
 	}
 
+	audit.EventFromContext(ctx).SetChange(articleTarget(article.ID), nil, article)
+
 	return &article, nil
 }
 
 func (c *Client) prepareDeleteArticle(ctx context.Context) (*sqlx.NamedStmt, error) {
-	query := `DELETE FROM articles WHERE id = :id`
+	query := `DELETE FROM articles WHERE tenant_id = :tenant_id AND id = :id RETURNING id, title, description, body, tags`
 	return c.DB.PrepareNamedContext(ctx, query)
 }
 
@@ -169,34 +247,46 @@ func (c *Client) DeleteArticle(ctx context.Context, id int) error {
 	ctx, span := tracing.StartSpan(ctx, "DeleteArticle")
 	defer span.End()
 
-	args := struct {
-		ID int `db:"id"`
-	}{
-		ID: id,
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return err
 	}
 
-	result, err := c.ArticleStmt.Delete.ExecContext(ctx, args)
-	if err != nil {
-		return fmt.Errorf("error deleting article with id %d: %v", id, err)
+	args := struct {
+		TenantID string `db:"tenant_id"`
+		ID       int    `db:"id"`
+	}{
+		TenantID: tenantID,
+		ID:       id,
 	}
 
-	rows, err := result.RowsAffected()
+	var deleted article.Article
+	err = c.scoped(ctx, tenantID, c.ArticleStmt.Delete, func(stmt *sqlx.NamedStmt) error {
+		return stmt.GetContext(ctx, &deleted, args)
+	})
 	if err != nil {
-		return fmt.Errorf("error getting affected rows: %v", err)
+		switch err {
+		case sql.ErrNoRows:
+			return &client.ErrNotFound{Err: fmt.Errorf("no article with id %d to delete", id)}
+		default:
+			return fmt.Errorf("error deleting article with id %d: %v", id, err)
+		}
 	}
 
-	if rows == 0 {
-		return &client.ErrNotFound{Err: fmt.Errorf("no article with id %d to delete", id)}
-	}
+	audit.EventFromContext(ctx).SetChange(articleTarget(id), deleted, nil)
 
 	return nil
 }
 
 func (c *Client) prepareUpdateArticle(ctx context.Context) (*sqlx.NamedStmt, error) {
+	// The old row is selected in the same statement so the audit log gets the
+	// state the update actually replaced. Null tags keep the current ones.
 	query := `UPDATE articles
-						SET title = :title, description = :description, body = :body
-						WHERE id = :id
-						RETURNING id, title, description, body`
+						SET title = :title, description = :description, body = :body, tags = COALESCE(:tags, old.tags)
+						FROM (SELECT id, title, description, body, tags FROM articles WHERE tenant_id = :tenant_id AND id = :id FOR UPDATE) AS old
+						WHERE articles.id = old.id
+						RETURNING articles.id, articles.title, articles.description, articles.body, articles.tags,
+							old.id AS "old.id", old.title AS "old.title", old.description AS "old.description", old.body AS "old.body", old.tags AS "old.tags"`
 	return c.DB.PrepareNamedContext(ctx, query)
 }
 
@@ -204,16 +294,28 @@ func (c *Client) UpdateArticle(ctx context.Context, id int, payload article.Payl
 	ctx, span := tracing.StartSpan(ctx, "UpdateArticle")
 	defer span.End()
 
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	args := struct {
 		article.Payload
-		ID int `db:"id"`
+		TenantID string `db:"tenant_id"`
+		ID       int    `db:"id"`
 	}{
-		Payload: payload,
-		ID:      id,
+		Payload:  payload,
+		TenantID: tenantID,
+		ID:       id,
 	}
 
-	var article article.Article
-	err := c.ArticleStmt.Update.GetContext(ctx, &article, args)
+	var updated struct {
+		article.Article
+		Old article.Article `db:"old"`
+	}
+	err = c.scoped(ctx, tenantID, c.ArticleStmt.Update, func(stmt *sqlx.NamedStmt) error {
+		return stmt.GetContext(ctx, &updated, args)
+	})
 	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
@@ -223,5 +325,158 @@ func (c *Client) UpdateArticle(ctx context.Context, id int, payload article.Payl
 		}
 	}
 
-	return &article, nil
+	audit.EventFromContext(ctx).SetChange(articleTarget(id), updated.Old, updated.Article)
+
+	return &updated.Article, nil
+}
+
+func (c *Client) prepareSelectArticlesPage(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT id, title, description, body, tags FROM articles
+						WHERE tenant_id = :tenant_id AND (:tag = '' OR :tag = ANY(tags))
+						ORDER BY id DESC
+						LIMIT :limit OFFSET :offset`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// SelectArticlesPage selects up to limit articles after skipping offset
+// articles, newest first. Only the articles with the tag are selected, unless
+// it's empty.
+func (c *Client) SelectArticlesPage(ctx context.Context, tag string, offset, limit int) ([]article.Article, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectArticlesPage")
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := struct {
+		TenantID string `db:"tenant_id"`
+		Tag      string `db:"tag"`
+		Offset   int    `db:"offset"`
+		Limit    int    `db:"limit"`
+	}{
+		TenantID: tenantID,
+		Tag:      tag,
+		Offset:   offset,
+		Limit:    limit,
+	}
+
+	articles := []article.Article{}
+	err = c.scoped(ctx, tenantID, c.ArticleStmt.SelectPage, func(stmt *sqlx.NamedStmt) error {
+		return stmt.SelectContext(ctx, &articles, args)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error selecting articles page: %v", err)
+	}
+
+	return articles, nil
+}
+
+func (c *Client) prepareSelectArticleTags(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := "SELECT DISTINCT unnest(tags) AS tag FROM articles WHERE tenant_id = :tenant_id ORDER BY tag"
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// SelectArticleTags selects the tags used by any article, in order.
+func (c *Client) SelectArticleTags(ctx context.Context) ([]string, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectArticleTags")
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := struct {
+		TenantID string `db:"tenant_id"`
+	}{
+		TenantID: tenantID,
+	}
+
+	tags := []string{}
+	err = c.scoped(ctx, tenantID, c.ArticleStmt.SelectTags, func(stmt *sqlx.NamedStmt) error {
+		return stmt.SelectContext(ctx, &tags, args)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error selecting article tags: %v", err)
+	}
+
+	return tags, nil
+}
+
+func (c *Client) prepareSelectArticlesAfter(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT id, title, description, body, tags FROM articles
+						WHERE tenant_id = :tenant_id AND id > :after_id
+						ORDER BY id
+						LIMIT :limit`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// SelectArticlesAfter selects up to limit articles following the article with
+// the ID, in the order of their IDs. Pages are read by their last ID, so they
+// don't shift when articles are added or deleted.
+func (c *Client) SelectArticlesAfter(ctx context.Context, afterID, limit int) ([]article.Article, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectArticlesAfter")
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := struct {
+		TenantID string `db:"tenant_id"`
+		AfterID  int    `db:"after_id"`
+		Limit    int    `db:"limit"`
+	}{
+		TenantID: tenantID,
+		AfterID:  afterID,
+		Limit:    limit,
+	}
+
+	articles := []article.Article{}
+	err = c.scoped(ctx, tenantID, c.ArticleStmt.SelectAfter, func(stmt *sqlx.NamedStmt) error {
+		return stmt.SelectContext(ctx, &articles, args)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error selecting articles after id %d: %v", afterID, err)
+	}
+
+	return articles, nil
+}
+
+func (c *Client) prepareCountArticles(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := "SELECT count(*) FROM articles WHERE tenant_id = :tenant_id"
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) CountArticles(ctx context.Context) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "CountArticles")
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	args := struct {
+		TenantID string `db:"tenant_id"`
+	}{
+		TenantID: tenantID,
+	}
+
+	var count int
+	err = c.scoped(ctx, tenantID, c.ArticleStmt.Count, func(stmt *sqlx.NamedStmt) error {
+		return stmt.GetContext(ctx, &count, args)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error counting articles: %v", err)
+	}
+
+	return count, nil
+}
+
+func articleTarget(id int) string {
+	return fmt.Sprintf("article:%d", id)
 }
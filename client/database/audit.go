@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditStmt struct {
+	Insert *sqlx.NamedStmt
+	Select *sqlx.NamedStmt
+}
+
+func (auditStmt *AuditStmt) Close() error {
+	errs := []error{}
+
+	err := auditStmt.Insert.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing insert audit event statement: %v", err))
+	}
+
+	err = auditStmt.Select.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select audit events statement: %v", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareAuditStatements(ctx context.Context) (*AuditStmt, error) {
+	var auditStmt AuditStmt
+	var err error
+
+	auditStmt.Insert, err = c.prepareInsertAuditEvent(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing insert audit event statement: %v", err)
+	}
+
+	auditStmt.Select, err = c.prepareSelectAuditEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select audit events statement: %v", err)
+	}
+
+	return &auditStmt, nil
+}
+
+func (c *Client) prepareInsertAuditEvent(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `INSERT INTO audit_events (tenant_id, actor, role, action, target, status, ip, user_agent, trace_id, before_digest, after_digest, details)
+						VALUES (NULLIF(:tenant_id, ''), :actor, :role, :action, :target, :status, :ip, :user_agent, :trace_id, :before_digest, :after_digest, :details)`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) RecordAuditEvent(ctx context.Context, event *audit.Event) error {
+	ctx, span := tracing.StartSpan(ctx, "RecordAuditEvent")
+	defer span.End()
+
+	_, err := c.AuditStmt.Insert.ExecContext(ctx, event)
+	if err != nil {
+		return fmt.Errorf("error inserting audit event: %v", err)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareSelectAuditEvents(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT id, occurred_at, COALESCE(tenant_id, '') AS tenant_id, actor, role, action, target, status, ip, user_agent, trace_id, before_digest, after_digest, details
+						FROM audit_events
+						WHERE (:tenant_id = '' OR tenant_id = :tenant_id)
+							AND (:actor = '' OR actor = :actor)
+							AND (:action = '' OR action = :action)
+							AND (:target = '' OR target = :target)
+							AND (CAST(:since AS timestamptz) IS NULL OR occurred_at >= :since)
+							AND (CAST(:until AS timestamptz) IS NULL OR occurred_at < :until)
+							AND (:before_id = 0 OR id < :before_id)
+						ORDER BY id DESC
+						LIMIT NULLIF(:limit, 0)`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) SelectAuditEvents(ctx context.Context, filter audit.Filter) ([]audit.Event, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectAuditEvents")
+	defer span.End()
+
+	events := []audit.Event{}
+	err := c.AuditStmt.Select.SelectContext(ctx, &events, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting audit events: %v", err)
+	}
+
+	return events, nil
+}
+
+// StreamAuditEvents calls fn for every event matching the filter without
+// loading them all in memory, stopping at the first error returned by fn.
+func (c *Client) StreamAuditEvents(ctx context.Context, filter audit.Filter, fn func(event *audit.Event) error) error {
+	ctx, span := tracing.StartSpan(ctx, "StreamAuditEvents")
+	defer span.End()
+
+	rows, err := c.AuditStmt.Select.QueryxContext(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("error querying audit events: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event audit.Event
+		err = rows.StructScan(&event)
+		if err != nil {
+			return fmt.Errorf("error scanning audit event: %v", err)
+		}
+
+		err = fn(&event)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("error iterating audit events: %v", err)
+	}
+
+	return nil
+}
@@ -13,15 +13,35 @@ import (
 )
 
 type Client struct {
-	DB          *sqlx.DB
-	ArticleStmt *ArticleStmt
+	// connectionString is used for the dedicated connections of listeners.
+	connectionString string
+
+	// RowLevelSecurity runs the tenant queries in transactions scoped to the
+	// tenant, for the policies of migration 0011 to apply.
+	RowLevelSecurity bool
+
+	DB               *sqlx.DB
+	ArticleStmt      *ArticleStmt
+	APIKeyStmt       *APIKeyStmt
+	TOTPStmt         *TOTPStmt
+	AuditStmt        *AuditStmt
+	RateLimitStmt    *RateLimitStmt
+	HealthStmt       *HealthStmt
+	IdempotencyStmt  *IdempotencyStmt
+	ArticleEventStmt *ArticleEventStmt
+	WebhookStmt      *WebhookStmt
+	ModeStmt         *ModeStmt
+	FeatureFlagStmt  *FeatureFlagStmt
+	TenantStmt       *TenantStmt
 }
 
 func New(ctx context.Context, creds Credentials) (*Client, error) {
 	var c Client
 	var err error
 
-	c.DB, err = sqlx.ConnectContext(ctx, "postgres", creds.ToConnectionString())
+	c.connectionString = creds.ToConnectionString()
+
+	c.DB, err = sqlx.ConnectContext(ctx, "postgres", c.connectionString)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to database: %v", err)
 	}
@@ -31,6 +51,61 @@ func New(ctx context.Context, creds Credentials) (*Client, error) {
 		return nil, fmt.Errorf("error preparing article statements: %v", err)
 	}
 
+	c.APIKeyStmt, err = c.prepareAPIKeyStatements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing api key statements: %v", err)
+	}
+
+	c.TOTPStmt, err = c.prepareTOTPStatements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing totp statements: %v", err)
+	}
+
+	c.AuditStmt, err = c.prepareAuditStatements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing audit statements: %v", err)
+	}
+
+	c.RateLimitStmt, err = c.prepareRateLimitStatements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing rate limit statements: %v", err)
+	}
+
+	c.IdempotencyStmt, err = c.prepareIdempotencyStatements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing idempotency statements: %v", err)
+	}
+
+	c.ArticleEventStmt, err = c.prepareArticleEventStatements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing article event statements: %v", err)
+	}
+
+	c.WebhookStmt, err = c.prepareWebhookStatements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing webhook statements: %v", err)
+	}
+
+	c.ModeStmt, err = c.prepareModeStatements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing app mode statements: %v", err)
+	}
+
+	c.FeatureFlagStmt, err = c.prepareFeatureFlagStatements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing feature flag statements: %v", err)
+	}
+
+	c.TenantStmt, err = c.prepareTenantStatements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing tenant statements: %v", err)
+	}
+
+	c.HealthStmt, err = c.prepareHealthStatements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing health statements: %v", err)
+	}
+
 	return &c, nil
 }
 
@@ -62,6 +137,61 @@ func (c *Client) Close() error {
 		errs = append(errs, fmt.Errorf("error closing article statements: %v", err))
 	}
 
+	err = c.APIKeyStmt.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing api key statements: %v", err))
+	}
+
+	err = c.TOTPStmt.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing totp statements: %v", err))
+	}
+
+	err = c.AuditStmt.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing audit statements: %v", err))
+	}
+
+	err = c.RateLimitStmt.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing rate limit statements: %v", err))
+	}
+
+	err = c.IdempotencyStmt.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing idempotency statements: %v", err))
+	}
+
+	err = c.ArticleEventStmt.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing article event statements: %v", err))
+	}
+
+	err = c.WebhookStmt.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing webhook statements: %v", err))
+	}
+
+	err = c.ModeStmt.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing app mode statements: %v", err))
+	}
+
+	err = c.FeatureFlagStmt.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing feature flag statements: %v", err))
+	}
+
+	err = c.TenantStmt.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing tenant statements: %v", err))
+	}
+
+	err = c.HealthStmt.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing health statements: %v", err))
+	}
+
 	err = c.DB.Close()
 	if err != nil {
 		errs = append(errs, err)
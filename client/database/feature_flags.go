@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/goodleby/golang-app/featureflag"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/jmoiron/sqlx"
+)
+
+type FeatureFlagStmt struct {
+	SelectAll *sqlx.NamedStmt
+}
+
+func (featureFlagStmt *FeatureFlagStmt) Close() error {
+	errs := []error{}
+
+	err := featureFlagStmt.SelectAll.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select all feature flags statement: %v", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareFeatureFlagStatements(ctx context.Context) (*FeatureFlagStmt, error) {
+	var featureFlagStmt FeatureFlagStmt
+	var err error
+
+	featureFlagStmt.SelectAll, err = c.prepareSelectFeatureFlags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select all feature flags statement: %v", err)
+	}
+
+	return &featureFlagStmt, nil
+}
+
+// featureFlagRow is a feature flag as stored, the rules and variants are JSON.
+type featureFlagRow struct {
+	Key      string `db:"key"`
+	Enabled  bool   `db:"enabled"`
+	Rules    []byte `db:"rules"`
+	Rollout  int    `db:"rollout"`
+	Variants []byte `db:"variants"`
+}
+
+func (row *featureFlagRow) toFlag() (featureflag.Flag, error) {
+	flag := featureflag.Flag{
+		Key:     row.Key,
+		Enabled: row.Enabled,
+		Rollout: row.Rollout,
+	}
+
+	err := json.Unmarshal(row.Rules, &flag.Rules)
+	if err != nil {
+		return featureflag.Flag{}, fmt.Errorf("error decoding rules of feature flag %q: %v", row.Key, err)
+	}
+
+	err = json.Unmarshal(row.Variants, &flag.Variants)
+	if err != nil {
+		return featureflag.Flag{}, fmt.Errorf("error decoding variants of feature flag %q: %v", row.Key, err)
+	}
+
+	return flag, nil
+}
+
+func (c *Client) prepareSelectFeatureFlags(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT key, enabled, rules, rollout, variants FROM feature_flags`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) SelectFeatureFlags(ctx context.Context) ([]featureflag.Flag, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectFeatureFlags")
+	defer span.End()
+
+	rows := []featureFlagRow{}
+	err := c.FeatureFlagStmt.SelectAll.SelectContext(ctx, &rows, struct{}{})
+	if err != nil {
+		return nil, fmt.Errorf("error selecting feature flags: %v", err)
+	}
+
+	flags := make([]featureflag.Flag, 0, len(rows))
+	for _, row := range rows {
+		flag, err := row.toFlag()
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, flag)
+	}
+
+	return flags, nil
+}
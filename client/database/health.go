@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/goodleby/golang-app/health"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/jmoiron/sqlx"
+)
+
+type HealthStmt struct {
+	Select *sqlx.NamedStmt
+}
+
+func (healthStmt *HealthStmt) Close() error {
+	errs := []error{}
+
+	err := healthStmt.Select.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select health statement: %v", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareHealthStatements(ctx context.Context) (*HealthStmt, error) {
+	var healthStmt HealthStmt
+	var err error
+
+	healthStmt.Select, err = c.prepareSelectHealth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select health statement: %v", err)
+	}
+
+	return &healthStmt, nil
+}
+
+func (c *Client) prepareSelectHealth(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT 1`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// RegisterHealthChecks registers the readiness checks of the database.
+func (c *Client) RegisterHealthChecks(registerer health.Registerer) {
+	registerer.Register("database", c.Ping)
+	registerer.Register("database_statements", c.CheckStatements)
+}
+
+// Ping checks that a connection to the database can be established.
+func (c *Client) Ping(ctx context.Context) error {
+	ctx, span := tracing.StartSpan(ctx, "Ping")
+	defer span.End()
+
+	err := c.DB.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("error pinging database: %v", err)
+	}
+
+	return nil
+}
+
+// CheckStatements runs a prepared statement, which fails when the connection
+// pool only holds broken connections.
+func (c *Client) CheckStatements(ctx context.Context) error {
+	ctx, span := tracing.StartSpan(ctx, "CheckStatements")
+	defer span.End()
+
+	var result int
+	err := c.HealthStmt.Select.GetContext(ctx, &result, struct{}{})
+	if err != nil {
+		return fmt.Errorf("error running health statement: %v", err)
+	}
+
+	if result != 1 {
+		return fmt.Errorf("health statement returned %d, want 1", result)
+	}
+
+	return nil
+}
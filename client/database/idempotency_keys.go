@@ -0,0 +1,240 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goodleby/golang-app/idempotency"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/jmoiron/sqlx"
+)
+
+type IdempotencyStmt struct {
+	Begin    *sqlx.NamedStmt
+	Select   *sqlx.NamedStmt
+	Complete *sqlx.NamedStmt
+	Release  *sqlx.NamedStmt
+	Sweep    *sqlx.NamedStmt
+}
+
+func (idempotencyStmt *IdempotencyStmt) Close() error {
+	errs := []error{}
+
+	err := idempotencyStmt.Begin.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing begin idempotent request statement: %v", err))
+	}
+
+	err = idempotencyStmt.Select.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select idempotency key statement: %v", err))
+	}
+
+	err = idempotencyStmt.Complete.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing complete idempotent request statement: %v", err))
+	}
+
+	err = idempotencyStmt.Release.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing release idempotent request statement: %v", err))
+	}
+
+	err = idempotencyStmt.Sweep.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing sweep idempotency keys statement: %v", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareIdempotencyStatements(ctx context.Context) (*IdempotencyStmt, error) {
+	var idempotencyStmt IdempotencyStmt
+	var err error
+
+	idempotencyStmt.Begin, err = c.prepareBeginIdempotentRequest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing begin idempotent request statement: %v", err)
+	}
+
+	idempotencyStmt.Select, err = c.prepareSelectIdempotencyKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select idempotency key statement: %v", err)
+	}
+
+	idempotencyStmt.Complete, err = c.prepareCompleteIdempotentRequest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing complete idempotent request statement: %v", err)
+	}
+
+	idempotencyStmt.Release, err = c.prepareReleaseIdempotentRequest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing release idempotent request statement: %v", err)
+	}
+
+	idempotencyStmt.Sweep, err = c.prepareSweepIdempotencyKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing sweep idempotency keys statement: %v", err)
+	}
+
+	return &idempotencyStmt, nil
+}
+
+// The key is only taken over when it has expired, so concurrent requests with
+// the same key can't both lock it.
+func (c *Client) prepareBeginIdempotentRequest(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `INSERT INTO idempotency_keys AS ik (key, fingerprint, expires_at)
+						VALUES (:key, :fingerprint, now() + CAST(:lock_seconds AS double precision) * interval '1 second')
+						ON CONFLICT (key) DO UPDATE SET
+							fingerprint = EXCLUDED.fingerprint,
+							status = NULL,
+							content_type = NULL,
+							body = NULL,
+							created_at = now(),
+							expires_at = EXCLUDED.expires_at
+						WHERE ik.expires_at <= now()
+						RETURNING key`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) prepareSelectIdempotencyKey(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT fingerprint, status, content_type, body FROM idempotency_keys WHERE key = :key`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) prepareCompleteIdempotentRequest(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `UPDATE idempotency_keys
+						SET status = :status, content_type = :content_type, body = :body,
+							expires_at = now() + CAST(:ttl_seconds AS double precision) * interval '1 second'
+						WHERE key = :key`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) prepareReleaseIdempotentRequest(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `DELETE FROM idempotency_keys WHERE key = :key AND status IS NULL`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) prepareSweepIdempotencyKeys(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at <= now()`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// BeginIdempotentRequest locks the key for the request with the fingerprint.
+// It returns nil if the key was free, otherwise the record of the key.
+func (c *Client) BeginIdempotentRequest(ctx context.Context, key, fingerprint string) (*idempotency.Record, error) {
+	ctx, span := tracing.StartSpan(ctx, "BeginIdempotentRequest")
+	defer span.End()
+
+	args := struct {
+		Key         string  `db:"key"`
+		Fingerprint string  `db:"fingerprint"`
+		LockSeconds float64 `db:"lock_seconds"`
+	}{
+		Key:         key,
+		Fingerprint: fingerprint,
+		LockSeconds: idempotency.LockTTL.Seconds(),
+	}
+
+	var locked string
+	err := c.IdempotencyStmt.Begin.GetContext(ctx, &locked, args)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("error locking idempotency key: %v", err)
+	}
+
+	var row struct {
+		Fingerprint string         `db:"fingerprint"`
+		Status      sql.NullInt32  `db:"status"`
+		ContentType sql.NullString `db:"content_type"`
+		Body        []byte         `db:"body"`
+	}
+	err = c.IdempotencyStmt.Select.GetContext(ctx, &row, args)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Released in the meantime, reported as in progress so the client
+			// retries.
+			return &idempotency.Record{Fingerprint: fingerprint}, nil
+		}
+		return nil, fmt.Errorf("error selecting idempotency key: %v", err)
+	}
+
+	record := idempotency.Record{Fingerprint: row.Fingerprint}
+	if row.Status.Valid {
+		record.Response = &idempotency.Response{
+			Status:      int(row.Status.Int32),
+			ContentType: row.ContentType.String,
+			Body:        row.Body,
+		}
+	}
+
+	return &record, nil
+}
+
+// CompleteIdempotentRequest stores the response of the key for the TTL.
+func (c *Client) CompleteIdempotentRequest(ctx context.Context, key string, response *idempotency.Response, ttl time.Duration) error {
+	ctx, span := tracing.StartSpan(ctx, "CompleteIdempotentRequest")
+	defer span.End()
+
+	args := struct {
+		Key         string  `db:"key"`
+		Status      int     `db:"status"`
+		ContentType string  `db:"content_type"`
+		Body        []byte  `db:"body"`
+		TTLSeconds  float64 `db:"ttl_seconds"`
+	}{
+		Key:         key,
+		Status:      response.Status,
+		ContentType: response.ContentType,
+		Body:        response.Body,
+		TTLSeconds:  ttl.Seconds(),
+	}
+
+	_, err := c.IdempotencyStmt.Complete.ExecContext(ctx, args)
+	if err != nil {
+		return fmt.Errorf("error storing idempotent response: %v", err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotentRequest frees the key, so the request can be retried.
+func (c *Client) ReleaseIdempotentRequest(ctx context.Context, key string) error {
+	ctx, span := tracing.StartSpan(ctx, "ReleaseIdempotentRequest")
+	defer span.End()
+
+	args := struct {
+		Key string `db:"key"`
+	}{
+		Key: key,
+	}
+
+	_, err := c.IdempotencyStmt.Release.ExecContext(ctx, args)
+	if err != nil {
+		return fmt.Errorf("error releasing idempotency key: %v", err)
+	}
+
+	return nil
+}
+
+// SweepIdempotencyKeys deletes the expired keys.
+func (c *Client) SweepIdempotencyKeys(ctx context.Context) error {
+	ctx, span := tracing.StartSpan(ctx, "SweepIdempotencyKeys")
+	defer span.End()
+
+	_, err := c.IdempotencyStmt.Sweep.ExecContext(ctx, struct{}{})
+	if err != nil {
+		return fmt.Errorf("error sweeping idempotency keys: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goodleby/golang-app/ratelimit"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/jmoiron/sqlx"
+)
+
+type RateLimitStmt struct {
+	Take  *sqlx.NamedStmt
+	Sweep *sqlx.NamedStmt
+}
+
+func (rateLimitStmt *RateLimitStmt) Close() error {
+	errs := []error{}
+
+	err := rateLimitStmt.Take.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing take rate limit token statement: %v", err))
+	}
+
+	err = rateLimitStmt.Sweep.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing sweep rate limits statement: %v", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareRateLimitStatements(ctx context.Context) (*RateLimitStmt, error) {
+	var rateLimitStmt RateLimitStmt
+	var err error
+
+	rateLimitStmt.Take, err = c.prepareTakeRateLimitToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing take rate limit token statement: %v", err)
+	}
+
+	rateLimitStmt.Sweep, err = c.prepareSweepRateLimits(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing sweep rate limits statement: %v", err)
+	}
+
+	return &rateLimitStmt, nil
+}
+
+// The bucket is refilled and a token taken in a single statement, so replicas
+// sharing the limit can't race each other.
+func (c *Client) prepareTakeRateLimitToken(ctx context.Context) (*sqlx.NamedStmt, error) {
+	refilled := `LEAST(CAST(:burst AS double precision),
+							rl.tokens + CAST(EXTRACT(EPOCH FROM now() - rl.updated_at) AS double precision) * CAST(:rate AS double precision))`
+	query := `INSERT INTO rate_limits AS rl (key, tokens, allowed, updated_at)
+						VALUES (:key, CAST(:burst AS double precision) - 1, true, now())
+						ON CONFLICT (key) DO UPDATE SET
+							allowed = ` + refilled + ` >= 1,
+							tokens = ` + refilled + ` - CASE WHEN ` + refilled + ` >= 1 THEN 1 ELSE 0 END,
+							updated_at = now()
+						RETURNING tokens, allowed`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// TakeRateLimitToken takes a token from the bucket of the key if there is one.
+func (c *Client) TakeRateLimitToken(ctx context.Context, key string, limit ratelimit.Limit) (*ratelimit.Result, error) {
+	ctx, span := tracing.StartSpan(ctx, "TakeRateLimitToken")
+	defer span.End()
+
+	args := struct {
+		Key   string  `db:"key"`
+		Burst float64 `db:"burst"`
+		Rate  float64 `db:"rate"`
+	}{
+		Key:   key,
+		Burst: float64(limit.Requests),
+		Rate:  limit.Rate(),
+	}
+
+	var bucket struct {
+		Tokens  float64 `db:"tokens"`
+		Allowed bool    `db:"allowed"`
+	}
+	err := c.RateLimitStmt.Take.GetContext(ctx, &bucket, args)
+	if err != nil {
+		return nil, fmt.Errorf("error taking rate limit token for key %q: %v", key, err)
+	}
+
+	return ratelimit.NewResult(limit, bucket.Tokens, bucket.Allowed), nil
+}
+
+func (c *Client) prepareSweepRateLimits(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `DELETE FROM rate_limits WHERE updated_at < :before`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// SweepRateLimits deletes the buckets not used since the time.
+func (c *Client) SweepRateLimits(ctx context.Context, before time.Time) error {
+	ctx, span := tracing.StartSpan(ctx, "SweepRateLimits")
+	defer span.End()
+
+	args := struct {
+		Before time.Time `db:"before"`
+	}{
+		Before: before,
+	}
+
+	_, err := c.RateLimitStmt.Sweep.ExecContext(ctx, args)
+	if err != nil {
+		return fmt.Errorf("error sweeping rate limits: %v", err)
+	}
+
+	return nil
+}
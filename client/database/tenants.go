@@ -0,0 +1,223 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/model/tenant"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/jmoiron/sqlx"
+)
+
+type TenantStmt struct {
+	SelectAll    *sqlx.NamedStmt
+	Insert       *sqlx.NamedStmt
+	UpdateStatus *sqlx.NamedStmt
+}
+
+func (tenantStmt *TenantStmt) Close() error {
+	errs := []error{}
+
+	err := tenantStmt.SelectAll.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select all tenants statement: %v", err))
+	}
+
+	err = tenantStmt.Insert.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing insert tenant statement: %v", err))
+	}
+
+	err = tenantStmt.UpdateStatus.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing update tenant status statement: %v", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareTenantStatements(ctx context.Context) (*TenantStmt, error) {
+	var tenantStmt TenantStmt
+	var err error
+
+	tenantStmt.SelectAll, err = c.prepareSelectTenants(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select all tenants statement: %v", err)
+	}
+
+	tenantStmt.Insert, err = c.prepareInsertTenant(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing insert tenant statement: %v", err)
+	}
+
+	tenantStmt.UpdateStatus, err = c.prepareUpdateTenantStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing update tenant status statement: %v", err)
+	}
+
+	return &tenantStmt, nil
+}
+
+const tenantColumns = `id, name, hosts, status, created_at, updated_at, suspended_at,
+	signing_secret, admin_key_hash, editor_key_hash, viewer_key_hash`
+
+func (c *Client) prepareSelectTenants(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT ` + tenantColumns + ` FROM tenants ORDER BY id`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// SelectTenants selects every tenant with its keys.
+func (c *Client) SelectTenants(ctx context.Context) ([]tenant.Tenant, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectTenants")
+	defer span.End()
+
+	tenants := []tenant.Tenant{}
+	err := c.TenantStmt.SelectAll.SelectContext(ctx, &tenants, struct{}{})
+	if err != nil {
+		return nil, fmt.Errorf("error selecting tenants: %v", err)
+	}
+
+	return tenants, nil
+}
+
+func (c *Client) prepareInsertTenant(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `INSERT INTO tenants (id, name, hosts, signing_secret, admin_key_hash, editor_key_hash, viewer_key_hash)
+						VALUES (:id, :name, COALESCE(CAST(:hosts AS TEXT[]), '{}'), :signing_secret, :admin_key_hash, :editor_key_hash, :viewer_key_hash)
+						ON CONFLICT (id) DO NOTHING
+						RETURNING ` + tenantColumns
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// InsertTenant inserts the tenant, failing with client.ErrConflict if its ID
+// is taken.
+func (c *Client) InsertTenant(ctx context.Context, t tenant.Tenant) (*tenant.Tenant, error) {
+	ctx, span := tracing.StartSpan(ctx, "InsertTenant")
+	defer span.End()
+
+	var inserted tenant.Tenant
+	err := c.TenantStmt.Insert.GetContext(ctx, &inserted, t)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, &client.ErrConflict{Err: fmt.Errorf("tenant %q already exists", t.ID)}
+		default:
+			return nil, fmt.Errorf("error inserting tenant %q: %v", t.ID, err)
+		}
+	}
+
+	audit.EventFromContext(ctx).SetChange(tenantTarget(inserted.ID), nil, inserted)
+
+	return &inserted, nil
+}
+
+func (c *Client) prepareUpdateTenantStatus(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `UPDATE tenants
+						SET status = :status, updated_at = now(),
+							suspended_at = CASE WHEN :status = 'suspended' THEN COALESCE(tenants.suspended_at, now()) END
+						FROM (SELECT ` + tenantColumns + ` FROM tenants WHERE id = :id FOR UPDATE) AS old
+						WHERE tenants.id = old.id
+						RETURNING tenants.id, tenants.name, tenants.hosts, tenants.status, tenants.created_at, tenants.updated_at, tenants.suspended_at,
+							tenants.signing_secret, tenants.admin_key_hash, tenants.editor_key_hash, tenants.viewer_key_hash,
+							old.status AS "old.status", old.updated_at AS "old.updated_at", old.suspended_at AS "old.suspended_at"`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// UpdateTenantStatus suspends or resumes the tenant.
+func (c *Client) UpdateTenantStatus(ctx context.Context, id string, status tenant.Status) (*tenant.Tenant, error) {
+	ctx, span := tracing.StartSpan(ctx, "UpdateTenantStatus")
+	defer span.End()
+
+	args := struct {
+		ID     string        `db:"id"`
+		Status tenant.Status `db:"status"`
+	}{
+		ID:     id,
+		Status: status,
+	}
+
+	var updated struct {
+		tenant.Tenant
+		Old struct {
+			Status      tenant.Status `db:"status"`
+			UpdatedAt   sql.NullTime  `db:"updated_at"`
+			SuspendedAt sql.NullTime  `db:"suspended_at"`
+		} `db:"old"`
+	}
+	err := c.TenantStmt.UpdateStatus.GetContext(ctx, &updated, args)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, &client.ErrNotFound{Err: fmt.Errorf("no tenant %q to update", id)}
+		default:
+			return nil, fmt.Errorf("error updating status of tenant %q: %v", id, err)
+		}
+	}
+
+	old := updated.Tenant
+	old.Status = updated.Old.Status
+	old.UpdatedAt = updated.Old.UpdatedAt.Time
+	old.SuspendedAt = nil
+	if updated.Old.SuspendedAt.Valid {
+		old.SuspendedAt = &updated.Old.SuspendedAt.Time
+	}
+	audit.EventFromContext(ctx).SetChange(tenantTarget(id), old, updated.Tenant)
+
+	return &updated.Tenant, nil
+}
+
+func tenantTarget(id string) string {
+	return fmt.Sprintf("tenant:%s", id)
+}
+
+// tenantID returns the tenant the queries of the context are scoped to. There
+// is no default, so a caller that forgot to resolve the tenant can't read or
+// write the articles of another one.
+func tenantID(ctx context.Context) (string, error) {
+	id := tenant.IDFromContext(ctx)
+	if id == "" {
+		return "", errors.New("no tenant in context")
+	}
+
+	return id, nil
+}
+
+// scoped calls fn with the statement. With row-level security on, it runs in
+// a transaction that sets app.tenant_id for the policies of the tenant
+// tables, so a query missing its tenant filter still can't leak rows.
+func (c *Client) scoped(ctx context.Context, tenantID string, stmt *sqlx.NamedStmt, fn func(stmt *sqlx.NamedStmt) error) error {
+	if !c.RowLevelSecurity {
+		return fn(stmt)
+	}
+
+	tx, err := c.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "SELECT set_config('app.tenant_id', $1, true)", tenantID)
+	if err != nil {
+		return fmt.Errorf("error setting tenant of transaction: %v", err)
+	}
+
+	// The error of fn is returned as is, callers check for sql.ErrNoRows.
+	err = fn(tx.NamedStmtContext(ctx, stmt))
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
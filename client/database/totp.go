@@ -0,0 +1,225 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/totp"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type TOTPStmt struct {
+	Select          *sqlx.NamedStmt
+	Upsert          *sqlx.NamedStmt
+	UseStep         *sqlx.NamedStmt
+	UseRecoveryCode *sqlx.NamedStmt
+}
+
+func (totpStmt *TOTPStmt) Close() error {
+	errs := []error{}
+
+	err := totpStmt.Select.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select totp enrollment statement: %v", err))
+	}
+
+	err = totpStmt.Upsert.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing upsert totp enrollment statement: %v", err))
+	}
+
+	err = totpStmt.UseStep.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing use totp step statement: %v", err))
+	}
+
+	err = totpStmt.UseRecoveryCode.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing use totp recovery code statement: %v", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareTOTPStatements(ctx context.Context) (*TOTPStmt, error) {
+	var totpStmt TOTPStmt
+	var err error
+
+	totpStmt.Select, err = c.prepareSelectTOTPEnrollment(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select totp enrollment statement: %v", err)
+	}
+
+	totpStmt.Upsert, err = c.prepareUpsertTOTPEnrollment(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing upsert totp enrollment statement: %v", err)
+	}
+
+	totpStmt.UseStep, err = c.prepareUseTOTPStep(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing use totp step statement: %v", err)
+	}
+
+	totpStmt.UseRecoveryCode, err = c.prepareUseTOTPRecoveryCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing use totp recovery code statement: %v", err)
+	}
+
+	return &totpStmt, nil
+}
+
+func (c *Client) prepareSelectTOTPEnrollment(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT subject, secret, recovery_code_hashes, confirmed_at, last_used_step, created_at
+						FROM totp_enrollments WHERE subject = :subject`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) SelectTOTPEnrollment(ctx context.Context, subject string) (*totp.Enrollment, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectTOTPEnrollment")
+	defer span.End()
+
+	args := struct {
+		Subject string `db:"subject"`
+	}{
+		Subject: subject,
+	}
+
+	var enrollment totp.Enrollment
+	err := c.TOTPStmt.Select.GetContext(ctx, &enrollment, args)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, &client.ErrNotFound{Err: fmt.Errorf("totp enrollment for %q not found: %v", subject, err)}
+		default:
+			return nil, fmt.Errorf("error selecting totp enrollment for %q: %v", subject, err)
+		}
+	}
+
+	return &enrollment, nil
+}
+
+func (c *Client) prepareUpsertTOTPEnrollment(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `INSERT INTO totp_enrollments (subject, secret, recovery_code_hashes)
+						VALUES (:subject, :secret, :recovery_code_hashes)
+						ON CONFLICT (subject) DO UPDATE
+						SET secret = EXCLUDED.secret, recovery_code_hashes = EXCLUDED.recovery_code_hashes, created_at = now()
+						WHERE totp_enrollments.confirmed_at IS NULL`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// UpsertTOTPEnrollment creates a pending enrollment or replaces a pending one.
+// Confirmed enrollments are left untouched and reported as a conflict.
+func (c *Client) UpsertTOTPEnrollment(ctx context.Context, subject, secret string, recoveryCodeHashes []string) error {
+	ctx, span := tracing.StartSpan(ctx, "UpsertTOTPEnrollment")
+	defer span.End()
+
+	args := struct {
+		Subject            string         `db:"subject"`
+		Secret             string         `db:"secret"`
+		RecoveryCodeHashes pq.StringArray `db:"recovery_code_hashes"`
+	}{
+		Subject:            subject,
+		Secret:             secret,
+		RecoveryCodeHashes: recoveryCodeHashes,
+	}
+
+	result, err := c.TOTPStmt.Upsert.ExecContext(ctx, args)
+	if err != nil {
+		return fmt.Errorf("error upserting totp enrollment for %q: %v", subject, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting affected rows: %v", err)
+	}
+
+	if rows == 0 {
+		return &client.ErrConflict{Err: fmt.Errorf("totp enrollment for %q is already confirmed", subject)}
+	}
+
+	return nil
+}
+
+func (c *Client) prepareUseTOTPStep(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `UPDATE totp_enrollments
+						SET last_used_step = :step, confirmed_at = COALESCE(confirmed_at, now())
+						WHERE subject = :subject AND (last_used_step IS NULL OR last_used_step < :step)`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// UseTOTPStep records the time step of a verified code, confirming the
+// enrollment on first use. Steps that were already used are not found.
+func (c *Client) UseTOTPStep(ctx context.Context, subject string, step int64) error {
+	ctx, span := tracing.StartSpan(ctx, "UseTOTPStep")
+	defer span.End()
+
+	args := struct {
+		Subject string `db:"subject"`
+		Step    int64  `db:"step"`
+	}{
+		Subject: subject,
+		Step:    step,
+	}
+
+	result, err := c.TOTPStmt.UseStep.ExecContext(ctx, args)
+	if err != nil {
+		return fmt.Errorf("error using totp step for %q: %v", subject, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting affected rows: %v", err)
+	}
+
+	if rows == 0 {
+		return &client.ErrNotFound{Err: fmt.Errorf("no unused totp step %d for %q", step, subject)}
+	}
+
+	return nil
+}
+
+func (c *Client) prepareUseTOTPRecoveryCode(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `UPDATE totp_enrollments
+						SET recovery_code_hashes = array_remove(recovery_code_hashes, :code_hash)
+						WHERE subject = :subject AND confirmed_at IS NOT NULL AND :code_hash = ANY(recovery_code_hashes)`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// UseTOTPRecoveryCode consumes a recovery code so it can't be used again.
+func (c *Client) UseTOTPRecoveryCode(ctx context.Context, subject, codeHash string) error {
+	ctx, span := tracing.StartSpan(ctx, "UseTOTPRecoveryCode")
+	defer span.End()
+
+	args := struct {
+		Subject  string `db:"subject"`
+		CodeHash string `db:"code_hash"`
+	}{
+		Subject:  subject,
+		CodeHash: codeHash,
+	}
+
+	result, err := c.TOTPStmt.UseRecoveryCode.ExecContext(ctx, args)
+	if err != nil {
+		return fmt.Errorf("error using totp recovery code for %q: %v", subject, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting affected rows: %v", err)
+	}
+
+	if rows == 0 {
+		return &client.ErrNotFound{Err: fmt.Errorf("no unused recovery code for %q", subject)}
+	}
+
+	return nil
+}
@@ -0,0 +1,526 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/model/webhook"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type WebhookStmt struct {
+	SelectAll        *sqlx.Stmt
+	Select           *sqlx.NamedStmt
+	Insert           *sqlx.NamedStmt
+	Update           *sqlx.NamedStmt
+	Delete           *sqlx.NamedStmt
+	SelectDeliveries *sqlx.NamedStmt
+	Redeliver        *sqlx.NamedStmt
+	ClaimDeliveries  *sqlx.NamedStmt
+	SucceedDelivery  *sqlx.NamedStmt
+	FailDelivery     *sqlx.NamedStmt
+}
+
+func (webhookStmt *WebhookStmt) Close() error {
+	errs := []error{}
+
+	err := webhookStmt.SelectAll.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select all webhooks statement: %v", err))
+	}
+
+	err = webhookStmt.Select.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select webhook statement: %v", err))
+	}
+
+	err = webhookStmt.Insert.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing insert webhook statement: %v", err))
+	}
+
+	err = webhookStmt.Update.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing update webhook statement: %v", err))
+	}
+
+	err = webhookStmt.Delete.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing delete webhook statement: %v", err))
+	}
+
+	err = webhookStmt.SelectDeliveries.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing select webhook deliveries statement: %v", err))
+	}
+
+	err = webhookStmt.Redeliver.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing redeliver webhook statement: %v", err))
+	}
+
+	err = webhookStmt.ClaimDeliveries.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing claim webhook deliveries statement: %v", err))
+	}
+
+	err = webhookStmt.SucceedDelivery.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing succeed webhook delivery statement: %v", err))
+	}
+
+	err = webhookStmt.FailDelivery.Close()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error closing fail webhook delivery statement: %v", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareWebhookStatements(ctx context.Context) (*WebhookStmt, error) {
+	var webhookStmt WebhookStmt
+	var err error
+
+	webhookStmt.SelectAll, err = c.prepareSelectAllWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select all webhooks statement: %v", err)
+	}
+
+	webhookStmt.Select, err = c.prepareSelectWebhook(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select webhook statement: %v", err)
+	}
+
+	webhookStmt.Insert, err = c.prepareInsertWebhook(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing insert webhook statement: %v", err)
+	}
+
+	webhookStmt.Update, err = c.prepareUpdateWebhook(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing update webhook statement: %v", err)
+	}
+
+	webhookStmt.Delete, err = c.prepareDeleteWebhook(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing delete webhook statement: %v", err)
+	}
+
+	webhookStmt.SelectDeliveries, err = c.prepareSelectWebhookDeliveries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing select webhook deliveries statement: %v", err)
+	}
+
+	webhookStmt.Redeliver, err = c.prepareRedeliverWebhook(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing redeliver webhook statement: %v", err)
+	}
+
+	webhookStmt.ClaimDeliveries, err = c.prepareClaimWebhookDeliveries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing claim webhook deliveries statement: %v", err)
+	}
+
+	webhookStmt.SucceedDelivery, err = c.prepareSucceedWebhookDelivery(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing succeed webhook delivery statement: %v", err)
+	}
+
+	webhookStmt.FailDelivery, err = c.prepareFailWebhookDelivery(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing fail webhook delivery statement: %v", err)
+	}
+
+	return &webhookStmt, nil
+}
+
+const (
+	webhookColumns  = "id, url, events, enabled, consecutive_failures, disabled_at, created_at, updated_at"
+	deliveryColumns = "id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, error, created_at"
+)
+
+func (c *Client) prepareSelectAllWebhooks(ctx context.Context) (*sqlx.Stmt, error) {
+	query := "SELECT " + webhookColumns + " FROM webhooks ORDER BY id"
+	return c.DB.PreparexContext(ctx, query)
+}
+
+func (c *Client) SelectAllWebhooks(ctx context.Context) ([]webhook.Webhook, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectAllWebhooks")
+	defer span.End()
+
+	webhooks := []webhook.Webhook{}
+	err := c.WebhookStmt.SelectAll.SelectContext(ctx, &webhooks)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting webhooks: %v", err)
+	}
+
+	return webhooks, nil
+}
+
+func (c *Client) prepareSelectWebhook(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := "SELECT " + webhookColumns + " FROM webhooks WHERE id = :id"
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) SelectWebhook(ctx context.Context, id int) (*webhook.Webhook, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectWebhook")
+	defer span.End()
+
+	args := struct {
+		ID int `db:"id"`
+	}{
+		ID: id,
+	}
+
+	var webhook webhook.Webhook
+	err := c.WebhookStmt.Select.GetContext(ctx, &webhook, args)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, &client.ErrNotFound{Err: fmt.Errorf("webhook with id %d not found: %v", id, err)}
+		default:
+			return nil, fmt.Errorf("error selecting webhook with id %d: %v", id, err)
+		}
+	}
+
+	return &webhook, nil
+}
+
+func (c *Client) prepareInsertWebhook(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `INSERT INTO webhooks (tenant_id, url, events, secret, enabled, disabled_at)
+						VALUES (:tenant_id, :url, :events, :secret, :enabled, CASE WHEN :enabled THEN NULL ELSE now() END)
+						RETURNING ` + webhookColumns
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// InsertWebhook inserts a webhook receiving the events of the tenant of the
+// context.
+func (c *Client) InsertWebhook(ctx context.Context, payload webhook.Payload) (*webhook.Webhook, error) {
+	ctx, span := tracing.StartSpan(ctx, "InsertWebhook")
+	defer span.End()
+
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting a webhook: %v", err)
+	}
+
+	args := webhookArgs(tenantID, 0, payload)
+
+	var webhook webhook.Webhook
+	err = c.WebhookStmt.Insert.GetContext(ctx, &webhook, args)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting a webhook: %v", err)
+	}
+
+	audit.EventFromContext(ctx).SetChange(webhookTarget(webhook.ID), nil, webhook)
+
+	return &webhook, nil
+}
+
+func (c *Client) prepareUpdateWebhook(ctx context.Context) (*sqlx.NamedStmt, error) {
+	// Updating an enabled webhook resets its failures, e.g. after its URL was
+	// fixed, so it isn't disabled again by the next failure.
+	query := `UPDATE webhooks
+						SET url = :url, events = :events, secret = :secret, enabled = :enabled,
+							consecutive_failures = CASE WHEN :enabled THEN 0 ELSE webhooks.consecutive_failures END,
+							disabled_at = CASE WHEN :enabled THEN NULL ELSE COALESCE(webhooks.disabled_at, now()) END,
+							updated_at = now()
+						FROM (SELECT ` + webhookColumns + ` FROM webhooks WHERE id = :id FOR UPDATE) AS old
+						WHERE webhooks.id = old.id
+						RETURNING webhooks.id, webhooks.url, webhooks.events, webhooks.enabled, webhooks.consecutive_failures,
+							webhooks.disabled_at, webhooks.created_at, webhooks.updated_at,
+							old.id AS "old.id", old.url AS "old.url", old.events AS "old.events", old.enabled AS "old.enabled",
+							old.consecutive_failures AS "old.consecutive_failures", old.disabled_at AS "old.disabled_at",
+							old.created_at AS "old.created_at", old.updated_at AS "old.updated_at"`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func (c *Client) UpdateWebhook(ctx context.Context, id int, payload webhook.Payload) (*webhook.Webhook, error) {
+	ctx, span := tracing.StartSpan(ctx, "UpdateWebhook")
+	defer span.End()
+
+	args := webhookArgs("", id, payload)
+
+	var updated struct {
+		webhook.Webhook
+		Old webhook.Webhook `db:"old"`
+	}
+	err := c.WebhookStmt.Update.GetContext(ctx, &updated, args)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, &client.ErrNotFound{Err: fmt.Errorf("no webhook with id %d to update: %v", id, err)}
+		default:
+			return nil, fmt.Errorf("error updating webhook with id %d: %v", id, err)
+		}
+	}
+
+	audit.EventFromContext(ctx).SetChange(webhookTarget(id), updated.Old, updated.Webhook)
+
+	return &updated.Webhook, nil
+}
+
+func (c *Client) prepareDeleteWebhook(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `DELETE FROM webhooks WHERE id = :id RETURNING ` + webhookColumns
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// DeleteWebhook deletes the webhook with its deliveries.
+func (c *Client) DeleteWebhook(ctx context.Context, id int) error {
+	ctx, span := tracing.StartSpan(ctx, "DeleteWebhook")
+	defer span.End()
+
+	args := struct {
+		ID int `db:"id"`
+	}{
+		ID: id,
+	}
+
+	var deleted webhook.Webhook
+	err := c.WebhookStmt.Delete.GetContext(ctx, &deleted, args)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return &client.ErrNotFound{Err: fmt.Errorf("no webhook with id %d to delete", id)}
+		default:
+			return fmt.Errorf("error deleting webhook with id %d: %v", id, err)
+		}
+	}
+
+	audit.EventFromContext(ctx).SetChange(webhookTarget(id), deleted, nil)
+
+	return nil
+}
+
+func (c *Client) prepareSelectWebhookDeliveries(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+						WHERE webhook_id = :webhook_id
+							AND (:status = '' OR status = :status)
+							AND (:before_id = 0 OR id < :before_id)
+						ORDER BY id DESC
+						LIMIT NULLIF(:limit, 0)`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// SelectWebhookDeliveries selects the deliveries of a webhook, newest first.
+func (c *Client) SelectWebhookDeliveries(ctx context.Context, filter webhook.DeliveryFilter) ([]webhook.Delivery, error) {
+	ctx, span := tracing.StartSpan(ctx, "SelectWebhookDeliveries")
+	defer span.End()
+
+	deliveries := []webhook.Delivery{}
+	err := c.WebhookStmt.SelectDeliveries.SelectContext(ctx, &deliveries, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting deliveries of webhook with id %d: %v", filter.WebhookID, err)
+	}
+
+	return deliveries, nil
+}
+
+func (c *Client) prepareRedeliverWebhook(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `UPDATE webhook_deliveries
+						SET status = 'pending', attempts = 0, next_attempt_at = now(), response_status = NULL, error = NULL
+						WHERE id = :id AND webhook_id = :webhook_id
+						RETURNING ` + deliveryColumns
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// RedeliverWebhook queues the delivery to be sent again right away, with a
+// fresh set of attempts.
+func (c *Client) RedeliverWebhook(ctx context.Context, webhookID int, deliveryID int64) (*webhook.Delivery, error) {
+	ctx, span := tracing.StartSpan(ctx, "RedeliverWebhook")
+	defer span.End()
+
+	args := struct {
+		ID        int64 `db:"id"`
+		WebhookID int   `db:"webhook_id"`
+	}{
+		ID:        deliveryID,
+		WebhookID: webhookID,
+	}
+
+	var delivery webhook.Delivery
+	err := c.WebhookStmt.Redeliver.GetContext(ctx, &delivery, args)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, &client.ErrNotFound{Err: fmt.Errorf("no delivery with id %d of webhook with id %d: %v", deliveryID, webhookID, err)}
+		default:
+			return nil, fmt.Errorf("error redelivering delivery with id %d: %v", deliveryID, err)
+		}
+	}
+
+	audit.EventFromContext(ctx).SetChange(fmt.Sprintf("webhook_delivery:%d", deliveryID), nil, nil)
+
+	return &delivery, nil
+}
+
+func (c *Client) prepareClaimWebhookDeliveries(ctx context.Context) (*sqlx.NamedStmt, error) {
+	// Claimed deliveries are due again once the lease is over, so they are
+	// retried if the replica sending them dies. Deliveries of disabled
+	// webhooks wait until the webhook is enabled again.
+	query := `UPDATE webhook_deliveries AS d
+						SET attempts = d.attempts + 1, last_attempt_at = now(),
+							next_attempt_at = now() + CAST(:lease_seconds AS double precision) * interval '1 second'
+						FROM webhooks AS w
+						WHERE d.webhook_id = w.id AND d.id IN (
+							SELECT pending.id FROM webhook_deliveries AS pending
+								JOIN webhooks ON webhooks.id = pending.webhook_id AND webhooks.enabled
+								WHERE pending.status = 'pending' AND pending.next_attempt_at <= now()
+								ORDER BY pending.next_attempt_at
+								LIMIT :limit
+								FOR UPDATE OF pending SKIP LOCKED
+						)
+						RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.attempts, w.url, w.secret`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// ClaimWebhookDeliveries claims up to limit due deliveries for the lease.
+// Concurrent callers never claim the same delivery.
+func (c *Client) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]webhook.Attempt, error) {
+	ctx, span := tracing.StartSpan(ctx, "ClaimWebhookDeliveries")
+	defer span.End()
+
+	args := struct {
+		Limit        int     `db:"limit"`
+		LeaseSeconds float64 `db:"lease_seconds"`
+	}{
+		Limit:        limit,
+		LeaseSeconds: lease.Seconds(),
+	}
+
+	attempts := []webhook.Attempt{}
+	err := c.WebhookStmt.ClaimDeliveries.SelectContext(ctx, &attempts, args)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %v", err)
+	}
+
+	return attempts, nil
+}
+
+func (c *Client) prepareSucceedWebhookDelivery(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `WITH delivered AS (
+							UPDATE webhook_deliveries
+							SET status = 'succeeded', response_status = :response_status, error = NULL
+							WHERE id = :id
+							RETURNING webhook_id
+						)
+						UPDATE webhooks SET consecutive_failures = 0
+						WHERE id = (SELECT webhook_id FROM delivered)`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// SucceedWebhookDelivery marks the delivery as succeeded and resets the
+// failures of its webhook.
+func (c *Client) SucceedWebhookDelivery(ctx context.Context, deliveryID int64, responseStatus int) error {
+	ctx, span := tracing.StartSpan(ctx, "SucceedWebhookDelivery")
+	defer span.End()
+
+	args := struct {
+		ID             int64 `db:"id"`
+		ResponseStatus int   `db:"response_status"`
+	}{
+		ID:             deliveryID,
+		ResponseStatus: responseStatus,
+	}
+
+	_, err := c.WebhookStmt.SucceedDelivery.ExecContext(ctx, args)
+	if err != nil {
+		return fmt.Errorf("error marking webhook delivery with id %d as succeeded: %v", deliveryID, err)
+	}
+
+	return nil
+}
+
+func (c *Client) prepareFailWebhookDelivery(ctx context.Context) (*sqlx.NamedStmt, error) {
+	query := `WITH failed AS (
+							UPDATE webhook_deliveries
+							SET status = CASE WHEN CAST(:retry_at AS timestamptz) IS NULL THEN 'failed' ELSE 'pending' END,
+								next_attempt_at = COALESCE(CAST(:retry_at AS timestamptz), next_attempt_at),
+								response_status = NULLIF(:response_status, 0), error = :error
+							WHERE id = :id
+							RETURNING webhook_id
+						)
+						UPDATE webhooks
+						SET consecutive_failures = consecutive_failures + 1,
+							enabled = enabled AND consecutive_failures + 1 < :disable_after,
+							disabled_at = CASE WHEN enabled AND consecutive_failures + 1 >= :disable_after THEN now() ELSE disabled_at END
+						WHERE id = (SELECT webhook_id FROM failed)
+						RETURNING COALESCE(disabled_at = now(), false) AS disabled`
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+// FailWebhookDelivery records a failed attempt of the delivery. It's retried
+// at retryAt, or given up on if retryAt is nil. The webhook is disabled after
+// disableAfter consecutive failures, which is reported.
+func (c *Client) FailWebhookDelivery(ctx context.Context, deliveryID int64, result webhook.Result, retryAt *time.Time, disableAfter int) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "FailWebhookDelivery")
+	defer span.End()
+
+	args := struct {
+		ID             int64      `db:"id"`
+		ResponseStatus int        `db:"response_status"`
+		Error          string     `db:"error"`
+		RetryAt        *time.Time `db:"retry_at"`
+		DisableAfter   int        `db:"disable_after"`
+	}{
+		ID:             deliveryID,
+		ResponseStatus: result.ResponseStatus,
+		Error:          fmt.Sprint(result.Err),
+		RetryAt:        retryAt,
+		DisableAfter:   disableAfter,
+	}
+
+	var disabled bool
+	err := c.WebhookStmt.FailDelivery.GetContext(ctx, &disabled, args)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// The webhook was deleted in the meantime.
+			return false, nil
+		default:
+			return false, fmt.Errorf("error marking webhook delivery with id %d as failed: %v", deliveryID, err)
+		}
+	}
+
+	return disabled, nil
+}
+
+// webhookArgs are the arguments of the insert and update statements, the
+// tenant of a webhook can't be updated.
+func webhookArgs(tenantID string, id int, payload webhook.Payload) any {
+	events := payload.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	return struct {
+		TenantID string         `db:"tenant_id"`
+		ID       int            `db:"id"`
+		URL      string         `db:"url"`
+		Events   pq.StringArray `db:"events"`
+		Secret   string         `db:"secret"`
+		Enabled  bool           `db:"enabled"`
+	}{
+		TenantID: tenantID,
+		ID:       id,
+		URL:      payload.URL,
+		Events:   events,
+		Secret:   payload.Secret,
+		Enabled:  payload.IsEnabled(),
+	}
+}
+
+func webhookTarget(id int) string {
+	return fmt.Sprintf("webhook:%d", id)
+}
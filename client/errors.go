@@ -1,5 +1,9 @@
 package client
 
+// Error types of this package have a Code, which is stable and can be relied on
+// by API clients, unlike the error messages. Their Message is safe to show to
+// API clients, while Err may hold internals, e.g. the database errors.
+
 type ErrNotFound struct {
 	Err error
 }
@@ -12,6 +16,14 @@ func (e *ErrNotFound) Unwrap() error {
 	return e.Err
 }
 
+func (e *ErrNotFound) Code() string {
+	return "not_found"
+}
+
+func (e *ErrNotFound) Message() string {
+	return "not found"
+}
+
 type ErrUnauthorized struct {
 	Err error
 }
@@ -23,3 +35,31 @@ func (e *ErrUnauthorized) Error() string {
 func (e *ErrUnauthorized) Unwrap() error {
 	return e.Err
 }
+
+func (e *ErrUnauthorized) Code() string {
+	return "unauthorized"
+}
+
+func (e *ErrUnauthorized) Message() string {
+	return "missing or invalid credentials"
+}
+
+type ErrConflict struct {
+	Err error
+}
+
+func (e *ErrConflict) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrConflict) Unwrap() error {
+	return e.Err
+}
+
+func (e *ErrConflict) Code() string {
+	return "conflict"
+}
+
+func (e *ErrConflict) Message() string {
+	return "conflicts with the current state"
+}
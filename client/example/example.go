@@ -48,6 +48,7 @@ func (c *Client) FetchExampleData(ctx context.Context) (ExampleData, error) {
 	if err != nil {
 		return ExampleData{}, fmt.Errorf("error doing http request: %v", err)
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode >= 400 {
 		return ExampleData{}, fmt.Errorf("received non-successful status code: %s", res.Status)
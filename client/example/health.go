@@ -0,0 +1,40 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/goodleby/golang-app/health"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+// RegisterHealthChecks registers the readiness checks of the example API.
+func (c *Client) RegisterHealthChecks(registerer health.Registerer) {
+	registerer.Register("example", c.CheckEndpoint)
+}
+
+// CheckEndpoint checks that the example endpoint responds successfully.
+func (c *Client) CheckEndpoint(ctx context.Context) error {
+	ctx, span := tracing.StartSpan(ctx, "CheckEndpoint")
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.ExampleEndpoint, nil)
+	if err != nil {
+		return fmt.Errorf("error creating new request: %v", err)
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error doing http request: %v", err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode >= 400 {
+		return fmt.Errorf("received non-successful status code: %s", res.Status)
+	}
+
+	return nil
+}
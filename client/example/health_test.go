@@ -0,0 +1,46 @@
+package example
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientCheckEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{
+			name:   "should pass when the endpoint responds",
+			status: http.StatusOK,
+		},
+		{
+			name:    "should fail when the endpoint is missing",
+			status:  http.StatusNotFound,
+			wantErr: true,
+		},
+		{
+			name:    "should fail on server errors",
+			status:  http.StatusBadGateway,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			c := New(ts.URL)
+
+			err := c.CheckEndpoint(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -8,13 +8,19 @@ import (
 	"github.com/goodleby/golang-app/model/article"
 )
 
+const addArticleTopicID string = "golang-app-add-article"
+
+// AddArticleSubscriptionID is the subscription of the processor to the add
+// article messages.
+const AddArticleSubscriptionID string = "golang-app-add-article-sub"
+
 func (c *Client) PublishAddArticle(ctx context.Context, payload article.Payload) error {
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("error marshalling article payload: %v", err)
 	}
 
-	err = c.send(ctx, "golang-app-add-article", data)
+	err = c.send(ctx, addArticleTopicID, data)
 	if err != nil {
 		return fmt.Errorf("error sending add article message: %v", err)
 	}
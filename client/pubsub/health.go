@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"cloud.google.com/go/iam"
+	"github.com/goodleby/golang-app/health"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+// RegisterHealthChecks registers the readiness checks of Pub/Sub.
+func (c *Client) RegisterHealthChecks(registerer health.Registerer) {
+	registerer.Register("pubsub", c.CheckTopic)
+	registerer.Register("pubsub_subscription", c.CheckSubscription)
+}
+
+// CheckTopic checks that Pub/Sub is reachable and the topic we publish to
+// exists and can be published to.
+func (c *Client) CheckTopic(ctx context.Context) error {
+	ctx, span := tracing.StartSpan(ctx, "CheckTopic")
+	defer span.End()
+
+	topic := c.Client.Topic(addArticleTopicID)
+
+	exists, err := topic.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("error checking topic %q: %v", addArticleTopicID, err)
+	}
+
+	if !exists {
+		return fmt.Errorf("topic %q doesn't exist", addArticleTopicID)
+	}
+
+	err = checkPermissions(ctx, topic.IAM(), topicPermissions)
+	if err != nil {
+		return fmt.Errorf("error checking permissions on topic %q: %v", addArticleTopicID, err)
+	}
+
+	return nil
+}
+
+// CheckSubscription checks that the subscription the processor receives from
+// exists and can be consumed.
+func (c *Client) CheckSubscription(ctx context.Context) error {
+	ctx, span := tracing.StartSpan(ctx, "CheckSubscription")
+	defer span.End()
+
+	subscription := c.Client.Subscription(AddArticleSubscriptionID)
+
+	exists, err := subscription.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("error checking subscription %q: %v", AddArticleSubscriptionID, err)
+	}
+
+	if !exists {
+		return fmt.Errorf("subscription %q doesn't exist", AddArticleSubscriptionID)
+	}
+
+	err = checkPermissions(ctx, subscription.IAM(), subscriptionPermissions)
+	if err != nil {
+		return fmt.Errorf("error checking permissions on subscription %q: %v", AddArticleSubscriptionID, err)
+	}
+
+	return nil
+}
+
+// checkPermissions fails unless the caller has all the permissions on the
+// resource.
+func checkPermissions(ctx context.Context, handle *iam.Handle, permissions []string) error {
+	granted, err := handle.TestPermissions(ctx, permissions)
+	if err != nil {
+		return fmt.Errorf("error testing permissions: %v", err)
+	}
+
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission) {
+			return fmt.Errorf("permission %q isn't granted", permission)
+		}
+	}
+
+	return nil
+}
+
+var (
+	topicPermissions        = []string{"pubsub.topics.publish"}
+	subscriptionPermissions = []string{"pubsub.subscriptions.consume"}
+)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/pubsub"
+	"github.com/goodleby/golang-app/model/tenant"
 	"github.com/goodleby/golang-app/tracing"
 )
 
@@ -35,7 +36,12 @@ func (c *Client) send(ctx context.Context, topicID string, data []byte) error {
 	topic := c.Client.Topic(topicID)
 	defer topic.Stop()
 
-	result := topic.Publish(ctx, &pubsub.Message{Data: data, Attributes: tracing.NewCarrier(ctx)})
+	attributes := tracing.NewCarrier(ctx)
+	if id := tenant.IDFromContext(ctx); id != "" {
+		attributes[tenant.Attribute] = id
+	}
+
+	result := topic.Publish(ctx, &pubsub.Message{Data: data, Attributes: attributes})
 	id, err := result.Get(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to publish message %s to topic %q: %v", id, topicID, err)
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goodleby/golang-app/model/webhook"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+const (
+	IDHeader        string = "Webhook-Id"
+	EventHeader     string = "Webhook-Event"
+	SignatureHeader string = "Webhook-Signature"
+)
+
+// Client sends webhook deliveries.
+type Client struct {
+	HTTPClient *http.Client
+	UserAgent  string
+}
+
+func New(timeout time.Duration, userAgent string) *Client {
+	var c Client
+
+	c.UserAgent = userAgent
+	c.HTTPClient = &http.Client{
+		Timeout:   timeout,
+		Transport: tracing.NewTracedTransport(http.DefaultTransport),
+		// A redirect could send the signed payload anywhere.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &c
+}
+
+// Send posts the payload of the attempt signed with the webhook secret. Any
+// 2xx response is a successful delivery.
+func (c *Client) Send(ctx context.Context, attempt webhook.Attempt) webhook.Result {
+	ctx, span := tracing.StartSpan(ctx, "SendWebhook")
+	defer span.End()
+
+	span.SetTag("webhook_id", strconv.Itoa(attempt.WebhookID))
+	span.SetTag("delivery_id", strconv.FormatInt(attempt.DeliveryID, 10))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, attempt.URL, bytes.NewReader(attempt.Payload))
+	if err != nil {
+		return webhook.Result{Err: fmt.Errorf("error creating new request: %v", err)}
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set(IDHeader, strconv.FormatInt(attempt.DeliveryID, 10))
+	req.Header.Set(EventHeader, attempt.EventType)
+	req.Header.Set(SignatureHeader, Sign(attempt.Secret, time.Now(), attempt.Payload))
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return webhook.Result{Err: fmt.Errorf("error doing http request: %v", err)}
+	}
+	defer res.Body.Close()
+
+	// Drained so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxResponseSize))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return webhook.Result{ResponseStatus: res.StatusCode, Err: fmt.Errorf("received non-successful status code: %s", res.Status)}
+	}
+
+	return webhook.Result{ResponseStatus: res.StatusCode}
+}
+
+// Sign returns the signature header of the body sent at the time. The
+// signature is the hex HMAC-SHA256 of the unix timestamp, a dot and the body,
+// e.g. "t=1700000000,v1=5257a869...". The timestamp lets receivers reject
+// replayed deliveries.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+// Verify checks the signature header of the body, which must have been
+// signed within the tolerance of now. It's what receivers are expected to do.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid signature timestamp %q", ts)
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return fmt.Errorf("signature timestamp is %v off", age.Round(time.Second))
+	}
+
+	expected := signature(secret, ts, body)
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+
+	return errors.New("no matching signature")
+}
+
+func signature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+const maxResponseSize int64 = 64 << 10
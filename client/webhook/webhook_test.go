@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/model/webhook"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":1}`)
+
+	tests := []struct {
+		name    string
+		header  string
+		secret  string
+		body    []byte
+		wantErr bool
+	}{
+		{
+			name:   "should accept a fresh signature",
+			header: Sign("secret", now.Add(-time.Minute), body),
+			secret: "secret",
+			body:   body,
+		},
+		{
+			name:   "should accept any of several signatures",
+			header: "v1=deadbeef," + Sign("secret", now, body),
+			secret: "secret",
+			body:   body,
+		},
+		{
+			name:    "should reject another secret",
+			header:  Sign("other", now, body),
+			secret:  "secret",
+			body:    body,
+			wantErr: true,
+		},
+		{
+			name:    "should reject a changed body",
+			header:  Sign("secret", now, body),
+			secret:  "secret",
+			body:    []byte(`{"id":2}`),
+			wantErr: true,
+		},
+		{
+			name:    "should reject an old signature",
+			header:  Sign("secret", now.Add(-10*time.Minute), body),
+			secret:  "secret",
+			body:    body,
+			wantErr: true,
+		},
+		{
+			name:    "should reject a header without timestamp",
+			header:  "v1=deadbeef",
+			secret:  "secret",
+			body:    body,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, 5*time.Minute, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientSend(t *testing.T) {
+	attempt := webhook.Attempt{
+		DeliveryID: 7,
+		WebhookID:  3,
+		EventType:  "article.created",
+		Payload:    []byte(`{"id":42,"type":"article.created"}`),
+		Number:     1,
+		Secret:     "0123456789abcdef",
+	}
+
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus int
+		wantErr    bool
+	}{
+		{
+			name:       "should succeed on 2xx",
+			status:     http.StatusNoContent,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "should fail on server errors",
+			status:     http.StatusInternalServerError,
+			wantStatus: http.StatusInternalServerError,
+			wantErr:    true,
+		},
+		{
+			name:       "should fail on client errors",
+			status:     http.StatusGone,
+			wantStatus: http.StatusGone,
+			wantErr:    true,
+		},
+		{
+			name:       "should not follow redirects",
+			status:     http.StatusFound,
+			wantStatus: http.StatusFound,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			var gotBody []byte
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				gotBody, _ = io.ReadAll(r.Body)
+				if tt.status == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			c := New(time.Second, "golang-app")
+			attempt := attempt
+			attempt.URL = ts.URL
+
+			result := c.Send(context.Background(), attempt)
+			if (result.Err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", result.Err, tt.wantErr)
+			}
+			if result.ResponseStatus != tt.wantStatus {
+				t.Errorf("Send() status = %d, want %d", result.ResponseStatus, tt.wantStatus)
+			}
+
+			if got.Header.Get(IDHeader) != "7" {
+				t.Errorf("%s = %q, want %q", IDHeader, got.Header.Get(IDHeader), "7")
+			}
+			if got.Header.Get(EventHeader) != attempt.EventType {
+				t.Errorf("%s = %q, want %q", EventHeader, got.Header.Get(EventHeader), attempt.EventType)
+			}
+			if string(gotBody) != string(attempt.Payload) {
+				t.Errorf("body = %s, want %s", gotBody, attempt.Payload)
+			}
+
+			err := Verify(attempt.Secret, got.Header.Get(SignatureHeader), gotBody, time.Minute, time.Now())
+			if err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Proxies are the trusted proxies in front of the app, e.g. the load balancer.
+// They append the address of their peer to X-Forwarded-For, the entries before
+// theirs are set by the client and can't be trusted.
+type Proxies []netip.Prefix
+
+// ParseProxies parses the addresses of the proxies in the CIDR notation, a
+// single address is a prefix of its full length.
+func ParseProxies(addrs []string) (Proxies, error) {
+	var proxies Proxies
+	for _, s := range addrs {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy address %q: %v", s, err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy address %q: %v", s, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
+
+// Resolve returns the address of the original client of the connection from
+// the peer, e.g. 10.0.0.1:4321, and the X-Forwarded-For values. The entries
+// are read from the right while they were appended by trusted proxies, so the
+// result is the peer itself unless it is a trusted proxy.
+func (p Proxies) Resolve(peer string, forwardedFor []string) string {
+	ip := host(peer)
+	if !p.trusts(ip) {
+		return ip
+	}
+
+	var entries []string
+	for _, value := range forwardedFor {
+		entries = append(entries, strings.Split(value, ",")...)
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := strings.TrimSpace(entries[i])
+		if _, err := netip.ParseAddr(entry); err != nil {
+			// The proxy appended no address, the hop before it is unknown.
+			return ip
+		}
+
+		ip = entry
+		if !p.trusts(ip) {
+			return ip
+		}
+	}
+
+	return ip
+}
+
+func (p Proxies) trusts(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// host strips the port of the address, if any.
+func host(addr string) string {
+	h, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return h
+}
+
+type clientIPKey struct{}
+
+func NewContext(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// FromContext returns the address of the original client or an empty string.
+func FromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
@@ -0,0 +1,80 @@
+package clientip
+
+import "testing"
+
+func TestProxies_Resolve(t *testing.T) {
+	proxies, err := ParseProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("ParseProxies() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		proxies      Proxies
+		peer         string
+		forwardedFor []string
+		want         string
+	}{
+		{
+			name:         "no trusted proxies",
+			peer:         "203.0.113.7:4321",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "untrusted peer",
+			proxies:      proxies,
+			peer:         "203.0.113.7:4321",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "trusted peer",
+			proxies:      proxies,
+			peer:         "10.1.2.3:4321",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "forged entries",
+			proxies:      proxies,
+			peer:         "10.1.2.3:4321",
+			forwardedFor: []string{"1.2.3.4, 5.6.7.8", "198.51.100.1, 192.168.1.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "invalid entry",
+			proxies:      proxies,
+			peer:         "10.1.2.3:4321",
+			forwardedFor: []string{"198.51.100.1, unknown"},
+			want:         "10.1.2.3",
+		},
+		{
+			name:    "no forwarded for",
+			proxies: proxies,
+			peer:    "10.1.2.3:4321",
+			want:    "10.1.2.3",
+		},
+		{
+			name:         "only trusted proxies",
+			proxies:      proxies,
+			peer:         "10.1.2.3:4321",
+			forwardedFor: []string{"10.9.9.9"},
+			want:         "10.9.9.9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.proxies.Resolve(tt.peer, tt.forwardedFor); got != tt.want {
+				t.Errorf("Proxies.Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProxies(t *testing.T) {
+	_, err := ParseProxies([]string{"10.0.0.0/33"})
+	if err == nil {
+		t.Errorf("ParseProxies() error = nil, want error")
+	}
+}
@@ -0,0 +1,181 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/goodleby/golang-app/metrics"
+	"github.com/goodleby/golang-app/model/webhook"
+)
+
+// Store is the durable queue of webhook deliveries.
+type Store interface {
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]webhook.Attempt, error)
+	SucceedWebhookDelivery(ctx context.Context, deliveryID int64, responseStatus int) error
+	FailWebhookDelivery(ctx context.Context, deliveryID int64, result webhook.Result, retryAt *time.Time, disableAfter int) (bool, error)
+}
+
+type Sender interface {
+	Send(ctx context.Context, attempt webhook.Attempt) webhook.Result
+}
+
+type Config struct {
+	// Concurrency is the number of deliveries sent at once.
+	Concurrency  int
+	PollInterval time.Duration
+	// Lease is how long a claimed delivery is reserved for this replica. It
+	// has to be longer than a send can take.
+	Lease time.Duration
+	// MaxAttempts is the number of attempts before a delivery fails for good.
+	MaxAttempts int
+	// BackoffBase is the delay before the first retry, doubled for each one
+	// after it up to BackoffMax.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// DisableAfter is the number of consecutive failed attempts after which
+	// a webhook is disabled.
+	DisableAfter int
+}
+
+// Dispatcher sends the queued webhook deliveries and retries the failed ones
+// with exponential backoff. Any number of replicas can run it. Dispatcher is
+// an app service.
+type Dispatcher struct {
+	config Config
+	store  Store
+	sender Sender
+	now    func() time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func New(config Config, store Store, sender Sender) *Dispatcher {
+	return &Dispatcher{
+		config: config,
+		store:  store,
+		sender: sender,
+		now:    time.Now,
+		done:   make(chan struct{}),
+	}
+}
+
+func (d *Dispatcher) Start(ctx context.Context, errc chan<- error) {
+	ctx, d.cancel = context.WithCancel(ctx)
+	defer close(d.done)
+
+	slog.Info(fmt.Sprintf("Webhook dispatcher sending up to %d deliveries at once", d.config.Concurrency))
+
+	for {
+		claimed, err := d.dispatch(ctx)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error dispatching webhook deliveries: %v", err))
+		}
+
+		// A full batch means more deliveries are probably due.
+		if err == nil && claimed == d.config.Concurrency {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.config.PollInterval):
+		}
+	}
+}
+
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	if d.cancel == nil {
+		return nil
+	}
+	d.cancel()
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("error stopping webhook dispatcher: %v", ctx.Err())
+	}
+}
+
+// dispatch sends a batch of due deliveries and returns how many there were.
+func (d *Dispatcher) dispatch(ctx context.Context) (int, error) {
+	attempts, err := d.store.ClaimWebhookDeliveries(ctx, d.config.Concurrency, d.config.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("error claiming deliveries: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, attempt := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, attempt)
+		}()
+	}
+	wg.Wait()
+
+	return len(attempts), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, attempt webhook.Attempt) {
+	start := d.now()
+	result := d.sender.Send(ctx, attempt)
+	metrics.ObserveWebhookDeliveryDuration(d.now().Sub(start))
+
+	if ctx.Err() != nil {
+		// Interrupted by the shutdown, the delivery is retried when its lease
+		// is over.
+		return
+	}
+
+	if result.Err == nil {
+		metrics.RecordWebhookDelivery(attempt.EventType, webhook.DeliverySucceeded)
+
+		err := d.store.SucceedWebhookDelivery(ctx, attempt.DeliveryID, result.ResponseStatus)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error recording webhook delivery %d: %v", attempt.DeliveryID, err))
+		}
+		return
+	}
+
+	var retryAt *time.Time
+	outcome := webhook.DeliveryFailed
+	if attempt.Number < d.config.MaxAttempts {
+		at := d.now().Add(d.backoff(attempt.Number))
+		retryAt = &at
+		outcome = "retrying"
+	}
+	metrics.RecordWebhookDelivery(attempt.EventType, outcome)
+
+	slog.Warn(fmt.Sprintf("Webhook delivery %d attempt %d failed: %v", attempt.DeliveryID, attempt.Number, result.Err))
+
+	disabled, err := d.store.FailWebhookDelivery(ctx, attempt.DeliveryID, result, retryAt, d.config.DisableAfter)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error recording webhook delivery %d: %v", attempt.DeliveryID, err))
+		return
+	}
+
+	if disabled {
+		slog.Warn(fmt.Sprintf("Webhook %d disabled after %d consecutive failures", attempt.WebhookID, d.config.DisableAfter))
+	}
+}
+
+// backoff returns the delay before retrying after the attempt. A random extra
+// of up to a fifth spreads out the retries of deliveries that failed
+// together, e.g. while the endpoint was down.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.config.BackoffMax
+	if shift := attempt - 1; shift < 32 {
+		if exp := d.config.BackoffBase << shift; exp > 0 && exp < delay {
+			delay = exp
+		}
+	}
+
+	return delay + rand.N(delay/5+1)
+}
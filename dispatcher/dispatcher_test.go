@@ -0,0 +1,158 @@
+package dispatcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	webhookclient "github.com/goodleby/golang-app/client/webhook"
+	"github.com/goodleby/golang-app/model/webhook"
+)
+
+// fakeStore hands out the queued attempts once and records the outcomes.
+type fakeStore struct {
+	mu        sync.Mutex
+	queued    []webhook.Attempt
+	succeeded map[int64]int
+	failed    map[int64]*time.Time
+	failures  int
+}
+
+func (s *fakeStore) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]webhook.Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := min(limit, len(s.queued))
+	claimed := s.queued[:n]
+	s.queued = s.queued[n:]
+
+	return claimed, nil
+}
+
+func (s *fakeStore) SucceedWebhookDelivery(ctx context.Context, deliveryID int64, responseStatus int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.succeeded[deliveryID] = responseStatus
+	s.failures = 0
+
+	return nil
+}
+
+func (s *fakeStore) FailWebhookDelivery(ctx context.Context, deliveryID int64, result webhook.Result, retryAt *time.Time, disableAfter int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failed[deliveryID] = retryAt
+	s.failures++
+
+	return s.failures == disableAfter, nil
+}
+
+func newFakeStore(attempts ...webhook.Attempt) *fakeStore {
+	return &fakeStore{
+		queued:    attempts,
+		succeeded: map[int64]int{},
+		failed:    map[int64]*time.Time{},
+	}
+}
+
+var testConfig = Config{
+	Concurrency:  2,
+	PollInterval: time.Millisecond,
+	Lease:        time.Minute,
+	MaxAttempts:  3,
+	BackoffBase:  time.Second,
+	BackoffMax:   time.Minute,
+	DisableAfter: 10,
+}
+
+func TestDispatcherDispatch(t *testing.T) {
+	const secret = "0123456789abcdef"
+
+	var mu sync.Mutex
+	var verified int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		err := webhookclient.Verify(secret, r.Header.Get(webhookclient.SignatureHeader), body, time.Minute, time.Now())
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		mu.Lock()
+		verified++
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newFakeStore(
+		webhook.Attempt{DeliveryID: 1, WebhookID: 1, EventType: "article.created", Payload: []byte(`{"id":1}`), Number: 1, URL: receiver.URL, Secret: secret},
+		webhook.Attempt{DeliveryID: 2, WebhookID: 2, EventType: "article.created", Payload: []byte(`{"id":1}`), Number: 1, URL: down.URL, Secret: secret},
+		webhook.Attempt{DeliveryID: 3, WebhookID: 2, EventType: "article.updated", Payload: []byte(`{"id":2}`), Number: 3, URL: down.URL, Secret: secret},
+	)
+
+	d := New(testConfig, store, webhookclient.New(time.Second, "golang-app"))
+	d.now = func() time.Time { return now }
+
+	for range 2 {
+		_, err := d.dispatch(context.Background())
+		if err != nil {
+			t.Fatalf("dispatch() error = %v", err)
+		}
+	}
+
+	if verified != 1 {
+		t.Errorf("verified deliveries = %d, want 1", verified)
+	}
+
+	if status, ok := store.succeeded[1]; !ok || status != http.StatusOK {
+		t.Errorf("delivery 1 succeeded = %v with status %d, want true with status %d", ok, status, http.StatusOK)
+	}
+
+	retryAt, ok := store.failed[2]
+	if !ok || retryAt == nil {
+		t.Fatalf("delivery 2 retry = %v, want a retry", retryAt)
+	}
+	if delay := retryAt.Sub(now); delay < testConfig.BackoffBase || delay > testConfig.BackoffBase*6/5 {
+		t.Errorf("delivery 2 retried after %v, want about %v", delay, testConfig.BackoffBase)
+	}
+
+	if retryAt, ok := store.failed[3]; !ok || retryAt != nil {
+		t.Errorf("delivery 3 retry = %v, want no retry after the last attempt", retryAt)
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 5, want: 16 * time.Second},
+		{attempt: 7, want: time.Minute},
+		{attempt: 100, want: time.Minute},
+	}
+	for _, tt := range tests {
+		d := New(testConfig, nil, nil)
+
+		got := d.backoff(tt.attempt)
+		if got < tt.want || got > tt.want*6/5 {
+			t.Errorf("backoff(%d) = %v, want %v plus up to a fifth", tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -17,22 +19,128 @@ type Config struct {
 	LogFormat         string     `env:"LOG_FORMAT,default=json"`
 	TracingSampleRate float64    `env:"TRACING_SAMPLE_RATE,default=1"`
 
+	AccessLogSampleRate   float64  `env:"ACCESS_LOG_SAMPLE_RATE,default=1"`
+	AccessLogExcludePaths []string `env:"ACCESS_LOG_EXCLUDE_PATHS,default=/_healthz,/_livez,/_readyz"`
+
+	HealthCheckTimeout  time.Duration `env:"HEALTH_CHECK_TIMEOUT,default=2s"`
+	HealthCheckCacheTTL time.Duration `env:"HEALTH_CHECK_CACHE_TTL,default=5s"`
+
+	// Rate limits are in the requests/duration form, e.g. articles:600/1m.
+	RateLimitStore  string            `env:"RATE_LIMIT_STORE,default=memory"`
+	RateLimitGroups map[string]string `env:"RATE_LIMIT_GROUPS,default=auth:30/1m,pubsub:60/1m,articles:600/1m,admin:120/1m,web:600/1m"`
+	RateLimitRoles  map[string]string `env:"RATE_LIMIT_ROLES,default="`
+
+	// Idempotency store is memory (per replica) or postgres (shared by replicas).
+	IdempotencyStore string        `env:"IDEMPOTENCY_STORE,default=memory"`
+	IdempotencyTTL   time.Duration `env:"IDEMPOTENCY_TTL,default=24h"`
+
+	// The latest events are kept in memory for clients resuming the stream.
+	ArticleEventsBacklog   int           `env:"ARTICLE_EVENTS_BACKLOG,default=1000"`
+	ArticleEventsRetention time.Duration `env:"ARTICLE_EVENTS_RETENTION,default=168h"`
+
+	// Clients are told to retry requests rejected in read-only or maintenance
+	// mode after this long.
+	ModeRetryAfter time.Duration `env:"MODE_RETRY_AFTER,default=1m"`
+
+	// Feature flag store is none, file (a JSON array of flags) or postgres, the
+	// flags are reloaded at the interval.
+	FeatureFlagsStore          string        `env:"FEATURE_FLAGS_STORE,default=none"`
+	FeatureFlagsFile           string        `env:"FEATURE_FLAGS_FILE,default="`
+	FeatureFlagsReloadInterval time.Duration `env:"FEATURE_FLAGS_RELOAD_INTERVAL,default=30s"`
+	// The route groups hidden behind a flag until it's on, e.g. admin:new-admin.
+	FeatureFlagGroups map[string]string `env:"FEATURE_FLAG_GROUPS,default="`
+
+	// Requests are scoped to the tenant named by the header, else served at the
+	// host, else claimed by the auth token, else to the fallback one. An empty
+	// fallback rejects the requests that name no tenant. Row-level security
+	// makes Postgres enforce the scoping too.
+	TenantHeader           string        `env:"TENANT_HEADER,default=X-Tenant-ID"`
+	TenantFallback         string        `env:"TENANT_FALLBACK,default=default"`
+	TenantReloadInterval   time.Duration `env:"TENANT_RELOAD_INTERVAL,default=30s"`
+	TenantRowLevelSecurity bool          `env:"TENANT_ROW_LEVEL_SECURITY,default=false"`
+
+	// Failed webhook deliveries are retried with exponential backoff from the
+	// base up to the max delay.
+	WebhookConcurrency  int           `env:"WEBHOOK_CONCURRENCY,default=10"`
+	WebhookTimeout      time.Duration `env:"WEBHOOK_TIMEOUT,default=10s"`
+	WebhookMaxAttempts  int           `env:"WEBHOOK_MAX_ATTEMPTS,default=10"`
+	WebhookBackoffBase  time.Duration `env:"WEBHOOK_BACKOFF_BASE,default=30s"`
+	WebhookBackoffMax   time.Duration `env:"WEBHOOK_BACKOFF_MAX,default=6h"`
+	WebhookDisableAfter int           `env:"WEBHOOK_DISABLE_AFTER,default=20"`
+
 	Host           string   `env:"HOST,default=0.0.0.0"`
 	Port           uint16   `env:"PORT,default=8000"`
 	AllowedOrigins []string `env:"ALLOWED_ORIGINS,default=http://localhost:3000"`
+	// X-Forwarded-For entries, also of gRPC calls, are only believed as far as
+	// they were appended by these proxies, addresses or CIDRs, e.g. the load
+	// balancer.
+	TrustedProxies []string `env:"TRUSTED_PROXIES,default="`
+
+	// TLS is on when the cert file is set, client auth is none, optional or
+	// require. Client roles map certificate subject common names to roles.
+	TLSCertFile     string            `env:"TLS_CERT_FILE,default="`
+	TLSKeyFile      string            `env:"TLS_KEY_FILE,default="`
+	TLSClientAuth   string            `env:"TLS_CLIENT_AUTH,default=none"`
+	TLSClientCAFile string            `env:"TLS_CLIENT_CA_FILE,default="`
+	TLSClientRoles  map[string]string `env:"TLS_CLIENT_ROLES,default="`
+	H2C             bool              `env:"H2C,default=false"`
+
+	// Limits of the listener. Route groups, i.e. public, pubsub, auth,
+	// articles and admin, can override the request timeout and the max body
+	// size in bytes, e.g. admin:1m.
+	HTTPReadHeaderTimeout time.Duration            `env:"HTTP_READ_HEADER_TIMEOUT,default=5s"`
+	HTTPReadTimeout       time.Duration            `env:"HTTP_READ_TIMEOUT,default=5s"`
+	HTTPWriteTimeout      time.Duration            `env:"HTTP_WRITE_TIMEOUT,default=5s"`
+	HTTPIdleTimeout       time.Duration            `env:"HTTP_IDLE_TIMEOUT,default=2m"`
+	HTTPMaxHeaderBytes    int                      `env:"HTTP_MAX_HEADER_BYTES,default=1048576"`
+	RequestTimeout        time.Duration            `env:"REQUEST_TIMEOUT,default=5s"`
+	RequestTimeouts       map[string]time.Duration `env:"REQUEST_TIMEOUTS,default="`
+	MaxBodySize           int64                    `env:"MAX_BODY_SIZE,default=1048576"`
+	MaxBodySizes          map[string]int64         `env:"MAX_BODY_SIZES,default="`
+
+	CompressionMinSize int `env:"COMPRESSION_MIN_SIZE,default=1024"`
+
+	// Persisted queries are a JSON array of queries, with GRAPHQL_PERSISTED_ONLY
+	// only those are allowed.
+	GraphQLMaxDepth         int    `env:"GRAPHQL_MAX_DEPTH,default=8"`
+	GraphQLMaxComplexity    int    `env:"GRAPHQL_MAX_COMPLEXITY,default=1000"`
+	GraphQLPersistedQueries string `env:"GRAPHQL_PERSISTED_QUERIES,default="`
+	GraphQLPersistedOnly    bool   `env:"GRAPHQL_PERSISTED_ONLY,default=false"`
+
+	// The sitemap uses the first host of the tenant if WEB_BASE_URL is empty,
+	// and for the tenants other than the default one.
+	WebBaseURL     string        `env:"WEB_BASE_URL,default="`
+	WebPageSize    int           `env:"WEB_PAGE_SIZE,default=10"`
+	WebCacheMaxAge time.Duration `env:"WEB_CACHE_MAX_AGE,default=5m"`
+
+	GRPCHost string `env:"GRPC_HOST,default=0.0.0.0"`
+	GRPCPort uint16 `env:"GRPC_PORT,default=9000"`
+
+	AdminHost string `env:"ADMIN_HOST,default=0.0.0.0"`
+	AdminPort uint16 `env:"ADMIN_PORT,default=9090"`
 
 	DatabaseUser     string `env:"DATABASE_USER,required"`
-	DatabasePassword string `env:"DATABASE_PASSWORD,required"`
+	DatabasePassword string `env:"DATABASE_PASSWORD,required" redact:"true"`
 	DatabaseHost     string `env:"DATABASE_HOST,default=127.0.0.1"`
 	DatabasePort     uint16 `env:"DATABASE_PORT,default=5432"`
 	DatabaseName     string `env:"DATABASE_NAME,default=postgres"`
-	DatabaseOptions  string `env:"DATABASE_OPTIONS,default="`
+	DatabaseOptions  string `env:"DATABASE_OPTIONS,default=" redact:"true"`
 
-	AuthSecret    string        `env:"AUTH_SECRET,required"`
-	AuthTokenTTL  time.Duration `env:"AUTH_TOKEN_TTL,default=20m"`
-	AuthAdminKey  string        `env:"AUTH_ADMIN_KEY,required"`
-	AuthEditorKey string        `env:"AUTH_EDITOR_KEY,required"`
-	AuthViewerKey string        `env:"AUTH_VIEWER_KEY,required"`
+	AuthSecret         string        `env:"AUTH_SECRET,required" redact:"true"`
+	AuthTokenTTL       time.Duration `env:"AUTH_TOKEN_TTL,default=20m"`
+	AuthAdminKey       string        `env:"AUTH_ADMIN_KEY,required" redact:"true"`
+	AuthEditorKey      string        `env:"AUTH_EDITOR_KEY,required" redact:"true"`
+	AuthViewerKey      string        `env:"AUTH_VIEWER_KEY,required" redact:"true"`
+	AuthMFAAccessLevel int           `env:"AUTH_MFA_ACCESS_LEVEL,default=30"`
+
+	OIDCIssuerURL    string            `env:"OIDC_ISSUER_URL,default="`
+	OIDCClientID     string            `env:"OIDC_CLIENT_ID,default="`
+	OIDCClientSecret string            `env:"OIDC_CLIENT_SECRET,default=" redact:"true"`
+	OIDCRedirectURL  string            `env:"OIDC_REDIRECT_URL,default="`
+	OIDCScopes       []string          `env:"OIDC_SCOPES,default=openid,profile,email,groups"`
+	OIDCGroupsClaim  string            `env:"OIDC_GROUPS_CLAIM,default=groups"`
+	OIDCRoleMapping  map[string]string `env:"OIDC_ROLE_MAPPING,default="`
+	OIDCPostLoginURL string            `env:"OIDC_POST_LOGIN_URL,default=/"`
 
 	GoogleApplicationCredentials string `env:"GOOGLE_APPLICATION_CREDENTIALS,required"`
 	PubSubProjectID              string `env:"PUBSUB_PROJECT_ID,required"`
@@ -56,3 +164,38 @@ func LoadConfig(ctx context.Context) (*Config, error) {
 
 	return &c, nil
 }
+
+// Redacted returns the config keyed by the env variable names, with the values
+// of the fields tagged redact replaced.
+func (c *Config) Redacted() map[string]any {
+	redacted := map[string]any{}
+
+	v := reflect.ValueOf(c).Elem()
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+
+		name, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+		if name == "" {
+			continue
+		}
+
+		value := v.Field(i).Interface()
+		switch {
+		case field.Tag.Get("redact") == "true":
+			if !v.Field(i).IsZero() {
+				value = redactedValue
+			}
+		case field.Type.Implements(stringerType):
+			// Durations and levels read better as strings, e.g. 20m0s.
+			value = value.(fmt.Stringer).String()
+		}
+
+		redacted[name] = value
+	}
+
+	return redacted
+}
+
+const redactedValue string = "[REDACTED]"
+
+var stringerType = reflect.TypeFor[fmt.Stringer]()
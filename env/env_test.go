@@ -0,0 +1,40 @@
+package env
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestConfigRedacted(t *testing.T) {
+	config := Config{
+		LogLevel:         slog.LevelInfo,
+		AuthTokenTTL:     20 * time.Minute,
+		DatabaseUser:     "postgres",
+		DatabasePassword: "secret",
+		DatabaseOptions:  "sslmode=verify-full sslpassword=secret",
+		AuthSecret:       "secret",
+	}
+
+	redacted := config.Redacted()
+
+	tests := []struct {
+		key  string
+		want any
+	}{
+		{key: "DATABASE_USER", want: "postgres"},
+		{key: "DATABASE_PASSWORD", want: redactedValue},
+		{key: "DATABASE_OPTIONS", want: redactedValue},
+		{key: "AUTH_SECRET", want: redactedValue},
+		{key: "OIDC_CLIENT_SECRET", want: ""},
+		{key: "LOG_LEVEL", want: "INFO"},
+		{key: "AUTH_TOKEN_TTL", want: "20m0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := redacted[tt.key]; got != tt.want {
+				t.Errorf("Redacted()[%q] = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
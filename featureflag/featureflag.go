@@ -0,0 +1,181 @@
+package featureflag
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"slices"
+
+	"github.com/goodleby/golang-app/validation"
+)
+
+// Flag is a boolean flag, on or off, or a variant flag serving one of its
+// variants when on.
+type Flag struct {
+	Key string `json:"key"`
+	// Enabled turns the flag off for everyone when false, whatever the rules
+	// and rollout.
+	Enabled bool `json:"enabled"`
+	// Rules are checked in order, the flag is on for the users matching one.
+	Rules []Rule `json:"rules"`
+	// Rollout is the percentage of the other users the flag is on for. Users
+	// are bucketed by a hash of their ID, so they keep their bucket as the
+	// rollout grows. Anonymous users are only included at 100.
+	Rollout int `json:"rollout"`
+	// Variants are split between the users by weight, none for boolean flags.
+	Variants []Variant `json:"variants"`
+}
+
+// Rule matches the users by role or ID.
+type Rule struct {
+	Roles []string `json:"roles"`
+	Users []string `json:"users"`
+	// Variant is served to the matching users of a variant flag, or else one
+	// picked by weight.
+	Variant string `json:"variant"`
+}
+
+type Variant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// Target is who the flags are evaluated for.
+type Target struct {
+	UserID string
+	Role   string
+}
+
+// Evaluation is the outcome of a flag for a target, Variant is On or Off for
+// boolean flags.
+type Evaluation struct {
+	Key     string `json:"key"`
+	Enabled bool   `json:"enabled"`
+	Variant string `json:"variant"`
+	Reason  string `json:"reason"`
+}
+
+const (
+	On  string = "on"
+	Off string = "off"
+)
+
+// Reasons of evaluations.
+const (
+	ReasonUnknown  string = "unknown"
+	ReasonDisabled string = "disabled"
+	ReasonRule     string = "rule"
+	ReasonRollout  string = "rollout"
+	ReasonDefault  string = "default"
+)
+
+var keyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+func (f *Flag) Validate() error {
+	var errs validation.Errors
+
+	if !keyPattern.MatchString(f.Key) {
+		errs.Add("key", "must be lowercase letters, digits, dots, dashes or underscores")
+	}
+
+	if f.Rollout < 0 || f.Rollout > 100 {
+		errs.Add("rollout", "must be between 0 and 100")
+	}
+
+	var names []string
+	for i, variant := range f.Variants {
+		switch {
+		case variant.Name == "":
+			errs.Add(fmt.Sprintf("variants[%d].name", i), "must not be empty")
+		case slices.Contains(names, variant.Name):
+			errs.Add(fmt.Sprintf("variants[%d].name", i), fmt.Sprintf("duplicate variant %q", variant.Name))
+		}
+		names = append(names, variant.Name)
+
+		if variant.Weight < 0 {
+			errs.Add(fmt.Sprintf("variants[%d].weight", i), "must not be negative")
+		}
+	}
+
+	for i, rule := range f.Rules {
+		if len(rule.Roles) == 0 && len(rule.Users) == 0 {
+			errs.Add(fmt.Sprintf("rules[%d]", i), "must match roles or users")
+		}
+
+		if rule.Variant != "" && !slices.Contains(names, rule.Variant) {
+			errs.Add(fmt.Sprintf("rules[%d].variant", i), fmt.Sprintf("unknown variant %q", rule.Variant))
+		}
+	}
+
+	return errs.Err()
+}
+
+// Evaluate decides whether the flag is on for the target and which variant it
+// gets.
+func (f *Flag) Evaluate(target Target) Evaluation {
+	if !f.Enabled {
+		return Evaluation{Key: f.Key, Variant: Off, Reason: ReasonDisabled}
+	}
+
+	for _, rule := range f.Rules {
+		if rule.matches(target) {
+			return f.on(target, rule.Variant, ReasonRule)
+		}
+	}
+
+	if f.Rollout >= 100 || (target.UserID != "" && bucket(f.Key, target.UserID) < f.Rollout*100) {
+		return f.on(target, "", ReasonRollout)
+	}
+
+	return Evaluation{Key: f.Key, Variant: Off, Reason: ReasonDefault}
+}
+
+func (f *Flag) on(target Target, variant, reason string) Evaluation {
+	switch {
+	case len(f.Variants) == 0:
+		variant = On
+	case variant == "":
+		variant = f.pickVariant(target)
+	}
+
+	return Evaluation{Key: f.Key, Enabled: true, Variant: variant, Reason: reason}
+}
+
+// pickVariant picks a variant by weight, the same for a user on every
+// evaluation.
+func (f *Flag) pickVariant(target Target) string {
+	total := 0
+	for _, variant := range f.Variants {
+		total += variant.Weight
+	}
+	if total == 0 {
+		return f.Variants[0].Name
+	}
+
+	n := bucket(f.Key+"/variant", target.UserID) % total
+	for _, variant := range f.Variants {
+		if n < variant.Weight {
+			return variant.Name
+		}
+		n -= variant.Weight
+	}
+
+	return f.Variants[len(f.Variants)-1].Name
+}
+
+func (r *Rule) matches(target Target) bool {
+	return (target.Role != "" && slices.Contains(r.Roles, target.Role)) ||
+		(target.UserID != "" && slices.Contains(r.Users, target.UserID))
+}
+
+// bucket hashes the user into one of 10000 buckets, hundredths of a percent.
+// The key is part of the hash so users don't get the same bucket for every
+// flag.
+func bucket(key, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key + "/" + userID))
+
+	return int(h.Sum32() % buckets)
+}
+
+const buckets = 10000
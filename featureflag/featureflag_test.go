@@ -0,0 +1,244 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goodleby/golang-app/client/auth"
+)
+
+func TestFlag_Evaluate(t *testing.T) {
+	variants := []Variant{{Name: "blue", Weight: 1}, {Name: "green", Weight: 1}}
+
+	tests := []struct {
+		name        string
+		flag        Flag
+		target      Target
+		wantEnabled bool
+		wantVariant string
+		wantReason  string
+	}{
+		{
+			name:        "disabled flag is off for matching users",
+			flag:        Flag{Key: "new-editor", Rules: []Rule{{Roles: []string{"editor"}}}, Rollout: 100},
+			target:      Target{UserID: "alice", Role: "editor"},
+			wantVariant: Off,
+			wantReason:  ReasonDisabled,
+		},
+		{
+			name:        "rule matches the role",
+			flag:        Flag{Key: "new-editor", Enabled: true, Rules: []Rule{{Roles: []string{"editor"}}}},
+			target:      Target{UserID: "alice", Role: "editor"},
+			wantEnabled: true,
+			wantVariant: On,
+			wantReason:  ReasonRule,
+		},
+		{
+			name:        "rule matches the user",
+			flag:        Flag{Key: "new-editor", Enabled: true, Rules: []Rule{{Users: []string{"bob"}}}},
+			target:      Target{UserID: "bob", Role: "viewer"},
+			wantEnabled: true,
+			wantVariant: On,
+			wantReason:  ReasonRule,
+		},
+		{
+			name:        "no rule matches and no rollout",
+			flag:        Flag{Key: "new-editor", Enabled: true, Rules: []Rule{{Roles: []string{"editor"}}}},
+			target:      Target{UserID: "carol", Role: "viewer"},
+			wantVariant: Off,
+			wantReason:  ReasonDefault,
+		},
+		{
+			name:        "full rollout includes anonymous users",
+			flag:        Flag{Key: "new-editor", Enabled: true, Rollout: 100},
+			target:      Target{Role: AnonymousRole},
+			wantEnabled: true,
+			wantVariant: On,
+			wantReason:  ReasonRollout,
+		},
+		{
+			name:        "partial rollout excludes anonymous users",
+			flag:        Flag{Key: "new-editor", Enabled: true, Rollout: 99},
+			target:      Target{Role: AnonymousRole},
+			wantVariant: Off,
+			wantReason:  ReasonDefault,
+		},
+		{
+			name:        "rule pins the variant",
+			flag:        Flag{Key: "theme", Enabled: true, Rules: []Rule{{Roles: []string{"admin"}, Variant: "green"}}, Variants: variants},
+			target:      Target{UserID: "alice", Role: "admin"},
+			wantEnabled: true,
+			wantVariant: "green",
+			wantReason:  ReasonRule,
+		},
+		{
+			name:        "weightless variants serve the first one",
+			flag:        Flag{Key: "theme", Enabled: true, Rollout: 100, Variants: []Variant{{Name: "blue"}, {Name: "green"}}},
+			target:      Target{UserID: "alice", Role: "viewer"},
+			wantEnabled: true,
+			wantVariant: "blue",
+			wantReason:  ReasonRollout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.flag.Evaluate(tt.target)
+			if got.Enabled != tt.wantEnabled || got.Variant != tt.wantVariant || got.Reason != tt.wantReason {
+				t.Errorf("Flag.Evaluate() = %+v, want enabled %v, variant %q, reason %q", got, tt.wantEnabled, tt.wantVariant, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestFlag_EvaluateRollout(t *testing.T) {
+	users := 2000
+	flag := Flag{Key: "new-editor", Enabled: true}
+
+	var included []string
+	for _, rollout := range []int{10, 50} {
+		flag.Rollout = rollout
+
+		var enabled []string
+		for i := 0; i < users; i++ {
+			userID := fmt.Sprintf("user-%d", i)
+			if flag.Evaluate(Target{UserID: userID}).Enabled {
+				enabled = append(enabled, userID)
+			}
+		}
+
+		// Allows for the spread of the hash.
+		want := users * rollout / 100
+		if len(enabled) < want*8/10 || len(enabled) > want*12/10 {
+			t.Errorf("Flag.Evaluate() at %d%% enabled %d users, want about %d", rollout, len(enabled), want)
+		}
+
+		// Growing the rollout keeps the users already included.
+		set := map[string]bool{}
+		for _, userID := range enabled {
+			set[userID] = true
+		}
+		for _, userID := range included {
+			if !set[userID] {
+				t.Fatalf("Flag.Evaluate() at %d%% dropped %s", rollout, userID)
+			}
+		}
+		included = enabled
+	}
+}
+
+func TestFlag_EvaluateVariants(t *testing.T) {
+	flag := Flag{Key: "theme", Enabled: true, Rollout: 100, Variants: []Variant{{Name: "blue", Weight: 3}, {Name: "green", Weight: 1}}}
+
+	counts := map[string]int{}
+	for i := 0; i < 2000; i++ {
+		target := Target{UserID: fmt.Sprintf("user-%d", i)}
+
+		variant := flag.Evaluate(target).Variant
+		if again := flag.Evaluate(target).Variant; again != variant {
+			t.Fatalf("Flag.Evaluate() variant = %q then %q, want the same", variant, again)
+		}
+		counts[variant]++
+	}
+
+	if counts["blue"] < counts["green"]*2 {
+		t.Errorf("Flag.Evaluate() variants = %v, want about 3 blue to 1 green", counts)
+	}
+}
+
+func TestFlag_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    Flag
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			flag: Flag{Key: "theme", Rollout: 50, Variants: []Variant{{Name: "blue", Weight: 1}}, Rules: []Rule{{Roles: []string{"admin"}, Variant: "blue"}}},
+		},
+		{
+			name:    "invalid key",
+			flag:    Flag{Key: "New Theme"},
+			wantErr: true,
+		},
+		{
+			name:    "rollout over 100",
+			flag:    Flag{Key: "theme", Rollout: 101},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate variants",
+			flag:    Flag{Key: "theme", Variants: []Variant{{Name: "blue"}, {Name: "blue"}}},
+			wantErr: true,
+		},
+		{
+			name:    "rule matching nobody",
+			flag:    Flag{Key: "theme", Rules: []Rule{{}}},
+			wantErr: true,
+		},
+		{
+			name:    "rule with unknown variant",
+			flag:    Flag{Key: "theme", Rules: []Rule{{Roles: []string{"admin"}, Variant: "red"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.flag.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Flag.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	write := func(content string) {
+		err := os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("error writing flags file: %v", err)
+		}
+	}
+
+	write(`[{"key": "new-editor", "enabled": true, "rules": [{"roles": ["editor"]}]}]`)
+
+	flags, err := New(context.Background(), FileSource{Path: path}, 0)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	editor := auth.NewContext(context.Background(), &auth.Claims{RoleName: "editor"})
+
+	if !flags.Enabled(editor, "new-editor") {
+		t.Errorf("Flags.Enabled() for editor = false, want true")
+	}
+	if flags.Enabled(context.Background(), "new-editor") {
+		t.Errorf("Flags.Enabled() for anonymous = true, want false")
+	}
+	if got := flags.Evaluate(editor, "missing"); got.Enabled || got.Reason != ReasonUnknown {
+		t.Errorf("Flags.Evaluate() of unknown flag = %+v, want off with reason %q", got, ReasonUnknown)
+	}
+
+	write(`[{"key": "new-editor", "enabled": false}]`)
+
+	err = flags.Reload(context.Background())
+	if err != nil {
+		t.Fatalf("Flags.Reload() error = %v", err)
+	}
+	if flags.Enabled(editor, "new-editor") {
+		t.Errorf("Flags.Enabled() after reload = true, want false")
+	}
+
+	write(`[{"key": "new-editor", "enabled": true, "rollout": 200}]`)
+
+	err = flags.Reload(context.Background())
+	if err == nil {
+		t.Fatalf("Flags.Reload() of invalid flags error = nil, want error")
+	}
+	if got := flags.EvaluateAll(editor); len(got) != 1 || got[0].Reason != ReasonDisabled {
+		t.Errorf("Flags.EvaluateAll() after invalid reload = %+v, want the previous flags", got)
+	}
+}
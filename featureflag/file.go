@@ -0,0 +1,36 @@
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// FileSource reads the flags from a JSON file holding an array of flags, e.g.
+// a mounted ConfigMap.
+type FileSource struct {
+	Path string
+}
+
+func (s FileSource) SelectFeatureFlags(ctx context.Context) ([]Flag, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading feature flags file: %v", err)
+	}
+
+	var flags []Flag
+	err = json.Unmarshal(data, &flags)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding feature flags file %s: %v", s.Path, err)
+	}
+
+	return flags, nil
+}
+
+// StaticSource is a fixed set of flags, e.g. none.
+type StaticSource []Flag
+
+func (s StaticSource) SelectFeatureFlags(ctx context.Context) ([]Flag, error) {
+	return s, nil
+}
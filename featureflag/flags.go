@@ -0,0 +1,172 @@
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/metrics"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+// Source is where the flags are defined, e.g. a file or a database table.
+type Source interface {
+	SelectFeatureFlags(ctx context.Context) ([]Flag, error)
+}
+
+// Flags evaluates the flags of the source, reloading them regularly so
+// changes apply without a restart. Flags is an app service.
+type Flags struct {
+	source   Source
+	interval time.Duration
+
+	mu    sync.RWMutex
+	flags map[string]Flag
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New loads the flags, which must be valid from the start.
+func New(ctx context.Context, source Source, interval time.Duration) (*Flags, error) {
+	f := &Flags{
+		source:   source,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+
+	err := f.Reload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *Flags) Start(ctx context.Context, errc chan<- error) {
+	ctx, f.cancel = context.WithCancel(ctx)
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := f.Reload(ctx)
+			if err != nil {
+				// The previous flags are evaluated until the source is fixed.
+				slog.Error(fmt.Sprintf("Error reloading feature flags: %v", err))
+			}
+		}
+	}
+}
+
+func (f *Flags) Stop(ctx context.Context) error {
+	if f.cancel == nil {
+		return nil
+	}
+	f.cancel()
+
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("error stopping feature flags: %v", ctx.Err())
+	}
+}
+
+// Reload replaces the flags with the ones of the source if they are all valid.
+func (f *Flags) Reload(ctx context.Context) error {
+	loaded, err := f.source.SelectFeatureFlags(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading feature flags: %v", err)
+	}
+
+	flags := make(map[string]Flag, len(loaded))
+	var errs []error
+	for _, flag := range loaded {
+		err := flag.Validate()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid feature flag %q: %v", flag.Key, err))
+			continue
+		}
+		if _, ok := flags[flag.Key]; ok {
+			errs = append(errs, fmt.Errorf("duplicate feature flag %q", flag.Key))
+			continue
+		}
+		flags[flag.Key] = flag
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	f.mu.Lock()
+	f.flags = flags
+	f.mu.Unlock()
+
+	return nil
+}
+
+// Evaluate evaluates the flag for the caller in the context, see
+// TargetFromContext. Unknown flags are off. The outcome is recorded in the
+// span and the metrics.
+func (f *Flags) Evaluate(ctx context.Context, key string) Evaluation {
+	f.mu.RLock()
+	flag, ok := f.flags[key]
+	f.mu.RUnlock()
+
+	evaluation := Evaluation{Key: key, Variant: Off, Reason: ReasonUnknown}
+	if ok {
+		evaluation = flag.Evaluate(TargetFromContext(ctx))
+	}
+
+	span := tracing.SpanFromContext(ctx)
+	span.SetTag("feature_flag."+key, evaluation.Variant)
+
+	metrics.RecordFeatureFlagEvaluation(key, evaluation.Variant, evaluation.Reason)
+
+	return evaluation
+}
+
+// Enabled reports whether the flag is on for the caller in the context.
+func (f *Flags) Enabled(ctx context.Context, key string) bool {
+	return f.Evaluate(ctx, key).Enabled
+}
+
+// EvaluateAll evaluates every flag for the caller in the context, sorted by
+// key.
+func (f *Flags) EvaluateAll(ctx context.Context) []Evaluation {
+	f.mu.RLock()
+	keys := slices.Sorted(maps.Keys(f.flags))
+	f.mu.RUnlock()
+
+	evaluations := make([]Evaluation, 0, len(keys))
+	for _, key := range keys {
+		evaluations = append(evaluations, f.Evaluate(ctx, key))
+	}
+
+	return evaluations
+}
+
+// TargetFromContext targets the caller authenticated in the context, see
+// auth.Claims.Caller, or an anonymous one.
+func TargetFromContext(ctx context.Context) Target {
+	claims := auth.ClaimsFromContext(ctx)
+	if claims == nil {
+		return Target{Role: AnonymousRole}
+	}
+
+	return Target{UserID: claims.Caller(), Role: claims.RoleName}
+}
+
+// AnonymousRole is the role of unauthenticated callers, as in rate limits.
+const AnonymousRole string = "anonymous"
@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports an error when a dependency isn't usable.
+type Check func(ctx context.Context) error
+
+// Registerer is implemented by Checker, clients use it to register the checks
+// of their dependencies.
+type Registerer interface {
+	Register(name string, check Check)
+}
+
+var ErrShuttingDown = errors.New("service is shutting down")
+
+// Checker runs the registered readiness checks. Each check runs with a timeout
+// and its result is cached, so frequent probes don't hammer the dependencies.
+type Checker struct {
+	timeout      time.Duration
+	cacheTTL     time.Duration
+	mu           sync.Mutex
+	checks       []*check
+	shuttingDown atomic.Bool
+	now          func() time.Time
+}
+
+type check struct {
+	name string
+	fn   Check
+	// mu is held while the check runs, so concurrent probes wait for the same
+	// result instead of running the check again.
+	mu     sync.Mutex
+	result *Result
+}
+
+func NewChecker(timeout, cacheTTL time.Duration) *Checker {
+	return &Checker{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		now:      time.Now,
+	}
+}
+
+func (c *Checker) Register(name string, fn Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, &check{name: name, fn: fn})
+}
+
+// Shutdown fails every following readiness check, so the service stops
+// receiving traffic while it drains.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// CheckReadiness runs the checks concurrently and reports whether all of them
+// pass.
+func (c *Checker) CheckReadiness(ctx context.Context) *Report {
+	if c.shuttingDown.Load() {
+		return &Report{Status: StatusDown, Error: ErrShuttingDown.Error()}
+	}
+
+	c.mu.Lock()
+	checks := append([]*check(nil), c.checks...)
+	c.mu.Unlock()
+
+	report := Report{Status: StatusUp, Checks: make([]Result, len(checks))}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return &report
+}
+
+func (c *Checker) run(ctx context.Context, check *check) Result {
+	check.mu.Lock()
+	defer check.mu.Unlock()
+
+	if check.result != nil && c.now().Sub(check.result.CheckedAt) < c.cacheTTL {
+		return *check.result
+	}
+
+	// Probes going away mustn't cancel a check other probes are waiting for.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	start := c.now()
+	err := check.fn(ctx)
+
+	result := Result{
+		Name:      check.name,
+		Status:    StatusUp,
+		Duration:  c.now().Sub(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	check.result = &result
+
+	return result
+}
+
+const (
+	StatusUp   string = "up"
+	StatusDown string = "down"
+)
+
+type Report struct {
+	Status string   `json:"status"`
+	Error  string   `json:"error,omitempty"`
+	Checks []Result `json:"checks,omitempty"`
+}
+
+// HTTPStatus is the status code of the readiness probe response.
+func (r *Report) HTTPStatus() int {
+	if r.Status != StatusUp {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusOK
+}
+
+type Result struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
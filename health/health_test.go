@@ -0,0 +1,108 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckerCheckReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     map[string]Check
+		shutdown   bool
+		wantStatus string
+		wantError  string
+	}{
+		{
+			name: "should be up when every check passes",
+			checks: map[string]Check{
+				"database": func(ctx context.Context) error { return nil },
+				"pubsub":   func(ctx context.Context) error { return nil },
+			},
+			wantStatus: StatusUp,
+		},
+		{
+			name: "should be down when a check fails",
+			checks: map[string]Check{
+				"database": func(ctx context.Context) error { return nil },
+				"pubsub":   func(ctx context.Context) error { return errors.New("unreachable") },
+			},
+			wantStatus: StatusDown,
+		},
+		{
+			name: "should be down when a check times out",
+			checks: map[string]Check{
+				"database": func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+			wantStatus: StatusDown,
+		},
+		{
+			name: "should be down without running checks when shutting down",
+			checks: map[string]Check{
+				"database": func(ctx context.Context) error {
+					t.Error("CheckReadiness() ran a check while shutting down")
+					return nil
+				},
+			},
+			shutdown:   true,
+			wantStatus: StatusDown,
+			wantError:  ErrShuttingDown.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(10*time.Millisecond, time.Minute)
+			for name, check := range tt.checks {
+				checker.Register(name, check)
+			}
+			if tt.shutdown {
+				checker.Shutdown()
+			}
+
+			report := checker.CheckReadiness(context.Background())
+
+			if report.Status != tt.wantStatus {
+				t.Errorf("CheckReadiness() status = %v, want %v", report.Status, tt.wantStatus)
+			}
+			if report.Error != tt.wantError {
+				t.Errorf("CheckReadiness() error = %v, want %v", report.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestCheckerCachesResults(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	checker := NewChecker(time.Second, 5*time.Second)
+	checker.now = func() time.Time { return now }
+
+	calls := 0
+	checker.Register("database", func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+
+	steps := []struct {
+		advance   time.Duration
+		wantCalls int
+	}{
+		{advance: 0, wantCalls: 1},
+		{advance: 4 * time.Second, wantCalls: 1},
+		{advance: time.Second, wantCalls: 2},
+	}
+	for i, step := range steps {
+		now = now.Add(step.advance)
+
+		checker.CheckReadiness(context.Background())
+
+		if calls != step.wantCalls {
+			t.Errorf("step %d: check calls = %v, want %v", i, calls, step.wantCalls)
+		}
+	}
+}
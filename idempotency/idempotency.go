@@ -0,0 +1,38 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Record is the state of an idempotency key. Response is nil while the first
+// request with the key is still being handled.
+type Record struct {
+	Fingerprint string
+	Response    *Response
+}
+
+// Response is the stored response replayed to retries.
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Fingerprint identifies the request a key was first used with, so reusing
+// the key for a different request can be told apart from a retry.
+func Fingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// LockTTL is how long a key stays locked by a request that never completes,
+// e.g. because the replica crashed, before it can be used again.
+const LockTTL time.Duration = time.Minute
@@ -0,0 +1,94 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps idempotency keys in memory, so retries are only detected
+// when they reach the same replica.
+type MemoryStore struct {
+	mu        sync.Mutex
+	keys      map[string]*entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type entry struct {
+	record    Record
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		keys:      map[string]*entry{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// BeginIdempotentRequest locks the key for the request with the fingerprint.
+// It returns nil if the key was free, otherwise the record of the key.
+func (s *MemoryStore) BeginIdempotentRequest(ctx context.Context, key, fingerprint string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	e, ok := s.keys[key]
+	if ok && now.Before(e.expiresAt) {
+		record := e.record
+		return &record, nil
+	}
+
+	s.keys[key] = &entry{
+		record:    Record{Fingerprint: fingerprint},
+		expiresAt: now.Add(LockTTL),
+	}
+
+	return nil, nil
+}
+
+// CompleteIdempotentRequest stores the response of the key for the TTL.
+func (s *MemoryStore) CompleteIdempotentRequest(ctx context.Context, key string, response *Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.keys[key]
+	if !ok {
+		return nil
+	}
+
+	e.record.Response = response
+	e.expiresAt = s.now().Add(ttl)
+
+	return nil
+}
+
+// ReleaseIdempotentRequest frees the key, so the request can be retried.
+func (s *MemoryStore) ReleaseIdempotentRequest(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+
+	return nil
+}
+
+// sweep drops the expired keys.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.keys {
+		if !now.Before(e.expiresAt) {
+			delete(s.keys, key)
+		}
+	}
+}
+
+const sweepInterval = time.Minute
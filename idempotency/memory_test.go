@@ -0,0 +1,55 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	response := &Response{Status: http.StatusOK, ContentType: "application/json", Body: []byte(`{"id":1}`)}
+
+	record, err := s.BeginIdempotentRequest(ctx, "key", "a")
+	if err != nil || record != nil {
+		t.Fatalf("BeginIdempotentRequest() = %v, %v, want the key locked", record, err)
+	}
+
+	record, err = s.BeginIdempotentRequest(ctx, "key", "a")
+	if err != nil || !reflect.DeepEqual(record, &Record{Fingerprint: "a"}) {
+		t.Fatalf("BeginIdempotentRequest() = %v, %v, want the request in progress", record, err)
+	}
+
+	err = s.CompleteIdempotentRequest(ctx, "key", response, time.Hour)
+	if err != nil {
+		t.Fatalf("CompleteIdempotentRequest() error = %v", err)
+	}
+
+	record, err = s.BeginIdempotentRequest(ctx, "key", "a")
+	if err != nil || !reflect.DeepEqual(record, &Record{Fingerprint: "a", Response: response}) {
+		t.Fatalf("BeginIdempotentRequest() = %v, %v, want the stored response", record, err)
+	}
+
+	now = now.Add(time.Hour)
+
+	record, err = s.BeginIdempotentRequest(ctx, "key", "b")
+	if err != nil || record != nil {
+		t.Fatalf("BeginIdempotentRequest() = %v, %v, want the expired key locked again", record, err)
+	}
+
+	err = s.ReleaseIdempotentRequest(ctx, "key")
+	if err != nil {
+		t.Fatalf("ReleaseIdempotentRequest() error = %v", err)
+	}
+
+	record, err = s.BeginIdempotentRequest(ctx, "key", "c")
+	if err != nil || record != nil {
+		t.Fatalf("BeginIdempotentRequest() = %v, %v, want the released key locked again", record, err)
+	}
+}
@@ -0,0 +1,66 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SweepStore is a shared store of keys, which aren't dropped when they expire
+// like the ones of MemoryStore.
+type SweepStore interface {
+	SweepIdempotencyKeys(ctx context.Context) error
+}
+
+// Sweeper regularly deletes the expired keys of the store. Sweeper is an app
+// service.
+type Sweeper struct {
+	store SweepStore
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewSweeper(store SweepStore) *Sweeper {
+	return &Sweeper{
+		store: store,
+		done:  make(chan struct{}),
+	}
+}
+
+func (s *Sweeper) Start(ctx context.Context, errc chan<- error) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	defer close(s.done)
+
+	ticker := time.NewTicker(storeSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.store.SweepIdempotencyKeys(ctx)
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error sweeping idempotency keys: %v", err))
+			}
+		}
+	}
+}
+
+func (s *Sweeper) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("error stopping idempotency sweeper: %v", ctx.Err())
+	}
+}
+
+const storeSweepInterval = 10 * time.Minute
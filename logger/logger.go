@@ -6,10 +6,16 @@ import (
 	"log/slog"
 )
 
+// Level is the minimum level of the default logger, it can be changed at
+// runtime.
+var Level = new(slog.LevelVar)
+
 func Init(level slog.Level, format string) {
+	Level.Set(level)
+
 	opts := &slog.HandlerOptions{
 		ReplaceAttr: renameMessageKey,
-		Level:       level,
+		Level:       Level,
 	}
 
 	var handler slog.Handler
@@ -13,18 +13,29 @@ var (
 		Name: "requests_handled",
 		Help: "Handled requests counter and metadata associated with them",
 	},
-		[]string{"status_code", "route_name"},
+		[]string{"status_code", "route_name", "tenant"},
 	))
 	requestsDuration = newCollector(prometheus.NewHistogram(prometheus.HistogramOpts{
 		Name:    "requests_duration",
 		Help:    "Time spent processing requests",
 		Buckets: []float64{.005, .01, .025, .05, .075, .1, .25, .5, .75, 1.0, 2.5, 5.0, 7.5, 10.0, math.Inf(1)},
 	}))
+	grpcRequestsHandled = newCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_requests_handled",
+		Help: "Handled gRPC calls counter and metadata associated with them",
+	},
+		[]string{"code", "method", "tenant"},
+	))
+	grpcRequestsDuration = newCollector(prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "grpc_requests_duration",
+		Help:    "Time spent processing gRPC calls",
+		Buckets: []float64{.005, .01, .025, .05, .075, .1, .25, .5, .75, 1.0, 2.5, 5.0, 7.5, 10.0, math.Inf(1)},
+	}))
 	eventsProcessed = newCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "events_processed",
 		Help: "Handled PubSub events counter and metadata associated with them",
 	},
-		[]string{"event_name", "status"},
+		[]string{"event_name", "status", "tenant"},
 	))
 	eventsDuration = newCollector(prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "events_duration",
@@ -33,20 +44,81 @@ var (
 	},
 		[]string{"event_name"},
 	))
+	panicsRecovered = newCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "panics_recovered",
+		Help: "Panics recovered from request and event handlers",
+	},
+		[]string{"source", "name"},
+	))
+	appMode = newCollector(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "app_mode",
+		Help: "Mode the app is in, 1 for the current one",
+	},
+		[]string{"mode"},
+	))
+	featureFlagEvaluations = newCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "feature_flag_evaluations",
+		Help: "Feature flag evaluations counter and their outcome",
+	},
+		[]string{"flag", "variant", "reason"},
+	))
+	webhookDeliveries = newCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_deliveries",
+		Help: "Webhook delivery attempts counter and their outcome",
+	},
+		[]string{"event_type", "outcome"},
+	))
+	webhookDeliveryDuration = newCollector(prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "webhook_delivery_duration",
+		Help:    "Time spent sending webhook deliveries",
+		Buckets: []float64{.005, .01, .025, .05, .075, .1, .25, .5, .75, 1.0, 2.5, 5.0, 7.5, 10.0, math.Inf(1)},
+	}))
 )
 
-func RecordRequestHandled(statusCode int, routeName string) {
-	requestsHandled.WithLabelValues(strconv.Itoa(statusCode), routeName).Inc()
+func RecordRequestHandled(statusCode int, routeName, tenant string) {
+	requestsHandled.WithLabelValues(strconv.Itoa(statusCode), routeName, tenant).Inc()
 }
 
 func ObserveRequestDuration(duration time.Duration) {
 	requestsDuration.Observe(duration.Seconds())
 }
 
-func RecordEventProcessed(eventName, status string) {
-	eventsProcessed.WithLabelValues(eventName, status).Inc()
+func RecordGRPCRequestHandled(code, method, tenant string) {
+	grpcRequestsHandled.WithLabelValues(code, method, tenant).Inc()
+}
+
+func ObserveGRPCRequestDuration(duration time.Duration) {
+	grpcRequestsDuration.Observe(duration.Seconds())
+}
+
+func RecordEventProcessed(eventName, status, tenant string) {
+	eventsProcessed.WithLabelValues(eventName, status, tenant).Inc()
 }
 
 func ObserveEventDuration(eventName string, duration time.Duration) {
 	eventsDuration.WithLabelValues(eventName).Observe(duration.Seconds())
 }
+
+// RecordPanicRecovered counts a panic of the source, http, grpc or event, in
+// the handler of the route, method or event name.
+func RecordPanicRecovered(source, name string) {
+	panicsRecovered.WithLabelValues(source, name).Inc()
+}
+
+// SetAppMode marks the mode as the current one.
+func SetAppMode(mode string) {
+	appMode.Reset()
+	appMode.WithLabelValues(mode).Set(1)
+}
+
+func RecordFeatureFlagEvaluation(flag, variant, reason string) {
+	featureFlagEvaluations.WithLabelValues(flag, variant, reason).Inc()
+}
+
+func RecordWebhookDelivery(eventType, outcome string) {
+	webhookDeliveries.WithLabelValues(eventType, outcome).Inc()
+}
+
+func ObserveWebhookDeliveryDuration(duration time.Duration) {
+	webhookDeliveryDuration.Observe(duration.Seconds())
+}
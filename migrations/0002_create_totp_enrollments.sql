@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS totp_enrollments (
+  subject TEXT PRIMARY KEY,
+  secret TEXT NOT NULL,
+  recovery_code_hashes TEXT[] NOT NULL DEFAULT '{}',
+  confirmed_at TIMESTAMPTZ,
+  last_used_step BIGINT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS article_events (
+  id BIGSERIAL PRIMARY KEY,
+  type TEXT NOT NULL,
+  article_id INTEGER NOT NULL,
+  article JSONB NOT NULL,
+  occurred_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS article_events_occurred_at_idx ON article_events (occurred_at);
+
+-- Every change of an article is recorded and announced on the article_events
+-- channel with the event ID as payload. IDs are taken from the sequence in
+-- insert order but become visible in commit order, so readers wait for the
+-- gaps before moving past them.
+CREATE OR REPLACE FUNCTION record_article_event() RETURNS trigger AS $$
+DECLARE
+  event_id BIGINT;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    INSERT INTO article_events (type, article_id, article)
+      VALUES ('article.deleted', OLD.id, to_jsonb(OLD))
+      RETURNING id INTO event_id;
+  ELSE
+    INSERT INTO article_events (type, article_id, article)
+      VALUES (CASE TG_OP WHEN 'INSERT' THEN 'article.created' ELSE 'article.updated' END, NEW.id, to_jsonb(NEW))
+      RETURNING id INTO event_id;
+  END IF;
+
+  PERFORM pg_notify('article_events', event_id::text);
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS articles_record_event ON articles;
+CREATE TRIGGER articles_record_event
+  AFTER INSERT OR UPDATE OR DELETE ON articles
+  FOR EACH ROW EXECUTE FUNCTION record_article_event();
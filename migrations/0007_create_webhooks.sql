@@ -0,0 +1,56 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+  id SERIAL PRIMARY KEY,
+  url TEXT NOT NULL,
+  -- Empty means all event types.
+  events TEXT[] NOT NULL DEFAULT '{}',
+  secret TEXT NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  consecutive_failures INTEGER NOT NULL DEFAULT 0,
+  disabled_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id BIGSERIAL PRIMARY KEY,
+  webhook_id INTEGER NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+  -- Not a foreign key, article events are pruned long before deliveries.
+  event_id BIGINT NOT NULL,
+  event_type TEXT NOT NULL,
+  payload JSONB NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_attempt_at TIMESTAMPTZ,
+  response_status INTEGER,
+  error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id DESC);
+
+-- Deliveries are queued in the transaction that records the article event, so
+-- no event is lost or delivered twice to the same webhook.
+CREATE OR REPLACE FUNCTION queue_webhook_deliveries() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+    SELECT webhooks.id, NEW.id, NEW.type, jsonb_build_object(
+        'id', NEW.id,
+        'type', NEW.type,
+        'article', NEW.article,
+        'occurredAt', NEW.occurred_at
+      )
+      FROM webhooks
+      WHERE webhooks.enabled AND (cardinality(webhooks.events) = 0 OR NEW.type = ANY (webhooks.events))
+    ON CONFLICT (webhook_id, event_id) DO NOTHING;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS article_events_queue_webhook_deliveries ON article_events;
+CREATE TRIGGER article_events_queue_webhook_deliveries
+  AFTER INSERT ON article_events
+  FOR EACH ROW EXECUTE FUNCTION queue_webhook_deliveries();
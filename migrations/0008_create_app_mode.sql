@@ -0,0 +1,23 @@
+-- A single row holding the mode all replicas run in.
+CREATE TABLE IF NOT EXISTS app_mode (
+  id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+  mode TEXT NOT NULL DEFAULT 'normal',
+  reason TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO app_mode (id) VALUES (true) ON CONFLICT (id) DO NOTHING;
+
+-- Changes are announced on the app_mode channel with the mode as payload.
+CREATE OR REPLACE FUNCTION notify_app_mode() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('app_mode', NEW.mode);
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS app_mode_notify ON app_mode;
+CREATE TRIGGER app_mode_notify
+  AFTER UPDATE ON app_mode
+  FOR EACH ROW EXECUTE FUNCTION notify_app_mode();
@@ -0,0 +1,4 @@
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- Tag pages select the articles containing the tag.
+CREATE INDEX IF NOT EXISTS articles_tags_idx ON articles USING GIN (tags);
@@ -0,0 +1,78 @@
+CREATE TABLE IF NOT EXISTS tenants (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  hosts TEXT[] NOT NULL DEFAULT '{}',
+  status TEXT NOT NULL DEFAULT 'active',
+  -- Null for the default tenant, which uses the keys of the deployment.
+  signing_secret BYTEA,
+  admin_key_hash TEXT,
+  editor_key_hash TEXT,
+  viewer_key_hash TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  suspended_at TIMESTAMPTZ
+);
+
+INSERT INTO tenants (id, name) VALUES ('default', 'Default') ON CONFLICT (id) DO NOTHING;
+
+-- The existing articles belong to the default tenant.
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE articles ALTER COLUMN tenant_id DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS articles_tenant_id_idx ON articles (tenant_id, id);
+
+ALTER TABLE article_events ADD COLUMN IF NOT EXISTS tenant_id TEXT;
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS tenant_id TEXT;
+
+-- The existing webhooks belong to the default tenant.
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE webhooks ALTER COLUMN tenant_id DROP DEFAULT;
+
+CREATE OR REPLACE FUNCTION record_article_event() RETURNS trigger AS $$
+DECLARE
+  event_id BIGINT;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    INSERT INTO article_events (type, article_id, tenant_id, article)
+      VALUES ('article.deleted', OLD.id, OLD.tenant_id, to_jsonb(OLD))
+      RETURNING id INTO event_id;
+  ELSE
+    INSERT INTO article_events (type, article_id, tenant_id, article)
+      VALUES (CASE TG_OP WHEN 'INSERT' THEN 'article.created' ELSE 'article.updated' END, NEW.id, NEW.tenant_id, to_jsonb(NEW))
+      RETURNING id INTO event_id;
+  END IF;
+
+  PERFORM pg_notify('article_events', event_id::text);
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Webhooks only receive the events of their tenant.
+CREATE OR REPLACE FUNCTION queue_webhook_deliveries() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+    SELECT webhooks.id, NEW.id, NEW.type, jsonb_build_object(
+        'id', NEW.id,
+        'type', NEW.type,
+        'article', NEW.article,
+        'occurredAt', NEW.occurred_at
+      )
+      FROM webhooks
+      WHERE webhooks.enabled AND webhooks.tenant_id = NEW.tenant_id
+        AND (cardinality(webhooks.events) = 0 OR NEW.type = ANY (webhooks.events))
+    ON CONFLICT (webhook_id, event_id) DO NOTHING;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Defense in depth for the tenant scoping of the queries. Policies only apply
+-- to roles that don't own the table, which see no articles unless the app sets
+-- app.tenant_id, see TENANT_ROW_LEVEL_SECURITY.
+ALTER TABLE articles ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS articles_tenant_isolation ON articles;
+CREATE POLICY articles_tenant_isolation ON articles
+  USING (tenant_id = current_setting('app.tenant_id', true))
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
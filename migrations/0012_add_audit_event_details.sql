@@ -0,0 +1,3 @@
+-- Details of the action other than its actor and target, e.g. the role a
+-- failed login attempted.
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS details JSONB;
@@ -0,0 +1,69 @@
+package apikey
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/goodleby/golang-app/validation"
+	"github.com/lib/pq"
+)
+
+type APIKey struct {
+	ID         int            `json:"id" db:"id"`
+	Name       string         `json:"name" db:"name"`
+	Prefix     string         `json:"prefix" db:"prefix"`
+	Scopes     pq.StringArray `json:"scopes" db:"scopes"`
+	ExpiresAt  time.Time      `json:"expiresAt" db:"expires_at"`
+	LastUsedAt *time.Time     `json:"lastUsedAt" db:"last_used_at"`
+	CreatedAt  time.Time      `json:"createdAt" db:"created_at"`
+	RevokedAt  *time.Time     `json:"revokedAt" db:"revoked_at"`
+}
+
+// Usable reports whether the key is neither revoked nor expired.
+func (k *APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && now.Before(k.ExpiresAt)
+}
+
+type Payload struct {
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (p *Payload) Validate() error {
+	var errs validation.Errors
+
+	if p.Name == "" {
+		errs.Add("name", "must not be empty")
+	}
+
+	if len(p.Scopes) == 0 {
+		errs.Add("scopes", "must not be empty")
+	}
+
+	for i, scope := range p.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			errs.Add(fmt.Sprintf("scopes[%d]", i), fmt.Sprintf("unknown scope %q", scope))
+		}
+	}
+
+	if p.ExpiresAt.IsZero() {
+		errs.Add("expiresAt", "must not be empty")
+	} else if p.ExpiresAt.Before(time.Now()) {
+		errs.Add("expiresAt", "must be in the future")
+	}
+
+	return errs.Err()
+}
+
+const (
+	ScopeArticlesRead  = "articles:read"
+	ScopeArticlesWrite = "articles:write"
+	ScopeAdmin         = "admin"
+	// ScopeIntrospect lets internal services introspect tokens without
+	// granting any other access.
+	ScopeIntrospect = "auth:introspect"
+)
+
+var Scopes = []string{ScopeArticlesRead, ScopeArticlesWrite, ScopeAdmin, ScopeIntrospect}
@@ -1,7 +1,12 @@
 package article
 
 import (
-	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/goodleby/golang-app/validation"
+	"github.com/lib/pq"
 )
 
 type Article struct {
@@ -13,20 +18,51 @@ type Payload struct {
 	Title       string `json:"title" db:"title"`
 	Description string `json:"description" db:"description"`
 	Body        string `json:"body" db:"body"`
+	// Tags are lowercase slugs, e.g. "release-notes". Updates without tags
+	// keep the current ones, an empty list removes them.
+	Tags pq.StringArray `json:"tags" db:"tags"`
 }
 
 func (p *Payload) Validate() error {
+	var errs validation.Errors
+
 	if p.Title == "" {
-		return errors.New("article payload Title is empty")
+		errs.Add("title", "must not be empty")
 	}
 
 	if p.Description == "" {
-		return errors.New("article payload Description is empty")
+		errs.Add("description", "must not be empty")
 	}
 
 	if p.Body == "" {
-		return errors.New("article payload Body is empty")
+		errs.Add("body", "must not be empty")
+	}
+
+	if len(p.Tags) > MaxTags {
+		errs.Add("tags", fmt.Sprintf("must not have more than %d tags", MaxTags))
+	}
+
+	for i, tag := range p.Tags {
+		switch {
+		case !ValidTag(tag):
+			errs.Add(fmt.Sprintf("tags[%d]", i), fmt.Sprintf("must be a lowercase slug of at most %d characters", MaxTagLength))
+		case slices.Contains(p.Tags[:i], tag):
+			errs.Add(fmt.Sprintf("tags[%d]", i), fmt.Sprintf("duplicate tag %q", tag))
+		}
 	}
 
-	return nil
+	return errs.Err()
 }
+
+// ValidTag reports whether the tag is a lowercase slug, so it can be used in
+// URLs as is.
+func ValidTag(tag string) bool {
+	return len(tag) <= MaxTagLength && tagPattern.MatchString(tag)
+}
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+const (
+	MaxTags      int = 10
+	MaxTagLength int = 32
+)
@@ -1,17 +1,24 @@
 package article
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goodleby/golang-app/validation"
+)
 
 func TestPayload_Validate(t *testing.T) {
 	type fields struct {
 		Title       string
 		Description string
 		Body        string
+		Tags        []string
 	}
 	tests := []struct {
-		name    string
-		fields  fields
-		wantErr bool
+		name       string
+		fields     fields
+		wantErr    bool
+		wantFields []string
 	}{
 		{
 			name:    "empty title",
@@ -28,6 +35,38 @@ func TestPayload_Validate(t *testing.T) {
 			fields:  fields{Title: "title", Description: "description", Body: ""},
 			wantErr: true,
 		},
+		{
+			name:       "all empty",
+			fields:     fields{},
+			wantErr:    true,
+			wantFields: []string{"title", "description", "body"},
+		},
+		{
+			name:   "valid",
+			fields: fields{Title: "title", Description: "description", Body: "body"},
+		},
+		{
+			name:   "valid tags",
+			fields: fields{Title: "title", Description: "description", Body: "body", Tags: []string{"go", "release-notes", "v2"}},
+		},
+		{
+			name:       "invalid tags",
+			fields:     fields{Title: "title", Description: "description", Body: "body", Tags: []string{"Go", "release notes", "-go", "go-", "go"}},
+			wantErr:    true,
+			wantFields: []string{"tags[0]", "tags[1]", "tags[2]", "tags[3]"},
+		},
+		{
+			name:       "duplicate tags",
+			fields:     fields{Title: "title", Description: "description", Body: "body", Tags: []string{"go", "sql", "go"}},
+			wantErr:    true,
+			wantFields: []string{"tags[2]"},
+		},
+		{
+			name:       "too many tags",
+			fields:     fields{Title: "title", Description: "description", Body: "body", Tags: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}},
+			wantErr:    true,
+			wantFields: []string{"tags"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -35,9 +74,22 @@ func TestPayload_Validate(t *testing.T) {
 				Title:       tt.fields.Title,
 				Description: tt.fields.Description,
 				Body:        tt.fields.Body,
+				Tags:        tt.fields.Tags,
+			}
+			err := p.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Payload.Validate() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if err := p.Validate(); (err != nil) != tt.wantErr {
-				t.Errorf("Payload.Validate() error = %v, wantErr %v", err, tt.wantErr)
+
+			if tt.wantFields != nil {
+				var fields []string
+				for _, fieldErr := range err.(validation.Errors) {
+					fields = append(fields, fieldErr.Field)
+				}
+
+				if !reflect.DeepEqual(fields, tt.wantFields) {
+					t.Errorf("Payload.Validate() fields = %v, want %v", fields, tt.wantFields)
+				}
 			}
 		})
 	}
@@ -0,0 +1,24 @@
+package article
+
+import "time"
+
+// Event is a change of an article. IDs increase monotonically across all
+// replicas. Deleted events hold the article as it was before the deletion.
+type Event struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	// TenantID is the tenant of the article, events are only streamed to
+	// its callers.
+	TenantID   string    `json:"-"`
+	Article    Article   `json:"article"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+const (
+	EventCreated string = "article.created"
+	EventUpdated string = "article.updated"
+	EventDeleted string = "article.deleted"
+)
+
+// EventTypes are all the types of article events.
+var EventTypes = []string{EventCreated, EventUpdated, EventDeleted}
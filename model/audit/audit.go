@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type Event struct {
+	ID           int64     `json:"id" db:"id"`
+	OccurredAt   time.Time `json:"occurredAt" db:"occurred_at"`
+	Tenant       string    `json:"tenant" db:"tenant_id"`
+	Actor        string    `json:"actor" db:"actor"`
+	Role         string    `json:"role" db:"role"`
+	Action       string    `json:"action" db:"action"`
+	Target       string    `json:"target" db:"target"`
+	Status       int       `json:"status" db:"status"`
+	IP           string    `json:"ip" db:"ip"`
+	UserAgent    string    `json:"userAgent" db:"user_agent"`
+	TraceID      string    `json:"traceId" db:"trace_id"`
+	BeforeDigest *string   `json:"beforeDigest" db:"before_digest"`
+	AfterDigest  *string   `json:"afterDigest" db:"after_digest"`
+	Details      Details   `json:"details,omitempty" db:"details"`
+}
+
+// Details are facts about the action other than its actor and target, e.g.
+// the role a failed login attempted.
+type Details map[string]string
+
+func (d Details) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(d)
+}
+
+func (d *Details) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*d = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, d)
+	case string:
+		return json.Unmarshal([]byte(src), d)
+	default:
+		return fmt.Errorf("can't scan %T into audit details", src)
+	}
+}
+
+// SetActor sets who performed the action, unless it's already known. It is a
+// no-op on a nil event, so callers don't have to check if auditing is on.
+func (e *Event) SetActor(actor, role string) {
+	if e == nil {
+		return
+	}
+
+	if e.Actor == "" {
+		e.Actor = actor
+		e.Role = role
+	}
+}
+
+// SetDetail adds a detail of the action. It is a no-op on a nil event.
+func (e *Event) SetDetail(key, value string) {
+	if e == nil {
+		return
+	}
+
+	if e.Details == nil {
+		e.Details = Details{}
+	}
+	e.Details[key] = value
+}
+
+// SetTarget sets what the action was taken on, for actions whose state isn't
+// digested, see SetChange. It is a no-op on a nil event.
+func (e *Event) SetTarget(target string) {
+	if e == nil {
+		return
+	}
+
+	e.Target = target
+}
+
+// SetChange sets the target and digests of its state before and after the
+// action. Either state can be nil when the target was created or removed.
+func (e *Event) SetChange(target string, before, after any) {
+	if e == nil {
+		return
+	}
+
+	e.Target = target
+	e.BeforeDigest = digest(before)
+	e.AfterDigest = digest(after)
+}
+
+func digest(v any) *string {
+	if v == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	sum := sha256.Sum256(data)
+	hexSum := hex.EncodeToString(sum[:])
+
+	return &hexSum
+}
+
+type eventKey struct{}
+
+func NewContext(ctx context.Context, event *Event) context.Context {
+	return context.WithValue(ctx, eventKey{}, event)
+}
+
+// EventFromContext returns the event being recorded for the current request
+// or nil if the request isn't audited.
+func EventFromContext(ctx context.Context) *Event {
+	event, _ := ctx.Value(eventKey{}).(*Event)
+	return event
+}
+
+type Filter struct {
+	Tenant string     `db:"tenant_id"`
+	Actor  string     `db:"actor"`
+	Action string     `db:"action"`
+	Target string     `db:"target"`
+	Since  *time.Time `db:"since"`
+	Until  *time.Time `db:"until"`
+	// BeforeID is used for paging backwards from the newest events.
+	BeforeID int64 `db:"before_id"`
+	// Limit of 0 means no limit.
+	Limit int `db:"limit"`
+}
@@ -0,0 +1,79 @@
+package mode
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/goodleby/golang-app/validation"
+)
+
+// Mode is what the app accepts: everything, reads only, or nothing but health
+// checks and admin requests.
+type Mode string
+
+const (
+	Normal      Mode = "normal"
+	ReadOnly    Mode = "read-only"
+	Maintenance Mode = "maintenance"
+)
+
+var Modes = []Mode{Normal, ReadOnly, Maintenance}
+
+// AllowsReads reports whether the mode serves requests that don't change
+// anything.
+func (m Mode) AllowsReads() bool {
+	return m != Maintenance
+}
+
+// AllowsWrites reports whether the mode accepts changes.
+func (m Mode) AllowsWrites() bool {
+	return m == Normal
+}
+
+// State is the mode shared by all replicas.
+type State struct {
+	Mode Mode `json:"mode" db:"mode"`
+	// Reason is shown to the admins, e.g. the migration being run.
+	Reason    string    `json:"reason" db:"reason"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type Payload struct {
+	Mode   Mode   `json:"mode"`
+	Reason string `json:"reason"`
+}
+
+func (p *Payload) Validate() error {
+	var errs validation.Errors
+
+	if !slices.Contains(Modes, p.Mode) {
+		errs.Add("mode", fmt.Sprintf("must be one of %v", Modes))
+	}
+
+	if len(p.Reason) > MaxReasonLength {
+		errs.Add("reason", fmt.Sprintf("must be at most %d characters", MaxReasonLength))
+	}
+
+	return errs.Err()
+}
+
+const MaxReasonLength int = 256
+
+// ErrUnavailable is the error of requests the current mode doesn't allow.
+type ErrUnavailable struct {
+	Mode Mode
+}
+
+func (e *ErrUnavailable) Error() string {
+	return fmt.Sprintf("unavailable in %s mode", e.Mode)
+}
+
+// Code is "maintenance" or "read_only".
+func (e *ErrUnavailable) Code() string {
+	if e.Mode == ReadOnly {
+		return "read_only"
+	}
+
+	return string(e.Mode)
+}
@@ -0,0 +1,34 @@
+package session
+
+import "time"
+
+// Session describes the caller of a request.
+type Session struct {
+	ID          string    `json:"id"`
+	Subject     string    `json:"subject"`
+	Role        string    `json:"role"`
+	AccessLevel int       `json:"accessLevel"`
+	Permissions []string  `json:"permissions"`
+	MFA         bool      `json:"mfa"`
+	MFAPending  bool      `json:"mfaPending"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// Introspection is the RFC 7662 token introspection response. Only Active is
+// set for tokens that aren't valid.
+type Introspection struct {
+	Active      bool   `json:"active"`
+	Scope       string `json:"scope,omitempty"`
+	TokenType   string `json:"token_type,omitempty"`
+	Subject     string `json:"sub,omitempty"`
+	ExpiresAt   int64  `json:"exp,omitempty"`
+	IssuedAt    int64  `json:"iat,omitempty"`
+	ID          string `json:"jti,omitempty"`
+	Role        string `json:"role,omitempty"`
+	AccessLevel int    `json:"access_level,omitempty"`
+}
+
+const (
+	TokenTypeSession = "session"
+	TokenTypeAPIKey  = "api_key"
+)
@@ -0,0 +1,20 @@
+package tenant
+
+import "context"
+
+type tenantIDKey struct{}
+
+// NewContext returns a copy of ctx scoped to the tenant.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantIDKey{}, id)
+}
+
+// IDFromContext returns the ID of the tenant of the context or an empty
+// string.
+func IDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantIDKey{}).(string)
+	return id
+}
+
+// Attribute carries the tenant ID in messages and metadata.
+const Attribute = "tenant"
@@ -0,0 +1,136 @@
+package tenant
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/goodleby/golang-app/validation"
+	"github.com/lib/pq"
+)
+
+// Tenant is a site hosted by the deployment, with its own articles, role keys
+// and token signing secret.
+type Tenant struct {
+	ID          string         `json:"id" db:"id"`
+	Name        string         `json:"name" db:"name"`
+	Hosts       pq.StringArray `json:"hosts" db:"hosts"`
+	Status      Status         `json:"status" db:"status"`
+	CreatedAt   time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time      `json:"updatedAt" db:"updated_at"`
+	SuspendedAt *time.Time     `json:"suspendedAt" db:"suspended_at"`
+	// Keys are never returned after the tenant was provisioned.
+	Keys `json:"-"`
+}
+
+// Keys are the secrets of a tenant. The default tenant has none, it uses the
+// role keys and secret of the deployment.
+type Keys struct {
+	SigningSecret []byte  `db:"signing_secret"`
+	AdminKeyHash  *string `db:"admin_key_hash"`
+	EditorKeyHash *string `db:"editor_key_hash"`
+	ViewerKeyHash *string `db:"viewer_key_hash"`
+}
+
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusSuspended Status = "suspended"
+)
+
+type Payload struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Hosts are the hostnames the tenant is served at, without port.
+	Hosts []string `json:"hosts"`
+}
+
+func (p *Payload) Validate() error {
+	var errs validation.Errors
+
+	switch {
+	case p.ID == "":
+		errs.Add("id", "must not be empty")
+	case len(p.ID) > MaxIDLength || !idPattern.MatchString(p.ID):
+		errs.Add("id", fmt.Sprintf("must be a lowercase slug of at most %d characters", MaxIDLength))
+	case p.ID == DefaultID:
+		errs.Add("id", fmt.Sprintf("%q is reserved", DefaultID))
+	}
+
+	if p.Name == "" {
+		errs.Add("name", "must not be empty")
+	}
+
+	for i, host := range p.Hosts {
+		if host != strings.ToLower(host) || !hostPattern.MatchString(host) {
+			errs.Add(fmt.Sprintf("hosts[%d]", i), "must be a lowercase hostname without port")
+		}
+	}
+
+	return errs.Err()
+}
+
+// Provisioned is a new tenant with the role keys to log in, shown only once.
+type Provisioned struct {
+	Tenant
+	RoleKeys RoleKeys `json:"roleKeys"`
+}
+
+type RoleKeys struct {
+	Admin  string `json:"admin"`
+	Editor string `json:"editor"`
+	Viewer string `json:"viewer"`
+}
+
+// ErrUnknown is returned for tenants that aren't provisioned.
+type ErrUnknown struct {
+	ID string
+}
+
+func (e *ErrUnknown) Error() string {
+	return fmt.Sprintf("unknown tenant %q", e.ID)
+}
+
+func (e *ErrUnknown) Code() string {
+	return "unknown_tenant"
+}
+
+// ErrSuspended is returned for the requests to suspended tenants.
+type ErrSuspended struct {
+	ID string
+}
+
+func (e *ErrSuspended) Error() string {
+	return fmt.Sprintf("tenant %q is suspended", e.ID)
+}
+
+func (e *ErrSuspended) Code() string {
+	return "tenant_suspended"
+}
+
+// ErrUnresolved is returned for requests that name no tenant when there is no
+// fallback tenant.
+type ErrUnresolved struct{}
+
+func (e *ErrUnresolved) Error() string {
+	return "no tenant in the header, host or auth token"
+}
+
+func (e *ErrUnresolved) Code() string {
+	return "tenant_required"
+}
+
+var (
+	idPattern   = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	hostPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+)
+
+const (
+	// DefaultID is the tenant of single-tenant deployments, it owns the
+	// articles created before tenants existed. Its admins administer the
+	// deployment, e.g. provision the other tenants.
+	DefaultID   string = "default"
+	MaxIDLength int    = 63
+)
@@ -0,0 +1,69 @@
+package tenant
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goodleby/golang-app/validation"
+)
+
+func TestPayload_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    Payload
+		wantErr    bool
+		wantFields []string
+	}{
+		{
+			name:    "valid",
+			payload: Payload{ID: "acme", Name: "Acme", Hosts: []string{"acme.example.com", "localhost"}},
+		},
+		{
+			name:    "no hosts",
+			payload: Payload{ID: "acme-blog", Name: "Acme blog"},
+		},
+		{
+			name:       "all empty",
+			payload:    Payload{},
+			wantErr:    true,
+			wantFields: []string{"id", "name"},
+		},
+		{
+			name:       "invalid id",
+			payload:    Payload{ID: "Acme Inc", Name: "Acme"},
+			wantErr:    true,
+			wantFields: []string{"id"},
+		},
+		{
+			name:       "default id",
+			payload:    Payload{ID: DefaultID, Name: "Default"},
+			wantErr:    true,
+			wantFields: []string{"id"},
+		},
+		{
+			name:       "invalid hosts",
+			payload:    Payload{ID: "acme", Name: "Acme", Hosts: []string{"acme.example.com:8080", "Acme.example.com", "https://acme.example.com", "-acme.example.com"}},
+			wantErr:    true,
+			wantFields: []string{"hosts[0]", "hosts[1]", "hosts[2]", "hosts[3]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.payload.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Payload.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantFields != nil {
+				var fields []string
+				for _, fieldErr := range err.(validation.Errors) {
+					fields = append(fields, fieldErr.Field)
+				}
+
+				if !reflect.DeepEqual(fields, tt.wantFields) {
+					t.Errorf("Payload.Validate() fields = %v, want %v", fields, tt.wantFields)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package totp
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Enrollment is the TOTP second factor registered for a token subject.
+type Enrollment struct {
+	Subject            string         `db:"subject"`
+	Secret             string         `db:"secret"`
+	RecoveryCodeHashes pq.StringArray `db:"recovery_code_hashes"`
+	ConfirmedAt        *time.Time     `db:"confirmed_at"`
+	LastUsedStep       *int64         `db:"last_used_step"`
+	CreatedAt          time.Time      `db:"created_at"`
+}
+
+func (e *Enrollment) Confirmed() bool {
+	return e.ConfirmedAt != nil
+}
+
+type Setup struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioningUri"`
+	RecoveryCodes   []string `json:"recoveryCodes"`
+}
+
+type VerifyPayload struct {
+	Code string `json:"code"`
+}
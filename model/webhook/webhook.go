@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/validation"
+	"github.com/lib/pq"
+)
+
+// Webhook is a subscription of an endpoint to the article events of its
+// tenant. The secret is never returned after it was set.
+type Webhook struct {
+	ID                  int            `json:"id" db:"id"`
+	URL                 string         `json:"url" db:"url"`
+	Events              pq.StringArray `json:"events" db:"events"`
+	Enabled             bool           `json:"enabled" db:"enabled"`
+	ConsecutiveFailures int            `json:"consecutiveFailures" db:"consecutive_failures"`
+	DisabledAt          *time.Time     `json:"disabledAt" db:"disabled_at"`
+	CreatedAt           time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt           time.Time      `json:"updatedAt" db:"updated_at"`
+}
+
+type Payload struct {
+	URL string `json:"url"`
+	// Events are the article event types to deliver, all of them if empty.
+	Events []string `json:"events"`
+	// Secret signs the deliveries.
+	Secret string `json:"secret"`
+	// Enabled defaults to true. Enabling a webhook resets its failures.
+	Enabled *bool `json:"enabled"`
+}
+
+func (p *Payload) Validate() error {
+	var errs validation.Errors
+
+	u, err := url.Parse(p.URL)
+	switch {
+	case p.URL == "":
+		errs.Add("url", "must not be empty")
+	case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		errs.Add("url", "must be an absolute http or https URL")
+	}
+
+	for i, event := range p.Events {
+		if !slices.Contains(article.EventTypes, event) {
+			errs.Add(fmt.Sprintf("events[%d]", i), fmt.Sprintf("unknown event type %q", event))
+		}
+	}
+
+	if len(p.Secret) < MinSecretLength {
+		errs.Add("secret", fmt.Sprintf("must be at least %d characters", MinSecretLength))
+	}
+
+	return errs.Err()
+}
+
+// IsEnabled reports whether the payload enables the webhook.
+func (p *Payload) IsEnabled() bool {
+	return p.Enabled == nil || *p.Enabled
+}
+
+const MinSecretLength int = 16
+
+// Delivery is an article event queued for a webhook, with the outcome of its
+// last attempt.
+type Delivery struct {
+	ID             int64           `json:"id" db:"id"`
+	WebhookID      int             `json:"webhookId" db:"webhook_id"`
+	EventID        int64           `json:"eventId" db:"event_id"`
+	EventType      string          `json:"eventType" db:"event_type"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt" db:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt" db:"last_attempt_at"`
+	ResponseStatus *int            `json:"responseStatus" db:"response_status"`
+	Error          *string         `json:"error" db:"error"`
+	CreatedAt      time.Time       `json:"createdAt" db:"created_at"`
+}
+
+const (
+	DeliveryPending   string = "pending"
+	DeliverySucceeded string = "succeeded"
+	DeliveryFailed    string = "failed"
+)
+
+// Attempt is a delivery claimed for sending, with the endpoint to send it to.
+type Attempt struct {
+	DeliveryID int64           `db:"id"`
+	WebhookID  int             `db:"webhook_id"`
+	EventType  string          `db:"event_type"`
+	Payload    json.RawMessage `db:"payload"`
+	// Number is the count of attempts including this one.
+	Number int    `db:"attempts"`
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+// Result is the outcome of an attempt. ResponseStatus is 0 if the endpoint
+// didn't respond.
+type Result struct {
+	ResponseStatus int
+	Err            error
+}
+
+type DeliveryFilter struct {
+	WebhookID int    `db:"webhook_id"`
+	Status    string `db:"status"`
+	// BeforeID is used for paging backwards from the newest deliveries.
+	BeforeID int64 `db:"before_id"`
+	Limit    int   `db:"limit"`
+}
@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/goodleby/golang-app/validation"
+)
+
+func TestPayload_Validate(t *testing.T) {
+	const secret = "0123456789abcdef"
+
+	tests := []struct {
+		name       string
+		payload    Payload
+		wantFields []string
+	}{
+		{
+			name:    "valid payload",
+			payload: Payload{URL: "https://example.com/hooks", Events: []string{"article.created"}, Secret: secret},
+		},
+		{
+			name:    "all events",
+			payload: Payload{URL: "http://receiver:8080", Secret: secret},
+		},
+		{
+			name:       "relative url",
+			payload:    Payload{URL: "/hooks", Secret: secret},
+			wantFields: []string{"url"},
+		},
+		{
+			name:       "unsupported scheme",
+			payload:    Payload{URL: "ftp://example.com", Secret: secret},
+			wantFields: []string{"url"},
+		},
+		{
+			name:       "unknown event and short secret",
+			payload:    Payload{URL: "https://example.com", Events: []string{"article.created", "article.read"}, Secret: "short"},
+			wantFields: []string{"events[1]", "secret"},
+		},
+		{
+			name:       "all empty",
+			payload:    Payload{},
+			wantFields: []string{"url", "secret"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.payload.Validate()
+			if (err != nil) != (tt.wantFields != nil) {
+				t.Fatalf("Payload.Validate() error = %v, want errors for %v", err, tt.wantFields)
+			}
+			if err == nil {
+				return
+			}
+
+			var errs validation.Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Payload.Validate() error = %T, want validation.Errors", err)
+			}
+
+			var fields []string
+			for _, fieldErr := range errs {
+				fields = append(fields, fieldErr.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("Payload.Validate() fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
@@ -1,18 +1,19 @@
 package processor
 
 import (
+	"github.com/goodleby/golang-app/client/pubsub"
 	"github.com/goodleby/golang-app/processor/event"
 	"github.com/goodleby/golang-app/processor/handler"
 	"github.com/goodleby/golang-app/processor/middleware"
 )
 
 func (p *Processor) setupEvents() {
-	p.use(middleware.Trace, middleware.Metrics)
+	p.use(middleware.Trace, middleware.Tenant(p.Clients.Tenants), middleware.Metrics, middleware.Recover)
 
 	p.handle(event.Event{
 		Name:           "AddArticle",
-		SubscriptionID: "golang-app-add-article-sub",
-		Handler:        handler.AddArticle(p.Clients.DB),
+		SubscriptionID: pubsub.AddArticleSubscriptionID,
+		Handler:        handler.AddArticle(p.Clients.DB, p.Clients.Mode),
 		Throttle:       1,
 	})
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/model/mode"
 	"github.com/goodleby/golang-app/processor/event"
 )
 
@@ -13,8 +14,19 @@ type ArticleInserter interface {
 	InsertArticle(ctx context.Context, payload article.Payload) (*article.Article, error)
 }
 
-func AddArticle(articleInserter ArticleInserter) event.Handler {
+type ModeReader interface {
+	CurrentMode() mode.State
+}
+
+// AddArticle nacks the messages unless in normal mode, so they are redelivered
+// once writes are accepted again.
+func AddArticle(articleInserter ArticleInserter, modes ModeReader) event.Handler {
 	return func(ctx context.Context, msg *event.Message) {
+		if current := modes.CurrentMode().Mode; !current.AllowsWrites() {
+			HandleError(ctx, msg, fmt.Errorf("error adding an article: %w", &mode.ErrUnavailable{Mode: current}), true)
+			return
+		}
+
 		var payload article.Payload
 		err := json.Unmarshal(msg.Data, &payload)
 		if err != nil {
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/goodleby/golang-app/metrics"
+	"github.com/goodleby/golang-app/model/tenant"
 	"github.com/goodleby/golang-app/processor/event"
 )
 
@@ -14,7 +15,7 @@ func Metrics(eventName string, next event.Handler) event.Handler {
 		next(ctx, msg)
 		duration := time.Since(start)
 
-		metrics.RecordEventProcessed(eventName, msg.Status)
+		metrics.RecordEventProcessed(eventName, msg.Status, tenant.IDFromContext(ctx))
 		metrics.ObserveEventDuration(eventName, duration)
 	}
 }
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/goodleby/golang-app/metrics"
+	"github.com/goodleby/golang-app/processor/event"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+// Recover nacks messages whose handler panicked, so they are redelivered
+// instead of taking the processor down. The panic is recorded in the span,
+// logged with its stack and counted.
+func Recover(eventName string, next event.Handler) event.Handler {
+	return func(ctx context.Context, msg *event.Message) {
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+
+			stack := debug.Stack()
+
+			span := tracing.SpanFromContext(ctx)
+			span.RecordPanic(value, stack)
+
+			metrics.RecordPanicRecovered("event", eventName)
+
+			slog.Error(fmt.Sprintf("Event panic: %v", value),
+				"event", eventName,
+				"stack", string(stack),
+				"trace_id", span.TraceID(),
+			)
+
+			msg.SetStatus(event.StatusRetry)
+			msg.Nack()
+		}()
+
+		next(ctx, msg)
+	}
+}
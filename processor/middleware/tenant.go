@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/goodleby/golang-app/model/tenant"
+	"github.com/goodleby/golang-app/processor/event"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type TenantResolver interface {
+	Resolve(header, host, claim string) (*tenant.Tenant, error)
+}
+
+// Tenant scopes the handling of messages to the tenant they were published
+// for, the default one for the messages published before tenants existed.
+// The messages of unknown tenants are dropped and the ones of suspended
+// tenants are redelivered until the tenant is resumed.
+func Tenant(tenants TenantResolver) event.Middleware {
+	return func(eventName string, next event.Handler) event.Handler {
+		return func(ctx context.Context, msg *event.Message) {
+			id := msg.Attributes[tenant.Attribute]
+			if id == "" {
+				id = tenant.DefaultID
+			}
+
+			span := tracing.SpanFromContext(ctx)
+			span.SetTag("tenant", id)
+
+			ctx = tenant.NewContext(ctx, id)
+
+			_, err := tenants.Resolve(id, "", "")
+			if err != nil {
+				span.RecordError(err)
+				slog.Error(fmt.Sprintf("Event error: %v", err), "event", eventName)
+
+				switch err.(type) {
+				case *tenant.ErrSuspended:
+					msg.SetStatus(event.StatusRetry)
+					msg.Nack()
+				default:
+					msg.SetStatus(event.StatusFailed)
+					msg.Ack()
+				}
+				return
+			}
+
+			next(ctx, msg)
+		}
+	}
+}
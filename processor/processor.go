@@ -9,6 +9,7 @@ import (
 	"cloud.google.com/go/pubsub"
 	"github.com/goodleby/golang-app/processor/event"
 	"github.com/goodleby/golang-app/processor/handler"
+	"github.com/goodleby/golang-app/processor/middleware"
 )
 
 type Processor struct {
@@ -18,8 +19,10 @@ type Processor struct {
 }
 
 type Clients struct {
-	PubSub PubSubClient
-	DB     DBClient
+	PubSub  PubSubClient
+	DB      DBClient
+	Mode    handler.ModeReader
+	Tenants middleware.TenantResolver
 }
 
 type PubSubClient interface {
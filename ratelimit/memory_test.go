@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_TakeRateLimitToken(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	limit := Limit{Requests: 2, Per: 2 * time.Second}
+
+	tests := []struct {
+		name           string
+		advance        time.Duration
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:          "first request uses the burst",
+			wantAllowed:   true,
+			wantRemaining: 1,
+		},
+		{
+			name:          "second request empties the bucket",
+			wantAllowed:   true,
+			wantRemaining: 0,
+		},
+		{
+			name:           "third request is limited",
+			wantAllowed:    false,
+			wantRemaining:  0,
+			wantRetryAfter: time.Second,
+		},
+		{
+			name:          "bucket refills over time",
+			advance:       time.Second,
+			wantAllowed:   true,
+			wantRemaining: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+
+			got, err := s.TakeRateLimitToken(context.Background(), "key", limit)
+			if err != nil {
+				t.Fatalf("MemoryStore.TakeRateLimitToken() error = %v", err)
+			}
+			if got.Allowed != tt.wantAllowed {
+				t.Errorf("MemoryStore.TakeRateLimitToken() allowed = %v, want %v", got.Allowed, tt.wantAllowed)
+			}
+			if got.Remaining != tt.wantRemaining {
+				t.Errorf("MemoryStore.TakeRateLimitToken() remaining = %v, want %v", got.Remaining, tt.wantRemaining)
+			}
+			if got.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("MemoryStore.TakeRateLimitToken() retry after = %v, want %v", got.RetryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
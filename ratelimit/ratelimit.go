@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows a burst of Requests which refills evenly over Per.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit parses limits in the "100/1m" form.
+func ParseLimit(s string) (Limit, error) {
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, want requests/duration", s)
+	}
+
+	var limit Limit
+	var err error
+
+	limit.Requests, err = strconv.Atoi(requests)
+	if err != nil || limit.Requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit requests %q", requests)
+	}
+
+	limit.Per, err = time.ParseDuration(per)
+	if err != nil || limit.Per <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit duration %q", per)
+	}
+
+	return limit, nil
+}
+
+// ParseLimits parses a map of limits in the "100/1m" form.
+func ParseLimits(m map[string]string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(m))
+	for name, s := range m {
+		limit, err := ParseLimit(s)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s rate limit: %v", name, err)
+		}
+		limits[name] = limit
+	}
+
+	return limits, nil
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// Rate is the number of tokens refilled per second.
+func (l Limit) Rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Refill returns the tokens of a bucket after elapsed time, capped at the
+// burst size.
+func (l Limit) Refill(tokens float64, elapsed time.Duration) float64 {
+	return math.Min(float64(l.Requests), tokens+elapsed.Seconds()*l.Rate())
+}
+
+// Limits are the configured limits by route group and by role.
+type Limits struct {
+	Groups map[string]Limit
+	Roles  map[string]Limit
+}
+
+// Longest returns the longest window of the limits, the time after which an
+// unused bucket of any of them is full.
+func (l Limits) Longest() time.Duration {
+	var longest time.Duration
+	for _, limits := range []map[string]Limit{l.Groups, l.Roles} {
+		for _, limit := range limits {
+			longest = max(longest, limit.Per)
+		}
+	}
+
+	return longest
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// Reset is when the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is when the next request is allowed, only set if not allowed.
+	RetryAfter time.Duration
+}
+
+// NewResult describes a bucket left with the given tokens after a request.
+func NewResult(limit Limit, tokens float64, allowed bool) *Result {
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     secondsDuration((float64(limit.Requests) - tokens) / limit.Rate()),
+	}
+
+	if !allowed {
+		result.RetryAfter = secondsDuration((1 - tokens) / limit.Rate())
+	}
+
+	return &result
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(math.Max(0, seconds) * float64(time.Second))
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SweepStore is a shared store of buckets, which aren't dropped when used like
+// the ones of MemoryStore.
+type SweepStore interface {
+	SweepRateLimits(ctx context.Context, before time.Time) error
+}
+
+// Sweeper regularly deletes the buckets of the store that haven't been used
+// for the longest window of the limits. They are full, the same as new ones.
+// Sweeper is an app service.
+type Sweeper struct {
+	store SweepStore
+	idle  time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewSweeper(store SweepStore, limits Limits) *Sweeper {
+	return &Sweeper{
+		store: store,
+		idle:  limits.Longest(),
+		done:  make(chan struct{}),
+	}
+}
+
+func (s *Sweeper) Start(ctx context.Context, errc chan<- error) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	defer close(s.done)
+
+	ticker := time.NewTicker(storeSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.store.SweepRateLimits(ctx, time.Now().Add(-s.idle))
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error sweeping rate limits: %v", err))
+			}
+		}
+	}
+}
+
+func (s *Sweeper) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("error stopping rate limit sweeper: %v", ctx.Err())
+	}
+}
+
+const storeSweepInterval = 10 * time.Minute
@@ -0,0 +1,146 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/rpc/articlev1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type articleService struct {
+	articlev1.UnimplementedArticleServiceServer
+
+	db DBClient
+}
+
+func (a *articleService) GetArticle(ctx context.Context, req *articlev1.GetArticleRequest) (*articlev1.Article, error) {
+	article, err := a.db.SelectArticle(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, toStatus(ctx, fmt.Errorf("error selecting article %d: %w", req.GetId(), err))
+	}
+
+	return toArticle(article), nil
+}
+
+func (a *articleService) ListArticles(ctx context.Context, req *articlev1.ListArticlesRequest) (*articlev1.ListArticlesResponse, error) {
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	var afterID int
+	if req.GetPageToken() != "" {
+		var err error
+		afterID, err = decodePageToken(req.GetPageToken())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+
+	// One more article than the page tells whether there is a next page.
+	page, err := a.db.SelectArticlesAfter(ctx, afterID, pageSize+1)
+	if err != nil {
+		return nil, toStatus(ctx, fmt.Errorf("error selecting articles: %w", err))
+	}
+
+	var res articlev1.ListArticlesResponse
+	if len(page) > pageSize {
+		page = page[:pageSize]
+		res.NextPageToken = encodePageToken(page[len(page)-1].ID)
+	}
+
+	res.Articles = make([]*articlev1.Article, len(page))
+	for i := range page {
+		res.Articles[i] = toArticle(&page[i])
+	}
+
+	return &res, nil
+}
+
+func (a *articleService) CreateArticle(ctx context.Context, req *articlev1.CreateArticleRequest) (*articlev1.Article, error) {
+	payload := article.Payload{
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+		Body:        req.GetBody(),
+	}
+
+	err := payload.Validate()
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	article, err := a.db.InsertArticle(ctx, payload)
+	if err != nil {
+		return nil, toStatus(ctx, fmt.Errorf("error inserting article: %w", err))
+	}
+
+	return toArticle(article), nil
+}
+
+func (a *articleService) UpdateArticle(ctx context.Context, req *articlev1.UpdateArticleRequest) (*articlev1.Article, error) {
+	payload := article.Payload{
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+		Body:        req.GetBody(),
+	}
+
+	err := payload.Validate()
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	article, err := a.db.UpdateArticle(ctx, int(req.GetId()), payload)
+	if err != nil {
+		return nil, toStatus(ctx, fmt.Errorf("error updating article %d: %w", req.GetId(), err))
+	}
+
+	return toArticle(article), nil
+}
+
+func (a *articleService) DeleteArticle(ctx context.Context, req *articlev1.DeleteArticleRequest) (*emptypb.Empty, error) {
+	err := a.db.DeleteArticle(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, toStatus(ctx, fmt.Errorf("error deleting article %d: %w", req.GetId(), err))
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func toArticle(a *article.Article) *articlev1.Article {
+	return &articlev1.Article{
+		Id:          int64(a.ID),
+		Title:       a.Title,
+		Description: a.Description,
+		Body:        a.Body,
+	}
+}
+
+// Page tokens are opaque to clients, so the paging can change.
+func encodePageToken(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodePageToken(token string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(string(data))
+}
+
+const (
+	defaultPageSize int = 20
+	maxPageSize     int = 100
+)
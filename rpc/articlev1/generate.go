@@ -0,0 +1,4 @@
+// Package articlev1 is the generated code of the article service.
+package articlev1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative article.proto
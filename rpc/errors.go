@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/goodleby/golang-app/validation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus converts the error to a status with the code of its kind, the
+// invalid fields are in the BadRequest details. Internal errors are logged and
+// their message is hidden from the caller.
+func toStatus(ctx context.Context, err error) error {
+	span := tracing.SpanFromContext(ctx)
+	span.RecordError(err)
+
+	var validationErrs validation.Errors
+	if errors.As(err, &validationErrs) {
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(validationErrs))
+		for i, fieldErr := range validationErrs {
+			violations[i] = &errdetails.BadRequest_FieldViolation{Field: fieldErr.Field, Description: fieldErr.Message}
+		}
+
+		st, detailsErr := status.New(codes.InvalidArgument, "validation failed").WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+		if detailsErr != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return st.Err()
+	}
+
+	var notFound *client.ErrNotFound
+	if errors.As(err, &notFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	slog.Error(fmt.Sprintf("gRPC handler error: %v", err), "trace_id", span.TraceID())
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// httpStatus maps the code to its HTTP status, so gRPC calls are recorded in
+// the audit log like their REST counterparts.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
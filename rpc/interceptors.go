@@ -0,0 +1,250 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/metrics"
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/model/mode"
+	"github.com/goodleby/golang-app/model/tenant"
+	"github.com/goodleby/golang-app/rpc/articlev1"
+	"github.com/goodleby/golang-app/server/middleware"
+	"github.com/goodleby/golang-app/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type method struct {
+	access auth.AccessLevel
+	// action is the audit log action, mutations only.
+	action string
+}
+
+// methods are the access levels and audit actions of the article service,
+// which match the REST routes. Methods that aren't listed, i.e. health and
+// reflection, need no credentials.
+var methods = map[string]method{
+	articlev1.ArticleService_GetArticle_FullMethodName:    {access: auth.ViewerAccess},
+	articlev1.ArticleService_ListArticles_FullMethodName:  {access: auth.ViewerAccess},
+	articlev1.ArticleService_CreateArticle_FullMethodName: {access: auth.EditorAccess, action: "articles.create"},
+	articlev1.ArticleService_UpdateArticle_FullMethodName: {access: auth.EditorAccess, action: "articles.update"},
+	articlev1.ArticleService_DeleteArticle_FullMethodName: {access: auth.EditorAccess, action: "articles.delete"},
+}
+
+// trace continues the trace of the caller propagated in the metadata.
+func (s *Server) trace(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	carrier := map[string]string{}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		if len(values) > 0 {
+			carrier[key] = values[0]
+		}
+	}
+
+	ctx, span := tracing.StartSpanFromCarrier(ctx, carrier, info.FullMethod)
+	defer span.End()
+
+	span.SetTag("rpc.system", "grpc")
+	span.SetTag("rpc.method", info.FullMethod)
+
+	res, err := handler(ctx, req)
+
+	span.SetTag("rpc.grpc.status_code", status.Code(err).String())
+
+	return res, err
+}
+
+// recover responds with an internal error to calls whose handler panicked,
+// instead of taking the server down, like middleware.Recover. The panic is
+// recorded in the span, logged with its stack and counted.
+func (s *Server) recover(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+	defer func() {
+		value := recover()
+		if value == nil {
+			return
+		}
+
+		stack := debug.Stack()
+
+		span := tracing.SpanFromContext(ctx)
+		span.RecordPanic(value, stack)
+
+		metrics.RecordPanicRecovered("grpc", info.FullMethod)
+
+		slog.Error(fmt.Sprintf("Handler panic: %v", value),
+			"method", info.FullMethod,
+			"stack", string(stack),
+			"trace_id", span.TraceID(),
+		)
+
+		res, err = nil, status.Error(codes.Internal, "internal error")
+	}()
+
+	return handler(ctx, req)
+}
+
+// tenant scopes the calls to the article service to the tenant named in the
+// x-tenant-id metadata, else to the one claimed by the auth token, like
+// middleware.Tenant.
+func (s *Server) tenant(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if _, ok := methods[info.FullMethod]; !ok {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	token, _ := strings.CutPrefix(firstValue(md, "authorization"), "Bearer ")
+
+	t, err := s.Clients.Tenants.Resolve(firstValue(md, tenantMetadata), "", auth.TokenTenant(token))
+	if err != nil {
+		switch err.(type) {
+		case *tenant.ErrUnresolved:
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case *tenant.ErrSuspended:
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		default:
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+	}
+
+	span := tracing.SpanFromContext(ctx)
+	span.SetTag("tenant", t.ID)
+
+	return handler(tenant.NewContext(ctx, t.ID), req)
+}
+
+func (s *Server) metrics(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+
+	res, err := handler(ctx, req)
+
+	duration := time.Since(start)
+
+	metrics.RecordGRPCRequestHandled(status.Code(err).String(), info.FullMethod, tenant.IDFromContext(ctx))
+	metrics.ObserveGRPCRequestDuration(duration)
+
+	return res, err
+}
+
+// mode rejects the calls to the article service in maintenance mode, and the
+// mutations unless in normal mode, like middleware.Available and
+// middleware.Writable.
+func (s *Server) mode(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	m, ok := methods[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	current := s.Clients.Mode.CurrentMode().Mode
+	if !current.AllowsReads() || (m.action != "" && !current.AllowsWrites()) {
+		return nil, status.Error(codes.Unavailable, (&mode.ErrUnavailable{Mode: current}).Error())
+	}
+
+	return handler(ctx, req)
+}
+
+// auth checks the access level of the caller, identified either by an API key
+// in the x-api-key metadata or by the auth token in the authorization
+// metadata. The claims of the caller are passed down in the context.
+func (s *Server) auth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	m, ok := methods[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	claims, err := s.readClaims(ctx)
+	if err == nil && claims == nil {
+		return nil, status.Error(codes.Unauthenticated, "no api key or auth token")
+	}
+	if err != nil {
+		var unauthorized *client.ErrUnauthorized
+		if errors.As(err, &unauthorized) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, toStatus(ctx, err)
+	}
+
+	if claims.AccessLevel < m.access {
+		return nil, status.Error(codes.PermissionDenied, "insufficient access level")
+	}
+
+	return handler(auth.NewContext(ctx, claims), req)
+}
+
+// readClaims returns nil claims if the call has no credentials.
+func (s *Server) readClaims(ctx context.Context) (*auth.Claims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if apiKey := firstValue(md, "x-api-key"); apiKey != "" {
+		return s.Clients.Auth.ReadAPIKeyClaims(ctx, apiKey)
+	}
+
+	token, ok := strings.CutPrefix(firstValue(md, "authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, nil
+	}
+
+	return s.Clients.Auth.ReadTokenClaims(ctx, token)
+}
+
+// audit records the mutations in the audit log, see middleware.Audit.
+func (s *Server) audit(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	m, ok := methods[info.FullMethod]
+	if !ok || m.action == "" {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	span := tracing.SpanFromContext(ctx)
+
+	event := &audit.Event{
+		Action:    m.action,
+		IP:        s.peerIP(ctx, md),
+		UserAgent: firstValue(md, "user-agent"),
+		TraceID:   span.TraceID(),
+	}
+
+	if claims := auth.ClaimsFromContext(ctx); claims != nil {
+		event.SetActor(claims.Subject, claims.RoleName)
+	}
+
+	res, err := handler(audit.NewContext(ctx, event), req)
+
+	middleware.RecordAuditEvent(ctx, s.Clients.DB, event, httpStatus(status.Code(err)))
+
+	return res, err
+}
+
+func firstValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// peerIP returns the address of the original client, see
+// clientip.Proxies.Resolve.
+func (s *Server) peerIP(ctx context.Context, md metadata.MD) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	return s.Config.TrustedProxies.Resolve(p.Addr.String(), md.Get("x-forwarded-for"))
+}
+
+// tenantMetadata names the tenant of calls, like the tenant header of the REST
+// API.
+const tenantMetadata string = "x-tenant-id"
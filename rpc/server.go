@@ -0,0 +1,115 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/goodleby/golang-app/clientip"
+	"github.com/goodleby/golang-app/rpc/articlev1"
+	"github.com/goodleby/golang-app/server/handler"
+	"github.com/goodleby/golang-app/server/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server serves the articles over gRPC for internal services, next to the
+// REST API. It also serves the standard health service and server reflection.
+type Server struct {
+	Host    string
+	Port    uint16
+	GRPC    *grpc.Server
+	Health  *health.Server
+	Config  Config
+	Clients Clients
+}
+
+type Config struct {
+	Host string
+	Port uint16
+	// TrustedProxies are the proxies whose x-forwarded-for entries are
+	// believed. Without them the address of the peer is the caller's.
+	TrustedProxies clientip.Proxies
+}
+
+type Clients struct {
+	DB      DBClient
+	Auth    middleware.TokenClaimsReader
+	Mode    middleware.ModeReader
+	Tenants middleware.TenantResolver
+}
+
+type DBClient interface {
+	handler.ArticlesAfterSelector
+	handler.ArticleSelector
+	handler.ArticleInserter
+	handler.ArticleUpdater
+	handler.ArticleDeleter
+	middleware.AuditRecorder
+}
+
+func New(ctx context.Context, config Config, clients Clients) (*Server, error) {
+	var s Server
+
+	s.Host = config.Host
+	s.Port = config.Port
+	s.Config = config
+	s.Clients = clients
+
+	s.GRPC = grpc.NewServer(grpc.ChainUnaryInterceptor(
+		s.trace,
+		s.recover,
+		s.tenant,
+		s.metrics,
+		s.mode,
+		s.auth,
+		s.audit,
+	))
+
+	articlev1.RegisterArticleServiceServer(s.GRPC, &articleService{db: clients.DB})
+
+	s.Health = health.NewServer()
+	healthpb.RegisterHealthServer(s.GRPC, s.Health)
+	s.Health.SetServingStatus(articlev1.ArticleService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	reflection.Register(s.GRPC)
+
+	return &s, nil
+}
+
+func (s *Server) Start(ctx context.Context, errc chan<- error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", s.Host, s.Port))
+	if err != nil {
+		errc <- fmt.Errorf("error listening for grpc: %v", err)
+		return
+	}
+
+	slog.Info(fmt.Sprintf("gRPC server is listening at %s:%d", s.Host, s.Port))
+	err = s.GRPC.Serve(listener)
+	if err != nil {
+		errc <- fmt.Errorf("error serving grpc: %v", err)
+	}
+}
+
+// Stop lets the pending calls finish, unless the context is done first.
+func (s *Server) Stop(ctx context.Context) error {
+	// Tells the clients watching the health to go elsewhere.
+	s.Health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.GRPC.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.GRPC.Stop()
+		return fmt.Errorf("error gracefully stopping grpc server: %v", ctx.Err())
+	}
+}
@@ -0,0 +1,493 @@
+package rpc
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/model/mode"
+	"github.com/goodleby/golang-app/model/tenant"
+	"github.com/goodleby/golang-app/rpc/articlev1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeDB struct {
+	articles map[int]article.Article
+	nextID   int
+	events   []audit.Event
+}
+
+func newFakeDB(n int) *fakeDB {
+	db := &fakeDB{articles: map[int]article.Article{}, nextID: n + 1}
+	for id := 1; id <= n; id++ {
+		db.articles[id] = article.Article{ID: id, Payload: article.Payload{Title: fmt.Sprintf("Article %d", id), Description: "description", Body: "body"}}
+	}
+
+	return db
+}
+
+func (db *fakeDB) SelectArticlesAfter(ctx context.Context, afterID, limit int) ([]article.Article, error) {
+	articles := []article.Article{}
+	for id := afterID + 1; id < db.nextID && len(articles) < limit; id++ {
+		if a, ok := db.articles[id]; ok {
+			articles = append(articles, a)
+		}
+	}
+
+	return articles, nil
+}
+
+func (db *fakeDB) SelectArticle(ctx context.Context, id int) (*article.Article, error) {
+	a, ok := db.articles[id]
+	if !ok {
+		return nil, &client.ErrNotFound{Err: fmt.Errorf("article %d not found", id)}
+	}
+
+	return &a, nil
+}
+
+func (db *fakeDB) InsertArticle(ctx context.Context, payload article.Payload) (*article.Article, error) {
+	a := article.Article{ID: db.nextID, Payload: payload}
+	db.articles[a.ID] = a
+	db.nextID++
+
+	return &a, nil
+}
+
+func (db *fakeDB) UpdateArticle(ctx context.Context, id int, payload article.Payload) (*article.Article, error) {
+	if _, ok := db.articles[id]; !ok {
+		return nil, &client.ErrNotFound{Err: fmt.Errorf("article %d not found", id)}
+	}
+
+	a := article.Article{ID: id, Payload: payload}
+	db.articles[id] = a
+
+	return &a, nil
+}
+
+func (db *fakeDB) DeleteArticle(ctx context.Context, id int) error {
+	if _, ok := db.articles[id]; !ok {
+		return &client.ErrNotFound{Err: fmt.Errorf("article %d not found", id)}
+	}
+
+	delete(db.articles, id)
+
+	return nil
+}
+
+func (db *fakeDB) RecordAuditEvent(ctx context.Context, event *audit.Event) error {
+	db.events = append(db.events, *event)
+	return nil
+}
+
+// fakeAuth accepts the tokens and API keys named after their access level.
+type fakeAuth struct{}
+
+var accessLevels = map[string]auth.AccessLevel{
+	"viewer": auth.ViewerAccess,
+	"editor": auth.EditorAccess,
+}
+
+func (fakeAuth) ReadTokenClaims(ctx context.Context, token string) (*auth.Claims, error) {
+	access, ok := accessLevels[token]
+	if !ok {
+		return nil, &client.ErrUnauthorized{Err: fmt.Errorf("invalid token")}
+	}
+
+	claims := &auth.Claims{RoleName: token, AccessLevel: access}
+	claims.Subject = token + "@example.com"
+
+	return claims, nil
+}
+
+func (fakeAuth) ReadAPIKeyClaims(ctx context.Context, key string) (*auth.Claims, error) {
+	return fakeAuth{}.ReadTokenClaims(ctx, key)
+}
+
+func (fakeAuth) ReadCertificateClaims(ctx context.Context, cert *x509.Certificate) (*auth.Claims, error) {
+	return fakeAuth{}.ReadTokenClaims(ctx, cert.Subject.CommonName)
+}
+
+type fakeModes struct {
+	mode mode.Mode
+}
+
+func (f fakeModes) CurrentMode() mode.State {
+	return mode.State{Mode: f.mode}
+}
+
+// fakeTenants serves the default tenant, and the acme one suspended.
+type fakeTenants struct{}
+
+func (fakeTenants) Resolve(header, host, claim string) (*tenant.Tenant, error) {
+	switch header {
+	case "", tenant.DefaultID:
+		return &tenant.Tenant{ID: tenant.DefaultID, Status: tenant.StatusActive}, nil
+	case "acme":
+		return nil, &tenant.ErrSuspended{ID: header}
+	default:
+		return nil, &tenant.ErrUnknown{ID: header}
+	}
+}
+
+func newTestConn(t *testing.T, db DBClient) *grpc.ClientConn {
+	t.Helper()
+
+	return newTestConnInMode(t, db, mode.Normal)
+}
+
+func newTestConnInMode(t *testing.T, db DBClient, m mode.Mode) *grpc.ClientConn {
+	t.Helper()
+
+	s, err := New(context.Background(), Config{}, Clients{DB: db, Auth: fakeAuth{}, Mode: fakeModes{mode: m}, Tenants: fakeTenants{}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	go s.GRPC.Serve(listener)
+	t.Cleanup(s.GRPC.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("error dialing server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		call     func(ctx context.Context, c articlev1.ArticleServiceClient) error
+		wantCode codes.Code
+	}{
+		{
+			name: "no credentials",
+			ctx:  context.Background(),
+			call: func(ctx context.Context, c articlev1.ArticleServiceClient) error {
+				_, err := c.GetArticle(ctx, &articlev1.GetArticleRequest{Id: 1})
+				return err
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "invalid token",
+			ctx:  withToken("forged"),
+			call: func(ctx context.Context, c articlev1.ArticleServiceClient) error {
+				_, err := c.GetArticle(ctx, &articlev1.GetArticleRequest{Id: 1})
+				return err
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "viewer reads with a token",
+			ctx:  withToken("viewer"),
+			call: func(ctx context.Context, c articlev1.ArticleServiceClient) error {
+				_, err := c.GetArticle(ctx, &articlev1.GetArticleRequest{Id: 1})
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "viewer reads with an api key",
+			ctx:  metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "viewer"),
+			call: func(ctx context.Context, c articlev1.ArticleServiceClient) error {
+				_, err := c.ListArticles(ctx, &articlev1.ListArticlesRequest{})
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "viewer can't delete",
+			ctx:  withToken("viewer"),
+			call: func(ctx context.Context, c articlev1.ArticleServiceClient) error {
+				_, err := c.DeleteArticle(ctx, &articlev1.DeleteArticleRequest{Id: 1})
+				return err
+			},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "editor deletes",
+			ctx:  withToken("editor"),
+			call: func(ctx context.Context, c articlev1.ArticleServiceClient) error {
+				_, err := c.DeleteArticle(ctx, &articlev1.DeleteArticleRequest{Id: 1})
+				return err
+			},
+			wantCode: codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := articlev1.NewArticleServiceClient(newTestConn(t, newFakeDB(1)))
+
+			err := tt.call(tt.ctx, c)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("code = %v, want %v (%v)", code, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestMode(t *testing.T) {
+	get := func(ctx context.Context, c articlev1.ArticleServiceClient) error {
+		_, err := c.GetArticle(ctx, &articlev1.GetArticleRequest{Id: 1})
+		return err
+	}
+	create := func(ctx context.Context, c articlev1.ArticleServiceClient) error {
+		_, err := c.CreateArticle(ctx, &articlev1.CreateArticleRequest{Title: "t", Description: "d", Body: "b"})
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		mode     mode.Mode
+		call     func(ctx context.Context, c articlev1.ArticleServiceClient) error
+		wantCode codes.Code
+	}{
+		{
+			name:     "read-only reads",
+			mode:     mode.ReadOnly,
+			call:     get,
+			wantCode: codes.OK,
+		},
+		{
+			name:     "read-only rejects mutations",
+			mode:     mode.ReadOnly,
+			call:     create,
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:     "maintenance rejects reads",
+			mode:     mode.Maintenance,
+			call:     get,
+			wantCode: codes.Unavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := articlev1.NewArticleServiceClient(newTestConnInMode(t, newFakeDB(1), tt.mode))
+
+			err := tt.call(withToken("editor"), c)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("code = %v, want %v (%v)", code, tt.wantCode, err)
+			}
+		})
+	}
+
+	health := healthpb.NewHealthClient(newTestConnInMode(t, newFakeDB(0), mode.Maintenance))
+	_, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Errorf("Check() in maintenance mode error = %v", err)
+	}
+}
+
+func TestTenant(t *testing.T) {
+	tests := []struct {
+		name     string
+		tenantID string
+		wantCode codes.Code
+	}{
+		{
+			name:     "default",
+			wantCode: codes.OK,
+		},
+		{
+			name:     "suspended",
+			tenantID: "acme",
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "unknown",
+			tenantID: "globex",
+			wantCode: codes.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := articlev1.NewArticleServiceClient(newTestConn(t, newFakeDB(1)))
+
+			ctx := withToken("viewer")
+			if tt.tenantID != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, tenantMetadata, tt.tenantID)
+			}
+
+			_, err := c.GetArticle(ctx, &articlev1.GetArticleRequest{Id: 1})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("code = %v, want %v (%v)", code, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestHealthIsPublic(t *testing.T) {
+	c := healthpb.NewHealthClient(newTestConn(t, newFakeDB(0)))
+
+	res, err := c.Check(context.Background(), &healthpb.HealthCheckRequest{Service: articlev1.ArticleService_ServiceDesc.ServiceName})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Check() = %v, want %v", res.GetStatus(), healthpb.HealthCheckResponse_SERVING)
+	}
+}
+
+// panickingDB panics on selecting an article.
+type panickingDB struct {
+	*fakeDB
+}
+
+func (db panickingDB) SelectArticle(ctx context.Context, id int) (*article.Article, error) {
+	panic("boom")
+}
+
+func TestRecover(t *testing.T) {
+	c := articlev1.NewArticleServiceClient(newTestConn(t, panickingDB{newFakeDB(1)}))
+	ctx := withToken("viewer")
+
+	_, err := c.GetArticle(ctx, &articlev1.GetArticleRequest{Id: 1})
+	if code := status.Code(err); code != codes.Internal {
+		t.Errorf("GetArticle() code = %v, want %v", code, codes.Internal)
+	}
+
+	// The server keeps serving after the panic.
+	_, err = c.ListArticles(ctx, &articlev1.ListArticlesRequest{})
+	if err != nil {
+		t.Errorf("ListArticles() error = %v", err)
+	}
+}
+
+func TestListArticles(t *testing.T) {
+	c := articlev1.NewArticleServiceClient(newTestConn(t, newFakeDB(5)))
+	ctx := withToken("viewer")
+
+	var ids []int64
+	req := &articlev1.ListArticlesRequest{PageSize: 2}
+	for page := 0; page < 3; page++ {
+		res, err := c.ListArticles(ctx, req)
+		if err != nil {
+			t.Fatalf("ListArticles() error = %v", err)
+		}
+
+		for _, a := range res.GetArticles() {
+			ids = append(ids, a.GetId())
+		}
+
+		if wantNext := page < 2; (res.GetNextPageToken() != "") != wantNext {
+			t.Errorf("page %d next_page_token = %q, want it set %v", page, res.GetNextPageToken(), wantNext)
+		}
+		req.PageToken = res.GetNextPageToken()
+	}
+
+	if want := []int64{1, 2, 3, 4, 5}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("paged ids = %v, want %v", ids, want)
+	}
+
+	_, err := c.ListArticles(ctx, &articlev1.ListArticlesRequest{PageToken: "?"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("ListArticles() with invalid token code = %v, want %v", code, codes.InvalidArgument)
+	}
+}
+
+func TestMutations(t *testing.T) {
+	tests := []struct {
+		name           string
+		call           func(ctx context.Context, c articlev1.ArticleServiceClient) error
+		wantCode       codes.Code
+		wantViolations []string
+		wantAudit      audit.Event
+	}{
+		{
+			name: "create",
+			call: func(ctx context.Context, c articlev1.ArticleServiceClient) error {
+				_, err := c.CreateArticle(ctx, &articlev1.CreateArticleRequest{Title: "t", Description: "d", Body: "b"})
+				return err
+			},
+			wantCode:  codes.OK,
+			wantAudit: audit.Event{Action: "articles.create", Role: "editor", Status: 200},
+		},
+		{
+			name: "create invalid",
+			call: func(ctx context.Context, c articlev1.ArticleServiceClient) error {
+				_, err := c.CreateArticle(ctx, &articlev1.CreateArticleRequest{Body: "b"})
+				return err
+			},
+			wantCode:       codes.InvalidArgument,
+			wantViolations: []string{"title", "description"},
+			wantAudit:      audit.Event{Action: "articles.create", Role: "editor", Status: 400},
+		},
+		{
+			name: "update missing",
+			call: func(ctx context.Context, c articlev1.ArticleServiceClient) error {
+				_, err := c.UpdateArticle(ctx, &articlev1.UpdateArticleRequest{Id: 42, Title: "t", Description: "d", Body: "b"})
+				return err
+			},
+			wantCode:  codes.NotFound,
+			wantAudit: audit.Event{Action: "articles.update", Role: "editor", Status: 404},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(1)
+			c := articlev1.NewArticleServiceClient(newTestConn(t, db))
+
+			// Without trusted proxies, the caller can't set its audited IP.
+			ctx := metadata.AppendToOutgoingContext(withToken("editor"), "x-forwarded-for", "203.0.113.9")
+			err := tt.call(ctx, c)
+
+			st := status.Convert(err)
+			if st.Code() != tt.wantCode {
+				t.Errorf("code = %v, want %v (%v)", st.Code(), tt.wantCode, err)
+			}
+
+			var violations []string
+			for _, detail := range st.Details() {
+				if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+					for _, violation := range badRequest.GetFieldViolations() {
+						violations = append(violations, violation.GetField())
+					}
+				}
+			}
+			if !reflect.DeepEqual(violations, tt.wantViolations) {
+				t.Errorf("field violations = %v, want %v", violations, tt.wantViolations)
+			}
+
+			if len(db.events) != 1 {
+				t.Fatalf("audit events = %d, want 1", len(db.events))
+			}
+			got := db.events[0]
+			if got.IP == "203.0.113.9" {
+				t.Errorf("audit event IP = %v, want the peer address", got.IP)
+			}
+			got = audit.Event{Action: got.Action, Role: got.Role, Status: got.Status}
+			if !reflect.DeepEqual(got, tt.wantAudit) {
+				t.Errorf("audit event = %+v, want %+v", got, tt.wantAudit)
+			}
+		})
+	}
+}
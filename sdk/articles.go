@@ -0,0 +1,59 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/model/article"
+)
+
+func (c *Client) ListArticles(ctx context.Context) ([]article.Article, error) {
+	var articles []article.Article
+	err := c.do(ctx, request{method: http.MethodGet, path: "/articles", refresh: true}, &articles)
+	if err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+func (c *Client) GetArticle(ctx context.Context, id int) (*article.Article, error) {
+	var a article.Article
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/articles/%d", id), refresh: true}, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// CreateArticle adds an article, retries can't add it twice.
+func (c *Client) CreateArticle(ctx context.Context, payload article.Payload) (*article.Article, error) {
+	var a article.Article
+	err := c.do(ctx, request{method: http.MethodPost, path: "/articles", body: payload, idempotent: true, refresh: true}, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+func (c *Client) UpdateArticle(ctx context.Context, id int, payload article.Payload) (*article.Article, error) {
+	var a article.Article
+	err := c.do(ctx, request{method: http.MethodPut, path: fmt.Sprintf("/articles/%d", id), body: payload, refresh: true}, &a)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+func (c *Client) DeleteArticle(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/articles/%d", id), refresh: true}, nil)
+}
+
+// PublishArticle queues the article to be added by the event processor.
+func (c *Client) PublishArticle(ctx context.Context, payload article.Payload) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/pubsub/articles", body: payload, idempotent: true, refresh: true}, nil)
+}
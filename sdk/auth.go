@@ -0,0 +1,57 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Login logs in with the key of the role, the client then sends the auth
+// token with every request and refreshes it before it expires.
+func (c *Client) Login(ctx context.Context, role, key string) error {
+	body := struct {
+		Role string `json:"role"`
+		Key  string `json:"key"`
+	}{
+		Role: role,
+		Key:  key,
+	}
+
+	return c.do(ctx, request{method: http.MethodPost, path: "/auth/login", body: body}, nil)
+}
+
+// Refresh extends the auth token, which happens on its own before it expires.
+func (c *Client) Refresh(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/auth/refresh"}, nil)
+}
+
+// Logout drops the auth token, even if the API can't be reached.
+func (c *Client) Logout(ctx context.Context) error {
+	defer c.SetToken("", time.Time{})
+
+	return c.do(ctx, request{method: http.MethodPost, path: "/auth/logout"}, nil)
+}
+
+// refreshIfExpiring refreshes the token if it expires within the refresh
+// window. Concurrent calls wait for a single refresh.
+func (c *Client) refreshIfExpiring(ctx context.Context) error {
+	if !c.expiring() {
+		return nil
+	}
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	// Another call may have refreshed it meanwhile.
+	if !c.expiring() {
+		return nil
+	}
+
+	return c.Refresh(ctx)
+}
+
+func (c *Client) expiring() bool {
+	token, expires := c.Token()
+
+	return token != "" && !expires.IsZero() && time.Until(expires) < c.refreshBefore
+}
@@ -0,0 +1,333 @@
+// Package sdk is a Go client of the v1 API.
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client calls the v1 API on behalf of a single caller. It's safe for
+// concurrent use.
+type Client struct {
+	baseURL       string
+	http          *http.Client
+	authMode      AuthMode
+	retries       int
+	backoff       time.Duration
+	maxRetryWait  time.Duration
+	refreshBefore time.Duration
+	tenant        string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+
+	// refreshMu lets a single call refresh the expiring token.
+	refreshMu sync.Mutex
+}
+
+// AuthMode is how the auth token is sent, browsers use the cookie.
+type AuthMode int
+
+const (
+	CookieAuth AuthMode = iota
+	BearerAuth
+)
+
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with the client instead of
+// http.DefaultClient, e.g. for TLS settings.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.http = httpClient
+	}
+}
+
+func WithAuthMode(mode AuthMode) Option {
+	return func(c *Client) {
+		c.authMode = mode
+	}
+}
+
+// WithRetries sets how many times 429 and 5xx responses are retried, and the
+// backoff doubling between the retries when the API doesn't tell when to
+// retry with Retry-After.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithMaxRetryWait sets the longest wait before a retry, responses asking to
+// retry later fail straight away.
+func WithMaxRetryWait(wait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetryWait = wait
+	}
+}
+
+// WithRefreshBefore sets how long before its expiry the auth token is
+// refreshed.
+func WithRefreshBefore(d time.Duration) Option {
+	return func(c *Client) {
+		c.refreshBefore = d
+	}
+}
+
+// WithTenant names the tenant of the requests in the X-Tenant-ID header, for
+// deployments that don't tell the tenants apart by host.
+func WithTenant(id string) Option {
+	return func(c *Client) {
+		c.tenant = id
+	}
+}
+
+// New creates a client of the API at the base URL, e.g.
+// https://example.com/api/v1.
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		http:          http.DefaultClient,
+		authMode:      CookieAuth,
+		retries:       defaultRetries,
+		backoff:       defaultBackoff,
+		maxRetryWait:  defaultMaxRetryWait,
+		refreshBefore: defaultRefreshBefore,
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// SetToken authenticates the client with a token obtained elsewhere, a zero
+// expiry disables the automatic refresh.
+func (c *Client) SetToken(token string, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = token
+	c.expires = expires
+}
+
+// Token returns the current auth token and its expiry.
+func (c *Client) Token() (string, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.token, c.expires
+}
+
+type request struct {
+	method string
+	path   string
+	body   any
+	// idempotent requests get an Idempotency-Key, so retrying them is safe.
+	idempotent bool
+	// refresh refreshes the token first if it's about to expire.
+	refresh bool
+}
+
+// do sends the request, retrying it on 429 and 5xx responses, and decodes the
+// response into out unless it's nil.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return fmt.Errorf("error encoding request body: %v", err)
+		}
+	}
+
+	if req.refresh {
+		err := c.refreshIfExpiring(ctx)
+		if err != nil {
+			return fmt.Errorf("error refreshing auth token: %w", err)
+		}
+	}
+
+	var idempotencyKey string
+	if req.idempotent {
+		var err error
+		idempotencyKey, err = randomKey()
+		if err != nil {
+			return fmt.Errorf("error generating idempotency key: %v", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, c.baseURL+req.path, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("error creating request: %v", err)
+		}
+
+		httpReq.Header.Set("Accept", "application/json")
+		if body != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+		if idempotencyKey != "" {
+			httpReq.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+		if c.tenant != "" {
+			httpReq.Header.Set("X-Tenant-ID", c.tenant)
+		}
+		c.authenticate(httpReq)
+
+		res, err := c.http.Do(httpReq)
+		if err != nil {
+			return fmt.Errorf("error sending %s %s: %w", req.method, req.path, err)
+		}
+
+		if res.StatusCode < http.StatusBadRequest {
+			return c.readResponse(res, out)
+		}
+
+		apiErr := readError(res)
+
+		wait, ok := c.retryWait(res, attempt)
+		if !retryable(res.StatusCode) || !ok {
+			return typedError(apiErr)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("error waiting to retry %s %s: %w", req.method, req.path, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) authenticate(r *http.Request) {
+	token, _ := c.Token()
+	if token == "" {
+		return
+	}
+
+	switch c.authMode {
+	case BearerAuth:
+		r.Header.Set("Authorization", "Bearer "+token)
+	default:
+		r.AddCookie(&http.Cookie{Name: tokenCookie, Value: token})
+	}
+}
+
+// readResponse keeps the token set by the auth routes and decodes the body.
+func (c *Client) readResponse(res *http.Response, out any) error {
+	defer res.Body.Close()
+
+	for _, cookie := range res.Cookies() {
+		if cookie.Name != tokenCookie {
+			continue
+		}
+
+		if cookie.Value == "" || cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && !cookie.Expires.After(time.Now())) {
+			c.SetToken("", time.Time{})
+		} else {
+			c.SetToken(cookie.Value, cookie.Expires)
+		}
+	}
+
+	if out == nil {
+		_, err := io.Copy(io.Discard, res.Body)
+		return err
+	}
+
+	err := json.NewDecoder(res.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("error decoding response body: %v", err)
+	}
+
+	return nil
+}
+
+// readError reads the problem details of the error response, responses that
+// aren't problems, e.g. from a proxy, get the status as code.
+func readError(res *http.Response) *Error {
+	defer res.Body.Close()
+
+	apiErr := &Error{StatusCode: res.StatusCode}
+
+	err := json.NewDecoder(io.LimitReader(res.Body, maxErrorSize)).Decode(&apiErr.Problem)
+	if err != nil || apiErr.Problem.Code == "" {
+		apiErr.Problem = Problem{
+			Title:  http.StatusText(res.StatusCode),
+			Status: res.StatusCode,
+			Code:   strings.ReplaceAll(strings.ToLower(http.StatusText(res.StatusCode)), " ", "_"),
+		}
+	}
+
+	return apiErr
+}
+
+// retryWait returns how long to wait before retrying the response, and false
+// if the retries are exhausted or the API asks to wait too long.
+func (c *Client) retryWait(res *http.Response, attempt int) (time.Duration, bool) {
+	if attempt >= c.retries {
+		return 0, false
+	}
+
+	wait := c.backoff << attempt
+	if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+		wait = retryAfter
+	}
+
+	return wait, wait <= c.maxRetryWait
+}
+
+// parseRetryAfter parses both forms of Retry-After, seconds or a date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+func randomKey() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", errors.New("error reading random bytes")
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+const (
+	tokenCookie          string        = "token"
+	defaultRetries       int           = 3
+	defaultBackoff       time.Duration = 200 * time.Millisecond
+	defaultMaxRetryWait  time.Duration = 30 * time.Second
+	defaultRefreshBefore time.Duration = time.Minute
+	maxErrorSize         int64         = 1 << 20
+)
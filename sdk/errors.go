@@ -0,0 +1,139 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/validation"
+)
+
+// Problem is the RFC 9457 problem details the API responds with on errors.
+type Problem struct {
+	Type     string                  `json:"type"`
+	Title    string                  `json:"title"`
+	Status   int                     `json:"status"`
+	Detail   string                  `json:"detail"`
+	Instance string                  `json:"instance"`
+	Code     string                  `json:"code"`
+	Errors   []validation.FieldError `json:"errors"`
+}
+
+// Error is an error response of the API. The typed errors below wrap it for
+// the statuses callers usually handle, use errors.As to tell them apart.
+type Error struct {
+	StatusCode int
+	Problem    Problem
+}
+
+func (e *Error) Error() string {
+	if e.Problem.Detail != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Problem.Code, e.Problem.Detail)
+	}
+
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Code is the problem code, which is stable and can be relied on unlike the
+// messages.
+func (e *Error) Code() string {
+	return e.Problem.Code
+}
+
+type ErrBadRequest struct {
+	Err *Error
+}
+
+func (e *ErrBadRequest) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrBadRequest) Unwrap() error {
+	return e.Err
+}
+
+// Fields are the invalid fields of the request, if it failed validation.
+func (e *ErrBadRequest) Fields() []validation.FieldError {
+	return e.Err.Problem.Errors
+}
+
+type ErrUnauthorized struct {
+	Err *Error
+}
+
+func (e *ErrUnauthorized) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrUnauthorized) Unwrap() error {
+	return e.Err
+}
+
+type ErrForbidden struct {
+	Err *Error
+}
+
+func (e *ErrForbidden) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrForbidden) Unwrap() error {
+	return e.Err
+}
+
+type ErrNotFound struct {
+	Err *Error
+}
+
+func (e *ErrNotFound) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrNotFound) Unwrap() error {
+	return e.Err
+}
+
+type ErrConflict struct {
+	Err *Error
+}
+
+func (e *ErrConflict) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrConflict) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnavailable is returned once the retries are exhausted for 429 and 5xx
+// responses, e.g. in maintenance mode.
+type ErrUnavailable struct {
+	Err *Error
+}
+
+func (e *ErrUnavailable) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrUnavailable) Unwrap() error {
+	return e.Err
+}
+
+// typedError wraps the error response in the typed error of its status.
+func typedError(err *Error) error {
+	switch {
+	case err.StatusCode == http.StatusBadRequest:
+		return &ErrBadRequest{Err: err}
+	case err.StatusCode == http.StatusUnauthorized:
+		return &ErrUnauthorized{Err: err}
+	case err.StatusCode == http.StatusForbidden:
+		return &ErrForbidden{Err: err}
+	case err.StatusCode == http.StatusNotFound:
+		return &ErrNotFound{Err: err}
+	case err.StatusCode == http.StatusConflict:
+		return &ErrConflict{Err: err}
+	case retryable(err.StatusCode):
+		return &ErrUnavailable{Err: err}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,514 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/idempotency"
+	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/model/mode"
+	"github.com/goodleby/golang-app/model/tenant"
+	"github.com/goodleby/golang-app/sdk"
+	"github.com/goodleby/golang-app/server"
+)
+
+// fakeDB keeps the articles in memory, the other database methods aren't
+// called by the SDK.
+type fakeDB struct {
+	server.DBClient
+
+	mu       sync.Mutex
+	articles map[int]article.Article
+	nextID   int
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{articles: map[int]article.Article{}, nextID: 1}
+}
+
+func (db *fakeDB) SelectAllArticles(ctx context.Context) ([]article.Article, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	articles := []article.Article{}
+	for id := 1; id < db.nextID; id++ {
+		if a, ok := db.articles[id]; ok {
+			articles = append(articles, a)
+		}
+	}
+
+	return articles, nil
+}
+
+func (db *fakeDB) SelectArticle(ctx context.Context, id int) (*article.Article, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	a, ok := db.articles[id]
+	if !ok {
+		return nil, &client.ErrNotFound{Err: fmt.Errorf("article %d not found", id)}
+	}
+
+	return &a, nil
+}
+
+func (db *fakeDB) InsertArticle(ctx context.Context, payload article.Payload) (*article.Article, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	a := article.Article{ID: db.nextID, Payload: payload}
+	db.articles[a.ID] = a
+	db.nextID++
+
+	return &a, nil
+}
+
+func (db *fakeDB) UpdateArticle(ctx context.Context, id int, payload article.Payload) (*article.Article, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.articles[id]; !ok {
+		return nil, &client.ErrNotFound{Err: fmt.Errorf("article %d not found", id)}
+	}
+
+	a := article.Article{ID: id, Payload: payload}
+	db.articles[id] = a
+
+	return &a, nil
+}
+
+func (db *fakeDB) DeleteArticle(ctx context.Context, id int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.articles[id]; !ok {
+		return &client.ErrNotFound{Err: fmt.Errorf("article %d not found", id)}
+	}
+
+	delete(db.articles, id)
+
+	return nil
+}
+
+func (db *fakeDB) RecordAuditEvent(ctx context.Context, event *audit.Event) error {
+	return nil
+}
+
+type fakePubSub struct {
+	mu        sync.Mutex
+	published []article.Payload
+}
+
+func (p *fakePubSub) PublishAddArticle(ctx context.Context, payload article.Payload) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.published = append(p.published, payload)
+
+	return nil
+}
+
+// fakeModes is in maintenance for the next unavailable requests.
+type fakeModes struct {
+	unavailable atomic.Int32
+}
+
+func (f *fakeModes) CurrentMode() mode.State {
+	if f.unavailable.Add(-1) >= 0 {
+		return mode.State{Mode: mode.Maintenance}
+	}
+
+	return mode.State{Mode: mode.Normal}
+}
+
+func (f *fakeModes) SetMode(ctx context.Context, payload mode.Payload) (*mode.State, error) {
+	return &mode.State{Mode: payload.Mode, Reason: payload.Reason}, nil
+}
+
+// fakeTenants serves only the default tenant.
+type fakeTenants struct {
+	server.TenantsClient
+}
+
+func (fakeTenants) Resolve(header, host, claim string) (*tenant.Tenant, error) {
+	if header != "" && header != tenant.DefaultID {
+		return nil, &tenant.ErrUnknown{ID: header}
+	}
+
+	return &tenant.Tenant{ID: tenant.DefaultID, Status: tenant.StatusActive}, nil
+}
+
+const (
+	editorKey string = "editor-key"
+	viewerKey string = "viewer-key"
+)
+
+type testAPI struct {
+	URL       string
+	DB        *fakeDB
+	PubSub    *fakePubSub
+	Modes     *fakeModes
+	refreshes atomic.Int32
+}
+
+func newTestAPI(t *testing.T, tokenTTL time.Duration) *testAPI {
+	t.Helper()
+
+	ctx := context.Background()
+
+	api := &testAPI{DB: newFakeDB(), PubSub: &fakePubSub{}, Modes: &fakeModes{}}
+
+	s, err := server.New(ctx, server.Config{IdempotencyTTL: time.Minute, TenantHeader: "X-Tenant-ID"}, server.Clients{
+		DB:          api.DB,
+		Auth:        auth.New(ctx, "secret", tokenTTL, auth.Keys{Editor: editorKey, Viewer: viewerKey}, auth.Stores{}),
+		PubSub:      api.PubSub,
+		Idempotency: idempotency.NewMemoryStore(),
+		Mode:        api.Modes,
+		Tenants:     fakeTenants{},
+	})
+	if err != nil {
+		t.Fatalf("server.New() error = %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/auth/refresh" {
+			api.refreshes.Add(1)
+		}
+		s.Router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+
+	api.URL = ts.URL + "/api/v1"
+
+	return api
+}
+
+func TestClient_Articles(t *testing.T) {
+	tests := []struct {
+		name     string
+		authMode sdk.AuthMode
+	}{
+		{
+			name:     "cookie auth",
+			authMode: sdk.CookieAuth,
+		},
+		{
+			name:     "bearer auth",
+			authMode: sdk.BearerAuth,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			api := newTestAPI(t, time.Hour)
+
+			c := sdk.New(api.URL, sdk.WithAuthMode(tt.authMode))
+
+			err := c.Login(ctx, auth.EditorRole, editorKey)
+			if err != nil {
+				t.Fatalf("Client.Login() error = %v", err)
+			}
+
+			payload := article.Payload{Title: "Title", Description: "Description", Body: "Body", Tags: []string{"go"}}
+			created, err := c.CreateArticle(ctx, payload)
+			if err != nil {
+				t.Fatalf("Client.CreateArticle() error = %v", err)
+			}
+			if !reflect.DeepEqual(created.Payload, payload) {
+				t.Errorf("Client.CreateArticle() = %+v, want %+v", created.Payload, payload)
+			}
+
+			got, err := c.GetArticle(ctx, created.ID)
+			if err != nil {
+				t.Fatalf("Client.GetArticle() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, created) {
+				t.Errorf("Client.GetArticle() = %+v, want %+v", got, created)
+			}
+
+			payload.Title = "New title"
+			updated, err := c.UpdateArticle(ctx, created.ID, payload)
+			if err != nil {
+				t.Fatalf("Client.UpdateArticle() error = %v", err)
+			}
+			if updated.Title != payload.Title {
+				t.Errorf("Client.UpdateArticle() title = %q, want %q", updated.Title, payload.Title)
+			}
+
+			articles, err := c.ListArticles(ctx)
+			if err != nil {
+				t.Fatalf("Client.ListArticles() error = %v", err)
+			}
+			if len(articles) != 1 || !reflect.DeepEqual(articles[0], *updated) {
+				t.Errorf("Client.ListArticles() = %+v, want [%+v]", articles, updated)
+			}
+
+			err = c.DeleteArticle(ctx, created.ID)
+			if err != nil {
+				t.Fatalf("Client.DeleteArticle() error = %v", err)
+			}
+
+			_, err = c.GetArticle(ctx, created.ID)
+			var notFound *sdk.ErrNotFound
+			if !errors.As(err, &notFound) {
+				t.Errorf("Client.GetArticle() of deleted article error = %v, want *sdk.ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t, time.Hour)
+
+	t.Run("wrong key", func(t *testing.T) {
+		c := sdk.New(api.URL)
+
+		err := c.Login(ctx, auth.EditorRole, "wrong-key")
+		var unauthorized *sdk.ErrUnauthorized
+		if !errors.As(err, &unauthorized) {
+			t.Errorf("Client.Login() error = %v, want *sdk.ErrUnauthorized", err)
+		}
+	})
+
+	t.Run("not logged in", func(t *testing.T) {
+		c := sdk.New(api.URL)
+
+		_, err := c.ListArticles(ctx)
+		var unauthorized *sdk.ErrUnauthorized
+		if !errors.As(err, &unauthorized) {
+			t.Errorf("Client.ListArticles() error = %v, want *sdk.ErrUnauthorized", err)
+		}
+	})
+
+	t.Run("viewer creating an article", func(t *testing.T) {
+		c := sdk.New(api.URL)
+
+		err := c.Login(ctx, auth.ViewerRole, viewerKey)
+		if err != nil {
+			t.Fatalf("Client.Login() error = %v", err)
+		}
+
+		_, err = c.CreateArticle(ctx, article.Payload{Title: "Title", Description: "Description", Body: "Body"})
+		var forbidden *sdk.ErrForbidden
+		if !errors.As(err, &forbidden) {
+			t.Errorf("Client.CreateArticle() error = %v, want *sdk.ErrForbidden", err)
+		}
+	})
+
+	t.Run("unknown tenant", func(t *testing.T) {
+		c := sdk.New(api.URL, sdk.WithTenant("acme"))
+
+		err := c.Login(ctx, auth.EditorRole, editorKey)
+		var notFound *sdk.ErrNotFound
+		if !errors.As(err, &notFound) {
+			t.Errorf("Client.Login() error = %v, want *sdk.ErrNotFound", err)
+		}
+	})
+
+	t.Run("invalid article", func(t *testing.T) {
+		c := sdk.New(api.URL)
+
+		err := c.Login(ctx, auth.EditorRole, editorKey)
+		if err != nil {
+			t.Fatalf("Client.Login() error = %v", err)
+		}
+
+		_, err = c.CreateArticle(ctx, article.Payload{Title: "Title"})
+		var badRequest *sdk.ErrBadRequest
+		if !errors.As(err, &badRequest) {
+			t.Fatalf("Client.CreateArticle() error = %v, want *sdk.ErrBadRequest", err)
+		}
+		if len(badRequest.Fields()) != 2 {
+			t.Errorf("ErrBadRequest.Fields() = %v, want description and body", badRequest.Fields())
+		}
+
+		var apiErr *sdk.Error
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+			t.Errorf("Client.CreateArticle() error = %v, want *sdk.Error with status %d", err, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestClient_Retries(t *testing.T) {
+	tests := []struct {
+		name        string
+		unavailable int32
+		retries     int
+		wantErr     bool
+	}{
+		{
+			name:        "retried until available",
+			unavailable: 2,
+			retries:     2,
+		},
+		{
+			name:        "retries exhausted",
+			unavailable: 3,
+			retries:     2,
+			wantErr:     true,
+		},
+		{
+			name:        "no retries",
+			unavailable: 1,
+			retries:     0,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			api := newTestAPI(t, time.Hour)
+
+			// Retry-After is 0, the backoff isn't used.
+			c := sdk.New(api.URL, sdk.WithRetries(tt.retries, time.Hour))
+
+			api.Modes.unavailable.Store(tt.unavailable)
+
+			err := c.Login(ctx, auth.EditorRole, editorKey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Client.Login() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+
+			var unavailable *sdk.ErrUnavailable
+			if !errors.As(err, &unavailable) {
+				t.Fatalf("Client.Login() error = %v, want *sdk.ErrUnavailable", err)
+			}
+			if unavailable.Err.Code() != "maintenance" {
+				t.Errorf("Client.Login() error code = %q, want %q", unavailable.Err.Code(), "maintenance")
+			}
+		})
+	}
+}
+
+func TestClient_RetriesTooLate(t *testing.T) {
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer s.Close()
+
+	// Rate limited for an hour, longer than the client is willing to wait.
+	c := sdk.New(s.URL, sdk.WithMaxRetryWait(time.Second))
+
+	start := time.Now()
+	err := c.Login(ctx, auth.EditorRole, editorKey)
+	var apiErr *sdk.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Client.Login() error = %v, want *sdk.Error with status %d", err, http.StatusTooManyRequests)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Client.Login() took %v, want no wait", elapsed)
+	}
+}
+
+func TestClient_Refresh(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t, time.Hour)
+
+	tests := []struct {
+		name          string
+		refreshBefore time.Duration
+		wantRefreshes int32
+	}{
+		{
+			name:          "token far from expiry",
+			refreshBefore: time.Minute,
+			wantRefreshes: 0,
+		},
+		{
+			name:          "token about to expire",
+			refreshBefore: 2 * time.Hour,
+			wantRefreshes: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := sdk.New(api.URL, sdk.WithRefreshBefore(tt.refreshBefore))
+
+			err := c.Login(ctx, auth.ViewerRole, viewerKey)
+			if err != nil {
+				t.Fatalf("Client.Login() error = %v", err)
+			}
+
+			api.refreshes.Store(0)
+
+			_, err = c.ListArticles(ctx)
+			if err != nil {
+				t.Fatalf("Client.ListArticles() error = %v", err)
+			}
+
+			if got := api.refreshes.Load(); got != tt.wantRefreshes {
+				t.Errorf("Client.ListArticles() refreshes = %v, want %v", got, tt.wantRefreshes)
+			}
+
+			token, expires := c.Token()
+			if token == "" || expires.IsZero() {
+				t.Errorf("Client.Token() = %q, %v, want token and expiry", token, expires)
+			}
+		})
+	}
+}
+
+func TestClient_Logout(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t, time.Hour)
+
+	c := sdk.New(api.URL)
+
+	err := c.Login(ctx, auth.ViewerRole, viewerKey)
+	if err != nil {
+		t.Fatalf("Client.Login() error = %v", err)
+	}
+
+	err = c.Logout(ctx)
+	if err != nil {
+		t.Fatalf("Client.Logout() error = %v", err)
+	}
+
+	if token, _ := c.Token(); token != "" {
+		t.Errorf("Client.Token() after logout = %q, want empty", token)
+	}
+
+	_, err = c.ListArticles(ctx)
+	var unauthorized *sdk.ErrUnauthorized
+	if !errors.As(err, &unauthorized) {
+		t.Errorf("Client.ListArticles() after logout error = %v, want *sdk.ErrUnauthorized", err)
+	}
+}
+
+func TestClient_PublishArticle(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t, time.Hour)
+
+	c := sdk.New(api.URL)
+
+	payload := article.Payload{Title: "Title", Description: "Description", Body: "Body"}
+	err := c.PublishArticle(ctx, payload)
+	if err != nil {
+		t.Fatalf("Client.PublishArticle() error = %v", err)
+	}
+
+	api.PubSub.mu.Lock()
+	defer api.PubSub.mu.Unlock()
+
+	if !reflect.DeepEqual(api.PubSub.published, []article.Payload{payload}) {
+		t.Errorf("Client.PublishArticle() published %+v, want [%+v]", api.PubSub.published, payload)
+	}
+}
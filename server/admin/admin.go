@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/buildinfo"
+	"github.com/goodleby/golang-app/server/handler"
+)
+
+// Server is the admin listener for operators and the cluster: metrics, probes,
+// profiling and runtime settings. Its port must not be exposed publicly.
+type Server struct {
+	Host    string
+	Port    uint16
+	Router  chi.Router
+	HTTP    *http.Server
+	Config  Config
+	Clients Clients
+}
+
+type Config struct {
+	Host      string
+	Port      uint16
+	BuildInfo *buildinfo.Info
+	// Settings is the effective app config, served with the secrets redacted.
+	Settings handler.ConfigRedactor
+}
+
+type Clients struct {
+	Health   handler.ReadinessChecker
+	LogLevel handler.LogLeveler
+}
+
+func New(ctx context.Context, config Config, clients Clients) (*Server, error) {
+	var s Server
+
+	s.Host = config.Host
+	s.Port = config.Port
+	s.Router = chi.NewRouter()
+	s.HTTP = &http.Server{
+		Addr:        fmt.Sprintf("%s:%d", s.Host, s.Port),
+		Handler:     s.Router,
+		ReadTimeout: 5 * time.Second,
+		// CPU profiles and traces are written after the requested duration.
+		WriteTimeout: 2 * time.Minute,
+	}
+	s.Config = config
+	s.Clients = clients
+
+	s.setupRoutes()
+
+	return &s, nil
+}
+
+func (s *Server) Start(ctx context.Context, errc chan<- error) {
+	slog.Info(fmt.Sprintf("Admin server is listening at %s:%d", s.Host, s.Port))
+	err := s.HTTP.ListenAndServe()
+	if err != http.ErrServerClosed {
+		errc <- fmt.Errorf("error listening and serving admin: %v", err)
+	}
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	err := s.HTTP.Shutdown(ctx)
+	if err != nil {
+		return fmt.Errorf("error shutting down admin http server: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package admin
+
+import (
+	"net/http/pprof"
+
+	"github.com/goodleby/golang-app/server/handler"
+	"github.com/goodleby/golang-app/server/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func (s *Server) setupRoutes() {
+	s.Router.Use(middleware.RequestID)
+
+	s.Router.Get("/_livez", handler.Livez)
+	s.Router.Get("/_readyz", handler.Readyz(s.Clients.Health))
+	s.Router.Handle("/metrics", promhttp.Handler())
+
+	s.Router.Get("/buildinfo", handler.GetBuildInfo(s.Config.BuildInfo))
+	s.Router.Get("/config", handler.GetConfig(s.Config.Settings))
+	s.Router.Get("/log-level", handler.GetLogLevel(s.Clients.LogLevel))
+	s.Router.Put("/log-level", handler.SetLogLevel(s.Clients.LogLevel))
+
+	// pprof.Index serves the named profiles, e.g. /debug/pprof/heap.
+	s.Router.HandleFunc("/debug/pprof/*", pprof.Index)
+	s.Router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	s.Router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	s.Router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	s.Router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
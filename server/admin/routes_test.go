@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/buildinfo"
+	"github.com/goodleby/golang-app/health"
+)
+
+type settings map[string]any
+
+func (s settings) Redacted() map[string]any {
+	return s
+}
+
+func TestRoutes(t *testing.T) {
+	s, err := New(context.Background(), Config{
+		BuildInfo: buildinfo.Read("golang-app", "test"),
+		Settings:  settings{"AUTH_SECRET": "[REDACTED]"},
+	}, Clients{
+		Health:   health.NewChecker(time.Second, 0),
+		LogLevel: new(slog.LevelVar),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{method: http.MethodGet, target: "/_livez", wantStatus: http.StatusOK},
+		{method: http.MethodGet, target: "/_readyz", wantStatus: http.StatusOK},
+		{method: http.MethodGet, target: "/metrics", wantStatus: http.StatusOK},
+		{method: http.MethodGet, target: "/buildinfo", wantStatus: http.StatusOK},
+		{method: http.MethodGet, target: "/config", wantStatus: http.StatusOK},
+		{method: http.MethodGet, target: "/log-level", wantStatus: http.StatusOK},
+		{method: http.MethodGet, target: "/debug/pprof/", wantStatus: http.StatusOK},
+		{method: http.MethodGet, target: "/debug/pprof/heap", wantStatus: http.StatusOK},
+		{method: http.MethodGet, target: "/debug/pprof/cmdline", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			s.Router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("%s %s status = %v, want %v", tt.method, tt.target, w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/goodleby/golang-app/requestid"
+	"github.com/goodleby/golang-app/tracing"
+	"github.com/goodleby/golang-app/validation"
+)
+
+// Error is a GraphQL error with a code in its extensions, the way clients tell
+// errors apart.
+type Error struct {
+	Message string
+	Code    string
+	Fields  validation.Errors
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]any {
+	extensions := map[string]any{"code": e.Code}
+	if len(e.Fields) > 0 {
+		extensions["errors"] = e.Fields
+	}
+
+	return extensions
+}
+
+// Status is the HTTP status the REST API responds with in the same case.
+func (e *Error) Status() int {
+	switch e.Code {
+	case CodeBadUserInput, CodeQueryTooComplex, CodePersistedQueryOnly:
+		return http.StatusBadRequest
+	case CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case CodeForbidden:
+		return http.StatusForbidden
+	case CodeNotFound, CodePersistedQueryNotFound:
+		return http.StatusNotFound
+	case CodeServiceUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+const (
+	CodeBadUserInput           string = "BAD_USER_INPUT"
+	CodeUnauthenticated        string = "UNAUTHENTICATED"
+	CodeForbidden              string = "FORBIDDEN"
+	CodeNotFound               string = "NOT_FOUND"
+	CodeInternal               string = "INTERNAL_SERVER_ERROR"
+	CodeServiceUnavailable     string = "SERVICE_UNAVAILABLE"
+	CodeQueryTooComplex        string = "QUERY_TOO_COMPLEX"
+	CodePersistedQueryNotFound string = "PERSISTED_QUERY_NOT_FOUND"
+	CodePersistedQueryOnly     string = "PERSISTED_QUERY_ONLY"
+)
+
+// internalError logs err and hides it from the client, like server errors of
+// the REST API.
+func internalError(ctx context.Context, err error) error {
+	span := tracing.SpanFromContext(ctx)
+	span.RecordError(err)
+
+	slog.Error(fmt.Sprintf("GraphQL resolver error: %v", err),
+		"request_id", requestid.FromContext(ctx),
+		"trace_id", span.TraceID(),
+	)
+
+	return &Error{Message: "internal server error", Code: CodeInternal}
+}
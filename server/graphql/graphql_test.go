@@ -0,0 +1,395 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/model/mode"
+)
+
+type fakeArticles struct {
+	articles map[int]article.Article
+	nextID   int
+}
+
+func newFakeArticles(n int) *fakeArticles {
+	f := &fakeArticles{articles: map[int]article.Article{}, nextID: n + 1}
+	for id := 1; id <= n; id++ {
+		f.articles[id] = article.Article{ID: id, Payload: article.Payload{Title: fmt.Sprintf("Article %d", id), Description: "description", Body: "body"}}
+	}
+
+	return f
+}
+
+func (f *fakeArticles) SelectArticlesAfter(ctx context.Context, afterID, limit int) ([]article.Article, error) {
+	articles := []article.Article{}
+	for id := afterID + 1; id < f.nextID && len(articles) < limit; id++ {
+		if a, ok := f.articles[id]; ok {
+			articles = append(articles, a)
+		}
+	}
+
+	return articles, nil
+}
+
+func (f *fakeArticles) CountArticles(ctx context.Context) (int, error) {
+	return len(f.articles), nil
+}
+
+func (f *fakeArticles) SelectArticle(ctx context.Context, id int) (*article.Article, error) {
+	a, ok := f.articles[id]
+	if !ok {
+		return nil, &client.ErrNotFound{Err: fmt.Errorf("article %d not found", id)}
+	}
+
+	return &a, nil
+}
+
+func (f *fakeArticles) InsertArticle(ctx context.Context, payload article.Payload) (*article.Article, error) {
+	a := article.Article{ID: f.nextID, Payload: payload}
+	f.articles[a.ID] = a
+	f.nextID++
+
+	return &a, nil
+}
+
+func (f *fakeArticles) UpdateArticle(ctx context.Context, id int, payload article.Payload) (*article.Article, error) {
+	if _, ok := f.articles[id]; !ok {
+		return nil, &client.ErrNotFound{Err: fmt.Errorf("article %d not found", id)}
+	}
+
+	a := article.Article{ID: id, Payload: payload}
+	f.articles[id] = a
+
+	return &a, nil
+}
+
+func (f *fakeArticles) DeleteArticle(ctx context.Context, id int) error {
+	if _, ok := f.articles[id]; !ok {
+		return &client.ErrNotFound{Err: fmt.Errorf("article %d not found", id)}
+	}
+
+	delete(f.articles, id)
+
+	return nil
+}
+
+type fakeRecorder struct {
+	events []audit.Event
+}
+
+func (f *fakeRecorder) RecordAuditEvent(ctx context.Context, event *audit.Event) error {
+	f.events = append(f.events, *event)
+	return nil
+}
+
+type fakeModes struct {
+	mode mode.Mode
+}
+
+func (f fakeModes) CurrentMode() mode.State {
+	return mode.State{Mode: f.mode}
+}
+
+type response struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func (r *response) errorCodes() []string {
+	var codes []string
+	for _, err := range r.Errors {
+		code, _ := err.Extensions["code"].(string)
+		codes = append(codes, code)
+	}
+
+	return codes
+}
+
+func do(t *testing.T, h http.Handler, access auth.AccessLevel, method string, req Request) (int, *response) {
+	t.Helper()
+
+	var r *http.Request
+	switch method {
+	case http.MethodGet:
+		query := url.Values{"query": {req.Query}}
+		if req.Extensions.PersistedQuery != nil {
+			extensions, _ := json.Marshal(req.Extensions)
+			query.Set("extensions", string(extensions))
+		}
+		r = httptest.NewRequest(method, "/graphql?"+query.Encode(), nil)
+	default:
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatalf("error encoding request: %v", err)
+		}
+		r = httptest.NewRequest(method, "/graphql", bytes.NewReader(body))
+	}
+
+	r = r.WithContext(auth.NewContext(r.Context(), &auth.Claims{RoleName: "test", AccessLevel: access}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	var res response
+	if w.Code == http.StatusOK {
+		err := json.NewDecoder(w.Body).Decode(&res)
+		if err != nil {
+			t.Fatalf("error decoding response: %v", err)
+		}
+	}
+
+	return w.Code, &res
+}
+
+func newHandler(t *testing.T, articles *fakeArticles, recorder *fakeRecorder, config Config) http.Handler {
+	t.Helper()
+
+	return newHandlerInMode(t, articles, recorder, config, mode.Normal)
+}
+
+func newHandlerInMode(t *testing.T, articles *fakeArticles, recorder *fakeRecorder, config Config, m mode.Mode) http.Handler {
+	t.Helper()
+
+	schema, err := NewSchema(articles, recorder, fakeModes{mode: m})
+	if err != nil {
+		t.Fatalf("NewSchema() error = %v", err)
+	}
+
+	return Handler(schema, config)
+}
+
+func TestHandlerArticles(t *testing.T) {
+	h := newHandler(t, newFakeArticles(5), &fakeRecorder{}, Config{})
+
+	query := `query($after: String) {
+		articles(first: 2, after: $after) {
+			totalCount
+			edges { node { id title } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+
+	var ids []float64
+	var after any
+	for page := 0; page < 3; page++ {
+		_, res := do(t, h, auth.ViewerAccess, http.MethodPost, Request{Query: query, Variables: map[string]any{"after": after}})
+		if len(res.Errors) > 0 {
+			t.Fatalf("page %d errors = %v", page, res.Errors)
+		}
+
+		connection := res.Data["articles"].(map[string]any)
+		if connection["totalCount"] != float64(5) {
+			t.Errorf("totalCount = %v, want 5", connection["totalCount"])
+		}
+
+		for _, edge := range connection["edges"].([]any) {
+			ids = append(ids, edge.(map[string]any)["node"].(map[string]any)["id"].(float64))
+		}
+
+		pageInfo := connection["pageInfo"].(map[string]any)
+		after = pageInfo["endCursor"]
+		if wantNext := page < 2; pageInfo["hasNextPage"] != wantNext {
+			t.Errorf("page %d hasNextPage = %v, want %v", page, pageInfo["hasNextPage"], wantNext)
+		}
+	}
+
+	if want := []float64{1, 2, 3, 4, 5}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("paged ids = %v, want %v", ids, want)
+	}
+}
+
+func TestHandlerMutations(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        mode.Mode
+		access      auth.AccessLevel
+		query       string
+		wantCodes   []string
+		wantStatus  int
+		wantRecords int
+	}{
+		{
+			name:       "editor adds an article",
+			access:     auth.EditorAccess,
+			query:      `mutation { addArticle(input: {title: "t", description: "d", body: "b"}) { id } }`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "viewer can't add an article",
+			access:     auth.ViewerAccess,
+			query:      `mutation { addArticle(input: {title: "t", description: "d", body: "b"}) { id } }`,
+			wantCodes:  []string{CodeForbidden},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "invalid input",
+			access:     auth.EditorAccess,
+			query:      `mutation { addArticle(input: {title: "", description: "d", body: "b"}) { id } }`,
+			wantCodes:  []string{CodeBadUserInput},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "read-only mode",
+			mode:       mode.ReadOnly,
+			access:     auth.EditorAccess,
+			query:      `mutation { addArticle(input: {title: "t", description: "d", body: "b"}) { id } }`,
+			wantCodes:  []string{CodeServiceUnavailable},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "missing article",
+			access:     auth.EditorAccess,
+			query:      `mutation { deleteArticle(id: 42) }`,
+			wantCodes:  []string{CodeNotFound},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.mode
+			if m == "" {
+				m = mode.Normal
+			}
+
+			recorder := &fakeRecorder{}
+			h := newHandlerInMode(t, newFakeArticles(1), recorder, Config{}, m)
+
+			_, res := do(t, h, tt.access, http.MethodPost, Request{Query: tt.query})
+			if codes := res.errorCodes(); !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("error codes = %v, want %v", codes, tt.wantCodes)
+			}
+
+			if len(recorder.events) != 1 {
+				t.Fatalf("audit events = %d, want 1", len(recorder.events))
+			}
+			if recorder.events[0].Status != tt.wantStatus {
+				t.Errorf("audit event status = %d, want %d", recorder.events[0].Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHandlerMutationNeedsPost(t *testing.T) {
+	h := newHandler(t, newFakeArticles(1), &fakeRecorder{}, Config{})
+
+	status, _ := do(t, h, auth.EditorAccess, http.MethodGet, Request{Query: `mutation { deleteArticle(id: 1) }`})
+	if status != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", status, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHandlerLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantCodes []string
+	}{
+		{
+			name:  "within limits",
+			query: `{ articles(first: 10) { totalCount pageInfo { hasNextPage } } }`,
+		},
+		{
+			name:      "too complex",
+			query:     `{ articles(first: 100) { totalCount pageInfo { hasNextPage } } }`,
+			wantCodes: []string{CodeQueryTooComplex},
+		},
+		{
+			name:      "too deep through fragments",
+			query:     `{ articles { ...edges } } fragment edges on ArticleConnection { edges { node { ...node } } } fragment node on Article { id }`,
+			wantCodes: []string{CodeQueryTooComplex},
+		},
+		{
+			name:  "shallow introspection",
+			query: `{ __schema { queryType { name } } }`,
+		},
+		{
+			name:      "too deep introspection",
+			query:     `{ __schema { types { name fields { name type { name ofType { name ofType { name } } } } } } }`,
+			wantCodes: []string{CodeQueryTooComplex},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandler(t, newFakeArticles(3), &fakeRecorder{}, Config{MaxDepth: 3, MaxComplexity: 100})
+
+			_, res := do(t, h, auth.ViewerAccess, http.MethodPost, Request{Query: tt.query})
+			if codes := res.errorCodes(); !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("error codes = %v, want %v", codes, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestHandlerPersistedQueries(t *testing.T) {
+	persisted := `{ article(id: 1) { title } }`
+	queries := PersistedQueries{queryHash(persisted): persisted}
+
+	byHash := func(hash string) Request {
+		var req Request
+		req.Extensions.PersistedQuery = &PersistedQuery{Version: 1, SHA256Hash: hash}
+		return req
+	}
+
+	tests := []struct {
+		name          string
+		persistedOnly bool
+		method        string
+		req           Request
+		wantCodes     []string
+	}{
+		{
+			name:   "persisted query by hash",
+			method: http.MethodGet,
+			req:    byHash(queryHash(persisted)),
+		},
+		{
+			name:          "persisted query text in allowlist mode",
+			persistedOnly: true,
+			method:        http.MethodPost,
+			req:           Request{Query: persisted},
+		},
+		{
+			name:      "unknown hash",
+			method:    http.MethodGet,
+			req:       byHash(queryHash("{ articles { totalCount } }")),
+			wantCodes: []string{CodePersistedQueryNotFound},
+		},
+		{
+			name:   "other query outside allowlist mode",
+			method: http.MethodPost,
+			req:    Request{Query: `{ articles { totalCount } }`},
+		},
+		{
+			name:          "other query in allowlist mode",
+			persistedOnly: true,
+			method:        http.MethodPost,
+			req:           Request{Query: `{ articles { totalCount } }`},
+			wantCodes:     []string{CodePersistedQueryOnly},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandler(t, newFakeArticles(1), &fakeRecorder{}, Config{PersistedQueries: queries, PersistedOnly: tt.persistedOnly})
+
+			_, res := do(t, h, auth.ViewerAccess, tt.method, tt.req)
+			if codes := res.errorCodes(); !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("error codes = %v, want %v", codes, tt.wantCodes)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/goodleby/golang-app/server/handler"
+	"github.com/goodleby/golang-app/tracing"
+	gql "github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+type Config struct {
+	// MaxDepth and MaxComplexity limit the cost of operations, 0 means no
+	// limit.
+	MaxDepth         int
+	MaxComplexity    int
+	PersistedQueries PersistedQueries
+	// PersistedOnly rejects the queries that aren't persisted.
+	PersistedOnly bool
+}
+
+// Request is a GraphQL over HTTP request.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+	Extensions    struct {
+		PersistedQuery *PersistedQuery `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// Handler serves GraphQL requests as JSON POST bodies, or as GET query
+// parameters for queries only, so persisted queries can be cached.
+func Handler(schema gql.Schema, config Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span := tracing.SpanFromContext(ctx)
+
+		req, err := readRequest(r)
+		if err != nil {
+			handler.HandleError(ctx, w, fmt.Errorf("error reading graphql request: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		query, err := config.PersistedQueries.resolve(req, config.PersistedOnly)
+		if err != nil {
+			respond(w, errorResult(err))
+			return
+		}
+
+		doc, err := parser.Parse(parser.ParseParams{Source: query})
+		if err != nil {
+			respond(w, errorResult(err))
+			return
+		}
+
+		validation := gql.ValidateDocument(&schema, doc, nil)
+		if !validation.IsValid {
+			respond(w, &gql.Result{Errors: validation.Errors})
+			return
+		}
+
+		operation := findOperation(doc, req.OperationName)
+		if operation == nil {
+			respond(w, errorResult(&Error{Message: "unknown or ambiguous operation", Code: CodeBadUserInput}))
+			return
+		}
+
+		if operation.Operation != ast.OperationTypeQuery && r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			handler.HandleError(ctx, w, fmt.Errorf("error %s operations need a POST request", operation.Operation), http.StatusMethodNotAllowed, false)
+			return
+		}
+
+		cost := operationCost(doc, operation, req.Variables)
+
+		span.SetTag("graphql.operation_type", operation.Operation)
+		if operation.Name != nil {
+			span.SetTag("graphql.operation_name", operation.Name.Value)
+		}
+		span.SetTag("graphql.depth", strconv.Itoa(cost.Depth))
+		span.SetTag("graphql.complexity", strconv.Itoa(cost.Complexity))
+
+		err = config.checkCost(cost)
+		if err != nil {
+			respond(w, errorResult(err))
+			return
+		}
+
+		result := gql.Execute(gql.ExecuteParams{
+			Schema:        schema,
+			AST:           doc,
+			OperationName: req.OperationName,
+			Args:          req.Variables,
+			Context:       withRequest(ctx, r),
+		})
+
+		respond(w, result)
+	}
+}
+
+func readRequest(r *http.Request) (*Request, error) {
+	var req Request
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+
+		for param, dest := range map[string]any{"variables": &req.Variables, "extensions": &req.Extensions} {
+			if value := query.Get(param); value != "" {
+				err := json.Unmarshal([]byte(value), dest)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s: %v", param, err)
+				}
+			}
+		}
+	default:
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body: %w", err)
+		}
+	}
+
+	if req.Query == "" && req.Extensions.PersistedQuery == nil {
+		return nil, errors.New("no query")
+	}
+
+	return &req, nil
+}
+
+// findOperation returns the operation with the name, or the only operation if
+// the name is empty.
+func findOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+		} else if operation.Name != nil && operation.Name.Value == name {
+			return operation
+		}
+	}
+
+	return found
+}
+
+func (c Config) checkCost(cost Cost) error {
+	if c.MaxDepth > 0 && cost.Depth > c.MaxDepth {
+		return &Error{Message: fmt.Sprintf("query depth %d exceeds the limit of %d", cost.Depth, c.MaxDepth), Code: CodeQueryTooComplex}
+	}
+
+	if c.MaxComplexity > 0 && cost.Complexity > c.MaxComplexity {
+		return &Error{Message: fmt.Sprintf("query complexity %d exceeds the limit of %d", cost.Complexity, c.MaxComplexity), Code: CodeQueryTooComplex}
+	}
+
+	return nil
+}
+
+func errorResult(err error) *gql.Result {
+	formatted := gqlerrors.FormatError(err)
+	if extended, ok := err.(gqlerrors.ExtendedError); ok {
+		formatted.Extensions = extended.Extensions()
+	}
+
+	return &gql.Result{Errors: []gqlerrors.FormattedError{formatted}}
+}
+
+// respond writes the result. Errors are part of the result, so the status is
+// always OK as GraphQL over HTTP asks for with application/json.
+func respond(w http.ResponseWriter, result *gql.Result) {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err := json.NewEncoder(w).Encode(result)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error writing graphql response: %v", err))
+	}
+}
+
+type requestKey struct{}
+
+// withRequest passes the request down to the resolvers, mutations need it to
+// be audited.
+func withRequest(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+func requestFromContext(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(requestKey{}).(*http.Request)
+	return r
+}
@@ -0,0 +1,97 @@
+package graphql
+
+import (
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Cost is the depth and complexity of an operation, computed before it's
+// executed so expensive queries are rejected up front.
+type Cost struct {
+	// Depth is the deepest nesting of fields.
+	Depth int
+	// Complexity counts every field once per item it may be resolved for,
+	// fields of a page of first items count first times.
+	Complexity int
+}
+
+// operationCost returns the cost of the operation of a validated document.
+// Introspection fields count like any other, nesting them is as costly.
+func operationCost(doc *ast.Document, operation *ast.OperationDefinition, variables map[string]any) Cost {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	c := costCounter{fragments: fragments, variables: variables}
+	return c.selectionSet(operation.SelectionSet)
+}
+
+type costCounter struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+func (c *costCounter) selectionSet(selectionSet *ast.SelectionSet) Cost {
+	var cost Cost
+	if selectionSet == nil {
+		return cost
+	}
+
+	for _, selection := range selectionSet.Selections {
+		var selected Cost
+
+		switch selection := selection.(type) {
+		case *ast.Field:
+			children := c.selectionSet(selection.SelectionSet)
+			selected = Cost{
+				Depth:      children.Depth + 1,
+				Complexity: 1 + children.Complexity*c.multiplier(selection),
+			}
+		case *ast.InlineFragment:
+			selected = c.selectionSet(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			// Validation has ruled out fragment cycles.
+			if fragment, ok := c.fragments[selection.Name.Value]; ok {
+				selected = c.selectionSet(fragment.SelectionSet)
+			}
+		}
+
+		cost.Depth = max(cost.Depth, selected.Depth)
+		cost.Complexity += selected.Complexity
+	}
+
+	return cost
+}
+
+// multiplier is the number of items a field may return, the first argument of
+// paginated fields or a full page if it comes from an unknown variable.
+func (c *costCounter) multiplier(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			n, err := strconv.Atoi(value.Value)
+			if err == nil {
+				return max(n, 1)
+			}
+		case *ast.Variable:
+			if n, ok := c.variables[value.Name.Value].(float64); ok {
+				return max(int(n), 1)
+			}
+		}
+		return maxPageSize
+	}
+
+	if field.Name.Value == "articles" {
+		return defaultPageSize
+	}
+
+	return 1
+}
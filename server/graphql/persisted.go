@@ -0,0 +1,73 @@
+package graphql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// PersistedQueries are known queries by their SHA-256 hash. Clients can send
+// the hash instead of the query, as in Apollo automatic persisted queries.
+type PersistedQueries map[string]string
+
+// LoadPersistedQueries reads a JSON array of queries from the file.
+func LoadPersistedQueries(path string) (PersistedQueries, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading persisted queries: %v", err)
+	}
+
+	var queries []string
+	err = json.Unmarshal(data, &queries)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding persisted queries: %v", err)
+	}
+
+	persisted := PersistedQueries{}
+	for _, query := range queries {
+		persisted[queryHash(query)] = query
+	}
+
+	return persisted, nil
+}
+
+// resolve returns the query to execute for the request. Only persisted
+// queries are allowed in allowlist mode.
+func (p PersistedQueries) resolve(req *Request, allowlist bool) (string, error) {
+	var hash string
+	if req.Extensions.PersistedQuery != nil {
+		hash = req.Extensions.PersistedQuery.SHA256Hash
+	}
+
+	if hash == "" {
+		if allowlist {
+			if _, ok := p[queryHash(req.Query)]; !ok {
+				return "", &Error{Message: "only persisted queries are allowed", Code: CodePersistedQueryOnly}
+			}
+		}
+		return req.Query, nil
+	}
+
+	if req.Query != "" && queryHash(req.Query) != hash {
+		return "", &Error{Message: "query doesn't match its hash", Code: CodeBadUserInput}
+	}
+
+	query, ok := p[hash]
+	if !ok {
+		if allowlist || req.Query == "" {
+			return "", &Error{Message: "PersistedQueryNotFound", Code: CodePersistedQueryNotFound}
+		}
+		// Outside allowlist mode the query is just executed, registering
+		// queries at runtime isn't supported.
+		return req.Query, nil
+	}
+
+	return query, nil
+}
+
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
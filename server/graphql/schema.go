@@ -0,0 +1,402 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/server/handler"
+	"github.com/goodleby/golang-app/server/middleware"
+	"github.com/goodleby/golang-app/validation"
+	gql "github.com/graphql-go/graphql"
+)
+
+// ArticleStore is what the REST article handlers use, so both APIs behave
+// the same.
+type ArticleStore interface {
+	handler.ArticlesAfterSelector
+	handler.ArticleCounter
+	handler.ArticleSelector
+	handler.ArticleInserter
+	handler.ArticleUpdater
+	handler.ArticleDeleter
+}
+
+type resolver struct {
+	articles ArticleStore
+	recorder middleware.AuditRecorder
+	modes    middleware.ModeReader
+}
+
+// NewSchema builds the schema of the article domain. Queries need viewer and
+// mutations editor access and the normal mode, mutations are audited like the
+// matching REST routes.
+func NewSchema(articles ArticleStore, recorder middleware.AuditRecorder, modes middleware.ModeReader) (gql.Schema, error) {
+	r := &resolver{articles: articles, recorder: recorder, modes: modes}
+
+	articleType := gql.NewObject(gql.ObjectConfig{
+		Name: "Article",
+		Fields: gql.Fields{
+			"id":          articleField(gql.Int, func(a *article.Article) any { return a.ID }),
+			"title":       articleField(gql.String, func(a *article.Article) any { return a.Title }),
+			"description": articleField(gql.String, func(a *article.Article) any { return a.Description }),
+			"body":        articleField(gql.String, func(a *article.Article) any { return a.Body }),
+			"tags":        articleField(gql.NewList(gql.NewNonNull(gql.String)), func(a *article.Article) any { return append([]string{}, a.Tags...) }),
+		},
+	})
+
+	pageInfoType := gql.NewObject(gql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: gql.Fields{
+			"hasNextPage":     &gql.Field{Type: gql.NewNonNull(gql.Boolean)},
+			"hasPreviousPage": &gql.Field{Type: gql.NewNonNull(gql.Boolean)},
+			"startCursor":     &gql.Field{Type: gql.String},
+			"endCursor":       &gql.Field{Type: gql.String},
+		},
+	})
+
+	articleEdgeType := gql.NewObject(gql.ObjectConfig{
+		Name: "ArticleEdge",
+		Fields: gql.Fields{
+			"cursor": &gql.Field{Type: gql.NewNonNull(gql.String)},
+			"node":   &gql.Field{Type: gql.NewNonNull(articleType)},
+		},
+	})
+
+	articleConnectionType := gql.NewObject(gql.ObjectConfig{
+		Name: "ArticleConnection",
+		Fields: gql.Fields{
+			"edges":    &gql.Field{Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(articleEdgeType)))},
+			"pageInfo": &gql.Field{Type: gql.NewNonNull(pageInfoType)},
+			// Counted only when selected.
+			"totalCount": &gql.Field{Type: gql.NewNonNull(gql.Int), Resolve: r.articlesCount},
+		},
+	})
+
+	articleInputType := gql.NewInputObject(gql.InputObjectConfig{
+		Name: "ArticleInput",
+		Fields: gql.InputObjectConfigFieldMap{
+			"title":       &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+			"description": &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+			"body":        &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+			// Updates without tags keep the current ones.
+			"tags": &gql.InputObjectFieldConfig{Type: gql.NewList(gql.NewNonNull(gql.String))},
+		},
+	})
+
+	idArg := &gql.ArgumentConfig{Type: gql.NewNonNull(gql.Int)}
+	inputArg := &gql.ArgumentConfig{Type: gql.NewNonNull(articleInputType)}
+
+	query := gql.NewObject(gql.ObjectConfig{
+		Name: "Query",
+		Fields: gql.Fields{
+			"article": &gql.Field{
+				Type:    articleType,
+				Args:    gql.FieldConfigArgument{"id": idArg},
+				Resolve: r.article,
+			},
+			"articles": &gql.Field{
+				Type: gql.NewNonNull(articleConnectionType),
+				Args: gql.FieldConfigArgument{
+					"first": &gql.ArgumentConfig{Type: gql.Int, DefaultValue: defaultPageSize},
+					"after": &gql.ArgumentConfig{Type: gql.String},
+				},
+				Resolve: r.articlesPage,
+			},
+		},
+	})
+
+	mutation := gql.NewObject(gql.ObjectConfig{
+		Name: "Mutation",
+		Fields: gql.Fields{
+			"addArticle": &gql.Field{
+				Type:    gql.NewNonNull(articleType),
+				Args:    gql.FieldConfigArgument{"input": inputArg},
+				Resolve: r.addArticle,
+			},
+			"updateArticle": &gql.Field{
+				Type:    gql.NewNonNull(articleType),
+				Args:    gql.FieldConfigArgument{"id": idArg, "input": inputArg},
+				Resolve: r.updateArticle,
+			},
+			"deleteArticle": &gql.Field{
+				Type:    gql.NewNonNull(gql.Boolean),
+				Args:    gql.FieldConfigArgument{"id": idArg},
+				Resolve: r.deleteArticle,
+			},
+		},
+	})
+
+	return gql.NewSchema(gql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func articleField(fieldType gql.Output, fn func(a *article.Article) any) *gql.Field {
+	return &gql.Field{
+		Type: gql.NewNonNull(fieldType),
+		Resolve: func(p gql.ResolveParams) (any, error) {
+			a, ok := p.Source.(*article.Article)
+			if !ok {
+				return nil, fmt.Errorf("unexpected article source %T", p.Source)
+			}
+			return fn(a), nil
+		},
+	}
+}
+
+func (r *resolver) article(p gql.ResolveParams) (any, error) {
+	err := requireAccess(p.Context, auth.ViewerAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := r.articles.SelectArticle(p.Context, p.Args["id"].(int))
+	if err != nil {
+		var notFound *client.ErrNotFound
+		if errors.As(err, &notFound) {
+			// A missing article is null rather than an error.
+			return nil, nil
+		}
+		return nil, internalError(p.Context, fmt.Errorf("error selecting article: %w", err))
+	}
+
+	return a, nil
+}
+
+type articleConnection struct {
+	Edges    []articleEdge `json:"edges"`
+	PageInfo pageInfo      `json:"pageInfo"`
+}
+
+type articleEdge struct {
+	Cursor string           `json:"cursor"`
+	Node   *article.Article `json:"node"`
+}
+
+type pageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+// articlesPage returns the page of articles following the cursor, in the
+// order of their IDs, so pages don't shift when articles are added.
+func (r *resolver) articlesPage(p gql.ResolveParams) (any, error) {
+	err := requireAccess(p.Context, auth.ViewerAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	first, _ := p.Args["first"].(int)
+	if first < 0 || first > maxPageSize {
+		return nil, &Error{Message: fmt.Sprintf("first must be between 0 and %d", maxPageSize), Code: CodeBadUserInput}
+	}
+
+	afterID := 0
+	if after, _ := p.Args["after"].(string); after != "" {
+		afterID, err = decodeCursor(after)
+		if err != nil {
+			return nil, &Error{Message: err.Error(), Code: CodeBadUserInput}
+		}
+	}
+
+	// One more article than the page tells whether there is a next page.
+	articles, err := r.articles.SelectArticlesAfter(p.Context, afterID, first+1)
+	if err != nil {
+		return nil, internalError(p.Context, fmt.Errorf("error selecting articles: %w", err))
+	}
+
+	hasNextPage := len(articles) > first
+	articles = articles[:min(first, len(articles))]
+
+	connection := articleConnection{
+		Edges: make([]articleEdge, 0, len(articles)),
+		PageInfo: pageInfo{
+			HasNextPage: hasNextPage,
+			// Articles before the cursor may have been deleted since, which is
+			// only found out by paging backwards.
+			HasPreviousPage: afterID > 0,
+		},
+	}
+
+	for i := range articles {
+		connection.Edges = append(connection.Edges, articleEdge{Cursor: encodeCursor(articles[i].ID), Node: &articles[i]})
+	}
+
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+
+	return connection, nil
+}
+
+func (r *resolver) articlesCount(p gql.ResolveParams) (any, error) {
+	count, err := r.articles.CountArticles(p.Context)
+	if err != nil {
+		return nil, internalError(p.Context, fmt.Errorf("error counting articles: %w", err))
+	}
+
+	return count, nil
+}
+
+func (r *resolver) addArticle(p gql.ResolveParams) (any, error) {
+	return r.audited(p.Context, "articles.create", auth.EditorAccess, func(ctx context.Context) (any, error) {
+		payload, err := articlePayload(p.Args["input"])
+		if err != nil {
+			return nil, err
+		}
+
+		a, err := r.articles.InsertArticle(ctx, payload)
+		if err != nil {
+			return nil, internalError(ctx, fmt.Errorf("error adding an article: %w", err))
+		}
+
+		return a, nil
+	})
+}
+
+func (r *resolver) updateArticle(p gql.ResolveParams) (any, error) {
+	return r.audited(p.Context, "articles.update", auth.EditorAccess, func(ctx context.Context) (any, error) {
+		payload, err := articlePayload(p.Args["input"])
+		if err != nil {
+			return nil, err
+		}
+
+		a, err := r.articles.UpdateArticle(ctx, p.Args["id"].(int), payload)
+		if err != nil {
+			return nil, notFoundOrInternal(ctx, "article", fmt.Errorf("error updating article: %w", err))
+		}
+
+		return a, nil
+	})
+}
+
+func (r *resolver) deleteArticle(p gql.ResolveParams) (any, error) {
+	return r.audited(p.Context, "articles.delete", auth.EditorAccess, func(ctx context.Context) (any, error) {
+		err := r.articles.DeleteArticle(ctx, p.Args["id"].(int))
+		if err != nil {
+			return nil, notFoundOrInternal(ctx, "article", fmt.Errorf("error deleting article: %w", err))
+		}
+
+		return true, nil
+	})
+}
+
+// audited checks the access level and the mode, and records the mutation in the audit log
+// with the status the REST route would have responded with.
+func (r *resolver) audited(ctx context.Context, action string, access auth.AccessLevel, fn func(ctx context.Context) (any, error)) (any, error) {
+	req := requestFromContext(ctx)
+	if req == nil {
+		return nil, errors.New("error auditing mutation: no request in context")
+	}
+
+	event := middleware.NewAuditEvent(req, action)
+
+	result, err := func() (any, error) {
+		err := requireAccess(ctx, access)
+		if err != nil {
+			return nil, err
+		}
+		if current := r.modes.CurrentMode().Mode; !current.AllowsWrites() {
+			return nil, &Error{Message: fmt.Sprintf("unavailable in %s mode", current), Code: CodeServiceUnavailable}
+		}
+		return fn(audit.NewContext(ctx, event))
+	}()
+
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusInternalServerError
+		var gqlErr *Error
+		if errors.As(err, &gqlErr) {
+			status = gqlErr.Status()
+		}
+	}
+	middleware.RecordAuditEvent(ctx, r.recorder, event, status)
+
+	return result, err
+}
+
+func articlePayload(input any) (article.Payload, error) {
+	fields, _ := input.(map[string]any)
+
+	var payload article.Payload
+	payload.Title, _ = fields["title"].(string)
+	payload.Description, _ = fields["description"].(string)
+	payload.Body, _ = fields["body"].(string)
+	if tags, ok := fields["tags"].([]any); ok {
+		payload.Tags = make([]string, 0, len(tags))
+		for _, tag := range tags {
+			value, _ := tag.(string)
+			payload.Tags = append(payload.Tags, value)
+		}
+	}
+
+	err := payload.Validate()
+	if err != nil {
+		var errs validation.Errors
+		errors.As(err, &errs)
+		return article.Payload{}, &Error{Message: "invalid article input: " + err.Error(), Code: CodeBadUserInput, Fields: errs}
+	}
+
+	return payload, nil
+}
+
+func requireAccess(ctx context.Context, access auth.AccessLevel) error {
+	claims := auth.ClaimsFromContext(ctx)
+	if claims == nil {
+		return &Error{Message: "unauthenticated", Code: CodeUnauthenticated}
+	}
+
+	if claims.AccessLevel < access {
+		return &Error{Message: "insufficient access level", Code: CodeForbidden}
+	}
+
+	return nil
+}
+
+func notFoundOrInternal(ctx context.Context, resource string, err error) error {
+	var notFound *client.ErrNotFound
+	if errors.As(err, &notFound) {
+		return &Error{Message: resource + " not found", Code: CodeNotFound}
+	}
+
+	return internalError(ctx, err)
+}
+
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(id)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	value, ok := strings.CutPrefix(string(decoded), cursorPrefix)
+	if !ok {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	return id, nil
+}
+
+const (
+	cursorPrefix    string = "article:"
+	defaultPageSize int    = 20
+	maxPageSize     int    = 100
+)
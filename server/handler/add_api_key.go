@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/model/apikey"
+	"github.com/goodleby/golang-app/model/audit"
+)
+
+type APIKeyCreator interface {
+	CreateAPIKey(ctx context.Context, payload apikey.Payload) (key string, apiKey *apikey.APIKey, err error)
+}
+
+// APIKeyWithSecret is the only response that ever contains the plaintext key.
+type APIKeyWithSecret struct {
+	apikey.APIKey
+	Key string `json:"key"`
+}
+
+func AddAPIKey(apiKeyCreator APIKeyCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var payload apikey.Payload
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error decoding api key payload: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		err = payload.Validate()
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error invalid api key payload: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		key, apiKey, err := apiKeyCreator.CreateAPIKey(ctx, payload)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error creating api key: %w", err), http.StatusInternalServerError, true)
+			return
+		}
+
+		audit.EventFromContext(ctx).SetTarget(apiKeyTarget(apiKey.ID))
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(APIKeyWithSecret{APIKey: *apiKey, Key: key})
+		handleWritingErr(err)
+	}
+}
+
+// apiKeyTarget is the audit target of the actions on the API key.
+func apiKeyTarget(id int) string {
+	return fmt.Sprintf("api_key:%d", id)
+}
@@ -20,19 +20,19 @@ func AddArticlePubSub(publisher AddArticlePublisher) http.HandlerFunc {
 		var payload article.Payload
 		err := json.NewDecoder(r.Body).Decode(&payload)
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error decoding article payload: %v", err), http.StatusBadRequest, false)
+			HandleError(ctx, w, fmt.Errorf("error decoding article payload: %w", err), http.StatusBadRequest, false)
 			return
 		}
 
 		err = payload.Validate()
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error invalid article payload: %v", err), http.StatusBadRequest, false)
+			HandleError(ctx, w, fmt.Errorf("error invalid article payload: %w", err), http.StatusBadRequest, false)
 			return
 		}
 
 		err = publisher.PublishAddArticle(ctx, payload)
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error publishing add article: %v", err), http.StatusInternalServerError, true)
+			HandleError(ctx, w, fmt.Errorf("error publishing add article: %w", err), http.StatusInternalServerError, true)
 			return
 		}
 
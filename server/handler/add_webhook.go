@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/model/webhook"
+)
+
+type WebhookInserter interface {
+	InsertWebhook(ctx context.Context, payload webhook.Payload) (*webhook.Webhook, error)
+}
+
+func AddWebhook(webhookInserter WebhookInserter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var payload webhook.Payload
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error decoding webhook payload: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		err = payload.Validate()
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error invalid webhook payload: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		webhook, err := webhookInserter.InsertWebhook(ctx, payload)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error adding a webhook: %w", err), http.StatusInternalServerError, true)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(webhook)
+		handleWritingErr(err)
+	}
+}
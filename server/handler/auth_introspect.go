@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/model/session"
+)
+
+type TokenIntrospector interface {
+	IntrospectToken(ctx context.Context, token string) (*session.Introspection, error)
+}
+
+// AuthIntrospect implements RFC 7662 token introspection for session tokens
+// and API keys. The token is passed as a form parameter.
+func AuthIntrospect(tokenIntrospector TokenIntrospector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		err := r.ParseForm()
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error parsing introspection form: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		token := r.PostForm.Get("token")
+		if token == "" {
+			HandleError(ctx, w, errors.New("error introspecting token: token is empty"), http.StatusBadRequest, false)
+			return
+		}
+
+		introspection, err := tokenIntrospector.IntrospectToken(ctx, token)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error introspecting token: %w", err), http.StatusInternalServerError, true)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(introspection)
+		handleWritingErr(err)
+	}
+}
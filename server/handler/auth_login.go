@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/audit"
 )
 
 type TokenCreator interface {
@@ -26,17 +27,21 @@ func AuthLogin(tokenCreator TokenCreator) http.HandlerFunc {
 		var payload AuthLoginPayload
 		err := json.NewDecoder(r.Body).Decode(&payload)
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error decoding auth payload: %v", err), http.StatusBadRequest, false)
+			HandleError(ctx, w, fmt.Errorf("error decoding auth payload: %w", err), http.StatusBadRequest, false)
 			return
 		}
 
 		token, expires, err := tokenCreator.CreateRoleToken(ctx, payload.Role, payload.Key)
 		if err != nil {
+			// The caller is anonymous until the key is checked, the role it
+			// claimed is only a detail of the attempt.
+			audit.EventFromContext(ctx).SetDetail("role", payload.Role)
+
 			switch err.(type) {
 			case *client.ErrUnauthorized:
-				HandleError(ctx, w, fmt.Errorf("error creating role token: unauthorized: %v", err), http.StatusUnauthorized, false)
+				HandleError(ctx, w, fmt.Errorf("error creating role token: unauthorized: %w", err), http.StatusUnauthorized, false)
 			default:
-				HandleError(ctx, w, fmt.Errorf("error creating role token: %v", err), http.StatusInternalServerError, true)
+				HandleError(ctx, w, fmt.Errorf("error creating role token: %w", err), http.StatusInternalServerError, true)
 			}
 			return
 		}
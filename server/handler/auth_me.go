@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/session"
+)
+
+type SessionReader interface {
+	ReadSession(ctx context.Context, tokenString string) (*session.Session, error)
+}
+
+// AuthMe describes the session of the auth token cookie, since the cookie
+// itself can't be read by frontends.
+func AuthMe(sessionReader SessionReader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		token, err := ReadAuthToken(r)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error reading auth token: %w", err), http.StatusUnauthorized, false)
+			return
+		}
+
+		session, err := sessionReader.ReadSession(ctx, token)
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrUnauthorized:
+				HandleError(ctx, w, fmt.Errorf("error reading session: unauthorized: %w", err), http.StatusUnauthorized, false)
+			default:
+				HandleError(ctx, w, fmt.Errorf("error reading session: %w", err), http.StatusInternalServerError, true)
+			}
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(session)
+		handleWritingErr(err)
+	}
+}
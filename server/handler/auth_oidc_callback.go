@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+)
+
+type OIDCLoginCompleter interface {
+	CompleteOIDCLogin(ctx context.Context, stateToken, state, code string) (token string, expires time.Time, err error)
+}
+
+// AuthOIDCCallback completes the login started by AuthOIDCLogin, sets the auth
+// token cookie and redirects the user to redirectURL.
+func AuthOIDCCallback(loginCompleter OIDCLoginCompleter, redirectURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		stateCookie, err := r.Cookie("oidc_state")
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error reading oidc state cookie: %w", err), http.StatusUnauthorized, false)
+			return
+		}
+
+		// The state cookie is single use, expire it whatever the outcome is.
+		http.SetCookie(w, &http.Cookie{
+			Name:     "oidc_state",
+			Value:    "",
+			Expires:  time.Now(),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+			Path:     "/",
+		})
+
+		query := r.URL.Query()
+		if idpErr := query.Get("error"); idpErr != "" {
+			HandleError(ctx, w, errors.New("identity provider returned error: "+idpErr), http.StatusUnauthorized, false)
+			return
+		}
+
+		token, expires, err := loginCompleter.CompleteOIDCLogin(ctx, stateCookie.Value, query.Get("state"), query.Get("code"))
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrNotFound:
+				HandleError(ctx, w, fmt.Errorf("error completing oidc login: not found: %w", err), http.StatusNotFound, false)
+			case *client.ErrUnauthorized:
+				HandleError(ctx, w, fmt.Errorf("error completing oidc login: unauthorized: %w", err), http.StatusUnauthorized, false)
+			default:
+				HandleError(ctx, w, fmt.Errorf("error completing oidc login: %w", err), http.StatusInternalServerError, true)
+			}
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     "token",
+			Value:    token,
+			Expires:  expires,
+			HttpOnly: true,
+			Path:     "/",
+		})
+
+		http.Redirect(w, r, redirectURL, http.StatusFound)
+	}
+}
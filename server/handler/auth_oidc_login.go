@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+)
+
+type OIDCLoginStarter interface {
+	BeginOIDCLogin(ctx context.Context) (authURL, stateToken string, expires time.Time, err error)
+}
+
+// AuthOIDCLogin redirects the user to the identity provider and remembers the
+// login state in a short-lived cookie.
+func AuthOIDCLogin(loginStarter OIDCLoginStarter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		authURL, stateToken, expires, err := loginStarter.BeginOIDCLogin(ctx)
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrNotFound:
+				HandleError(ctx, w, fmt.Errorf("error beginning oidc login: not found: %w", err), http.StatusNotFound, false)
+			default:
+				HandleError(ctx, w, fmt.Errorf("error beginning oidc login: %w", err), http.StatusInternalServerError, true)
+			}
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     "oidc_state",
+			Value:    stateToken,
+			Expires:  expires,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+			Path:     "/",
+		})
+
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}
+}
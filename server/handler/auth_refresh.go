@@ -17,19 +17,19 @@ func AuthRefresh(tokenRefresher TokenRefresher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		tokenCookie, err := r.Cookie("token")
+		token, err := ReadAuthToken(r)
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error reading auth token cookie: %v", err), http.StatusUnauthorized, false)
+			HandleError(ctx, w, fmt.Errorf("error reading auth token: %w", err), http.StatusUnauthorized, false)
 			return
 		}
 
-		token, expires, err := tokenRefresher.RefreshToken(ctx, tokenCookie.Value)
+		token, expires, err := tokenRefresher.RefreshToken(ctx, token)
 		if err != nil {
 			switch err.(type) {
 			case *client.ErrUnauthorized:
-				HandleError(ctx, w, fmt.Errorf("error refreshing token: unauthorized: %v", err), http.StatusUnauthorized, false)
+				HandleError(ctx, w, fmt.Errorf("error refreshing token: unauthorized: %w", err), http.StatusUnauthorized, false)
 			default:
-				HandleError(ctx, w, fmt.Errorf("error refreshing token: %v", err), http.StatusInternalServerError, true)
+				HandleError(ctx, w, fmt.Errorf("error refreshing token: %w", err), http.StatusInternalServerError, true)
 			}
 			return
 		}
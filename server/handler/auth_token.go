@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ReadAuthToken reads the auth token from the Authorization bearer header,
+// used by API clients, or else from the token cookie, used by browsers.
+func ReadAuthToken(r *http.Request) (string, error) {
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		token, ok := strings.CutPrefix(authorization, "Bearer ")
+		if !ok || token == "" {
+			return "", errors.New("authorization header isn't a bearer token")
+		}
+		return token, nil
+	}
+
+	tokenCookie, err := r.Cookie("token")
+	if err != nil {
+		return "", errors.New("no bearer token or token cookie")
+	}
+
+	return tokenCookie.Value, nil
+}
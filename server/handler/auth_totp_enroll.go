@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/totp"
+)
+
+type TOTPEnroller interface {
+	EnrollTOTP(ctx context.Context, tokenString string) (*totp.Setup, error)
+}
+
+// AuthTOTPEnroll starts TOTP enrollment for the logged in subject. The secret
+// and recovery codes are only ever returned in this response.
+func AuthTOTPEnroll(totpEnroller TOTPEnroller) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		token, err := ReadAuthToken(r)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error reading auth token: %w", err), http.StatusUnauthorized, false)
+			return
+		}
+
+		setup, err := totpEnroller.EnrollTOTP(ctx, token)
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrUnauthorized:
+				HandleError(ctx, w, fmt.Errorf("error enrolling totp: unauthorized: %w", err), http.StatusUnauthorized, false)
+			case *client.ErrConflict:
+				HandleError(ctx, w, fmt.Errorf("error enrolling totp: conflict: %w", err), http.StatusConflict, false)
+			default:
+				HandleError(ctx, w, fmt.Errorf("error enrolling totp: %w", err), http.StatusInternalServerError, true)
+			}
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(setup)
+		handleWritingErr(err)
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/totp"
+)
+
+type TOTPVerifier interface {
+	VerifyTOTP(ctx context.Context, tokenString, code string) (token string, expires time.Time, err error)
+}
+
+// AuthTOTPVerify completes the second factor and replaces the auth token
+// cookie with one that grants the full access of the role.
+func AuthTOTPVerify(totpVerifier TOTPVerifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		token, err := ReadAuthToken(r)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error reading auth token: %w", err), http.StatusUnauthorized, false)
+			return
+		}
+
+		var payload totp.VerifyPayload
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error decoding totp payload: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		token, expires, err := totpVerifier.VerifyTOTP(ctx, token, payload.Code)
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrUnauthorized:
+				HandleError(ctx, w, fmt.Errorf("error verifying totp: unauthorized: %w", err), http.StatusUnauthorized, false)
+			default:
+				HandleError(ctx, w, fmt.Errorf("error verifying totp: %w", err), http.StatusInternalServerError, true)
+			}
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     "token",
+			Value:    token,
+			Expires:  expires,
+			HttpOnly: true,
+			Path:     "/",
+		})
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
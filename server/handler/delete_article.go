@@ -22,7 +22,7 @@ func DeleteArticle(articleDeleter ArticleDeleter) http.HandlerFunc {
 
 		id, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error converting id to int: %v", err), http.StatusBadRequest, false)
+			HandleError(ctx, w, fmt.Errorf("error converting id to int: %w", err), http.StatusBadRequest, false)
 			return
 		}
 
@@ -32,9 +32,9 @@ func DeleteArticle(articleDeleter ArticleDeleter) http.HandlerFunc {
 		if err != nil {
 			switch err.(type) {
 			case *client.ErrNotFound:
-				HandleError(ctx, w, fmt.Errorf("error deleting article: not found: %v", err), http.StatusNotFound, false)
+				HandleError(ctx, w, fmt.Errorf("error deleting article: not found: %w", err), http.StatusNotFound, false)
 			default:
-				HandleError(ctx, w, fmt.Errorf("error deleting article: %v", err), http.StatusInternalServerError, true)
+				HandleError(ctx, w, fmt.Errorf("error deleting article: %w", err), http.StatusInternalServerError, true)
 			}
 			return
 		}
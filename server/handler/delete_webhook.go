@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type WebhookDeleter interface {
+	DeleteWebhook(ctx context.Context, id int) error
+}
+
+func DeleteWebhook(webhookDeleter WebhookDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span := tracing.SpanFromContext(ctx)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error converting id to int: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		span.SetTag("id", chi.URLParam(r, "id"))
+
+		err = webhookDeleter.DeleteWebhook(ctx, id)
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrNotFound:
+				HandleError(ctx, w, fmt.Errorf("error deleting webhook: not found: %w", err), http.StatusNotFound, false)
+			default:
+				HandleError(ctx, w, fmt.Errorf("error deleting webhook: %w", err), http.StatusInternalServerError, true)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goodleby/golang-app/model/audit"
+)
+
+type AuditEventsStreamer interface {
+	StreamAuditEvents(ctx context.Context, filter audit.Filter, fn func(event *audit.Event) error) error
+}
+
+// ExportAuditEvents streams all audit events matching the filter as newline
+// delimited JSON, newest first.
+func ExportAuditEvents(auditEventsStreamer AuditEventsStreamer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		filter, err := parseAuditFilter(r.URL.Query())
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error parsing audit filter: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+
+		// The export outlives the server write timeout.
+		err = rc.SetWriteDeadline(time.Time{})
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			HandleError(ctx, w, fmt.Errorf("error clearing write deadline: %w", err), http.StatusInternalServerError, true)
+			return
+		}
+
+		encoder := json.NewEncoder(w)
+		var started bool
+
+		err = auditEventsStreamer.StreamAuditEvents(ctx, filter, func(event *audit.Event) error {
+			if !started {
+				w.Header().Add("Content-Type", "application/x-ndjson")
+				w.Header().Add("Content-Disposition", `attachment; filename="audit-events.ndjson"`)
+				w.WriteHeader(http.StatusOK)
+				started = true
+			}
+
+			return encoder.Encode(event)
+		})
+		if err != nil {
+			// Once the stream has started the status can't be changed anymore.
+			if started {
+				handleWritingErr(fmt.Errorf("error streaming audit events: %v", err))
+				return
+			}
+
+			HandleError(ctx, w, fmt.Errorf("error streaming audit events: %w", err), http.StatusInternalServerError, true)
+			return
+		}
+
+		if !started {
+			w.Header().Add("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/model/apikey"
+)
+
+type APIKeyLister interface {
+	ListAPIKeys(ctx context.Context) ([]apikey.APIKey, error)
+}
+
+func GetAllAPIKeys(apiKeyLister APIKeyLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		apiKeys, err := apiKeyLister.ListAPIKeys(ctx)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error listing api keys: %w", err), http.StatusInternalServerError, true)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(apiKeys)
+		handleWritingErr(err)
+	}
+}
@@ -2,7 +2,6 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -22,9 +21,14 @@ func GetArticle(articleSelector ArticleSelector) http.HandlerFunc {
 		ctx := r.Context()
 		span := tracing.SpanFromContext(ctx)
 
+		encoder, ok := negotiate(w, r)
+		if !ok {
+			return
+		}
+
 		id, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error converting id to int: %v", err), http.StatusBadRequest, false)
+			HandleError(ctx, w, fmt.Errorf("error converting id to int: %w", err), http.StatusBadRequest, false)
 			return
 		}
 
@@ -34,17 +38,13 @@ func GetArticle(articleSelector ArticleSelector) http.HandlerFunc {
 		if err != nil {
 			switch err.(type) {
 			case *client.ErrNotFound:
-				HandleError(ctx, w, fmt.Errorf("error selecting article: not found: %v", err), http.StatusNotFound, false)
+				HandleError(ctx, w, fmt.Errorf("error selecting article: not found: %w", err), http.StatusNotFound, false)
 			default:
-				HandleError(ctx, w, fmt.Errorf("error selecting article: %v", err), http.StatusInternalServerError, true)
+				HandleError(ctx, w, fmt.Errorf("error selecting article: %w", err), http.StatusInternalServerError, true)
 			}
 			return
 		}
 
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-
-		err = json.NewEncoder(w).Encode(article)
-		handleWritingErr(err)
+		respond(w, encoder, http.StatusOK, article)
 	}
 }
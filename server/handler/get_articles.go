@@ -2,7 +2,6 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -13,20 +12,31 @@ type AllArticlesSelector interface {
 	SelectAllArticles(ctx context.Context) ([]article.Article, error)
 }
 
+// ArticlesAfterSelector selects the pages of articles in the order of their
+// IDs, for the APIs that page through them.
+type ArticlesAfterSelector interface {
+	SelectArticlesAfter(ctx context.Context, afterID, limit int) ([]article.Article, error)
+}
+
+type ArticleCounter interface {
+	CountArticles(ctx context.Context) (int, error)
+}
+
 func GetAllArticles(articleSelector AllArticlesSelector) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
+		encoder, ok := negotiate(w, r)
+		if !ok {
+			return
+		}
+
 		articles, err := articleSelector.SelectAllArticles(ctx)
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error selecting articles: %v", err), http.StatusInternalServerError, true)
+			HandleError(ctx, w, fmt.Errorf("error selecting articles: %w", err), http.StatusInternalServerError, true)
 			return
 		}
 
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-
-		err = json.NewEncoder(w).Encode(articles)
-		handleWritingErr(err)
+		respond(w, encoder, http.StatusOK, articles)
 	}
 }
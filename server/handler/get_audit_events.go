@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/goodleby/golang-app/model/audit"
+)
+
+type AuditEventsSelector interface {
+	SelectAuditEvents(ctx context.Context, filter audit.Filter) ([]audit.Event, error)
+}
+
+func GetAuditEvents(auditEventsSelector AuditEventsSelector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		filter, err := parseAuditFilter(r.URL.Query())
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error parsing audit filter: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		if filter.Limit == 0 || filter.Limit > maxAuditEventsLimit {
+			filter.Limit = maxAuditEventsLimit
+		}
+
+		events, err := auditEventsSelector.SelectAuditEvents(ctx, filter)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error selecting audit events: %w", err), http.StatusInternalServerError, true)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(events)
+		handleWritingErr(err)
+	}
+}
+
+// parseAuditFilter reads the filter from query parameters. Times are RFC 3339
+// and "before" is the ID of the oldest event of the previous page.
+func parseAuditFilter(query url.Values) (audit.Filter, error) {
+	filter := audit.Filter{
+		Tenant: query.Get("tenant"),
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		Target: query.Get("target"),
+	}
+
+	for param, dest := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return audit.Filter{}, fmt.Errorf("invalid %s time: %v", param, err)
+		}
+		*dest = &t
+	}
+
+	if value := query.Get("before"); value != "" {
+		beforeID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || beforeID <= 0 {
+			return audit.Filter{}, fmt.Errorf("invalid before id %q", value)
+		}
+		filter.BeforeID = beforeID
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return audit.Filter{}, fmt.Errorf("invalid limit %q", value)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+const maxAuditEventsLimit = 1000
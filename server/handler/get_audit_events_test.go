@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func Test_parseAuditFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantSince time.Time
+		wantID    int64
+		wantLimit int
+		wantErr   bool
+	}{
+		{
+			name:  "empty query",
+			query: "",
+		},
+		{
+			name:      "all params",
+			query:     "tenant=acme&actor=admin&action=auth.login&since=2024-01-02T03:04:05Z&before=42&limit=10",
+			wantSince: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			wantID:    42,
+			wantLimit: 10,
+		},
+		{
+			name:    "invalid since",
+			query:   "since=yesterday",
+			wantErr: true,
+		},
+		{
+			name:    "negative limit",
+			query:   "limit=-1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid before",
+			query:   "before=abc",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("error parsing query: %v", err)
+			}
+
+			filter, err := parseAuditFilter(query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAuditFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !tt.wantSince.IsZero() && (filter.Since == nil || !filter.Since.Equal(tt.wantSince)) {
+				t.Errorf("parseAuditFilter() since = %v, want %v", filter.Since, tt.wantSince)
+			}
+			if filter.BeforeID != tt.wantID {
+				t.Errorf("parseAuditFilter() before = %v, want %v", filter.BeforeID, tt.wantID)
+			}
+			if filter.Limit != tt.wantLimit {
+				t.Errorf("parseAuditFilter() limit = %v, want %v", filter.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type ConfigRedactor interface {
+	Redacted() map[string]any
+}
+
+// GetConfig dumps the effective config with the secrets redacted.
+func GetConfig(config ConfigRedactor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+		err := json.NewEncoder(w).Encode(config.Redacted())
+		handleWritingErr(err)
+	}
+}
@@ -19,7 +19,7 @@ func GetExampleData(exampleFetcher ExampleDataFetcher) http.HandlerFunc {
 
 		exampleData, err := exampleFetcher.FetchExampleData(ctx)
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error fetching articles: %v", err), http.StatusInternalServerError, true)
+			HandleError(ctx, w, fmt.Errorf("error fetching articles: %w", err), http.StatusInternalServerError, true)
 			return
 		}
 
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/goodleby/golang-app/featureflag"
+)
+
+type FeatureFlagsEvaluator interface {
+	EvaluateAll(ctx context.Context) []featureflag.Evaluation
+}
+
+// GetFeatureFlags evaluates the flags for the caller, so clients can show the
+// features they have and pick the variants.
+func GetFeatureFlags(evaluator FeatureFlagsEvaluator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err := json.NewEncoder(w).Encode(evaluator.EvaluateAll(r.Context()))
+		handleWritingErr(err)
+	}
+}
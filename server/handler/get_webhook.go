@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/webhook"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type WebhookSelector interface {
+	SelectWebhook(ctx context.Context, id int) (*webhook.Webhook, error)
+}
+
+func GetWebhook(webhookSelector WebhookSelector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span := tracing.SpanFromContext(ctx)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error converting id to int: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		span.SetTag("id", chi.URLParam(r, "id"))
+
+		webhook, err := webhookSelector.SelectWebhook(ctx, id)
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrNotFound:
+				HandleError(ctx, w, fmt.Errorf("error selecting webhook: not found: %w", err), http.StatusNotFound, false)
+			default:
+				HandleError(ctx, w, fmt.Errorf("error selecting webhook: %w", err), http.StatusInternalServerError, true)
+			}
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(webhook)
+		handleWritingErr(err)
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/model/webhook"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type WebhookDeliveriesSelector interface {
+	SelectWebhookDeliveries(ctx context.Context, filter webhook.DeliveryFilter) ([]webhook.Delivery, error)
+}
+
+// GetWebhookDeliveries lists the deliveries of a webhook, newest first.
+func GetWebhookDeliveries(webhookDeliveriesSelector WebhookDeliveriesSelector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span := tracing.SpanFromContext(ctx)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error converting id to int: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		span.SetTag("id", chi.URLParam(r, "id"))
+
+		filter, err := parseDeliveryFilter(r.URL.Query())
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error parsing delivery filter: %w", err), http.StatusBadRequest, false)
+			return
+		}
+		filter.WebhookID = id
+
+		if filter.Limit == 0 || filter.Limit > maxWebhookDeliveriesLimit {
+			filter.Limit = maxWebhookDeliveriesLimit
+		}
+
+		deliveries, err := webhookDeliveriesSelector.SelectWebhookDeliveries(ctx, filter)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error selecting webhook deliveries: %w", err), http.StatusInternalServerError, true)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(deliveries)
+		handleWritingErr(err)
+	}
+}
+
+// parseDeliveryFilter reads the filter from query parameters, "before" is the
+// ID of the oldest delivery of the previous page.
+func parseDeliveryFilter(query url.Values) (webhook.DeliveryFilter, error) {
+	filter := webhook.DeliveryFilter{
+		Status: query.Get("status"),
+	}
+
+	statuses := []string{webhook.DeliveryPending, webhook.DeliverySucceeded, webhook.DeliveryFailed}
+	if filter.Status != "" && !slices.Contains(statuses, filter.Status) {
+		return webhook.DeliveryFilter{}, fmt.Errorf("invalid status %q", filter.Status)
+	}
+
+	if value := query.Get("before"); value != "" {
+		beforeID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || beforeID <= 0 {
+			return webhook.DeliveryFilter{}, fmt.Errorf("invalid before id %q", value)
+		}
+		filter.BeforeID = beforeID
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return webhook.DeliveryFilter{}, fmt.Errorf("invalid limit %q", value)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+const maxWebhookDeliveriesLimit = 100
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/model/webhook"
+)
+
+type WebhookLister interface {
+	SelectAllWebhooks(ctx context.Context) ([]webhook.Webhook, error)
+}
+
+func GetAllWebhooks(webhookLister WebhookLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		webhooks, err := webhookLister.SelectAllWebhooks(ctx)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error selecting webhooks: %w", err), http.StatusInternalServerError, true)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(webhooks)
+		handleWritingErr(err)
+	}
+}
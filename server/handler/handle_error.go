@@ -3,33 +3,164 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 
+	"github.com/goodleby/golang-app/requestid"
 	"github.com/goodleby/golang-app/tracing"
+	"github.com/goodleby/golang-app/validation"
 )
 
-type errorResponse struct {
-	Error      string `json:"error"`
-	StatusCode int    `json:"statusCode"`
+// Problem is an RFC 9457 problem details response.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	// Detail is only set for client errors, with the message meant for the
+	// client, see clientMessage. Server errors may leak internals.
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code identifies the kind of problem and doesn't change between versions.
+	Code   string                  `json:"code"`
+	Errors []validation.FieldError `json:"errors,omitempty"`
 }
 
+// HandleError responds with a problem describing err. The code comes from the
+// first error in the chain with a Code method, e.g. client.ErrNotFound, or
+// else from the status code. Errors caused by the limits of the route override
+// the status, see limitStatus.
 func HandleError(ctx context.Context, w http.ResponseWriter, err error, statusCode int, shouldLog bool) {
+	statusCode = limitStatus(ctx, err, statusCode)
+
 	span := tracing.SpanFromContext(ctx)
 
 	span.RecordError(err)
 
 	if shouldLog {
-		slog.Error(fmt.Sprintf("Handler error: %v", err), "status", statusCode)
+		slog.Error(fmt.Sprintf("Handler error: %v", err),
+			"status", statusCode,
+			"request_id", requestid.FromContext(ctx),
+			"trace_id", span.TraceID(),
+		)
+	} else if statusCode < http.StatusInternalServerError {
+		// The client only gets the message meant for it.
+		slog.Debug(fmt.Sprintf("Client error: %v", err),
+			"status", statusCode,
+			"request_id", requestid.FromContext(ctx),
+			"trace_id", span.TraceID(),
+		)
+	}
+
+	problem := Problem{
+		Title:    http.StatusText(statusCode),
+		Status:   statusCode,
+		Instance: requestid.FromContext(ctx),
+		Code:     statusCodeProblem(statusCode),
+	}
+
+	var coder interface{ Code() string }
+	if errors.As(err, &coder) {
+		problem.Code = coder.Code()
+	}
+
+	if statusCode == http.StatusServiceUnavailable && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		problem.Code = timeoutProblem
+	}
+
+	var validationErrs validation.Errors
+	if errors.As(err, &validationErrs) {
+		problem.Code = validationFailedProblem
+		problem.Errors = validationErrs
+	}
+
+	if statusCode < http.StatusInternalServerError {
+		problem.Detail = clientMessage(err)
 	}
 
-	w.Header().Add("Content-Type", "application/json")
+	problem.Type = ProblemTypeURI(problem.Code)
+
+	w.Header().Add("Content-Type", "application/problem+json")
 	w.WriteHeader(statusCode)
 
-	err = json.NewEncoder(w).Encode(errorResponse{
-		Error:      fmt.Sprintf("%s: %d", http.StatusText(statusCode), statusCode),
-		StatusCode: statusCode,
-	})
+	err = json.NewEncoder(w).Encode(problem)
 	handleWritingErr(err)
 }
+
+// limitStatus returns 413 for bodies over the size limit of the route, and 503
+// for server errors once the request deadline has passed, as they are most
+// likely caused by it.
+func limitStatus(ctx context.Context, err error, statusCode int) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	if statusCode >= http.StatusInternalServerError && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable
+	}
+
+	return statusCode
+}
+
+// clientMessage returns the message of the first error in the chain meant for
+// the client, e.g. the Message of client.ErrNotFound or validation.Errors, or
+// else the one of err without the errors it wraps, which may leak internals.
+func clientMessage(err error) string {
+	var validationErrs validation.Errors
+	if errors.As(err, &validationErrs) {
+		return validationErrs.Error()
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return maxBytesErr.Error()
+	}
+
+	var messenger interface{ Message() string }
+	if errors.As(err, &messenger) {
+		return messenger.Message()
+	}
+
+	// Coded errors wrapping nothing only describe the problem, e.g.
+	// tenant.ErrSuspended.
+	var coded interface {
+		error
+		Code() string
+	}
+	if errors.As(err, &coded) && errors.Unwrap(coded) == nil {
+		return coded.Error()
+	}
+
+	if wrapped := errors.Unwrap(err); wrapped != nil {
+		message, ok := strings.CutSuffix(err.Error(), ": "+wrapped.Error())
+		if ok {
+			return message
+		}
+	}
+
+	return err.Error()
+}
+
+// ProblemTypeURI returns the problem type URI of the problem code.
+func ProblemTypeURI(code string) string {
+	return "urn:problem:" + code
+}
+
+// statusCodeProblem derives the problem code from the status text, e.g.
+// "bad_request" for 400.
+func statusCodeProblem(statusCode int) string {
+	text := http.StatusText(statusCode)
+	if text == "" {
+		return "unknown"
+	}
+
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+const (
+	validationFailedProblem = "validation_failed"
+	timeoutProblem          = "timeout"
+)
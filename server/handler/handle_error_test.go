@@ -4,9 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/requestid"
+	"github.com/goodleby/golang-app/validation"
 )
 
 func TestHandleError(t *testing.T) {
@@ -18,34 +26,147 @@ func TestHandleError(t *testing.T) {
 	tests := []struct {
 		name       string
 		args       args
+		requestID  string
+		timedOut   bool
 		wantStatus int
-		wantBody   *errorResponse
+		wantBody   *Problem
 	}{
 		{
-			name: "should set passed status and write status text error",
+			name: "should hide the detail of server errors",
 			args: args{
 				err:        errors.New("Test error"),
 				statusCode: 500,
 				shouldLog:  false,
 			},
 			wantStatus: 500,
-			wantBody: &errorResponse{
-				Error:      "Internal Server Error: 500",
-				StatusCode: 500,
+			wantBody: &Problem{
+				Type:   "urn:problem:internal_server_error",
+				Title:  "Internal Server Error",
+				Status: 500,
+				Code:   "internal_server_error",
+			},
+		},
+		{
+			name: "should use the code of wrapped client errors",
+			args: args{
+				err:        fmt.Errorf("error getting article: %w", &client.ErrNotFound{Err: errors.New("article with id 1 not found: sql: no rows in result set")}),
+				statusCode: 404,
+			},
+			requestID:  "request-1",
+			wantStatus: 404,
+			wantBody: &Problem{
+				Type:     "urn:problem:not_found",
+				Title:    "Not Found",
+				Status:   404,
+				Detail:   "not found",
+				Instance: "request-1",
+				Code:     "not_found",
+			},
+		},
+		{
+			name: "should hide the errors wrapped by client errors",
+			args: args{
+				err:        &client.ErrUnauthorized{Err: errors.New("error parsing token claims: token is malformed: could not base64 decode signature")},
+				statusCode: 401,
+			},
+			wantStatus: 401,
+			wantBody: &Problem{
+				Type:   "urn:problem:unauthorized",
+				Title:  "Unauthorized",
+				Status: 401,
+				Detail: "missing or invalid credentials",
+				Code:   "unauthorized",
+			},
+		},
+		{
+			name: "should hide the wrapped errors of other client errors",
+			args: args{
+				err:        fmt.Errorf("error converting id to int: %w", strconv.ErrSyntax),
+				statusCode: 400,
+			},
+			wantStatus: 400,
+			wantBody: &Problem{
+				Type:   "urn:problem:bad_request",
+				Title:  "Bad Request",
+				Status: 400,
+				Detail: "error converting id to int",
+				Code:   "bad_request",
+			},
+		},
+		{
+			name: "should list every validation error",
+			args: args{
+				err: fmt.Errorf("error invalid payload: %w", validation.Errors{
+					{Field: "title", Message: "must not be empty"},
+					{Field: "body", Message: "must not be empty"},
+				}),
+				statusCode: 400,
+			},
+			wantStatus: 400,
+			wantBody: &Problem{
+				Type:   "urn:problem:validation_failed",
+				Title:  "Bad Request",
+				Status: 400,
+				Detail: "title: must not be empty; body: must not be empty",
+				Code:   "validation_failed",
+				Errors: []validation.FieldError{
+					{Field: "title", Message: "must not be empty"},
+					{Field: "body", Message: "must not be empty"},
+				},
+			},
+		},
+		{
+			name: "should report bodies over the limit as too large",
+			args: args{
+				err:        fmt.Errorf("error decoding article payload: %w", &http.MaxBytesError{Limit: 1024}),
+				statusCode: 400,
+			},
+			wantStatus: 413,
+			wantBody: &Problem{
+				Type:   "urn:problem:request_entity_too_large",
+				Title:  "Request Entity Too Large",
+				Status: 413,
+				Detail: "http: request body too large",
+				Code:   "request_entity_too_large",
+			},
+		},
+		{
+			name: "should report server errors after the deadline as unavailable",
+			args: args{
+				err:        errors.New("error selecting articles: canceling statement due to user request"),
+				statusCode: 500,
+			},
+			timedOut:   true,
+			wantStatus: 503,
+			wantBody: &Problem{
+				Type:   "urn:problem:timeout",
+				Title:  "Service Unavailable",
+				Status: 503,
+				Code:   "timeout",
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
+			ctx := requestid.NewContext(context.TODO(), tt.requestID)
+			if tt.timedOut {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, time.Now().Add(-time.Second))
+				defer cancel()
+			}
 
-			HandleError(context.TODO(), w, tt.args.err, tt.args.statusCode, tt.args.shouldLog)
+			HandleError(ctx, w, tt.args.err, tt.args.statusCode, tt.args.shouldLog)
 
 			if w.Code != tt.wantStatus {
 				t.Fatalf("HandleError() status = %v, want %v", w.Code, tt.wantStatus)
 			}
 
-			var resBody errorResponse
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/problem+json" {
+				t.Fatalf("HandleError() content type = %v, want application/problem+json", contentType)
+			}
+
+			var resBody Problem
 			err := json.NewDecoder(w.Body).Decode(&resBody)
 			if err != nil {
 				t.Fatalf("HandleError() error json decoding response body: %v", err)
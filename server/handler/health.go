@@ -1,14 +1,19 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+
+	"github.com/goodleby/golang-app/health"
 )
 
 type healthResponse struct {
 	Status string `json:"status"`
 }
 
+// Health reports OK, like it did before the liveness and readiness probes, for
+// the monitors that still poll it.
 func Health(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -18,3 +23,38 @@ func Health(w http.ResponseWriter, r *http.Request) {
 	})
 	handleWritingErr(err)
 }
+
+// Livez reports that the process is able to serve requests. It doesn't check
+// any dependencies, a failing database mustn't get the service restarted.
+func Livez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err := json.NewEncoder(w).Encode(healthResponse{
+		Status: health.StatusUp,
+	})
+	handleWritingErr(err)
+}
+
+type ReadinessChecker interface {
+	CheckReadiness(ctx context.Context) *health.Report
+}
+
+// Readyz reports whether the dependencies of the service are usable. The
+// verbose query parameter adds the result of every check.
+func Readyz(checker ReadinessChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := checker.CheckReadiness(r.Context())
+
+		if !r.URL.Query().Has("verbose") {
+			report = &health.Report{Status: report.Status}
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("Cache-Control", "no-store")
+		w.WriteHeader(report.HTTPStatus())
+
+		err := json.NewEncoder(w).Encode(report)
+		handleWritingErr(err)
+	}
+}
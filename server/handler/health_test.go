@@ -1,11 +1,16 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/health"
 )
 
 func TestHealth(t *testing.T) {
@@ -51,3 +56,112 @@ func TestHealth(t *testing.T) {
 		})
 	}
 }
+
+func TestLivez(t *testing.T) {
+	type args struct {
+		req *http.Request
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantStatus int
+		wantBody   *healthResponse
+	}{
+		{
+			name: "should return status up",
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/_livez", nil),
+			},
+			wantStatus: http.StatusOK,
+			wantBody: &healthResponse{
+				Status: health.StatusUp,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			Livez(w, tt.args.req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Livez() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+
+			var resBody healthResponse
+			err := json.NewDecoder(w.Body).Decode(&resBody)
+			if err != nil {
+				t.Fatalf("Livez() error json decoding response body: %v", err)
+			}
+
+			if !reflect.DeepEqual(&resBody, tt.wantBody) {
+				t.Fatalf("Livez() response body = %v, want %v", resBody, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		check      health.Check
+		shutdown   bool
+		target     string
+		wantStatus int
+		wantChecks int
+	}{
+		{
+			name:       "should be ready when the checks pass",
+			check:      func(ctx context.Context) error { return nil },
+			target:     "/_readyz",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should not be ready when a check fails",
+			check:      func(ctx context.Context) error { return errors.New("connection refused") },
+			target:     "/_readyz",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "should list the checks when verbose",
+			check:      func(ctx context.Context) error { return errors.New("connection refused") },
+			target:     "/_readyz?verbose",
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: 1,
+		},
+		{
+			name:       "should not be ready when shutting down",
+			check:      func(ctx context.Context) error { return nil },
+			shutdown:   true,
+			target:     "/_readyz?verbose",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := health.NewChecker(time.Second, 0)
+			checker.Register("database", tt.check)
+			if tt.shutdown {
+				checker.Shutdown()
+			}
+
+			w := httptest.NewRecorder()
+
+			Readyz(checker)(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Readyz() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+
+			var report health.Report
+			err := json.NewDecoder(w.Body).Decode(&report)
+			if err != nil {
+				t.Fatalf("Readyz() error json decoding response body: %v", err)
+			}
+
+			if len(report.Checks) != tt.wantChecks {
+				t.Fatalf("Readyz() checks = %v, want %v", len(report.Checks), tt.wantChecks)
+			}
+		})
+	}
+}
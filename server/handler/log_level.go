@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+type LogLeveler interface {
+	Level() slog.Level
+	Set(level slog.Level)
+}
+
+type LogLevelPayload struct {
+	Level slog.Level `json:"level"`
+}
+
+func GetLogLevel(leveler LogLeveler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err := json.NewEncoder(w).Encode(LogLevelPayload{Level: leveler.Level()})
+		handleWritingErr(err)
+	}
+}
+
+// SetLogLevel changes the level of the default logger until the next restart.
+func SetLogLevel(leveler LogLeveler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var payload LogLevelPayload
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error decoding log level payload: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		previous := leveler.Level()
+		leveler.Set(payload.Level)
+		slog.Warn(fmt.Sprintf("Log level changed from %s to %s", previous, payload.Level))
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(payload)
+		handleWritingErr(err)
+	}
+}
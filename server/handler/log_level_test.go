@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetLogLevel(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantLevel  slog.Level
+	}{
+		{
+			name:       "should set the level",
+			body:       `{"level":"debug"}`,
+			wantStatus: http.StatusOK,
+			wantLevel:  slog.LevelDebug,
+		},
+		{
+			name:       "should accept offsets",
+			body:       `{"level":"WARN+2"}`,
+			wantStatus: http.StatusOK,
+			wantLevel:  slog.LevelWarn + 2,
+		},
+		{
+			name:       "should reject unknown levels",
+			body:       `{"level":"verbose"}`,
+			wantStatus: http.StatusBadRequest,
+			wantLevel:  slog.LevelInfo,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var level slog.LevelVar
+			level.Set(slog.LevelInfo)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/log-level", strings.NewReader(tt.body))
+
+			SetLogLevel(&level)(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("SetLogLevel() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+
+			if level.Level() != tt.wantLevel {
+				t.Errorf("SetLogLevel() level = %v, want %v", level.Level(), tt.wantLevel)
+			}
+		})
+	}
+}
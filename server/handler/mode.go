@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/model/mode"
+)
+
+type ModeGetter interface {
+	CurrentMode() mode.State
+}
+
+type ModeSetter interface {
+	SetMode(ctx context.Context, payload mode.Payload) (*mode.State, error)
+}
+
+func GetMode(modeGetter ModeGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err := json.NewEncoder(w).Encode(modeGetter.CurrentMode())
+		handleWritingErr(err)
+	}
+}
+
+// SetMode switches the mode of all replicas.
+func SetMode(modeSetter ModeSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var payload mode.Payload
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error decoding mode payload: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		err = payload.Validate()
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error invalid mode payload: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		state, err := modeSetter.SetMode(ctx, payload)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error setting mode: %w", err), http.StatusInternalServerError, true)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(state)
+		handleWritingErr(err)
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/webhook"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type WebhookRedeliverer interface {
+	RedeliverWebhook(ctx context.Context, webhookID int, deliveryID int64) (*webhook.Delivery, error)
+}
+
+// RedeliverWebhook queues a delivery to be sent again. Deliveries of a
+// disabled webhook are sent once it's enabled again.
+func RedeliverWebhook(webhookRedeliverer WebhookRedeliverer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span := tracing.SpanFromContext(ctx)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error converting id to int: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error converting delivery id to int: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		span.SetTag("id", chi.URLParam(r, "id"))
+		span.SetTag("delivery_id", chi.URLParam(r, "deliveryID"))
+
+		delivery, err := webhookRedeliverer.RedeliverWebhook(ctx, id, deliveryID)
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrNotFound:
+				HandleError(ctx, w, fmt.Errorf("error redelivering webhook: not found: %w", err), http.StatusNotFound, false)
+			default:
+				HandleError(ctx, w, fmt.Errorf("error redelivering webhook: %w", err), http.StatusInternalServerError, true)
+			}
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+
+		err = json.NewEncoder(w).Encode(delivery)
+		handleWritingErr(err)
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/server/render"
+)
+
+// negotiate picks the representation of the response from the Accept header.
+// It responds with 406 and reports false when none is acceptable, so it's
+// called before doing any work.
+func negotiate(w http.ResponseWriter, r *http.Request) (render.Encoder, bool) {
+	w.Header().Add("Vary", "Accept")
+
+	encoder, err := render.NewEncoder(r.Header.Get("Accept"))
+	if err != nil {
+		HandleError(r.Context(), w, fmt.Errorf("error negotiating response representation: %w", err), http.StatusNotAcceptable, false)
+		return nil, false
+	}
+
+	return encoder, true
+}
+
+func respond(w http.ResponseWriter, encoder render.Encoder, status int, v any) {
+	w.Header().Add("Content-Type", encoder.ContentType())
+	w.WriteHeader(status)
+
+	err := encoder.Encode(w, v)
+	handleWritingErr(err)
+}
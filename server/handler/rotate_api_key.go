@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/apikey"
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type APIKeyRotator interface {
+	RotateAPIKey(ctx context.Context, id int) (key string, apiKey *apikey.APIKey, err error)
+}
+
+func RotateAPIKey(apiKeyRotator APIKeyRotator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span := tracing.SpanFromContext(ctx)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error converting id to int: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		span.SetTag("id", chi.URLParam(r, "id"))
+		audit.EventFromContext(ctx).SetTarget(apiKeyTarget(id))
+
+		key, apiKey, err := apiKeyRotator.RotateAPIKey(ctx, id)
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrNotFound:
+				HandleError(ctx, w, fmt.Errorf("error rotating api key: not found: %w", err), http.StatusNotFound, false)
+			default:
+				HandleError(ctx, w, fmt.Errorf("error rotating api key: %w", err), http.StatusInternalServerError, true)
+			}
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(APIKeyWithSecret{APIKey: *apiKey, Key: key})
+		handleWritingErr(err)
+	}
+}
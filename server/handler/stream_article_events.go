@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goodleby/golang-app/articlefeed"
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/model/tenant"
+)
+
+type ArticleEventSubscriber interface {
+	Subscribe(lastEventID int64) (*articlefeed.Subscription, []article.Event, bool)
+	Unsubscribe(subscription *articlefeed.Subscription)
+}
+
+// StreamArticleEvents streams article changes as server-sent events. Clients
+// resume with the Last-Event-ID header, when the missed events are no longer
+// in the backlog they get a reset event and should refetch the articles. The
+// stream ends when the auth token expires. Only the events of the tenant of the
+// request are streamed.
+func StreamArticleEvents(subscriber ArticleEventSubscriber) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		lastEventID, err := parseLastEventID(r)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error parsing last event id: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+
+		// The stream outlives the server write timeout.
+		err = rc.SetWriteDeadline(time.Time{})
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			HandleError(ctx, w, fmt.Errorf("error clearing write deadline: %w", err), http.StatusInternalServerError, true)
+			return
+		}
+
+		tenantID := tenant.IDFromContext(ctx)
+
+		subscription, missed, ok := subscriber.Subscribe(lastEventID)
+		defer subscriber.Unsubscribe(subscription)
+
+		var expired <-chan time.Time
+		if claims := auth.ClaimsFromContext(ctx); claims != nil && claims.ExpiresAt != nil {
+			timer := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+			defer timer.Stop()
+			expired = timer.C
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		sse := sseWriter{w: w, rc: rc}
+		sse.retry(sseRetry)
+
+		if !ok {
+			sse.event("", "reset", "{}")
+		}
+		for _, event := range missed {
+			if event.TenantID == tenantID {
+				sse.articleEvent(event)
+			}
+		}
+		lastEventID = max(lastEventID, sse.lastID)
+
+		heartbeat := time.NewTicker(sseHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for sse.err == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-expired:
+				return
+			case <-heartbeat.C:
+				sse.comment("heartbeat")
+			case event, ok := <-subscription.Events:
+				if !ok {
+					return
+				}
+				// Skips events the client already got from another replica.
+				if event.ID <= lastEventID || event.TenantID != tenantID {
+					continue
+				}
+				sse.articleEvent(event)
+				lastEventID = event.ID
+			}
+		}
+
+		handleWritingErr(sse.err)
+	}
+}
+
+func parseLastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		// EventSource can't set headers on the first connection.
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid event id %q", value)
+	}
+
+	return id, nil
+}
+
+// sseWriter writes server-sent events and flushes each of them. After an
+// error it writes nothing else.
+type sseWriter struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	lastID int64
+	err    error
+}
+
+func (s *sseWriter) articleEvent(event article.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		s.err = fmt.Errorf("error encoding article event %d: %v", event.ID, err)
+		return
+	}
+
+	s.event(strconv.FormatInt(event.ID, 10), event.Type, string(data))
+	s.lastID = event.ID
+}
+
+func (s *sseWriter) event(id, name, data string) {
+	var frame string
+	if id != "" {
+		frame += "id: " + id + "\n"
+	}
+	frame += "event: " + name + "\ndata: " + data + "\n\n"
+
+	s.write(frame)
+}
+
+func (s *sseWriter) retry(retry time.Duration) {
+	s.write(fmt.Sprintf("retry: %d\n\n", retry.Milliseconds()))
+}
+
+func (s *sseWriter) comment(comment string) {
+	s.write(": " + comment + "\n\n")
+}
+
+func (s *sseWriter) write(frame string) {
+	if s.err != nil {
+		return
+	}
+
+	_, err := fmt.Fprint(s.w, frame)
+	if err == nil {
+		err = s.rc.Flush()
+	}
+	s.err = err
+}
+
+const (
+	sseRetry             time.Duration = 3 * time.Second
+	sseHeartbeatInterval time.Duration = 15 * time.Second
+)
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/articlefeed"
+	"github.com/goodleby/golang-app/model/article"
+)
+
+// closedFeed ends every subscription right away, so the stream only holds
+// the missed events.
+type closedFeed struct {
+	*articlefeed.Feed
+}
+
+func (f closedFeed) Subscribe(lastEventID int64) (*articlefeed.Subscription, []article.Event, bool) {
+	subscription, missed, ok := f.Feed.Subscribe(lastEventID)
+	f.Feed.Unsubscribe(subscription)
+	return subscription, missed, ok
+}
+
+func TestStreamArticleEvents(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		header     string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "should resume after the last event id",
+			target:     "/articles/events",
+			header:     "3",
+			wantStatus: http.StatusOK,
+			wantBody: "retry: 3000\n\n" +
+				"id: 4\nevent: article.updated\ndata: {\"id\":4,\"type\":\"article.updated\",\"article\":{\"title\":\"\",\"description\":\"\",\"body\":\"\",\"tags\":null,\"id\":1},\"occurredAt\":\"0001-01-01T00:00:00Z\"}\n\n",
+		},
+		{
+			name:       "should accept the last event id as query parameter",
+			target:     "/articles/events?last_event_id=4",
+			wantStatus: http.StatusOK,
+			wantBody:   "retry: 3000\n\n",
+		},
+		{
+			name:       "should reset when the backlog doesn't reach back",
+			target:     "/articles/events",
+			header:     "1",
+			wantStatus: http.StatusOK,
+			wantBody:   "retry: 3000\n\nevent: reset\ndata: {}\n\n",
+		},
+		{
+			name:       "should reject invalid ids",
+			target:     "/articles/events",
+			header:     "abc",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := articlefeed.New(nil, 2, time.Hour)
+			for id := range int64(4) {
+				feed.Publish(article.Event{ID: id + 1, Type: article.EventUpdated, Article: article.Article{ID: 1}})
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Last-Event-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			StreamArticleEvents(closedFeed{feed})(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("StreamArticleEvents() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if contentType := w.Header().Get("Content-Type"); contentType != "text/event-stream" {
+				t.Errorf("StreamArticleEvents() content type = %v, want text/event-stream", contentType)
+			}
+
+			if body := w.Body.String(); body != tt.wantBody {
+				t.Errorf("StreamArticleEvents() body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestStreamArticleEventsLive(t *testing.T) {
+	feed := articlefeed.New(nil, 10, time.Hour)
+
+	server := httptest.NewServer(StreamArticleEvents(feed))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("error connecting to stream: %v", err)
+	}
+	defer res.Body.Close()
+
+	feed.Publish(article.Event{ID: 7, Type: article.EventCreated})
+
+	buf := make([]byte, 512)
+	var received string
+	for !strings.Contains(received, "id: 7\n") {
+		n, err := res.Body.Read(buf)
+		if err != nil {
+			t.Fatalf("error reading stream: %v, received %q", err, received)
+		}
+		received += string(buf[:n])
+	}
+}
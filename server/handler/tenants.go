@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/tenant"
+	"github.com/goodleby/golang-app/tenancy"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type TenantLister interface {
+	Tenants() []tenant.Tenant
+}
+
+type TenantGetter interface {
+	Tenant(id string) (*tenant.Tenant, error)
+}
+
+type TenantProvisioner interface {
+	Provision(ctx context.Context, payload tenant.Payload) (*tenant.Provisioned, error)
+}
+
+type TenantStatusSetter interface {
+	Suspend(ctx context.Context, id string) (*tenant.Tenant, error)
+	Resume(ctx context.Context, id string) (*tenant.Tenant, error)
+}
+
+func GetAllTenants(tenantLister TenantLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err := json.NewEncoder(w).Encode(tenantLister.Tenants())
+		handleWritingErr(err)
+	}
+}
+
+func GetTenant(tenantGetter TenantGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span := tracing.SpanFromContext(ctx)
+
+		id := chi.URLParam(r, "id")
+		span.SetTag("id", id)
+
+		t, err := tenantGetter.Tenant(id)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error getting tenant: not found: %w", err), http.StatusNotFound, false)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(t)
+		handleWritingErr(err)
+	}
+}
+
+// AddTenant provisions a tenant, responding with its role keys, which can't be
+// retrieved later.
+func AddTenant(tenantProvisioner TenantProvisioner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var payload tenant.Payload
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error decoding tenant payload: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		err = payload.Validate()
+		if err != nil {
+			HandleError(ctx, w, fmt.Errorf("error invalid tenant payload: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		provisioned, err := tenantProvisioner.Provision(ctx, payload)
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrConflict:
+				HandleError(ctx, w, fmt.Errorf("error provisioning tenant: conflict: %w", err), http.StatusConflict, false)
+			default:
+				HandleError(ctx, w, fmt.Errorf("error provisioning tenant: %w", err), http.StatusInternalServerError, true)
+			}
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(provisioned)
+		handleWritingErr(err)
+	}
+}
+
+// SuspendTenant stops serving the tenant, keeping its articles.
+func SuspendTenant(tenantStatusSetter TenantStatusSetter) http.HandlerFunc {
+	return setTenantStatus(func(ctx context.Context, id string) (*tenant.Tenant, error) {
+		return tenantStatusSetter.Suspend(ctx, id)
+	})
+}
+
+func ResumeTenant(tenantStatusSetter TenantStatusSetter) http.HandlerFunc {
+	return setTenantStatus(func(ctx context.Context, id string) (*tenant.Tenant, error) {
+		return tenantStatusSetter.Resume(ctx, id)
+	})
+}
+
+func setTenantStatus(set func(ctx context.Context, id string) (*tenant.Tenant, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span := tracing.SpanFromContext(ctx)
+
+		id := chi.URLParam(r, "id")
+		span.SetTag("id", id)
+
+		t, err := set(ctx, id)
+		if err == tenancy.ErrDefaultTenant {
+			HandleError(ctx, w, fmt.Errorf("error setting tenant status: %w", err), http.StatusBadRequest, false)
+			return
+		}
+		if err != nil {
+			switch err.(type) {
+			case *client.ErrNotFound:
+				HandleError(ctx, w, fmt.Errorf("error setting tenant status: not found: %w", err), http.StatusNotFound, false)
+			default:
+				HandleError(ctx, w, fmt.Errorf("error setting tenant status: %w", err), http.StatusInternalServerError, true)
+			}
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(t)
+		handleWritingErr(err)
+	}
+}
@@ -22,9 +22,14 @@ func UpdateArticle(articleUpdater ArticleUpdater) http.HandlerFunc {
 		ctx := r.Context()
 		span := tracing.SpanFromContext(ctx)
 
+		encoder, ok := negotiate(w, r)
+		if !ok {
+			return
+		}
+
 		id, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error converting id to int: %v", err), http.StatusBadRequest, false)
+			HandleError(ctx, w, fmt.Errorf("error converting id to int: %w", err), http.StatusBadRequest, false)
 			return
 		}
 
@@ -33,13 +38,13 @@ func UpdateArticle(articleUpdater ArticleUpdater) http.HandlerFunc {
 		var payload article.Payload
 		err = json.NewDecoder(r.Body).Decode(&payload)
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error decoding article payload: %v", err), http.StatusBadRequest, false)
+			HandleError(ctx, w, fmt.Errorf("error decoding article payload: %w", err), http.StatusBadRequest, false)
 			return
 		}
 
 		err = payload.Validate()
 		if err != nil {
-			HandleError(ctx, w, fmt.Errorf("error invalid article payload: %v", err), http.StatusBadRequest, false)
+			HandleError(ctx, w, fmt.Errorf("error invalid article payload: %w", err), http.StatusBadRequest, false)
 			return
 		}
 
@@ -47,17 +52,13 @@ func UpdateArticle(articleUpdater ArticleUpdater) http.HandlerFunc {
 		if err != nil {
 			switch err.(type) {
 			case *client.ErrNotFound:
-				HandleError(ctx, w, fmt.Errorf("error updating article: not found: %v", err), http.StatusNotFound, false)
+				HandleError(ctx, w, fmt.Errorf("error updating article: not found: %w", err), http.StatusNotFound, false)
 			default:
-				HandleError(ctx, w, fmt.Errorf("error updating article: %v", err), http.StatusInternalServerError, true)
+				HandleError(ctx, w, fmt.Errorf("error updating article: %w", err), http.StatusInternalServerError, true)
 			}
 			return
 		}
 
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-
-		err = json.NewEncoder(w).Encode(article)
-		handleWritingErr(err)
+		respond(w, encoder, http.StatusOK, article)
 	}
 }
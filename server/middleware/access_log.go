@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/requestid"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type AccessLogConfig struct {
+	// SampleRate is the share of successful requests that are logged, server
+	// errors are always logged.
+	SampleRate float64
+	// ExcludePaths are never logged, e.g. health checks.
+	ExcludePaths []string
+}
+
+// AccessLog writes a structured log line per request once it's handled.
+func AccessLog(config AccessLogConfig) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(config.ExcludePaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			crw := customResponseWriter{ResponseWriter: w}
+			start := time.Now()
+
+			next.ServeHTTP(&crw, r)
+
+			latency := time.Since(start)
+
+			if crw.status == 0 {
+				crw.status = http.StatusOK
+			}
+
+			if crw.status < http.StatusInternalServerError && rand.Float64() >= config.SampleRate {
+				return
+			}
+
+			ctx := r.Context()
+			span := tracing.SpanFromContext(ctx)
+
+			level := slog.LevelInfo
+			if crw.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			slog.Log(ctx, level, fmt.Sprintf("%s %s %d", r.Method, r.URL.Path, crw.status),
+				"method", r.Method,
+				"route", chi.RouteContext(ctx).RoutePattern(),
+				"path", r.URL.Path,
+				"status", crw.status,
+				"bytes", crw.bytes,
+				"latency", latency,
+				"ip", clientIP(r),
+				"user_agent", r.UserAgent(),
+				"request_id", requestid.FromContext(ctx),
+				"trace_id", span.TraceID(),
+			)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/requestid"
+)
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        AccessLogConfig
+		path          string
+		status        int
+		requestID     string
+		wantLogged    bool
+		wantRequestID string
+	}{
+		{
+			name:          "logs request with caller request id",
+			config:        AccessLogConfig{SampleRate: 1},
+			path:          "/articles/1",
+			status:        http.StatusOK,
+			requestID:     "caller-id",
+			wantLogged:    true,
+			wantRequestID: "caller-id",
+		},
+		{
+			name:   "skips excluded path",
+			config: AccessLogConfig{SampleRate: 1, ExcludePaths: []string{"/_healthz"}},
+			path:   "/_healthz",
+			status: http.StatusOK,
+		},
+		{
+			name:   "skips unsampled success",
+			config: AccessLogConfig{SampleRate: 0},
+			path:   "/articles/1",
+			status: http.StatusOK,
+		},
+		{
+			name:       "always logs server errors",
+			config:     AccessLogConfig{SampleRate: 0},
+			path:       "/articles/1",
+			status:     http.StatusInternalServerError,
+			requestID:  "not a valid id",
+			wantLogged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			defaultLogger := slog.Default()
+			slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+			t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+			router := chi.NewRouter()
+			router.Use(RequestID, AccessLog(tt.config))
+			router.Get("/*", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("body"))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(requestid.Header, tt.requestID)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			gotRequestID := w.Header().Get(requestid.Header)
+			if gotRequestID == "" || (tt.wantRequestID != "" && gotRequestID != tt.wantRequestID) {
+				t.Errorf("RequestID() header = %q, want %q", gotRequestID, tt.wantRequestID)
+			}
+
+			if (buf.Len() > 0) != tt.wantLogged {
+				t.Fatalf("AccessLog() logged = %q, want logged %v", buf.String(), tt.wantLogged)
+			}
+			if !tt.wantLogged {
+				return
+			}
+
+			var line map[string]any
+			err := json.Unmarshal(buf.Bytes(), &line)
+			if err != nil {
+				t.Fatalf("error decoding log line: %v", err)
+			}
+
+			if line["request_id"] != gotRequestID {
+				t.Errorf("AccessLog() request_id = %v, want %v", line["request_id"], gotRequestID)
+			}
+			if line["status"] != float64(tt.status) || line["bytes"] != float64(4) || line["route"] != "/*" {
+				t.Errorf("AccessLog() line = %v, want status %d, 4 bytes and route /*", line, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/model/tenant"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type AuditRecorder interface {
+	RecordAuditEvent(ctx context.Context, event *audit.Event) error
+}
+
+// Audit records the action performed by the request in the audit log once the
+// handler is done. Handlers and clients down the chain can fill in the target
+// and actor through audit.EventFromContext.
+func Audit(recorder AuditRecorder, action string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			event := NewAuditEvent(r, action)
+
+			crw := customResponseWriter{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(&crw, r.WithContext(audit.NewContext(ctx, event)))
+
+			RecordAuditEvent(ctx, recorder, event, crw.status)
+		})
+	}
+}
+
+// NewAuditEvent starts the audit event of an action performed by the request,
+// for actions that aren't a route of their own, e.g. GraphQL mutations.
+func NewAuditEvent(r *http.Request, action string) *audit.Event {
+	ctx := r.Context()
+	span := tracing.SpanFromContext(ctx)
+
+	event := &audit.Event{
+		Action:    action,
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+		TraceID:   span.TraceID(),
+	}
+
+	if claims := auth.ClaimsFromContext(ctx); claims != nil {
+		event.SetActor(claims.Subject, claims.RoleName)
+	}
+
+	return event
+}
+
+// RecordAuditEvent records the event with the status the action ended with.
+func RecordAuditEvent(ctx context.Context, recorder AuditRecorder, event *audit.Event, status int) {
+	event.Status = status
+	if event.Tenant == "" {
+		event.Tenant = tenant.IDFromContext(ctx)
+	}
+	event.SetActor(anonymousActor, "")
+
+	// The event must be recorded even if the client has gone away.
+	err := recorder.RecordAuditEvent(context.WithoutCancel(ctx), event)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error recording audit event: %v", err), "action", event.Action)
+	}
+}
+
+const anonymousActor = "anonymous"
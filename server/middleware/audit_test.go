@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goodleby/golang-app/model/audit"
+)
+
+type fakeAuditRecorder struct {
+	events []audit.Event
+}
+
+func (f *fakeAuditRecorder) RecordAuditEvent(ctx context.Context, event *audit.Event) error {
+	f.events = append(f.events, *event)
+	return nil
+}
+
+func TestAudit_failedLogin(t *testing.T) {
+	recorder := &fakeAuditRecorder{}
+
+	h := ClientIP(nil)(Audit(recorder, "auth.login")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		audit.EventFromContext(r.Context()).SetDetail("role", "admin")
+		w.WriteHeader(http.StatusUnauthorized)
+	})))
+
+	r := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.9")
+
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(recorder.events) != 1 {
+		t.Fatalf("audit events = %d, want 1", len(recorder.events))
+	}
+
+	event := recorder.events[0]
+	if event.Actor != anonymousActor || event.Role != "" {
+		t.Errorf("audit event actor = %q, role = %q, want %q and no role", event.Actor, event.Role, anonymousActor)
+	}
+	if event.Details["role"] != "admin" {
+		t.Errorf("audit event details = %v, want the attempted role", event.Details)
+	}
+	if event.IP != "192.0.2.1" {
+		t.Errorf("audit event IP = %q, want the peer address %q", event.IP, "192.0.2.1")
+	}
+	if event.Status != http.StatusUnauthorized {
+		t.Errorf("audit event status = %d, want %d", event.Status, http.StatusUnauthorized)
+	}
+}
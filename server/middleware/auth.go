@@ -2,48 +2,108 @@ package middleware
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/goodleby/golang-app/client"
 	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/model/tenant"
 	"github.com/goodleby/golang-app/server/handler"
 )
 
-type TokenAccessReader interface {
-	ReadTokenAccess(ctx context.Context, token string) (auth.AccessLevel, error)
+type TokenClaimsReader interface {
+	ReadTokenClaims(ctx context.Context, token string) (*auth.Claims, error)
+	ReadAPIKeyClaims(ctx context.Context, key string) (*auth.Claims, error)
+	ReadCertificateClaims(ctx context.Context, cert *x509.Certificate) (*auth.Claims, error)
 }
 
-func Auth(tokenReader TokenAccessReader, expectedAccess auth.AccessLevel) func(next http.Handler) http.Handler {
+// Auth checks the access level of the caller, identified by an API key in the
+// X-API-Key header, by the auth token, as a bearer token or cookie, or else by
+// the verified client certificate. The claims of the caller are passed down in the request context.
+// The claims passed down by Identify are reused, for callers to be rate limited
+// before they are rejected.
+func Auth(claimsReader TokenClaimsReader, expectedAccess auth.AccessLevel) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
 
-			tokenCookie, err := r.Cookie("token")
-			if err != nil {
-				handler.HandleError(ctx, w, fmt.Errorf("error reading auth token cookie: %v", err), http.StatusUnauthorized, false)
+			claims := auth.ClaimsFromContext(ctx)
+			var err error
+			if claims == nil {
+				claims, err = readClaims(ctx, claimsReader, r)
+			}
+			if err == nil && claims == nil {
+				handler.HandleError(ctx, w, errors.New("error reading credentials: no api key, auth token or client certificate"), http.StatusUnauthorized, false)
 				return
 			}
-
-			tokenAccess, err := tokenReader.ReadTokenAccess(ctx, tokenCookie.Value)
 			if err != nil {
 				switch err.(type) {
 				case *client.ErrUnauthorized:
-					handler.HandleError(ctx, w, fmt.Errorf("error checking token access: unauthorized: %v", err), http.StatusUnauthorized, false)
+					handler.HandleError(ctx, w, fmt.Errorf("error checking token access: unauthorized: %w", err), http.StatusUnauthorized, false)
 				default:
-					handler.HandleError(ctx, w, fmt.Errorf("error checking token access: %v", err), http.StatusInternalServerError, true)
+					handler.HandleError(ctx, w, fmt.Errorf("error checking token access: %w", err), http.StatusInternalServerError, true)
 				}
 				return
 			}
 
-			if tokenAccess < expectedAccess {
+			if claims.AccessLevel < expectedAccess {
 				handler.HandleError(ctx, w, errors.New("insufficient access level"), http.StatusForbidden, false)
 				return
 			}
 
 			// Token is valid, access level is sufficient, proceed to the handler.
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(ctx, claims)))
+		})
+	}
+}
+
+// Identify passes the claims of the caller down in the request context when
+// the caller is authenticated, without requiring it.
+func Identify(claimsReader TokenClaimsReader) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			claims, err := readClaims(ctx, claimsReader, r)
+			if err == nil && claims != nil {
+				ctx = auth.NewContext(ctx, claims)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// readClaims returns nil claims if the request has no credentials. The claims
+// must be of the tenant of the request, if resolved.
+func readClaims(ctx context.Context, claimsReader TokenClaimsReader, r *http.Request) (*auth.Claims, error) {
+	claims, err := readCredentials(ctx, claimsReader, r)
+	if err != nil || claims == nil {
+		return claims, err
+	}
+
+	if id := tenant.IDFromContext(ctx); id != "" && claims.TenantID() != id {
+		return nil, &client.ErrUnauthorized{Err: fmt.Errorf("credentials are for tenant %q", claims.TenantID())}
+	}
+
+	return claims, nil
+}
+
+func readCredentials(ctx context.Context, claimsReader TokenClaimsReader, r *http.Request) (*auth.Claims, error) {
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		return claimsReader.ReadAPIKeyClaims(ctx, apiKey)
+	}
+
+	if token, err := handler.ReadAuthToken(r); err == nil {
+		return claimsReader.ReadTokenClaims(ctx, token)
+	}
+
+	// Chains are only verified in the client certificate verification modes.
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return claimsReader.ReadCertificateClaims(ctx, r.TLS.VerifiedChains[0][0])
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/goodleby/golang-app/clientip"
+)
+
+// ClientIP passes the address of the original client down in the request
+// context. X-Forwarded-For is only believed as far as it was appended by the
+// trusted proxies, see clientip.Proxies.Resolve.
+func ClientIP(proxies clientip.Proxies) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := proxies.Resolve(r.RemoteAddr, r.Header.Values("X-Forwarded-For"))
+
+			next.ServeHTTP(w, r.WithContext(clientip.NewContext(r.Context(), ip)))
+		})
+	}
+}
+
+// clientIP returns the address of the original client, see ClientIP, or else
+// the address of the peer.
+func clientIP(r *http.Request) string {
+	if ip := clientip.FromContext(r.Context()); ip != "" {
+		return ip
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
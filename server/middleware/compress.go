@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/goodleby/golang-app/server/render"
+	"github.com/klauspost/compress/zstd"
+)
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressors are the supported content codings, pooled since they are
+// expensive to create. The order of encodings is the order of preference.
+var (
+	encodings   = []string{"zstd", "br", "gzip"}
+	compressors = map[string]*sync.Pool{
+		"zstd": {New: func() any {
+			encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+			return encoder
+		}},
+		"br": {New: func() any {
+			return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+		}},
+		"gzip": {New: func() any {
+			return gzip.NewWriter(nil)
+		}},
+	}
+)
+
+// Compress compresses responses with the content coding negotiated via
+// Accept-Encoding. Responses shorter than minSize bytes aren't worth it and
+// are sent as they are.
+func Compress(minSize int) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding, ok := render.Negotiate(r.Header.Get("Accept-Encoding"), encodings)
+			if !ok || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				minSize:        minSize,
+				status:         http.StatusOK,
+			}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressWriter buffers the response until minSize bytes are written, then
+// decides whether to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	minSize     int
+	status      int
+	wroteHeader bool
+	started     bool
+	buf         []byte
+	compressor  compressor
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+
+	cw.status = status
+	cw.wroteHeader = true
+
+	// Responses without a body are passed through straight away.
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.started {
+		return cw.write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) < cw.minSize {
+		return len(p), nil
+	}
+
+	cw.start(true)
+
+	buf := cw.buf
+	cw.buf = nil
+
+	_, err := cw.write(buf)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (cw *compressWriter) write(p []byte) (int, error) {
+	if cw.compressor != nil {
+		return cw.compressor.Write(p)
+	}
+
+	return cw.ResponseWriter.Write(p)
+}
+
+// start sends the header, with the content coding if the response is to be
+// compressed.
+func (cw *compressWriter) start(compress bool) {
+	cw.started = true
+
+	header := cw.Header()
+	if compress && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+
+		cw.compressor = compressors[cw.encoding].Get().(compressor)
+		cw.compressor.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// Flush sends what was written so far. A response flushed before reaching
+// minSize is expected to stream on, so it's compressed.
+func (cw *compressWriter) Flush() {
+	if !cw.started {
+		if !cw.wroteHeader {
+			cw.WriteHeader(http.StatusOK)
+		}
+
+		cw.start(true)
+
+		buf := cw.buf
+		cw.buf = nil
+
+		_, err := cw.write(buf)
+		if err != nil {
+			return
+		}
+	}
+
+	if cw.compressor != nil {
+		err := cw.compressor.Flush()
+		if err != nil {
+			return
+		}
+	}
+
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Close writes out a response that stayed under minSize uncompressed and
+// returns the compressor to its pool.
+func (cw *compressWriter) Close() error {
+	if !cw.started {
+		if !cw.wroteHeader {
+			// Nothing was written, net/http sends the default response.
+			return nil
+		}
+
+		cw.start(false)
+
+		_, err := cw.ResponseWriter.Write(cw.buf)
+		if err != nil {
+			return fmt.Errorf("error writing response: %v", err)
+		}
+
+		return nil
+	}
+
+	if cw.compressor == nil {
+		return nil
+	}
+
+	err := cw.compressor.Close()
+	cw.compressor.Reset(nil)
+	compressors[cw.encoding].Put(cw.compressor)
+	cw.compressor = nil
+	if err != nil {
+		return fmt.Errorf("error closing %s compressor: %v", cw.encoding, err)
+	}
+
+	return nil
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// compressible reports whether the content type is worth compressing. Event
+// streams are left alone so every event reaches the client when flushed.
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "json"),
+		strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/xml",
+		mediaType == "application/javascript",
+		mediaType == "application/msgpack",
+		mediaType == "":
+		return true
+	default:
+		return false
+	}
+}
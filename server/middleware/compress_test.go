@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("article ", 200)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantEncoding   string
+	}{
+		{
+			name:           "should prefer zstd",
+			acceptEncoding: "gzip, br, zstd",
+			contentType:    "application/json",
+			body:           large,
+			wantEncoding:   "zstd",
+		},
+		{
+			name:           "should respect q values",
+			acceptEncoding: "gzip, zstd;q=0.5",
+			contentType:    "application/json",
+			body:           large,
+			wantEncoding:   "gzip",
+		},
+		{
+			name:           "should compress with brotli",
+			acceptEncoding: "br",
+			contentType:    "text/csv",
+			body:           large,
+			wantEncoding:   "br",
+		},
+		{
+			name:           "should not compress small responses",
+			acceptEncoding: "gzip",
+			contentType:    "application/json",
+			body:           `{"id":1}`,
+		},
+		{
+			name:           "should not compress without accept encoding",
+			acceptEncoding: "",
+			contentType:    "application/json",
+			body:           large,
+		},
+		{
+			name:           "should not compress images",
+			acceptEncoding: "gzip",
+			contentType:    "image/png",
+			body:           large,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusOK)
+				// Written in chunks to cross the threshold midway.
+				for chunk := range strings.SplitAfterSeq(tt.body, " ") {
+					_, _ = io.WriteString(w, chunk)
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/articles", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Compress() content encoding = %q, want %q", got, tt.wantEncoding)
+			}
+
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Compress() vary = %q, want Accept-Encoding", got)
+			}
+
+			body := decompress(t, tt.wantEncoding, w.Body)
+			if body != tt.body {
+				t.Errorf("Compress() body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	handler := Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = io.WriteString(w, "{\"id\":1}\n")
+		http.NewResponseController(w).Flush()
+		_, _ = io.WriteString(w, "{\"id\":2}\n")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/articles", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if !w.Flushed {
+		t.Errorf("Compress() didn't flush the response")
+	}
+
+	if body := decompress(t, "gzip", w.Body); body != "{\"id\":1}\n{\"id\":2}\n" {
+		t.Errorf("Compress() body = %q", body)
+	}
+}
+
+func decompress(t *testing.T, encoding string, body *bytes.Buffer) string {
+	t.Helper()
+
+	var reader io.Reader
+	switch encoding {
+	case "":
+		reader = body
+	case "gzip":
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatalf("error creating gzip reader: %v", err)
+		}
+		reader = gzipReader
+	case "zstd":
+		zstdReader, err := zstd.NewReader(body)
+		if err != nil {
+			t.Fatalf("error creating zstd reader: %v", err)
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+	case "br":
+		reader = brotli.NewReader(body)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("error decompressing %s body: %v", encoding, err)
+	}
+
+	return string(data)
+}
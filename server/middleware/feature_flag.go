@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type FeatureFlagEvaluator interface {
+	Enabled(ctx context.Context, key string) bool
+}
+
+// FeatureFlag hides the routes behind the flag from the callers it's off for.
+// They get the same 404 as for a route that doesn't exist. The middlewares
+// before it still run, e.g. unauthenticated callers get the 401 of Auth, so
+// only the callers that pass them can't tell dark routes from missing ones.
+// Claims of authenticated callers are read by Auth or Identify, which must
+// come first.
+func FeatureFlag(flags FeatureFlagEvaluator, key string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !flags.Enabled(r.Context(), key) {
+				http.NotFound(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeFlags map[string]bool
+
+func (f fakeFlags) Enabled(ctx context.Context, key string) bool {
+	return f[key]
+}
+
+func TestFeatureFlag(t *testing.T) {
+	tests := []struct {
+		name       string
+		flags      fakeFlags
+		wantStatus int
+	}{
+		{
+			name:       "flag on",
+			flags:      fakeFlags{"new-editor": true},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "flag off",
+			flags:      fakeFlags{"new-editor": false},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unknown flag",
+			flags:      fakeFlags{},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := FeatureFlag(tt.flags, "new-editor")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/editor", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("FeatureFlag() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/idempotency"
+	"github.com/goodleby/golang-app/model/tenant"
+	"github.com/goodleby/golang-app/server/handler"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type IdempotencyStore interface {
+	BeginIdempotentRequest(ctx context.Context, key, fingerprint string) (*idempotency.Record, error)
+	CompleteIdempotentRequest(ctx context.Context, key string, response *idempotency.Response, ttl time.Duration) error
+	ReleaseIdempotentRequest(ctx context.Context, key string) error
+}
+
+const (
+	IdempotencyKeyHeader     string = "Idempotency-Key"
+	IdempotentReplayedHeader string = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  int    = 255
+)
+
+// Idempotency replays the stored response to requests retried with the same
+// Idempotency-Key header. Keys are scoped to the tenant, the caller (see
+// auth.Claims.Caller) and the route, so callers can't see each other's
+// responses. Requests without the header are handled as usual.
+func Idempotency(store IdempotencyStore, ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				handler.HandleError(ctx, w, fmt.Errorf("error idempotency key is longer than %d characters", maxIdempotencyKeyLength), http.StatusBadRequest, false)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				handler.HandleError(ctx, w, fmt.Errorf("error reading request body: %w", err), http.StatusBadRequest, false)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			caller := "ip:" + clientIP(r)
+			if claims := auth.ClaimsFromContext(ctx); claims != nil {
+				caller = "caller:" + claims.Caller()
+			}
+			storeKey := tenant.IDFromContext(ctx) + ":" + caller + ":" + r.Method + ":" + r.URL.Path + ":" + key
+			fingerprint := idempotency.Fingerprint(r.Method, r.URL.Path, body)
+
+			span := tracing.SpanFromContext(ctx)
+			span.SetTag("idempotency_key", key)
+
+			record, err := store.BeginIdempotentRequest(ctx, storeKey, fingerprint)
+			if err != nil {
+				handler.HandleError(ctx, w, fmt.Errorf("error beginning idempotent request: %w", err), http.StatusInternalServerError, true)
+				return
+			}
+
+			if record != nil {
+				switch {
+				case record.Fingerprint != fingerprint:
+					handler.HandleError(ctx, w, errors.New("error idempotency key was already used for a different request"), http.StatusUnprocessableEntity, false)
+				case record.Response == nil:
+					handler.HandleError(ctx, w, errors.New("error a request with the idempotency key is in progress"), http.StatusConflict, false)
+				default:
+					replay(w, record.Response)
+				}
+				return
+			}
+
+			rw := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			handled := false
+			defer func() {
+				// The response is stored even if the client is gone.
+				ctx := context.WithoutCancel(ctx)
+
+				// Server errors and panics aren't stored, so the request can be
+				// retried.
+				if !handled || rw.status >= http.StatusInternalServerError {
+					err := store.ReleaseIdempotentRequest(ctx, storeKey)
+					if err != nil {
+						slog.Error(fmt.Sprintf("Error releasing idempotency key: %v", err))
+					}
+					return
+				}
+
+				err := store.CompleteIdempotentRequest(ctx, storeKey, &idempotency.Response{
+					Status:      rw.status,
+					ContentType: rw.Header().Get("Content-Type"),
+					Body:        rw.body.Bytes(),
+				}, ttl)
+				if err != nil {
+					slog.Error(fmt.Sprintf("Error completing idempotent request: %v", err))
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+			handled = true
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, response *idempotency.Response) {
+	if response.ContentType != "" {
+		w.Header().Set("Content-Type", response.ContentType)
+	}
+	w.Header().Set(IdempotentReplayedHeader, strconv.FormatBool(true))
+	w.WriteHeader(response.Status)
+
+	_, err := w.Write(response.Body)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error writing replayed response: %v", err))
+	}
+}
+
+// recordingResponseWriter keeps a copy of the response.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingResponseWriter) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recordingResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/idempotency"
+	"github.com/goodleby/golang-app/model/tenant"
+)
+
+func TestIdempotency(t *testing.T) {
+	type request struct {
+		key  string
+		body string
+	}
+	tests := []struct {
+		name         string
+		status       int
+		first        request
+		retry        request
+		wantStatus   int
+		wantReplayed bool
+		wantCalls    int
+	}{
+		{
+			name:         "should replay the response to a retry",
+			status:       http.StatusOK,
+			first:        request{key: "key-1", body: `{"title":"a"}`},
+			retry:        request{key: "key-1", body: `{"title":"a"}`},
+			wantStatus:   http.StatusOK,
+			wantReplayed: true,
+			wantCalls:    1,
+		},
+		{
+			name:         "should replay client errors",
+			status:       http.StatusBadRequest,
+			first:        request{key: "key-1", body: `{}`},
+			retry:        request{key: "key-1", body: `{}`},
+			wantStatus:   http.StatusBadRequest,
+			wantReplayed: true,
+			wantCalls:    1,
+		},
+		{
+			name:       "should reject a key reused with a different body",
+			status:     http.StatusOK,
+			first:      request{key: "key-1", body: `{"title":"a"}`},
+			retry:      request{key: "key-1", body: `{"title":"b"}`},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCalls:  1,
+		},
+		{
+			name:       "should handle a retry after a server error",
+			status:     http.StatusInternalServerError,
+			first:      request{key: "key-1", body: `{"title":"a"}`},
+			retry:      request{key: "key-1", body: `{"title":"a"}`},
+			wantStatus: http.StatusInternalServerError,
+			wantCalls:  2,
+		},
+		{
+			name:       "should handle requests without a key",
+			status:     http.StatusOK,
+			first:      request{body: `{"title":"a"}`},
+			retry:      request{body: `{"title":"a"}`},
+			wantStatus: http.StatusOK,
+			wantCalls:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			handler := Idempotency(idempotency.NewMemoryStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{"id":1}`))
+			}))
+
+			var w *httptest.ResponseRecorder
+			for _, req := range []request{tt.first, tt.retry} {
+				r := httptest.NewRequest(http.MethodPost, "/articles", strings.NewReader(req.body))
+				if req.key != "" {
+					r.Header.Set(IdempotencyKeyHeader, req.key)
+				}
+				w = httptest.NewRecorder()
+
+				handler.ServeHTTP(w, r)
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Idempotency() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+
+			if replayed := w.Header().Get(IdempotentReplayedHeader) == "true"; replayed != tt.wantReplayed {
+				t.Errorf("Idempotency() replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+
+			if tt.wantReplayed && w.Body.String() != `{"id":1}` {
+				t.Errorf("Idempotency() replayed body = %v, want %v", w.Body.String(), `{"id":1}`)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("Idempotency() handler calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyConcurrentDuplicate(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+
+	var retry *httptest.ResponseRecorder
+	var handler http.Handler
+	handler = Idempotency(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The duplicate arrives while the first request is being handled.
+		if retry == nil {
+			retry = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/articles", strings.NewReader(`{}`))
+			req.Header.Set(IdempotencyKeyHeader, "key-1")
+			handler.ServeHTTP(retry, req)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/articles", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if retry.Code != http.StatusConflict {
+		t.Errorf("Idempotency() concurrent duplicate status = %v, want %v", retry.Code, http.StatusConflict)
+	}
+}
+
+func TestIdempotency_callers(t *testing.T) {
+	session := func(id string) *auth.Claims {
+		claims := &auth.Claims{RoleName: auth.EditorRole}
+		claims.ID = id
+		claims.Subject = auth.EditorRole
+		return claims
+	}
+
+	tests := []struct {
+		name     string
+		contexts []func(ctx context.Context) context.Context
+	}{
+		{
+			name: "should not replay across sessions of the same role",
+			contexts: []func(ctx context.Context) context.Context{
+				func(ctx context.Context) context.Context { return auth.NewContext(ctx, session("session-1")) },
+				func(ctx context.Context) context.Context { return auth.NewContext(ctx, session("session-2")) },
+			},
+		},
+		{
+			name: "should not replay across tenants",
+			contexts: []func(ctx context.Context) context.Context{
+				func(ctx context.Context) context.Context { return tenant.NewContext(ctx, "acme") },
+				func(ctx context.Context) context.Context { return tenant.NewContext(ctx, "globex") },
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			handler := Idempotency(idempotency.NewMemoryStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			for _, withCaller := range tt.contexts {
+				r := httptest.NewRequest(http.MethodPost, "/articles", strings.NewReader(`{"title":"a"}`))
+				r = r.WithContext(withCaller(r.Context()))
+				r.Header.Set(IdempotencyKeyHeader, "key-1")
+				w := httptest.NewRecorder()
+
+				handler.ServeHTTP(w, r)
+
+				if w.Header().Get(IdempotentReplayedHeader) == "true" {
+					t.Errorf("Idempotency() replayed a response stored by another caller")
+				}
+			}
+
+			if calls != len(tt.contexts) {
+				t.Errorf("Idempotency() handler calls = %v, want %v", calls, len(tt.contexts))
+			}
+		})
+	}
+}
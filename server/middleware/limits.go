@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/goodleby/golang-app/server/handler"
+)
+
+// Timeout gives the handlers a deadline in the request context and responds
+// with 503 once it has passed, like http.TimeoutHandler, whether the handler
+// returned or not. The handler response is buffered until it returns in time.
+// The read and write deadlines of the connection are moved to match, so routes
+// can have longer timeouts than the server.
+func Timeout(timeout time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline := time.Now().Add(timeout)
+
+			rc := http.NewResponseController(w)
+
+			err := rc.SetReadDeadline(deadline)
+			if err != nil && !errors.Is(err, http.ErrNotSupported) {
+				handler.HandleError(r.Context(), w, fmt.Errorf("error setting read deadline: %w", err), http.StatusInternalServerError, true)
+				return
+			}
+
+			// Leaves time to respond once the handler deadline has passed.
+			err = rc.SetWriteDeadline(deadline.Add(timeoutResponseGrace))
+			if err != nil && !errors.Is(err, http.ErrNotSupported) {
+				handler.HandleError(r.Context(), w, fmt.Errorf("error setting write deadline: %w", err), http.StatusInternalServerError, true)
+				return
+			}
+
+			ctx, cancel := context.WithDeadline(r.Context(), deadline)
+			defer cancel()
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			panicc := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						// The stack of the handler is lost once panicked again.
+						panicc <- &stackedPanic{value: p, stack: debug.Stack()}
+					}
+				}()
+
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicc:
+				// Lets Recover respond to the panic.
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				tw.flushTo(w)
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				tw.timedOut = true
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					handler.HandleError(ctx, w, fmt.Errorf("error handling request: %w", ctx.Err()), http.StatusServiceUnavailable, false)
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers the response of the handler, which is discarded if
+// the handler doesn't return before the deadline. It doesn't unwrap, flushing
+// or hijacking the connection would bypass the buffer.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.status = status
+	tw.wroteHeader = true
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.status = http.StatusOK
+		tw.wroteHeader = true
+	}
+
+	return tw.body.Write(b)
+}
+
+// flushTo writes the buffered response, the lock must be held.
+func (tw *timeoutWriter) flushTo(w http.ResponseWriter) {
+	maps.Copy(w.Header(), tw.header)
+
+	if !tw.wroteHeader {
+		tw.status = http.StatusOK
+	}
+	w.WriteHeader(tw.status)
+
+	_, err := w.Write(tw.body.Bytes())
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error writing buffered response: %v", err))
+	}
+}
+
+// BodyLimit rejects request bodies longer than maxBytes with 413, straight
+// away if the Content-Length tells, or else once the handler reads past the
+// limit, see handler.HandleError.
+func BodyLimit(maxBytes int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				handler.HandleError(r.Context(), w, &http.MaxBytesError{Limit: maxBytes}, http.StatusRequestEntityTooLarge, false)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+const timeoutResponseGrace time.Duration = time.Second
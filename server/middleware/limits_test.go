@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/server/handler"
+)
+
+func TestBodyLimit(t *testing.T) {
+	// Reads the body like the handlers do and fails with a client error.
+	h := BodyLimit(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		if err != nil {
+			handler.HandleError(r.Context(), w, fmt.Errorf("error reading body: %w", err), http.StatusBadRequest, false)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		wantStatus    int
+	}{
+		{
+			name:          "within the limit",
+			body:          "12345678",
+			contentLength: 8,
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:          "content length over the limit",
+			body:          "123456789",
+			contentLength: 9,
+			wantStatus:    http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:          "chunked body over the limit",
+			body:          "123456789",
+			contentLength: -1,
+			wantStatus:    http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/articles", strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("BodyLimit() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		work           time.Duration
+		ignoreDeadline bool
+		wantStatus     int
+		wantCode       string
+	}{
+		{
+			name:       "handler done in time",
+			work:       0,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "handler past the deadline",
+			work:       time.Minute,
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "timeout",
+		},
+		{
+			name:           "handler ignoring the deadline",
+			work:           200 * time.Millisecond,
+			ignoreDeadline: true,
+			wantStatus:     http.StatusServiceUnavailable,
+			wantCode:       "timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Waits for the work like a database query bound to the context.
+			h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.ignoreDeadline {
+					time.Sleep(tt.work)
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+
+				select {
+				case <-time.After(tt.work):
+					w.WriteHeader(http.StatusOK)
+				case <-r.Context().Done():
+					handler.HandleError(r.Context(), w, fmt.Errorf("error querying: %v", r.Context().Err()), http.StatusInternalServerError, false)
+				}
+			}))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Timeout() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantCode == "" {
+				return
+			}
+
+			var problem handler.Problem
+			err := json.NewDecoder(w.Body).Decode(&problem)
+			if err != nil {
+				t.Fatalf("error decoding problem: %v", err)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("Timeout() problem code = %q, want %q", problem.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestTimeoutPanicStack(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	h := Recover(Timeout(time.Second)(http.HandlerFunc(panickingHandler)))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Timeout() status = %v, want %v", w.Code, http.StatusInternalServerError)
+	}
+
+	var entry struct {
+		Msg   string `json:"msg"`
+		Stack string `json:"stack"`
+	}
+	err := json.NewDecoder(&buf).Decode(&entry)
+	if err != nil {
+		t.Fatalf("error decoding log entry: %v", err)
+	}
+	if entry.Msg != "Handler panic: boom" {
+		t.Errorf("Timeout() logged message = %q, want %q", entry.Msg, "Handler panic: boom")
+	}
+	if !strings.Contains(entry.Stack, "panickingHandler") {
+		t.Errorf("Timeout() logged stack doesn't name the handler:\n%s", entry.Stack)
+	}
+}
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+}
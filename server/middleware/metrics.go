@@ -7,6 +7,7 @@ import (
 
 	chi "github.com/go-chi/chi/v5"
 	"github.com/goodleby/golang-app/metrics"
+	"github.com/goodleby/golang-app/model/tenant"
 )
 
 func Metrics(next http.Handler) http.Handler {
@@ -20,17 +21,7 @@ func Metrics(next http.Handler) http.Handler {
 
 		routeName := fmt.Sprintf("%s %s", r.Method, chi.RouteContext(r.Context()).RoutePattern())
 
-		metrics.RecordRequestHandled(crw.status, routeName)
+		metrics.RecordRequestHandled(crw.status, routeName, tenant.IDFromContext(r.Context()))
 		metrics.ObserveRequestDuration(duration)
 	})
 }
-
-type customResponseWriter struct {
-	http.ResponseWriter
-	status int
-}
-
-func (crw *customResponseWriter) WriteHeader(status int) {
-	crw.status = status
-	crw.ResponseWriter.WriteHeader(status)
-}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goodleby/golang-app/model/mode"
+	"github.com/goodleby/golang-app/server/handler"
+)
+
+type ModeReader interface {
+	CurrentMode() mode.State
+}
+
+// Available rejects the requests with 503 in maintenance mode, telling the
+// clients when to retry.
+func Available(modes ModeReader, retryAfter time.Duration) func(next http.Handler) http.Handler {
+	return requireMode(modes, retryAfter, mode.Mode.AllowsReads)
+}
+
+// Writable rejects the requests with 503 unless in normal mode, for the routes
+// that change anything.
+func Writable(modes ModeReader, retryAfter time.Duration) func(next http.Handler) http.Handler {
+	return requireMode(modes, retryAfter, mode.Mode.AllowsWrites)
+}
+
+func requireMode(modes ModeReader, retryAfter time.Duration, allows func(mode.Mode) bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current := modes.CurrentMode().Mode
+			if !allows(current) {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter.Seconds())))
+				handler.HandleError(r.Context(), w, &mode.ErrUnavailable{Mode: current}, http.StatusServiceUnavailable, false)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/model/mode"
+	"github.com/goodleby/golang-app/server/handler"
+)
+
+type fakeModes struct {
+	mode mode.Mode
+}
+
+func (f fakeModes) CurrentMode() mode.State {
+	return mode.State{Mode: f.mode}
+}
+
+func TestAvailableAndWritable(t *testing.T) {
+	tests := []struct {
+		name       string
+		middleware func(modes ModeReader, retryAfter time.Duration) func(next http.Handler) http.Handler
+		mode       mode.Mode
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "available in normal mode",
+			middleware: Available,
+			mode:       mode.Normal,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "available in read-only mode",
+			middleware: Available,
+			mode:       mode.ReadOnly,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unavailable in maintenance mode",
+			middleware: Available,
+			mode:       mode.Maintenance,
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "maintenance",
+		},
+		{
+			name:       "writable in normal mode",
+			middleware: Writable,
+			mode:       mode.Normal,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "not writable in read-only mode",
+			middleware: Writable,
+			mode:       mode.ReadOnly,
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "read_only",
+		},
+		{
+			name:       "not writable in maintenance mode",
+			middleware: Writable,
+			mode:       mode.Maintenance,
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "maintenance",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.middleware(fakeModes{mode: tt.mode}, 90*time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/articles", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantCode == "" {
+				return
+			}
+
+			if got := w.Header().Get("Retry-After"); got != "90" {
+				t.Errorf("Retry-After = %q, want %q", got, "90")
+			}
+
+			var problem handler.Problem
+			err := json.NewDecoder(w.Body).Decode(&problem)
+			if err != nil {
+				t.Fatalf("error decoding problem: %v", err)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("problem code = %q, want %q", problem.Code, tt.wantCode)
+			}
+		})
+	}
+}
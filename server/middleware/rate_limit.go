@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/ratelimit"
+	"github.com/goodleby/golang-app/server/handler"
+)
+
+type RateLimitStore interface {
+	TakeRateLimitToken(ctx context.Context, key string, limit ratelimit.Limit) (*ratelimit.Result, error)
+}
+
+// RateLimit limits the requests of a caller to the route group and, across
+// all groups, to the limit of the caller's role. Callers are identified by
+// auth.Claims.Caller if authenticated, otherwise by IP. It goes after Identify
+// and before Auth, so floods of missing or invalid credentials are limited too.
+func RateLimit(store RateLimitStore, limits ratelimit.Limits, group string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			caller := "ip:" + clientIP(r)
+			role := anonymousActor
+			if claims := auth.ClaimsFromContext(ctx); claims != nil {
+				caller = "caller:" + claims.Caller()
+				role = claims.RoleName
+			}
+
+			var buckets []rateLimitBucket
+			if limit, ok := limits.Groups[group]; ok {
+				buckets = append(buckets, rateLimitBucket{key: "group:" + group + ":" + caller, limit: limit})
+			}
+			if limit, ok := limits.Roles[role]; ok {
+				buckets = append(buckets, rateLimitBucket{key: "role:" + role + ":" + caller, limit: limit})
+			}
+
+			var tightest *ratelimit.Result
+			for _, bucket := range buckets {
+				result, err := store.TakeRateLimitToken(ctx, bucket.key, bucket.limit)
+				if err != nil {
+					// Failing open, an unavailable store shouldn't take the API down.
+					slog.Error(fmt.Sprintf("Error taking rate limit token: %v", err), "group", group)
+					continue
+				}
+
+				if tightest == nil || !result.Allowed || (tightest.Allowed && result.Remaining < tightest.Remaining) {
+					tightest = result
+				}
+
+				if !result.Allowed {
+					break
+				}
+			}
+
+			if tightest != nil {
+				setRateLimitHeaders(w, tightest)
+
+				if !tightest.Allowed {
+					w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(tightest.RetryAfter.Seconds())))
+					handler.HandleError(ctx, w, errors.New("rate limit exceeded"), http.StatusTooManyRequests, false)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type rateLimitBucket struct {
+	key   string
+	limit ratelimit.Limit
+}
+
+// setRateLimitHeaders sets the headers of the IETF RateLimit header fields
+// draft.
+func setRateLimitHeaders(w http.ResponseWriter, result *ratelimit.Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit.Requests))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset.Seconds())))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit.Requests, ceilSeconds(result.Limit.Per.Seconds())))
+}
+
+func ceilSeconds(seconds float64) int {
+	return int(math.Ceil(seconds))
+}
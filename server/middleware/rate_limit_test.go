@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	limits := ratelimit.Limits{
+		Groups: map[string]ratelimit.Limit{"articles": {Requests: 3, Per: time.Minute}},
+		Roles:  map[string]ratelimit.Limit{auth.ViewerRole: {Requests: 1, Per: time.Minute}},
+	}
+
+	tests := []struct {
+		name            string
+		claims          *auth.Claims
+		forgeIP         bool
+		wantStatuses    []int
+		wantRetryAfter  string
+		wantLimitHeader string
+	}{
+		{
+			name:            "anonymous callers get the group limit",
+			wantStatuses:    []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+			wantRetryAfter:  "20",
+			wantLimitHeader: "3",
+		},
+		{
+			name:            "anonymous callers can't forge their IP",
+			forgeIP:         true,
+			wantStatuses:    []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+			wantRetryAfter:  "20",
+			wantLimitHeader: "3",
+		},
+		{
+			name:            "role limit applies when tighter",
+			claims:          &auth.Claims{RoleName: auth.ViewerRole},
+			wantStatuses:    []int{http.StatusOK, http.StatusTooManyRequests},
+			wantRetryAfter:  "60",
+			wantLimitHeader: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := ClientIP(nil)(RateLimit(ratelimit.NewMemoryStore(), limits, "articles")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})))
+
+			var w *httptest.ResponseRecorder
+			for i, wantStatus := range tt.wantStatuses {
+				req := httptest.NewRequest(http.MethodGet, "/articles", nil)
+				if tt.forgeIP {
+					req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+				}
+				if tt.claims != nil {
+					tt.claims.Subject = "user-1"
+					req = req.WithContext(auth.NewContext(req.Context(), tt.claims))
+				}
+
+				w = httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+
+				if w.Code != wantStatus {
+					t.Fatalf("RateLimit() request %d status = %v, want %v", i, w.Code, wantStatus)
+				}
+			}
+
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("RateLimit() Retry-After = %v, want %v", got, tt.wantRetryAfter)
+			}
+			if got := w.Header().Get("RateLimit-Limit"); got != tt.wantLimitHeader {
+				t.Errorf("RateLimit() RateLimit-Limit = %v, want %v", got, tt.wantLimitHeader)
+			}
+		})
+	}
+}
+
+func TestRateLimit_roleKeySessions(t *testing.T) {
+	limits := ratelimit.Limits{
+		Roles: map[string]ratelimit.Limit{auth.ViewerRole: {Requests: 1, Per: time.Minute}},
+	}
+
+	h := RateLimit(ratelimit.NewMemoryStore(), limits, "articles")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Role key logins share the role as subject, each session has its bucket.
+	for _, sessionID := range []string{"session-1", "session-2"} {
+		claims := &auth.Claims{RoleName: auth.ViewerRole}
+		claims.ID = sessionID
+		claims.Subject = auth.ViewerRole
+
+		req := httptest.NewRequest(http.MethodGet, "/articles", nil)
+		req = req.WithContext(auth.NewContext(req.Context(), claims))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("RateLimit() %s status = %v, want %v", sessionID, w.Code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/metrics"
+	"github.com/goodleby/golang-app/requestid"
+	"github.com/goodleby/golang-app/server/handler"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+// Recover responds with 500 to requests whose handler panicked, instead of
+// letting net/http drop the connection. The panic is recorded in the span,
+// logged with its stack and counted. http.ErrAbortHandler is panicked again,
+// handlers use it to abort the response on purpose.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		crw := customResponseWriter{ResponseWriter: w}
+
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+
+			stack := debug.Stack()
+			if p, ok := value.(*stackedPanic); ok {
+				value, stack = p.value, p.stack
+			}
+
+			if err, ok := value.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(value)
+			}
+
+			ctx := r.Context()
+			span := tracing.SpanFromContext(ctx)
+			span.RecordPanic(value, stack)
+
+			routeName := fmt.Sprintf("%s %s", r.Method, chi.RouteContext(ctx).RoutePattern())
+			metrics.RecordPanicRecovered("http", routeName)
+
+			slog.Error(fmt.Sprintf("Handler panic: %v", value),
+				"route", routeName,
+				"stack", string(stack),
+				"request_id", requestid.FromContext(ctx),
+				"trace_id", span.TraceID(),
+			)
+
+			// A started response can't be turned into an error anymore.
+			if crw.status != 0 {
+				return
+			}
+
+			handler.HandleError(ctx, &crw, fmt.Errorf("handler panic: %v", value), http.StatusInternalServerError, false)
+		}()
+
+		next.ServeHTTP(&crw, r)
+	})
+}
+
+// stackedPanic carries a panic recovered in another goroutine, with the stack
+// of that goroutine, for Recover to log where the handler panicked.
+type stackedPanic struct {
+	value any
+	stack []byte
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/goodleby/golang-app/requestid"
+)
+
+// RequestID passes the request ID from the X-Request-ID header down in the
+// request context and echoes it in the response. A new ID is generated if the
+// caller didn't send one or sent one that isn't safe to log.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestid.Header)
+		if !validRequestID.MatchString(requestID) {
+			requestID = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, requestID)
+
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), requestID)))
+	})
+}
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
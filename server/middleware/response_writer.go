@@ -0,0 +1,32 @@
+package middleware
+
+import "net/http"
+
+// customResponseWriter records the status and size of the response.
+type customResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (crw *customResponseWriter) WriteHeader(status int) {
+	crw.status = status
+	crw.ResponseWriter.WriteHeader(status)
+}
+
+func (crw *customResponseWriter) Write(b []byte) (int, error) {
+	if crw.status == 0 {
+		crw.status = http.StatusOK
+	}
+
+	n, err := crw.ResponseWriter.Write(b)
+	crw.bytes += n
+
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the optional interfaces of the
+// underlying writer, e.g. http.Flusher.
+func (crw *customResponseWriter) Unwrap() http.ResponseWriter {
+	return crw.ResponseWriter
+}
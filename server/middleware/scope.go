@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/server/handler"
+)
+
+// Scope checks that the caller authenticated by Auth has the given scope.
+func Scope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			claims := auth.ClaimsFromContext(ctx)
+			if claims == nil || !claims.HasPermission(scope) {
+				handler.HandleError(ctx, w, fmt.Errorf("missing %q scope", scope), http.StatusForbidden, false)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
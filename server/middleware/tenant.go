@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/model/tenant"
+	"github.com/goodleby/golang-app/server/handler"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+type TenantResolver interface {
+	Resolve(header, host, claim string) (*tenant.Tenant, error)
+}
+
+// Tenant scopes the request to the tenant named by the header, else to the one
+// served at the host, else to the one claimed by the auth token, see
+// tenancy.Registry.Resolve. An empty header doesn't name tenants. It goes
+// before Metrics, for requests to be counted per tenant.
+func Tenant(resolver TenantResolver, header string) func(next http.Handler) http.Handler {
+	return tenantScope(resolver, header, true)
+}
+
+// PageTenant is Tenant ignoring the auth token, for the public pages. Their
+// responses are shared by caches, which would mix up the tenants of the token
+// cookie.
+func PageTenant(resolver TenantResolver, header string) func(next http.Handler) http.Handler {
+	return tenantScope(resolver, header, false)
+}
+
+func tenantScope(resolver TenantResolver, header string, byToken bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			var named string
+			if header != "" {
+				named = r.Header.Get(header)
+				// Responses depend on the header, shared caches must not mix them up.
+				w.Header().Add("Vary", header)
+			}
+
+			var claimed string
+			if byToken {
+				token, _ := handler.ReadAuthToken(r)
+				claimed = auth.TokenTenant(token)
+			}
+
+			t, err := resolver.Resolve(named, r.Host, claimed)
+			if err != nil {
+				switch err.(type) {
+				case *tenant.ErrUnresolved:
+					handler.HandleError(ctx, w, err, http.StatusBadRequest, false)
+				case *tenant.ErrSuspended:
+					handler.HandleError(ctx, w, err, http.StatusForbidden, false)
+				default:
+					handler.HandleError(ctx, w, err, http.StatusNotFound, false)
+				}
+				return
+			}
+
+			span := tracing.SpanFromContext(ctx)
+			span.SetTag("tenant", t.ID)
+
+			next.ServeHTTP(w, r.WithContext(tenant.NewContext(ctx, t.ID)))
+		})
+	}
+}
+
+// PlatformTenant hides the routes administering the whole deployment, e.g.
+// API keys and webhooks, from the callers of tenants other than the default
+// one.
+func PlatformTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if tenant.IDFromContext(ctx) != tenant.DefaultID {
+			handler.HandleError(ctx, w, errors.New("error route is only served to the default tenant"), http.StatusNotFound, false)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/model/tenant"
+)
+
+// fakeTenants serves the default tenant, acme at acme.example.com and the
+// suspended globex, resolving like tenancy.Registry.
+type fakeTenants struct {
+	fallback string
+}
+
+func (f fakeTenants) Resolve(header, host, claim string) (*tenant.Tenant, error) {
+	id := header
+	if id == "" && host == "acme.example.com" {
+		id = "acme"
+	}
+	if id == "" {
+		id = claim
+	}
+	if id == "" {
+		id = f.fallback
+	}
+
+	switch id {
+	case "":
+		return nil, &tenant.ErrUnresolved{}
+	case tenant.DefaultID, "acme":
+		return &tenant.Tenant{ID: id, Status: tenant.StatusActive}, nil
+	case "globex":
+		return nil, &tenant.ErrSuspended{ID: id}
+	default:
+		return nil, &tenant.ErrUnknown{ID: id}
+	}
+}
+
+func TestTenant(t *testing.T) {
+	tests := []struct {
+		name       string
+		fallback   string
+		header     string
+		host       string
+		wantStatus int
+		wantTenant string
+	}{
+		{
+			name:       "fallback",
+			fallback:   tenant.DefaultID,
+			host:       "example.com",
+			wantStatus: http.StatusOK,
+			wantTenant: tenant.DefaultID,
+		},
+		{
+			name:       "host",
+			fallback:   tenant.DefaultID,
+			host:       "acme.example.com",
+			wantStatus: http.StatusOK,
+			wantTenant: "acme",
+		},
+		{
+			name:       "header",
+			host:       "example.com",
+			header:     "acme",
+			wantStatus: http.StatusOK,
+			wantTenant: "acme",
+		},
+		{
+			name:       "unresolved",
+			host:       "example.com",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "suspended",
+			host:       "example.com",
+			header:     "globex",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "unknown",
+			host:       "example.com",
+			header:     "initech",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTenant string
+			h := Tenant(fakeTenants{fallback: tt.fallback}, "X-Tenant-ID")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotTenant = tenant.IDFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/articles", nil)
+			r.Host = tt.host
+			if tt.header != "" {
+				r.Header.Set("X-Tenant-ID", tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if gotTenant != tt.wantTenant {
+				t.Errorf("tenant = %q, want %q", gotTenant, tt.wantTenant)
+			}
+			if got := w.Header().Get("Vary"); got != "X-Tenant-ID" {
+				t.Errorf("Vary = %q, want %q", got, "X-Tenant-ID")
+			}
+		})
+	}
+}
+
+func TestPageTenant(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{Tenant: "acme"}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		middleware func(resolver TenantResolver, header string) func(next http.Handler) http.Handler
+		wantTenant string
+	}{
+		{
+			name:       "api routes",
+			middleware: Tenant,
+			wantTenant: "acme",
+		},
+		{
+			name:       "pages",
+			middleware: PageTenant,
+			wantTenant: tenant.DefaultID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTenant string
+			h := tt.middleware(fakeTenants{fallback: tenant.DefaultID}, "X-Tenant-ID")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotTenant = tenant.IDFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Host = "example.com"
+			r.AddCookie(&http.Cookie{Name: "token", Value: token})
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if gotTenant != tt.wantTenant {
+				t.Errorf("tenant = %q, want %q", gotTenant, tt.wantTenant)
+			}
+		})
+	}
+}
+
+func TestPlatformTenant(t *testing.T) {
+	tests := []struct {
+		name       string
+		tenantID   string
+		wantStatus int
+	}{
+		{
+			name:       "default tenant",
+			tenantID:   tenant.DefaultID,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "other tenant",
+			tenantID:   "acme",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := PlatformTenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil)
+			r = r.WithContext(tenant.NewContext(r.Context(), tt.tenantID))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
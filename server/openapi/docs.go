@@ -0,0 +1,9 @@
+package openapi
+
+import _ "embed"
+
+// DocsHTML is a self-contained page that renders the document served next to
+// it as openapi.json.
+//
+//go:embed docs.html
+var DocsHTML []byte
@@ -0,0 +1,230 @@
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Route describes a single API operation for the generated document.
+type Route struct {
+	Method string
+	// Pattern is the chi route pattern relative to the server URL.
+	Pattern     string
+	OperationID string
+	Summary     string
+	Tag         string
+	// Auth marks routes that need the auth token, as a cookie or bearer token,
+	// or an API key.
+	Auth bool
+	// Params are query parameters and path parameters that aren't strings.
+	// Other path parameters are added from the pattern.
+	Params []Parameter
+	// Request is a value of the request body type, nil if there is no body.
+	Request            any
+	RequestContentType string
+	Responses          []Reply
+	// Errors are the error statuses the route responds with, besides the
+	// ones implied by Auth and Request, and the server errors.
+	Errors []int
+}
+
+// Reply is a response of a route. Body is a value of the response body type,
+// nil if there is no body.
+type Reply struct {
+	Status      int
+	Description string
+	ContentType string
+	// Alternatives are other media types of the body the client can ask for
+	// with the Accept header.
+	Alternatives []string
+	Body         any
+}
+
+// JSON is a reply with a JSON body of the type of body.
+func JSON(status int, body any) Reply {
+	return Reply{Status: status, ContentType: "application/json", Body: body}
+}
+
+// Negotiated is a reply with a body of the type of body in each of the
+// content types, the first one being the default.
+func Negotiated(status int, body any, contentTypes []string) Reply {
+	return Reply{Status: status, ContentType: contentTypes[0], Alternatives: contentTypes[1:], Body: body}
+}
+
+// Empty is a reply without a body.
+func Empty(status int, description string) Reply {
+	return Reply{Status: status, Description: description}
+}
+
+// QueryParam is an optional query parameter of the given JSON schema type.
+func QueryParam(name, schemaType, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: schemaType}}
+}
+
+// HeaderParam is an optional header parameter of the given JSON schema type.
+func HeaderParam(name, schemaType, description string) Parameter {
+	return Parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: schemaType}}
+}
+
+// PathParam is a path parameter of the given JSON schema type.
+func PathParam(name, schemaType string) Parameter {
+	return Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: schemaType}}
+}
+
+type Generator struct {
+	Title     string
+	Version   string
+	ServerURL string
+	// ErrorBody is a value of the type of all error responses.
+	ErrorBody        any
+	ErrorContentType string
+}
+
+// Generate builds the OpenAPI 3.1 document for the routes. Named Go types are
+// added to the components and referenced by name.
+func (g *Generator) Generate(routes []Route) *Document {
+	var values []any
+	if g.ErrorBody != nil {
+		values = append(values, g.ErrorBody)
+	}
+	for _, route := range routes {
+		if route.Request != nil {
+			values = append(values, route.Request)
+		}
+		for _, reply := range route.Responses {
+			if reply.Body != nil {
+				values = append(values, reply.Body)
+			}
+		}
+	}
+
+	schemas := newSchemaBuilder(values)
+
+	doc := Document{
+		OpenAPI: Version,
+		Info:    Info{Title: g.Title, Version: g.Version},
+		Servers: []Server{{URL: g.ServerURL}},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: schemas.components,
+			SecuritySchemes: map[string]SecurityScheme{
+				cookieAuth: {Type: "apiKey", In: "cookie", Name: "token"},
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				apiKeyAuth: {Type: "apiKey", In: "header", Name: "X-API-Key"},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		item, ok := doc.Paths[route.Pattern]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[route.Pattern] = item
+		}
+
+		item[strings.ToLower(route.Method)] = g.operation(route, schemas)
+	}
+
+	return &doc
+}
+
+func (g *Generator) operation(route Route, schemas *schemaBuilder) *Operation {
+	op := Operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Parameters:  pathParams(route),
+		Responses:   map[string]Response{},
+	}
+
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+
+	for _, param := range route.Params {
+		if param.In != "path" {
+			op.Parameters = append(op.Parameters, param)
+		}
+	}
+
+	if route.Request != nil {
+		contentType := route.RequestContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{contentType: {Schema: schemas.schemaOf(route.Request)}},
+		}
+	}
+
+	for _, reply := range route.Responses {
+		response := Response{Description: reply.Description}
+		if response.Description == "" {
+			response.Description = http.StatusText(reply.Status)
+		}
+
+		if reply.Body != nil {
+			response.Content = map[string]MediaType{reply.ContentType: {Schema: schemas.schemaOf(reply.Body)}}
+			for _, contentType := range reply.Alternatives {
+				response.Content[contentType] = MediaType{Schema: schemas.schemaOf(reply.Body)}
+			}
+		}
+
+		op.Responses[strconv.Itoa(reply.Status)] = response
+	}
+
+	errors := slices.Clone(route.Errors)
+	if route.Auth {
+		op.Security = []map[string][]string{{cookieAuth: {}}, {bearerAuth: {}}, {apiKeyAuth: {}}}
+		errors = append(errors, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	if route.Request != nil {
+		errors = append(errors, http.StatusRequestEntityTooLarge)
+	}
+	// Handlers running out of time respond with 503.
+	errors = append(errors, http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	for _, status := range errors {
+		response := Response{Description: http.StatusText(status)}
+		if g.ErrorBody != nil {
+			response.Content = map[string]MediaType{g.ErrorContentType: {Schema: schemas.schemaOf(g.ErrorBody)}}
+		}
+
+		op.Responses[strconv.Itoa(status)] = response
+	}
+
+	return &op
+}
+
+// pathParams returns the parameters of the route pattern, using the declared
+// ones where present.
+func pathParams(route Route) []Parameter {
+	var params []Parameter
+	for _, match := range pathParamRegexp.FindAllStringSubmatch(route.Pattern, -1) {
+		name := match[1]
+
+		i := slices.IndexFunc(route.Params, func(p Parameter) bool {
+			return p.In == "path" && p.Name == name
+		})
+		if i >= 0 {
+			params = append(params, route.Params[i])
+			continue
+		}
+
+		params = append(params, PathParam(name, "string"))
+	}
+
+	return params
+}
+
+var pathParamRegexp = regexp.MustCompile(`\{(\w+)\}`)
+
+const Version = "3.1.0"
+
+const cookieAuth = "cookieAuth"
+const bearerAuth = "bearerAuth"
+const apiKeyAuth = "apiKeyAuth"
@@ -0,0 +1,209 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// schemaBuilder turns Go types into schemas following encoding/json rules.
+type schemaBuilder struct {
+	names      map[reflect.Type]string
+	components map[string]*Schema
+}
+
+// newSchemaBuilder names the struct types reachable from the values. Types are
+// named after the Go type and prefixed with the package name only when the
+// same name is used in several packages, e.g. article.Payload and
+// apikey.Payload.
+func newSchemaBuilder(values []any) *schemaBuilder {
+	b := schemaBuilder{
+		names:      map[reflect.Type]string{},
+		components: map[string]*Schema{},
+	}
+
+	seen := map[reflect.Type]bool{}
+	for _, v := range values {
+		collectStructTypes(reflect.TypeOf(v), seen)
+	}
+
+	count := map[string]int{}
+	for t := range seen {
+		count[t.Name()]++
+	}
+
+	for t := range seen {
+		name := exported(t.Name())
+		if count[t.Name()] > 1 {
+			name = exported(path.Base(t.PkgPath())) + name
+		}
+
+		b.names[t] = name
+	}
+
+	return &b
+}
+
+func (b *schemaBuilder) schemaOf(v any) *Schema {
+	return b.schema(reflect.TypeOf(v))
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) *Schema {
+	switch {
+	case t.Kind() == reflect.Pointer:
+		return nullable(b.schema(t.Elem()))
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		// Custom JSON encoding can't be inferred from the type.
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		name, ok := b.names[t]
+		if !ok {
+			return b.structSchema(t)
+		}
+
+		if _, ok := b.components[name]; !ok {
+			// Registered before the fields so recursive types end up as refs.
+			b.components[name] = &Schema{}
+			*b.components[name] = *b.structSchema(t)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	schema := Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for _, field := range structFields(t) {
+		schema.Properties[field.name] = b.schema(field.typ)
+		if !field.omitempty {
+			schema.Required = append(schema.Required, field.name)
+		}
+	}
+
+	return &schema
+}
+
+type structField struct {
+	name      string
+	typ       reflect.Type
+	omitempty bool
+}
+
+// structFields returns the JSON fields of the struct, flattening embedded
+// structs like encoding/json does.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				fields = append(fields, structFields(fieldType)...)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields = append(fields, structField{
+			name:      name,
+			typ:       field.Type,
+			omitempty: strings.Contains(options, "omitempty"),
+		})
+	}
+
+	return fields
+}
+
+// collectStructTypes finds the named struct types used by t, which become the
+// component schemas.
+func collectStructTypes(t reflect.Type, seen map[reflect.Type]bool) {
+	if t == nil || t == timeType || seen[t] {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		collectStructTypes(t.Elem(), seen)
+	case reflect.Struct:
+		if t.Name() != "" {
+			seen[t] = true
+		}
+
+		for _, field := range structFields(t) {
+			collectStructTypes(field.typ, seen)
+		}
+	}
+}
+
+func nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{OneOf: []*Schema{schema, {Type: "null"}}}
+	}
+
+	if typeName, ok := schema.Type.(string); ok {
+		schema.Type = []string{typeName, "null"}
+	}
+
+	return schema
+}
+
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+
+	return string(runes)
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+var marshalerType = reflect.TypeFor[json.Marshaler]()
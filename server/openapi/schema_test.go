@@ -0,0 +1,54 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEmbedded struct {
+	ID int `json:"id"`
+}
+
+type testItem struct {
+	testEmbedded
+	Name     string     `json:"name"`
+	Note     string     `json:"note,omitempty"`
+	Created  *time.Time `json:"created"`
+	Parent   *testItem  `json:"parent"`
+	internal string
+}
+
+func Test_schemaBuilder(t *testing.T) {
+	b := newSchemaBuilder([]any{[]testItem{}})
+
+	got := b.schemaOf([]testItem{})
+	want := &Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/TestItem"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("schemaBuilder.schemaOf() = %+v, want %+v", got, want)
+	}
+
+	item := b.components["TestItem"]
+	if item == nil {
+		t.Fatalf("schemaBuilder.components has no TestItem")
+	}
+
+	wantRequired := []string{"id", "name", "created", "parent"}
+	if !reflect.DeepEqual(item.Required, wantRequired) {
+		t.Errorf("TestItem required = %v, want %v", item.Required, wantRequired)
+	}
+
+	wantCreated := &Schema{Type: []string{"string", "null"}, Format: "date-time"}
+	if !reflect.DeepEqual(item.Properties["created"], wantCreated) {
+		t.Errorf("TestItem created = %+v, want %+v", item.Properties["created"], wantCreated)
+	}
+
+	wantParent := &Schema{OneOf: []*Schema{{Ref: "#/components/schemas/TestItem"}, {Type: "null"}}}
+	if !reflect.DeepEqual(item.Properties["parent"], wantParent) {
+		t.Errorf("TestItem parent = %+v, want %+v", item.Properties["parent"], wantParent)
+	}
+
+	if _, ok := item.Properties["internal"]; ok {
+		t.Errorf("TestItem has unexported field internal")
+	}
+}
@@ -0,0 +1,86 @@
+package render
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Negotiate picks the offer the client prefers according to an Accept style
+// header, e.g. Accept or Accept-Encoding. Ties go to the earlier offer. The
+// header ranges may use wildcards like */*, text/* or *. It reports false when
+// the client accepts none of the offers.
+func Negotiate(header string, offers []string) (string, bool) {
+	ranges := parseRanges(header)
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q := quality(ranges, offer)
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best, bestQ > 0
+}
+
+type acceptRange struct {
+	value string
+	q     float64
+}
+
+func parseRanges(header string) []acceptRange {
+	var ranges []acceptRange
+
+	for part := range strings.SplitSeq(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		q := 1.0
+		for param := range strings.SplitSeq(params, ";") {
+			key, raw, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.ToLower(key) != "q" {
+				continue
+			}
+
+			parsed, err := strconv.ParseFloat(raw, 64)
+			if err == nil && parsed >= 0 && parsed <= 1 {
+				q = parsed
+			}
+		}
+
+		ranges = append(ranges, acceptRange{value: value, q: q})
+	}
+
+	return ranges
+}
+
+// quality returns the q value of the most specific range matching the offer.
+func quality(ranges []acceptRange, offer string) float64 {
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := matches(r.value, offer)
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+
+	return q
+}
+
+// matches returns how specific the range is for the offer, -1 if it doesn't
+// match at all.
+func matches(value, offer string) int {
+	switch {
+	case value == offer:
+		return 2
+	case value == "*" || value == "*/*":
+		return 0
+	case strings.HasSuffix(value, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(value, "*")):
+		return 1
+	default:
+		return -1
+	}
+}
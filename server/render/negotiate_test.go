@@ -0,0 +1,72 @@
+package render
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "application/x-ndjson", "text/csv"}
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "exact match",
+			header: "text/csv",
+			want:   "text/csv",
+			wantOk: true,
+		},
+		{
+			name:   "highest q value wins",
+			header: "application/json;q=0.5, application/x-ndjson",
+			want:   "application/x-ndjson",
+			wantOk: true,
+		},
+		{
+			name:   "ties go to the earlier offer",
+			header: "text/csv, application/json",
+			want:   "application/json",
+			wantOk: true,
+		},
+		{
+			name:   "wildcard",
+			header: "text/html, */*;q=0.8",
+			want:   "application/json",
+			wantOk: true,
+		},
+		{
+			name:   "type wildcard",
+			header: "text/*",
+			want:   "text/csv",
+			wantOk: true,
+		},
+		{
+			name:   "specific range overrides wildcard",
+			header: "*/*, application/json;q=0",
+			want:   "application/x-ndjson",
+			wantOk: true,
+		},
+		{
+			name:   "nothing acceptable",
+			header: "text/html",
+			wantOk: false,
+		},
+		{
+			name:   "empty header",
+			header: "",
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Negotiate(tt.header, offers)
+			if ok != tt.wantOk {
+				t.Fatalf("Negotiate() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if got != tt.want {
+				t.Errorf("Negotiate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoder writes values in one representation.
+type Encoder interface {
+	ContentType() string
+	Encode(w io.Writer, v any) error
+}
+
+var (
+	JSON        Encoder = jsonEncoder{}
+	NDJSON      Encoder = ndjsonEncoder{}
+	CSV         Encoder = csvEncoder{}
+	MessagePack Encoder = msgpackEncoder{}
+)
+
+// Encoders are the negotiable representations, in the order of preference.
+var Encoders = []Encoder{JSON, NDJSON, CSV, MessagePack}
+
+var ErrNotAcceptable = errors.New("none of the accepted media types can be produced")
+
+// ContentTypes returns the media types of the encoders.
+func ContentTypes(encoders []Encoder) []string {
+	contentTypes := make([]string, len(encoders))
+	for i, encoder := range encoders {
+		contentTypes[i] = encoder.ContentType()
+	}
+
+	return contentTypes
+}
+
+// NewEncoder negotiates the encoder for the Accept header, JSON when the
+// header is empty.
+func NewEncoder(accept string) (Encoder, error) {
+	if strings.TrimSpace(accept) == "" {
+		return JSON, nil
+	}
+
+	contentType, ok := Negotiate(accept, ContentTypes(Encoders))
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotAcceptable, accept)
+	}
+
+	for _, encoder := range Encoders {
+		if encoder.ContentType() == contentType {
+			return encoder, nil
+		}
+	}
+
+	return JSON, nil
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string {
+	return "application/json"
+}
+
+func (jsonEncoder) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// ndjsonEncoder writes every element of a list on its own line, so clients
+// can process it as it streams in.
+type ndjsonEncoder struct{}
+
+func (ndjsonEncoder) ContentType() string {
+	return "application/x-ndjson"
+}
+
+func (ndjsonEncoder) Encode(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+
+	list := reflect.ValueOf(v)
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return encoder.Encode(v)
+	}
+
+	for i := range list.Len() {
+		err := encoder.Encode(list.Index(i).Interface())
+		if err != nil {
+			return fmt.Errorf("error encoding element %d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// csvEncoder writes structs as rows with a header of their JSON field names.
+// Nested values other than times are written as JSON.
+type csvEncoder struct{}
+
+func (csvEncoder) ContentType() string {
+	return "text/csv"
+}
+
+func (csvEncoder) Encode(w io.Writer, v any) error {
+	list := reflect.ValueOf(v)
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		list = reflect.ValueOf([]any{v})
+	}
+
+	writer := csv.NewWriter(w)
+
+	var header []string
+	for i := range list.Len() {
+		row := reflect.Indirect(reflect.ValueOf(list.Index(i).Interface()))
+		if row.Kind() != reflect.Struct {
+			return fmt.Errorf("error encoding element %d: can't encode %s as a csv row", i, row.Kind())
+		}
+
+		fields := csvFields(row)
+		if header == nil {
+			for _, field := range fields {
+				header = append(header, field.name)
+			}
+
+			err := writer.Write(header)
+			if err != nil {
+				return fmt.Errorf("error writing csv header: %v", err)
+			}
+		}
+
+		record := make([]string, len(fields))
+		for j, field := range fields {
+			value, err := csvValue(field.value)
+			if err != nil {
+				return fmt.Errorf("error encoding field %s of element %d: %v", field.name, i, err)
+			}
+			record[j] = value
+		}
+
+		err := writer.Write(record)
+		if err != nil {
+			return fmt.Errorf("error writing csv row: %v", err)
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+type csvField struct {
+	name  string
+	value reflect.Value
+}
+
+// csvFields returns the JSON fields of the struct, flattening embedded structs
+// like encoding/json does.
+func csvFields(v reflect.Value) []csvField {
+	var fields []csvField
+
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := reflect.Indirect(v.Field(i))
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, csvFields(embedded)...)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields = append(fields, csvField{name: name, value: v.Field(i)})
+	}
+
+	return fields
+}
+
+func csvValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return value.String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface()), nil
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// msgpackEncoder uses the JSON field names, so both representations have the
+// same shape.
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) ContentType() string {
+	return "application/msgpack"
+}
+
+func (msgpackEncoder) Encode(w io.Writer, v any) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+
+	return encoder.Encode(v)
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type payload struct {
+	Title string `json:"title"`
+	Body  string `json:"body,omitempty"`
+}
+
+type item struct {
+	payload
+	ID        int        `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	Tags      []string   `json:"tags"`
+	internal  string
+}
+
+var items = []item{
+	{payload: payload{Title: "First", Body: "Hello, world"}, ID: 1, CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Tags: []string{"go"}},
+	{payload: payload{Title: "Second", Body: "Line\nbreak"}, ID: 2, CreatedAt: time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)},
+}
+
+func TestEncoders(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoder Encoder
+		v       any
+		want    string
+	}{
+		{
+			name:    "json list",
+			encoder: JSON,
+			v:       items[:1],
+			want:    `[{"title":"First","body":"Hello, world","id":1,"created_at":"2025-01-02T03:04:05Z","deleted_at":null,"tags":["go"]}]` + "\n",
+		},
+		{
+			name:    "ndjson list",
+			encoder: NDJSON,
+			v:       items,
+			want: `{"title":"First","body":"Hello, world","id":1,"created_at":"2025-01-02T03:04:05Z","deleted_at":null,"tags":["go"]}` + "\n" +
+				`{"title":"Second","body":"Line\nbreak","id":2,"created_at":"2025-01-03T00:00:00Z","deleted_at":null,"tags":null}` + "\n",
+		},
+		{
+			name:    "csv list",
+			encoder: CSV,
+			v:       items,
+			want: "title,body,id,created_at,deleted_at,tags\n" +
+				"First,\"Hello, world\",1,2025-01-02T03:04:05Z,,\"[\"\"go\"\"]\"\n" +
+				"Second,\"Line\nbreak\",2,2025-01-03T00:00:00Z,,null\n",
+		},
+		{
+			name:    "csv single item",
+			encoder: CSV,
+			v:       &items[0].payload,
+			want:    "title,body\nFirst,\"Hello, world\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			err := tt.encoder.Encode(&buf, tt.v)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessagePackUsesJSONNames(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := MessagePack.Encode(&buf, items[0])
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	var got map[string]any
+	err = msgpack.Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("error decoding message pack: %v", err)
+	}
+
+	if got["title"] != "First" || got["body"] != "Hello, world" {
+		t.Errorf("Encode() = %v, want the embedded fields under their json names", got)
+	}
+}
+
+func TestNewEncoder(t *testing.T) {
+	tests := []struct {
+		accept  string
+		want    Encoder
+		wantErr bool
+	}{
+		{accept: "", want: JSON},
+		{accept: "*/*", want: JSON},
+		{accept: "application/x-ndjson", want: NDJSON},
+		{accept: "application/msgpack", want: MessagePack},
+		{accept: "text/html", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			got, err := NewEncoder(tt.accept)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewEncoder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NewEncoder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,51 +6,213 @@ import (
 	chi "github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/model/apikey"
 	"github.com/goodleby/golang-app/server/handler"
 	"github.com/goodleby/golang-app/server/middleware"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func (s *Server) setupRoutes(allowedOrigins []string) {
+func (s *Server) setupRoutes() {
+	s.Router.Use(middleware.RequestID, middleware.ClientIP(s.Config.TrustedProxies), middleware.Trace, middleware.AccessLog(s.Config.AccessLog), middleware.Recover)
+
 	s.Router.Get("/_healthz", handler.Health)
-	s.Router.Handle("/metrics", promhttp.Handler())
+	s.Router.Get("/_livez", handler.Livez)
+	s.Router.Get("/_readyz", handler.Readyz(s.Clients.Health))
+
+	// Server-rendered pages
+	s.Router.Group(func(r chi.Router) {
+		r.Use(s.pageTenant(), middleware.Metrics, s.available(), s.limits("web"), s.rateLimit("web"), s.flagged("web"))
+
+		r.Get("/", s.web.Index)
+		r.Get("/articles/{id}", s.web.Article)
+		r.Get("/tags/{tag}", s.web.Tag)
+		r.Get("/sitemap.xml", s.web.Sitemap)
+		r.Get("/static/*", s.web.Static)
+	})
 
 	s.Router.Route(v1API, func(r chi.Router) {
-		r.Use(middleware.Trace, middleware.Metrics)
+		r.Use(s.tenant(), middleware.Metrics)
 
 		r.Use(cors.Handler(cors.Options{
-			AllowedOrigins:   allowedOrigins,
+			AllowedOrigins:   s.Config.AllowedOrigins,
 			AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
 			AllowCredentials: true,
 		}))
 
-		r.Get("/example", handler.GetExampleData(s.Clients.Example))
-		r.Post("/pubsub/articles", handler.AddArticlePubSub(s.Clients.PubSub))
+		r.Use(middleware.Compress(s.Config.CompressionMinSize))
+
+		r.Group(func(r chi.Router) {
+			r.Use(s.available(), s.limits("public"), s.flagged("public"))
+
+			r.Get("/openapi.json", handler.GetOpenAPI(apiDocument()))
+			r.Get("/docs", handler.GetDocs)
+
+			r.Get("/example", handler.GetExampleData(s.Clients.Example))
+		})
+
+		r.With(s.writable(), s.limits("pubsub"), s.rateLimit("pubsub"), s.flagged("pubsub"), s.idempotent(), middleware.Audit(s.Clients.DB, "articles.publish")).Post("/pubsub/articles", handler.AddArticlePubSub(s.Clients.PubSub))
 
 		// Auth routes
 		r.Group(func(r chi.Router) {
-			r.Post("/auth/login", handler.AuthLogin(s.Clients.Auth))
-			r.Post("/auth/refresh", handler.AuthRefresh(s.Clients.Auth))
-			r.Post("/auth/logout", handler.AuthLogout)
+			r.Use(s.available(), s.limits("auth"), middleware.Identify(s.Clients.Auth), s.rateLimit("auth"), s.flagged("auth"))
+
+			r.With(middleware.Audit(s.Clients.DB, "auth.login")).Post("/auth/login", handler.AuthLogin(s.Clients.Auth))
+			r.With(middleware.Audit(s.Clients.DB, "auth.refresh")).Post("/auth/refresh", handler.AuthRefresh(s.Clients.Auth))
+			r.With(middleware.Audit(s.Clients.DB, "auth.logout")).Post("/auth/logout", handler.AuthLogout)
+			r.Get("/auth/oidc/login", handler.AuthOIDCLogin(s.Clients.Auth))
+			r.With(middleware.Audit(s.Clients.DB, "auth.oidc_login")).Get("/auth/oidc/callback", handler.AuthOIDCCallback(s.Clients.Auth, s.Config.OIDCPostLoginURL))
+			r.With(middleware.Audit(s.Clients.DB, "auth.2fa_enroll")).Post("/auth/2fa/enroll", handler.AuthTOTPEnroll(s.Clients.Auth))
+			r.With(middleware.Audit(s.Clients.DB, "auth.2fa_verify")).Post("/auth/2fa/verify", handler.AuthTOTPVerify(s.Clients.Auth))
+			r.Get("/auth/me", handler.AuthMe(s.Clients.Auth))
+
+			r.Get("/feature-flags", handler.GetFeatureFlags(s.Clients.Flags))
+		})
+
+		// Token introspection for internal services
+		r.Group(func(r chi.Router) {
+			r.Use(s.available(), s.limits("auth"), middleware.Identify(s.Clients.Auth), s.rateLimit("auth"), middleware.Auth(s.Clients.Auth, 0), middleware.Scope(apikey.ScopeIntrospect), s.flagged("auth"))
+
+			r.Post("/auth/introspect", handler.AuthIntrospect(s.Clients.Auth))
 		})
 
 		// View articles
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.Auth(s.Clients.Auth, auth.ViewerAccess))
+			r.Use(s.available(), middleware.Identify(s.Clients.Auth), s.rateLimit("articles"), middleware.Auth(s.Clients.Auth, auth.ViewerAccess), s.flagged("articles"))
+
+			// The stream lasts as long as the auth token, past any request timeout.
+			r.Get("/articles/events", handler.StreamArticleEvents(s.Clients.ArticleFeed))
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.limits("articles"))
+
+				r.Get("/articles", handler.GetAllArticles(s.Clients.DB))
+				r.Get("/articles/{id}", handler.GetArticle(s.Clients.DB))
 
-			r.Get("/articles", handler.GetAllArticles(s.Clients.DB))
-			r.Get("/articles/{id}", handler.GetArticle(s.Clients.DB))
+				// Mutations check for editor access and the mode, and are audited by
+				// the resolvers.
+				r.Get("/graphql", s.graphQL.ServeHTTP)
+				r.Post("/graphql", s.graphQL.ServeHTTP)
+			})
 		})
 
 		// Edit articles
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.Auth(s.Clients.Auth, auth.EditorAccess))
+			r.Use(s.writable(), s.limits("articles"), middleware.Identify(s.Clients.Auth), s.rateLimit("articles"), middleware.Auth(s.Clients.Auth, auth.EditorAccess), s.flagged("articles"))
 
-			r.Post("/articles", handler.AddArticle(s.Clients.DB))
-			r.Delete("/articles/{id}", handler.DeleteArticle(s.Clients.DB))
-			r.Put("/articles/{id}", handler.UpdateArticle(s.Clients.DB))
+			r.With(s.idempotent(), middleware.Audit(s.Clients.DB, "articles.create")).Post("/articles", handler.AddArticle(s.Clients.DB))
+			r.With(middleware.Audit(s.Clients.DB, "articles.delete")).Delete("/articles/{id}", handler.DeleteArticle(s.Clients.DB))
+			r.With(middleware.Audit(s.Clients.DB, "articles.update")).Put("/articles/{id}", handler.UpdateArticle(s.Clients.DB))
+		})
+
+		// Admin routes, available in every mode to switch back. They administer
+		// the whole deployment, so only the default tenant is served.
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.PlatformTenant, middleware.Identify(s.Clients.Auth), s.rateLimit("admin"), middleware.Auth(s.Clients.Auth, auth.AdminAccess), s.flagged("admin"))
+
+			// The export lasts as long as it takes, past any request timeout.
+			r.Get("/admin/audit/export", handler.ExportAuditEvents(s.Clients.DB))
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.limits("admin"))
+
+				r.Get("/admin/api-keys", handler.GetAllAPIKeys(s.Clients.Auth))
+				r.With(middleware.Audit(s.Clients.DB, "api_keys.create")).Post("/admin/api-keys", handler.AddAPIKey(s.Clients.Auth))
+				r.With(middleware.Audit(s.Clients.DB, "api_keys.rotate")).Post("/admin/api-keys/{id}/rotate", handler.RotateAPIKey(s.Clients.Auth))
+				r.With(middleware.Audit(s.Clients.DB, "api_keys.revoke")).Delete("/admin/api-keys/{id}", handler.RevokeAPIKey(s.Clients.Auth))
+
+				r.Get("/admin/mode", handler.GetMode(s.Clients.Mode))
+				r.With(middleware.Audit(s.Clients.DB, "mode.update")).Put("/admin/mode", handler.SetMode(s.Clients.Mode))
+
+				r.Get("/admin/audit", handler.GetAuditEvents(s.Clients.DB))
+
+				r.Get("/admin/webhooks", handler.GetAllWebhooks(s.Clients.DB))
+				r.With(middleware.Audit(s.Clients.DB, "webhooks.create")).Post("/admin/webhooks", handler.AddWebhook(s.Clients.DB))
+				r.Get("/admin/webhooks/{id}", handler.GetWebhook(s.Clients.DB))
+				r.With(middleware.Audit(s.Clients.DB, "webhooks.update")).Put("/admin/webhooks/{id}", handler.UpdateWebhook(s.Clients.DB))
+				r.With(middleware.Audit(s.Clients.DB, "webhooks.delete")).Delete("/admin/webhooks/{id}", handler.DeleteWebhook(s.Clients.DB))
+				r.Get("/admin/webhooks/{id}/deliveries", handler.GetWebhookDeliveries(s.Clients.DB))
+				r.With(middleware.Audit(s.Clients.DB, "webhooks.redeliver")).Post("/admin/webhooks/{id}/deliveries/{deliveryID}/redeliver", handler.RedeliverWebhook(s.Clients.DB))
+
+				r.Get("/admin/tenants", handler.GetAllTenants(s.Clients.Tenants))
+				r.With(middleware.Audit(s.Clients.DB, "tenants.provision")).Post("/admin/tenants", handler.AddTenant(s.Clients.Tenants))
+				r.Get("/admin/tenants/{id}", handler.GetTenant(s.Clients.Tenants))
+				r.With(middleware.Audit(s.Clients.DB, "tenants.suspend")).Post("/admin/tenants/{id}/suspend", handler.SuspendTenant(s.Clients.Tenants))
+				r.With(middleware.Audit(s.Clients.DB, "tenants.resume")).Post("/admin/tenants/{id}/resume", handler.ResumeTenant(s.Clients.Tenants))
+			})
 		})
 	})
 }
 
+// tenant scopes the requests of the route group to their tenant, see
+// middleware.Tenant.
+func (s *Server) tenant() func(next http.Handler) http.Handler {
+	return middleware.Tenant(s.Clients.Tenants, s.Config.TenantHeader)
+}
+
+// pageTenant scopes the requests of the server-rendered pages to their tenant,
+// see middleware.PageTenant.
+func (s *Server) pageTenant() func(next http.Handler) http.Handler {
+	return middleware.PageTenant(s.Clients.Tenants, s.Config.TenantHeader)
+}
+
+// rateLimit limits the requests to the route group, see middleware.RateLimit.
+func (s *Server) rateLimit(group string) func(next http.Handler) http.Handler {
+	return middleware.RateLimit(s.Clients.RateLimit, s.Config.RateLimits, group)
+}
+
+// flagged hides the route group behind its feature flag, if it has one, see
+// middleware.FeatureFlag. It goes after Auth or Identify, for the flag to be
+// evaluated for the caller.
+func (s *Server) flagged(group string) func(next http.Handler) http.Handler {
+	key, ok := s.Config.FlaggedGroups[group]
+	if !ok {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return middleware.FeatureFlag(s.Clients.Flags, key)
+}
+
+// available rejects the requests of the route group in maintenance mode, see
+// middleware.Available.
+func (s *Server) available() func(next http.Handler) http.Handler {
+	return middleware.Available(s.Clients.Mode, s.Config.ModeRetryAfter)
+}
+
+// writable rejects the requests of the route group unless in normal mode, see
+// middleware.Writable.
+func (s *Server) writable() func(next http.Handler) http.Handler {
+	return middleware.Writable(s.Clients.Mode, s.Config.ModeRetryAfter)
+}
+
+// limits applies the request timeout and body size limit of the route group,
+// see RouteLimits.
+func (s *Server) limits(group string) func(next http.Handler) http.Handler {
+	timeout, ok := s.Config.RouteLimits.Timeouts[group]
+	if !ok {
+		timeout = s.Config.RouteLimits.Timeout
+	}
+
+	maxBodySize, ok := s.Config.RouteLimits.MaxBodySizes[group]
+	if !ok {
+		maxBodySize = s.Config.RouteLimits.MaxBodySize
+	}
+
+	return func(next http.Handler) http.Handler {
+		if maxBodySize > 0 {
+			next = middleware.BodyLimit(maxBodySize)(next)
+		}
+		if timeout > 0 {
+			next = middleware.Timeout(timeout)(next)
+		}
+
+		return next
+	}
+}
+
+// idempotent replays responses to retried requests, see middleware.Idempotency.
+func (s *Server) idempotent() func(next http.Handler) http.Handler {
+	return middleware.Idempotency(s.Clients.Idempotency, s.Config.IdempotencyTTL)
+}
+
 const v1API string = "/api/v1"
@@ -2,14 +2,19 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
 
 	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/clientip"
+	"github.com/goodleby/golang-app/ratelimit"
+	"github.com/goodleby/golang-app/server/graphql"
 	"github.com/goodleby/golang-app/server/handler"
 	"github.com/goodleby/golang-app/server/middleware"
+	"github.com/goodleby/golang-app/server/web"
 )
 
 type Server struct {
@@ -17,28 +22,130 @@ type Server struct {
 	Port    uint16
 	Router  chi.Router
 	HTTP    *http.Server
+	Config  Config
 	Clients Clients
+
+	graphQL http.Handler
+	web     *web.Site
+}
+
+type Config struct {
+	Host string
+	Port uint16
+	// TLS is nil to serve cleartext, see certs.ServerConfig.
+	TLS *tls.Config
+	// H2C serves cleartext HTTP/2 to the clients that know the server speaks
+	// it, e.g. a mesh sidecar terminating TLS.
+	H2C bool
+	// Timeouts and max header bytes of the listener, zero means no limit or
+	// the net/http default.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	RouteLimits       RouteLimits
+	AllowedOrigins    []string
+	// OIDCPostLoginURL is where users are redirected after the OIDC login.
+	OIDCPostLoginURL string
+	AccessLog        middleware.AccessLogConfig
+	// CompressionMinSize is the response size in bytes from which responses
+	// are compressed.
+	CompressionMinSize int
+	RateLimits         ratelimit.Limits
+	// IdempotencyTTL is how long responses are replayed to retries.
+	IdempotencyTTL time.Duration
+	GraphQL        graphql.Config
+	// ModeRetryAfter is when clients are told to retry the requests rejected
+	// because of the mode.
+	ModeRetryAfter time.Duration
+	Web            web.Config
+	// TrustedProxies are the proxies whose X-Forwarded-For entries are
+	// believed, see middleware.ClientIP.
+	TrustedProxies clientip.Proxies
+	// TenantHeader names the tenant of requests, see middleware.Tenant.
+	TenantHeader string
+	// FlaggedGroups are the route groups hidden behind the feature flag of
+	// the given key, see middleware.FeatureFlag.
+	FlaggedGroups map[string]string
+}
+
+// RouteLimits are the request timeout and body size limit of the route groups,
+// the groups that aren't listed get the defaults. Zero means no limit.
+type RouteLimits struct {
+	Timeout      time.Duration
+	MaxBodySize  int64
+	Timeouts     map[string]time.Duration
+	MaxBodySizes map[string]int64
 }
 
 type Clients struct {
-	DB      DBClient
-	Auth    AuthClient
-	PubSub  PubSubClient
-	Example ExampleClient
+	DB          DBClient
+	Auth        AuthClient
+	PubSub      PubSubClient
+	Example     ExampleClient
+	RateLimit   middleware.RateLimitStore
+	Idempotency middleware.IdempotencyStore
+	ArticleFeed handler.ArticleEventSubscriber
+	Health      handler.ReadinessChecker
+	Mode        ModeClient
+	Flags       FeatureFlagsClient
+	Tenants     TenantsClient
 }
 
 type DBClient interface {
 	handler.AllArticlesSelector
+	handler.ArticlesAfterSelector
+	handler.ArticleCounter
 	handler.ArticleSelector
 	handler.ArticleInserter
 	handler.ArticleUpdater
 	handler.ArticleDeleter
+	handler.AuditEventsSelector
+	handler.AuditEventsStreamer
+	handler.WebhookLister
+	handler.WebhookSelector
+	handler.WebhookInserter
+	handler.WebhookUpdater
+	handler.WebhookDeleter
+	handler.WebhookDeliveriesSelector
+	handler.WebhookRedeliverer
+	middleware.AuditRecorder
+	web.ArticleStore
 }
 
 type AuthClient interface {
 	handler.TokenCreator
 	handler.TokenRefresher
-	middleware.TokenAccessReader
+	handler.OIDCLoginStarter
+	handler.OIDCLoginCompleter
+	handler.TOTPEnroller
+	handler.TOTPVerifier
+	handler.SessionReader
+	handler.TokenIntrospector
+	handler.APIKeyLister
+	handler.APIKeyCreator
+	handler.APIKeyRotator
+	handler.APIKeyRevoker
+	middleware.TokenClaimsReader
+}
+
+type ModeClient interface {
+	middleware.ModeReader
+	handler.ModeSetter
+}
+
+type FeatureFlagsClient interface {
+	handler.FeatureFlagsEvaluator
+	middleware.FeatureFlagEvaluator
+}
+
+type TenantsClient interface {
+	middleware.TenantResolver
+	handler.TenantLister
+	handler.TenantGetter
+	handler.TenantProvisioner
+	handler.TenantStatusSetter
 }
 
 type PubSubClient interface {
@@ -49,28 +156,56 @@ type ExampleClient interface {
 	handler.ExampleDataFetcher
 }
 
-func New(ctx context.Context, host string, port uint16, allowedOrigins []string, clients Clients) (*Server, error) {
+func New(ctx context.Context, config Config, clients Clients) (*Server, error) {
 	var s Server
 
-	s.Host = host
-	s.Port = port
+	s.Host = config.Host
+	s.Port = config.Port
 	s.Router = chi.NewRouter()
 	s.HTTP = &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", s.Host, s.Port),
-		Handler:      s.Router,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+		Addr:              fmt.Sprintf("%s:%d", s.Host, s.Port),
+		Handler:           s.Router,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
 	}
+	s.HTTP.TLSConfig = config.TLS
+	s.HTTP.Protocols = new(http.Protocols)
+	s.HTTP.Protocols.SetHTTP1(true)
+	// HTTP/2 is only negotiated over TLS.
+	s.HTTP.Protocols.SetHTTP2(true)
+	s.HTTP.Protocols.SetUnencryptedHTTP2(config.H2C)
+	s.Config = config
 	s.Clients = clients
 
-	s.setupRoutes(allowedOrigins)
+	schema, err := graphql.NewSchema(clients.DB, clients.DB, clients.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("error creating graphql schema: %v", err)
+	}
+	s.graphQL = graphql.Handler(schema, config.GraphQL)
+
+	s.web, err = web.New(clients.DB, clients.Tenants, config.Web)
+	if err != nil {
+		return nil, fmt.Errorf("error creating web site: %v", err)
+	}
+
+	s.setupRoutes()
 
 	return &s, nil
 }
 
 func (s *Server) Start(ctx context.Context, errc chan<- error) {
-	slog.Info(fmt.Sprintf("Server is listening at %s:%d", s.Host, s.Port))
-	err := s.HTTP.ListenAndServe()
+	var err error
+	if s.HTTP.TLSConfig != nil {
+		slog.Info(fmt.Sprintf("Server is listening with tls at %s:%d", s.Host, s.Port))
+		// The certificate comes from the TLS config.
+		err = s.HTTP.ListenAndServeTLS("", "")
+	} else {
+		slog.Info(fmt.Sprintf("Server is listening at %s:%d", s.Host, s.Port))
+		err = s.HTTP.ListenAndServe()
+	}
 	if err != http.ErrServerClosed {
 		errc <- fmt.Errorf("error listening and serving: %v", err)
 	}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProtocols(t *testing.T) {
+	// Borrows the certificate of a test server and its client trusting it.
+	certServer := httptest.NewUnstartedServer(nil)
+	certServer.EnableHTTP2 = true
+	certServer.StartTLS()
+	defer certServer.Close()
+
+	tlsClient := certServer.Client()
+
+	h2cProtocols := new(http.Protocols)
+	h2cProtocols.SetUnencryptedHTTP2(true)
+	h2cClient := &http.Client{Transport: &http.Transport{Protocols: h2cProtocols}}
+
+	tests := []struct {
+		name       string
+		config     Config
+		scheme     string
+		client     *http.Client
+		wantProto  int
+		wantFailed bool
+	}{
+		{
+			name:      "http/1.1",
+			config:    Config{},
+			scheme:    "http",
+			client:    http.DefaultClient,
+			wantProto: 1,
+		},
+		{
+			name:      "http/2 over tls",
+			config:    Config{TLS: &tls.Config{Certificates: certServer.TLS.Certificates}},
+			scheme:    "https",
+			client:    tlsClient,
+			wantProto: 2,
+		},
+		{
+			name:      "h2c",
+			config:    Config{H2C: true},
+			scheme:    "http",
+			client:    h2cClient,
+			wantProto: 2,
+		},
+		{
+			name:       "h2c is off by default",
+			config:     Config{},
+			scheme:     "http",
+			client:     h2cClient,
+			wantFailed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(context.Background(), tt.config, Clients{})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("error listening: %v", err)
+			}
+
+			go func() {
+				if tt.config.TLS != nil {
+					s.HTTP.ServeTLS(listener, "", "")
+				} else {
+					s.HTTP.Serve(listener)
+				}
+			}()
+			defer s.HTTP.Close()
+
+			res, err := tt.client.Get(tt.scheme + "://" + listener.Addr().String() + "/_livez")
+			if tt.wantFailed {
+				if err == nil {
+					res.Body.Close()
+					t.Errorf("GET /_livez error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GET /_livez error = %v", err)
+			}
+			defer res.Body.Close()
+
+			if res.ProtoMajor != tt.wantProto {
+				t.Errorf("GET /_livez protocol = %s, want HTTP/%d", res.Proto, tt.wantProto)
+			}
+		})
+	}
+}
@@ -0,0 +1,517 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/goodleby/golang-app/client/example"
+	"github.com/goodleby/golang-app/featureflag"
+	"github.com/goodleby/golang-app/model/apikey"
+	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/model/audit"
+	"github.com/goodleby/golang-app/model/mode"
+	"github.com/goodleby/golang-app/model/session"
+	"github.com/goodleby/golang-app/model/tenant"
+	"github.com/goodleby/golang-app/model/totp"
+	"github.com/goodleby/golang-app/model/webhook"
+	"github.com/goodleby/golang-app/server/graphql"
+	"github.com/goodleby/golang-app/server/handler"
+	"github.com/goodleby/golang-app/server/middleware"
+	"github.com/goodleby/golang-app/server/openapi"
+	"github.com/goodleby/golang-app/server/render"
+)
+
+// apiSpec describes every route registered under v1API for the OpenAPI
+// document. A route without an entry here fails the server tests, as does a
+// route registered outside v1API that isn't a known page or probe.
+var apiSpec = []openapi.Route{
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/openapi.json",
+		OperationID: "getOpenAPI",
+		Summary:     "OpenAPI document of this API",
+		Tag:         "docs",
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, map[string]any{})},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/docs",
+		OperationID: "getDocs",
+		Summary:     "Interactive API docs",
+		Tag:         "docs",
+		Responses:   []openapi.Reply{{Status: http.StatusOK, ContentType: "text/html", Body: ""}},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/example",
+		OperationID: "getExampleData",
+		Summary:     "Fetch example data from the external API",
+		Tag:         "example",
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, example.ExampleData{})},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/pubsub/articles",
+		OperationID: "publishAddArticle",
+		Summary:     "Publish an add article event",
+		Tag:         "articles",
+		Params:      []openapi.Parameter{idempotencyKeyParam},
+		Request:     article.Payload{},
+		Responses:   []openapi.Reply{openapi.Empty(http.StatusAccepted, "Event published")},
+		Errors:      []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/auth/login",
+		OperationID: "login",
+		Summary:     "Log in with a role key",
+		Tag:         "auth",
+		Request:     handler.AuthLoginPayload{},
+		Responses:   []openapi.Reply{openapi.Empty(http.StatusNoContent, "Auth token cookie set")},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/auth/refresh",
+		OperationID: "refreshToken",
+		Summary:     "Extend the auth token",
+		Tag:         "auth",
+		Responses:   []openapi.Reply{openapi.Empty(http.StatusNoContent, "Auth token cookie set")},
+		Errors:      []int{http.StatusUnauthorized},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/auth/logout",
+		OperationID: "logout",
+		Summary:     "Expire the auth token cookie",
+		Tag:         "auth",
+		Responses:   []openapi.Reply{openapi.Empty(http.StatusNoContent, "Auth token cookie expired")},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/auth/oidc/login",
+		OperationID: "beginOIDCLogin",
+		Summary:     "Redirect to the identity provider",
+		Tag:         "auth",
+		Responses:   []openapi.Reply{openapi.Empty(http.StatusFound, "Redirect to the identity provider")},
+		Errors:      []int{http.StatusNotFound},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/auth/oidc/callback",
+		OperationID: "completeOIDCLogin",
+		Summary:     "Complete the login at the identity provider",
+		Tag:         "auth",
+		Params: []openapi.Parameter{
+			openapi.QueryParam("state", "string", "State returned by the identity provider"),
+			openapi.QueryParam("code", "string", "Authorization code"),
+			openapi.QueryParam("error", "string", "Error returned by the identity provider"),
+		},
+		Responses: []openapi.Reply{openapi.Empty(http.StatusFound, "Auth token cookie set, redirect to the app")},
+		Errors:    []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/auth/2fa/enroll",
+		OperationID: "enrollTOTP",
+		Summary:     "Start TOTP enrollment",
+		Tag:         "auth",
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, totp.Setup{})},
+		Errors:      []int{http.StatusUnauthorized, http.StatusConflict},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/auth/2fa/verify",
+		OperationID: "verifyTOTP",
+		Summary:     "Verify a TOTP or recovery code",
+		Tag:         "auth",
+		Request:     totp.VerifyPayload{},
+		Responses:   []openapi.Reply{openapi.Empty(http.StatusNoContent, "Auth token cookie with full access set")},
+		Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/auth/me",
+		OperationID: "getSession",
+		Summary:     "Session of the auth token cookie",
+		Tag:         "auth",
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, session.Session{})},
+		Errors:      []int{http.StatusUnauthorized},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/feature-flags",
+		OperationID: "getFeatureFlags",
+		Summary:     "Feature flags evaluated for the caller",
+		Tag:         "auth",
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, []featureflag.Evaluation{})},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/auth/introspect",
+		OperationID: "introspectToken",
+		Summary:     "Introspect a session token or API key (RFC 7662)",
+		Tag:         "auth",
+		Auth:        true,
+		Request: struct {
+			Token string `json:"token"`
+		}{},
+		RequestContentType: "application/x-www-form-urlencoded",
+		Responses:          []openapi.Reply{openapi.JSON(http.StatusOK, session.Introspection{})},
+		Errors:             []int{http.StatusBadRequest},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/articles",
+		OperationID: "getAllArticles",
+		Summary:     "List articles",
+		Tag:         "articles",
+		Auth:        true,
+		Responses:   []openapi.Reply{openapi.Negotiated(http.StatusOK, []article.Article{}, articleContentTypes)},
+		Errors:      []int{http.StatusNotAcceptable},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/articles/events",
+		OperationID: "streamArticleEvents",
+		Summary:     "Stream article changes as server-sent events",
+		Tag:         "articles",
+		Auth:        true,
+		Params: []openapi.Parameter{
+			openapi.HeaderParam("Last-Event-ID", "integer", "ID of the last event received, to resume the stream"),
+			openapi.QueryParam("last_event_id", "integer", "Same as Last-Event-ID, for clients that can't set headers"),
+		},
+		Responses: []openapi.Reply{{Status: http.StatusOK, ContentType: "text/event-stream", Body: article.Event{}}},
+		Errors:    []int{http.StatusBadRequest},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/articles/{id}",
+		OperationID: "getArticle",
+		Summary:     "Get an article",
+		Tag:         "articles",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "integer")},
+		Responses:   []openapi.Reply{openapi.Negotiated(http.StatusOK, article.Article{}, articleContentTypes)},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusNotAcceptable},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/articles",
+		OperationID: "addArticle",
+		Summary:     "Add an article",
+		Tag:         "articles",
+		Auth:        true,
+		Params:      []openapi.Parameter{idempotencyKeyParam},
+		Request:     article.Payload{},
+		Responses:   []openapi.Reply{openapi.Negotiated(http.StatusOK, article.Article{}, articleContentTypes)},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotAcceptable, http.StatusConflict, http.StatusUnprocessableEntity},
+	},
+	{
+		Method:      http.MethodDelete,
+		Pattern:     "/articles/{id}",
+		OperationID: "deleteArticle",
+		Summary:     "Delete an article",
+		Tag:         "articles",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "integer")},
+		Responses:   []openapi.Reply{openapi.Empty(http.StatusNoContent, "Article deleted")},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method:      http.MethodPut,
+		Pattern:     "/articles/{id}",
+		OperationID: "updateArticle",
+		Summary:     "Update an article",
+		Tag:         "articles",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "integer")},
+		Request:     article.Payload{},
+		Responses:   []openapi.Reply{openapi.Negotiated(http.StatusOK, article.Article{}, articleContentTypes)},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusNotAcceptable},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/graphql",
+		OperationID: "queryGraphQL",
+		Summary:     "Run a GraphQL query, e.g. a persisted one by its hash",
+		Tag:         "graphql",
+		Auth:        true,
+		Params: []openapi.Parameter{
+			openapi.QueryParam("query", "string", "GraphQL query"),
+			openapi.QueryParam("operationName", "string", "Operation to run if the query has several"),
+			openapi.QueryParam("variables", "string", "JSON object of variables"),
+			openapi.QueryParam("extensions", "string", "JSON object of extensions, e.g. persistedQuery with the sha256Hash of the query"),
+		},
+		Responses: []openapi.Reply{openapi.JSON(http.StatusOK, graphQLResult{})},
+		Errors:    []int{http.StatusBadRequest, http.StatusMethodNotAllowed},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/graphql",
+		OperationID: "postGraphQL",
+		Summary:     "Run a GraphQL query or mutation",
+		Tag:         "graphql",
+		Auth:        true,
+		Request:     graphql.Request{},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, graphQLResult{})},
+		Errors:      []int{http.StatusBadRequest},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/admin/api-keys",
+		OperationID: "getAllAPIKeys",
+		Summary:     "List API keys",
+		Tag:         "admin",
+		Auth:        true,
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, []apikey.APIKey{})},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/admin/api-keys",
+		OperationID: "addAPIKey",
+		Summary:     "Create an API key",
+		Tag:         "admin",
+		Auth:        true,
+		Request:     apikey.Payload{},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, handler.APIKeyWithSecret{})},
+		Errors:      []int{http.StatusBadRequest},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/admin/api-keys/{id}/rotate",
+		OperationID: "rotateAPIKey",
+		Summary:     "Replace the secret of an API key",
+		Tag:         "admin",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "integer")},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, handler.APIKeyWithSecret{})},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method:      http.MethodDelete,
+		Pattern:     "/admin/api-keys/{id}",
+		OperationID: "revokeAPIKey",
+		Summary:     "Revoke an API key",
+		Tag:         "admin",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "integer")},
+		Responses:   []openapi.Reply{openapi.Empty(http.StatusNoContent, "API key revoked")},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/admin/mode",
+		OperationID: "getMode",
+		Summary:     "Mode the app is in",
+		Tag:         "admin",
+		Auth:        true,
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, mode.State{})},
+	},
+	{
+		Method:      http.MethodPut,
+		Pattern:     "/admin/mode",
+		OperationID: "setMode",
+		Summary:     "Switch all replicas to normal, read-only or maintenance mode",
+		Tag:         "admin",
+		Auth:        true,
+		Request:     mode.Payload{},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, mode.State{})},
+		Errors:      []int{http.StatusBadRequest},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/admin/audit",
+		OperationID: "getAuditEvents",
+		Summary:     "Query the audit log, newest first",
+		Tag:         "admin",
+		Auth:        true,
+		Params:      auditFilterParams,
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, []audit.Event{})},
+		Errors:      []int{http.StatusBadRequest},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/admin/audit/export",
+		OperationID: "exportAuditEvents",
+		Summary:     "Export the audit log as newline delimited JSON",
+		Tag:         "admin",
+		Auth:        true,
+		Params:      auditFilterParams,
+		Responses:   []openapi.Reply{{Status: http.StatusOK, ContentType: "application/x-ndjson", Body: audit.Event{}}},
+		Errors:      []int{http.StatusBadRequest},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/admin/webhooks",
+		OperationID: "getAllWebhooks",
+		Summary:     "List webhooks",
+		Tag:         "admin",
+		Auth:        true,
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, []webhook.Webhook{})},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/admin/webhooks",
+		OperationID: "addWebhook",
+		Summary:     "Subscribe an endpoint to article events",
+		Tag:         "admin",
+		Auth:        true,
+		Request:     webhook.Payload{},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, webhook.Webhook{})},
+		Errors:      []int{http.StatusBadRequest},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/admin/webhooks/{id}",
+		OperationID: "getWebhook",
+		Summary:     "Get a webhook",
+		Tag:         "admin",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "integer")},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, webhook.Webhook{})},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method:      http.MethodPut,
+		Pattern:     "/admin/webhooks/{id}",
+		OperationID: "updateWebhook",
+		Summary:     "Update a webhook",
+		Tag:         "admin",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "integer")},
+		Request:     webhook.Payload{},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, webhook.Webhook{})},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method:      http.MethodDelete,
+		Pattern:     "/admin/webhooks/{id}",
+		OperationID: "deleteWebhook",
+		Summary:     "Delete a webhook and its deliveries",
+		Tag:         "admin",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "integer")},
+		Responses:   []openapi.Reply{openapi.Empty(http.StatusNoContent, "Webhook deleted")},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/admin/webhooks/{id}/deliveries",
+		OperationID: "getWebhookDeliveries",
+		Summary:     "Delivery log of a webhook, newest first",
+		Tag:         "admin",
+		Auth:        true,
+		Params: []openapi.Parameter{
+			openapi.PathParam("id", "integer"),
+			openapi.QueryParam("status", "string", "pending, succeeded or failed"),
+			openapi.QueryParam("before", "integer", "Only deliveries with a lower ID, for paging"),
+			openapi.QueryParam("limit", "integer", "Maximum number of deliveries"),
+		},
+		Responses: []openapi.Reply{openapi.JSON(http.StatusOK, []webhook.Delivery{})},
+		Errors:    []int{http.StatusBadRequest},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/admin/webhooks/{id}/deliveries/{deliveryID}/redeliver",
+		OperationID: "redeliverWebhook",
+		Summary:     "Send a delivery again",
+		Tag:         "admin",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "integer"), openapi.PathParam("deliveryID", "integer")},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusAccepted, webhook.Delivery{})},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/admin/tenants",
+		OperationID: "getAllTenants",
+		Summary:     "List tenants",
+		Tag:         "admin",
+		Auth:        true,
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, []tenant.Tenant{})},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/admin/tenants",
+		OperationID: "addTenant",
+		Summary:     "Provision a tenant, its role keys are only returned once",
+		Tag:         "admin",
+		Auth:        true,
+		Request:     tenant.Payload{},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, tenant.Provisioned{})},
+		Errors:      []int{http.StatusBadRequest, http.StatusConflict},
+	},
+	{
+		Method:      http.MethodGet,
+		Pattern:     "/admin/tenants/{id}",
+		OperationID: "getTenant",
+		Summary:     "Get a tenant",
+		Tag:         "admin",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "string")},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, tenant.Tenant{})},
+		Errors:      []int{http.StatusNotFound},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/admin/tenants/{id}/suspend",
+		OperationID: "suspendTenant",
+		Summary:     "Stop serving a tenant, its articles are kept",
+		Tag:         "admin",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "string")},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, tenant.Tenant{})},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method:      http.MethodPost,
+		Pattern:     "/admin/tenants/{id}/resume",
+		OperationID: "resumeTenant",
+		Summary:     "Serve a suspended tenant again",
+		Tag:         "admin",
+		Auth:        true,
+		Params:      []openapi.Parameter{openapi.PathParam("id", "string")},
+		Responses:   []openapi.Reply{openapi.JSON(http.StatusOK, tenant.Tenant{})},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+}
+
+// articleContentTypes are the representations of articles negotiated via the
+// Accept header.
+var articleContentTypes = render.ContentTypes(render.Encoders)
+
+// graphQLResult documents the GraphQL response, errors don't change the
+// status.
+type graphQLResult struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Path       []any          `json:"path,omitempty"`
+		Extensions map[string]any `json:"extensions,omitempty"`
+	} `json:"errors,omitempty"`
+}
+
+var idempotencyKeyParam = openapi.HeaderParam(middleware.IdempotencyKeyHeader, "string", "Unique key of the request, retries with the same key get the first response replayed")
+
+var auditFilterParams = []openapi.Parameter{
+	openapi.QueryParam("tenant", "string", "Tenant the action was performed in"),
+	openapi.QueryParam("actor", "string", "Subject that performed the action"),
+	openapi.QueryParam("action", "string", "Action, e.g. articles.update"),
+	openapi.QueryParam("target", "string", "Target, e.g. article:1"),
+	openapi.QueryParam("since", "string", "RFC 3339 time, inclusive"),
+	openapi.QueryParam("until", "string", "RFC 3339 time, exclusive"),
+	openapi.QueryParam("before", "integer", "Only events with a lower ID, for paging"),
+	openapi.QueryParam("limit", "integer", "Maximum number of events"),
+}
+
+func apiDocument() *openapi.Document {
+	generator := openapi.Generator{
+		Title:            "golang-app",
+		Version:          "v1",
+		ServerURL:        v1API,
+		ErrorBody:        handler.Problem{},
+		ErrorContentType: "application/problem+json",
+	}
+
+	return generator.Generate(apiSpec)
+}
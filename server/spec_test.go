@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	chi "github.com/go-chi/chi/v5"
+)
+
+// unversionedRoutes are the routes outside v1API, which aren't part of the
+// API and have no entry in apiSpec.
+var unversionedRoutes = map[string]bool{
+	"GET /_healthz":      true,
+	"GET /_livez":        true,
+	"GET /_readyz":       true,
+	"GET /":              true,
+	"GET /articles/{id}": true,
+	"GET /tags/{tag}":    true,
+	"GET /sitemap.xml":   true,
+	"GET /static/*":      true,
+}
+
+func TestAPISpec(t *testing.T) {
+	s, err := New(context.Background(), Config{}, Clients{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	specified := map[string]bool{}
+	for _, route := range apiSpec {
+		specified[route.Method+" "+route.Pattern] = true
+	}
+
+	registered := map[string]bool{}
+	registeredUnversioned := map[string]bool{}
+	err = chi.Walk(s.Router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		pattern, ok := strings.CutPrefix(route, v1API)
+		if !ok {
+			registeredUnversioned[method+" "+route] = true
+			if !unversionedRoutes[method+" "+route] {
+				t.Errorf("route %s %s is neither under %s nor a known unversioned route", method, route, v1API)
+			}
+			return nil
+		}
+
+		key := method + " " + pattern
+		registered[key] = true
+
+		if !specified[key] {
+			t.Errorf("route %s %s has no entry in apiSpec", method, route)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("chi.Walk() error = %v", err)
+	}
+
+	for key := range specified {
+		if !registered[key] {
+			t.Errorf("apiSpec entry %s has no registered route", key)
+		}
+	}
+
+	for key := range unversionedRoutes {
+		if !registeredUnversioned[key] {
+			t.Errorf("unversioned route %s isn't registered", key)
+		}
+	}
+
+	doc := apiDocument()
+	for _, route := range apiSpec {
+		op := doc.Paths[route.Pattern][strings.ToLower(route.Method)]
+		if op == nil {
+			t.Errorf("apiDocument() has no operation for %s %s", route.Method, route.Pattern)
+			continue
+		}
+
+		for _, param := range op.Parameters {
+			if param.In == "path" && !strings.Contains(route.Pattern, "{"+param.Name+"}") {
+				t.Errorf("apiDocument() %s %s has unknown path param %q", route.Method, route.Pattern, param.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/goodleby/golang-app/model/tenant"
+)
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+// Sitemap lists the index, article and tag pages for search engines.
+func (s *Site) Sitemap(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	articles, err := s.articles.SelectAllArticles(ctx)
+	if err != nil {
+		s.renderError(w, r, fmt.Errorf("error selecting articles: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := s.articles.SelectArticleTags(ctx)
+	if err != nil {
+		s.renderError(w, r, fmt.Errorf("error selecting article tags: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	baseURL, err := s.baseURL(ctx)
+	if err != nil {
+		s.renderError(w, r, fmt.Errorf("error getting base url: %w", err), http.StatusNotFound)
+		return
+	}
+
+	urlSet := sitemapURLSet{Xmlns: sitemapNamespace}
+	urlSet.URLs = append(urlSet.URLs, sitemapURL{Loc: baseURL + "/"})
+	for _, a := range articles {
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{Loc: baseURL + "/articles/" + strconv.Itoa(a.ID)})
+	}
+	for _, tag := range tags {
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{Loc: baseURL + "/tags/" + url.PathEscape(tag)})
+	}
+
+	body, err := xml.MarshalIndent(urlSet, "", "  ")
+	if err != nil {
+		s.renderError(w, r, fmt.Errorf("error encoding sitemap: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.respond(w, r, "application/xml; charset=utf-8", http.StatusOK, append([]byte(xml.Header), body...))
+}
+
+// baseURL returns the configured base URL for the default tenant, or else the
+// first host of the tenant. The host of the request is set by the client, it
+// could list pages at any host.
+func (s *Site) baseURL(ctx context.Context) (string, error) {
+	id := tenant.IDFromContext(ctx)
+	if id == "" {
+		id = tenant.DefaultID
+	}
+
+	if id == tenant.DefaultID && s.config.BaseURL != "" {
+		return s.config.BaseURL, nil
+	}
+
+	t, err := s.tenants.Tenant(id)
+	if err != nil {
+		return "", fmt.Errorf("error getting tenant: %w", err)
+	}
+
+	if len(t.Hosts) == 0 {
+		return "", fmt.Errorf("tenant %q has no host", id)
+	}
+
+	return "https://" + t.Hosts[0], nil
+}
+
+const sitemapNamespace string = "http://www.sitemaps.org/schemas/sitemap/0.9"
@@ -0,0 +1,312 @@
+// Package web serves the articles as server-rendered HTML pages, for clients
+// without JavaScript and for search engines. The pages are public and show
+// what the viewer role can see through the API, which is every article.
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/requestid"
+	"github.com/goodleby/golang-app/server/handler"
+	"github.com/goodleby/golang-app/tracing"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+//go:embed static
+var staticFS embed.FS
+
+type Config struct {
+	// BaseURL is the absolute URL the pages are served at, e.g.
+	// https://example.com, for the sitemap of the default tenant. The first
+	// host of the tenant is used if empty and for the other tenants.
+	BaseURL string
+	// PageSize is how many articles are listed per page.
+	PageSize int
+	// CacheMaxAge is how long shared caches and browsers may reuse the pages.
+	CacheMaxAge time.Duration
+}
+
+type ArticlesPageSelector interface {
+	SelectArticlesPage(ctx context.Context, tag string, offset, limit int) ([]article.Article, error)
+}
+
+type ArticleTagsSelector interface {
+	SelectArticleTags(ctx context.Context) ([]string, error)
+}
+
+type ArticleStore interface {
+	handler.AllArticlesSelector
+	handler.ArticleSelector
+	ArticlesPageSelector
+	ArticleTagsSelector
+}
+
+// Site renders the pages from the embedded templates.
+type Site struct {
+	articles  ArticleStore
+	tenants   handler.TenantGetter
+	config    Config
+	templates map[string]*template.Template
+	static    http.Handler
+}
+
+func New(articles ArticleStore, tenants handler.TenantGetter, config Config) (*Site, error) {
+	if config.PageSize <= 0 {
+		config.PageSize = defaultPageSize
+	}
+
+	s := &Site{
+		articles:  articles,
+		tenants:   tenants,
+		config:    config,
+		templates: map[string]*template.Template{},
+	}
+
+	for _, page := range []string{indexPage, articlePage, errorPage} {
+		tmpl, err := template.ParseFS(templateFS, "templates/layout.html", "templates/"+page)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s template: %v", page, err)
+		}
+		s.templates[page] = tmpl
+	}
+
+	static, err := fs.Sub(staticFS, "static")
+	if err != nil {
+		return nil, fmt.Errorf("error opening static assets: %v", err)
+	}
+	s.static = http.StripPrefix(staticPrefix, http.FileServerFS(static))
+
+	return s, nil
+}
+
+type listData struct {
+	Tag      string
+	Articles []article.Article
+	Page     int
+	PrevURL  string
+	NextURL  string
+}
+
+// Index lists the articles, newest first, a page at a time.
+func (s *Site) Index(w http.ResponseWriter, r *http.Request) {
+	s.list(w, r, "")
+}
+
+// Tag lists the articles with the tag like Index.
+func (s *Site) Tag(w http.ResponseWriter, r *http.Request) {
+	span := tracing.SpanFromContext(r.Context())
+
+	tag := chi.URLParam(r, "tag")
+	if !article.ValidTag(tag) {
+		s.renderError(w, r, fmt.Errorf("error invalid tag %q", tag), http.StatusNotFound)
+		return
+	}
+
+	span.SetTag("tag", tag)
+
+	s.list(w, r, tag)
+}
+
+func (s *Site) list(w http.ResponseWriter, r *http.Request, tag string) {
+	ctx := r.Context()
+
+	page := 1
+	if value := r.URL.Query().Get("page"); value != "" {
+		var err error
+		page, err = strconv.Atoi(value)
+		if err != nil || page < 1 {
+			s.renderError(w, r, fmt.Errorf("error invalid page %q", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// One more article tells if there is a next page.
+	articles, err := s.articles.SelectArticlesPage(ctx, tag, (page-1)*s.config.PageSize, s.config.PageSize+1)
+	if err != nil {
+		s.renderError(w, r, fmt.Errorf("error selecting articles page: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Only the first page may be empty.
+	if len(articles) == 0 && (page > 1 || tag != "") {
+		s.renderError(w, r, fmt.Errorf("error no articles on page %d", page), http.StatusNotFound)
+		return
+	}
+
+	data := listData{Tag: tag, Page: page}
+	if len(articles) > s.config.PageSize {
+		articles = articles[:s.config.PageSize]
+		data.NextURL = pageURL(r, page+1)
+	}
+	if page > 1 {
+		data.PrevURL = pageURL(r, page-1)
+	}
+	data.Articles = articles
+
+	s.render(w, r, indexPage, http.StatusOK, data)
+}
+
+// Article shows an article.
+func (s *Site) Article(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	span := tracing.SpanFromContext(ctx)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.renderError(w, r, fmt.Errorf("error converting id to int: %w", err), http.StatusNotFound)
+		return
+	}
+
+	span.SetTag("id", chi.URLParam(r, "id"))
+
+	a, err := s.articles.SelectArticle(ctx, id)
+	if err != nil {
+		switch err.(type) {
+		case *client.ErrNotFound:
+			s.renderError(w, r, fmt.Errorf("error selecting article: not found: %w", err), http.StatusNotFound)
+		default:
+			s.renderError(w, r, fmt.Errorf("error selecting article: %w", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.render(w, r, articlePage, http.StatusOK, a)
+}
+
+// Static serves the embedded stylesheet and other assets.
+func (s *Site) Static(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(staticMaxAge.Seconds())))
+	s.static.ServeHTTP(w, r)
+}
+
+// render executes the template of the page and responds with it, or with 304
+// if the client has the same page cached already.
+func (s *Site) render(w http.ResponseWriter, r *http.Request, page string, statusCode int, data any) {
+	var buf bytes.Buffer
+	err := s.templates[page].ExecuteTemplate(&buf, "layout", data)
+	if err != nil {
+		s.renderError(w, r, fmt.Errorf("error executing %s template: %w", page, err), http.StatusInternalServerError)
+		return
+	}
+
+	s.respond(w, r, "text/html; charset=utf-8", statusCode, buf.Bytes())
+}
+
+// respond writes the body with cache headers. Successful responses get an
+// ETag, so clients can revalidate them without downloading them again.
+func (s *Site) respond(w http.ResponseWriter, r *http.Request, contentType string, statusCode int, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+
+	if statusCode != http.StatusOK {
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(statusCode)
+		_, err := w.Write(body)
+		handleWritingErr(err)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.config.CacheMaxAge.Seconds())))
+	w.Header().Set("ETag", etag)
+
+	// The standard library only checks the preconditions of files.
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	_, err := w.Write(body)
+	handleWritingErr(err)
+}
+
+type errorData struct {
+	Status    int
+	Title     string
+	RequestID string
+}
+
+// renderError responds with the error page. Server errors are recorded in the
+// span and logged, client errors only in the span.
+func (s *Site) renderError(w http.ResponseWriter, r *http.Request, err error, statusCode int) {
+	ctx := r.Context()
+	span := tracing.SpanFromContext(ctx)
+
+	span.RecordError(err)
+
+	if statusCode >= http.StatusInternalServerError {
+		// Errors past the deadline are most likely caused by it.
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		slog.Error(fmt.Sprintf("Page error: %v", err),
+			"status", statusCode,
+			"request_id", requestid.FromContext(ctx),
+			"trace_id", span.TraceID(),
+		)
+	}
+
+	data := errorData{
+		Status:    statusCode,
+		Title:     http.StatusText(statusCode),
+		RequestID: requestid.FromContext(ctx),
+	}
+
+	var buf bytes.Buffer
+	err = s.templates[errorPage].ExecuteTemplate(&buf, "layout", data)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error executing %s template: %v", errorPage, err))
+		http.Error(w, data.Title, statusCode)
+		return
+	}
+
+	s.respond(w, r, "text/html; charset=utf-8", statusCode, buf.Bytes())
+}
+
+// pageURL returns the URL of the request for another page.
+func pageURL(r *http.Request, page int) string {
+	u := *r.URL
+	query := u.Query()
+	if page == 1 {
+		query.Del("page")
+	} else {
+		query.Set("page", strconv.Itoa(page))
+	}
+	u.RawQuery = query.Encode()
+
+	return u.RequestURI()
+}
+
+func handleWritingErr(err error) {
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error writing to http.ResponseWriter: %v", err))
+	}
+}
+
+const (
+	indexPage       string        = "index.html"
+	articlePage     string        = "article.html"
+	errorPage       string        = "error.html"
+	staticPrefix    string        = "/static/"
+	staticMaxAge    time.Duration = 24 * time.Hour
+	defaultPageSize int           = 10
+)
@@ -0,0 +1,328 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/model/article"
+	"github.com/goodleby/golang-app/model/tenant"
+)
+
+type fakeStore struct {
+	articles []article.Article
+}
+
+// newFakeStore has n articles, the even ones tagged "even".
+func newFakeStore(n int) *fakeStore {
+	s := &fakeStore{}
+	for id := 1; id <= n; id++ {
+		a := article.Article{ID: id, Payload: article.Payload{Title: fmt.Sprintf("Article %d", id), Description: "description", Body: "body"}}
+		if id%2 == 0 {
+			a.Tags = []string{"even"}
+		}
+		s.articles = append(s.articles, a)
+	}
+
+	return s
+}
+
+func (s *fakeStore) SelectAllArticles(ctx context.Context) ([]article.Article, error) {
+	return s.articles, nil
+}
+
+func (s *fakeStore) SelectArticle(ctx context.Context, id int) (*article.Article, error) {
+	for _, a := range s.articles {
+		if a.ID == id {
+			return &a, nil
+		}
+	}
+
+	return nil, &client.ErrNotFound{Err: fmt.Errorf("article %d not found", id)}
+}
+
+func (s *fakeStore) SelectArticlesPage(ctx context.Context, tag string, offset, limit int) ([]article.Article, error) {
+	var articles []article.Article
+	for _, a := range slices.Backward(s.articles) {
+		if tag == "" || slices.Contains(a.Tags, tag) {
+			articles = append(articles, a)
+		}
+	}
+
+	articles = articles[min(offset, len(articles)):]
+
+	return articles[:min(limit, len(articles))], nil
+}
+
+func (s *fakeStore) SelectArticleTags(ctx context.Context) ([]string, error) {
+	var tags []string
+	for _, a := range s.articles {
+		for _, tag := range a.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return tags, nil
+}
+
+// fakeTenants serves acme at acme.example.com and initech at no host.
+type fakeTenants struct{}
+
+func (fakeTenants) Tenant(id string) (*tenant.Tenant, error) {
+	switch id {
+	case tenant.DefaultID:
+		return &tenant.Tenant{ID: id}, nil
+	case "acme":
+		return &tenant.Tenant{ID: id, Hosts: []string{"acme.example.com", "www.acme.example.com"}}, nil
+	case "initech":
+		return &tenant.Tenant{ID: id}, nil
+	default:
+		return nil, &tenant.ErrUnknown{ID: id}
+	}
+}
+
+func newTestRouter(t *testing.T, store ArticleStore) chi.Router {
+	t.Helper()
+
+	s, err := New(store, fakeTenants{}, Config{BaseURL: "https://example.com", PageSize: 2, CacheMaxAge: time.Minute})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/", s.Index)
+	r.Get("/articles/{id}", s.Article)
+	r.Get("/tags/{tag}", s.Tag)
+	r.Get("/sitemap.xml", s.Sitemap)
+	r.Get("/static/*", s.Static)
+
+	return r
+}
+
+func TestSite(t *testing.T) {
+	store := newFakeStore(5)
+	store.articles = append(store.articles, article.Article{ID: 6, Payload: article.Payload{Title: "<script>alert(1)</script>", Description: "description", Body: "body"}})
+
+	r := newTestRouter(t, store)
+
+	tests := []struct {
+		name        string
+		target      string
+		wantStatus  int
+		wantContain []string
+		wantMissing []string
+	}{
+		{
+			name:        "first page",
+			target:      "/",
+			wantStatus:  http.StatusOK,
+			wantContain: []string{`href="/articles/5"`, `href="/?page=2"`},
+			wantMissing: []string{`href="/articles/4"`, `rel="prev"`},
+		},
+		{
+			name:        "middle page",
+			target:      "/?page=2",
+			wantStatus:  http.StatusOK,
+			wantContain: []string{`href="/articles/4"`, `href="/articles/3"`, `href="/"`, `href="/?page=3"`},
+		},
+		{
+			name:        "last page",
+			target:      "/?page=3",
+			wantStatus:  http.StatusOK,
+			wantContain: []string{`href="/articles/1"`, `href="/?page=2"`},
+			wantMissing: []string{`rel="next"`},
+		},
+		{
+			name:       "page past the end",
+			target:     "/?page=4",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid page",
+			target:     "/?page=first",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "article",
+			target:      "/articles/2",
+			wantStatus:  http.StatusOK,
+			wantContain: []string{"<h1>Article 2</h1>", `href="/tags/even"`},
+		},
+		{
+			name:        "article title is escaped",
+			target:      "/articles/6",
+			wantStatus:  http.StatusOK,
+			wantContain: []string{"&lt;script&gt;alert(1)&lt;/script&gt;"},
+			wantMissing: []string{"<script>"},
+		},
+		{
+			name:       "missing article",
+			target:     "/articles/7",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid article id",
+			target:     "/articles/first",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:        "tag",
+			target:      "/tags/even",
+			wantStatus:  http.StatusOK,
+			wantContain: []string{`href="/articles/4"`, `href="/articles/2"`},
+			wantMissing: []string{`href="/articles/5"`, `rel="next"`},
+		},
+		{
+			name:       "unused tag",
+			target:     "/tags/odd",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid tag",
+			target:     "/tags/Even",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:        "sitemap",
+			target:      "/sitemap.xml",
+			wantStatus:  http.StatusOK,
+			wantContain: []string{"<loc>https://example.com/</loc>", "<loc>https://example.com/articles/6</loc>", "<loc>https://example.com/tags/even</loc>"},
+		},
+		{
+			name:        "static asset",
+			target:      "/static/style.css",
+			wantStatus:  http.StatusOK,
+			wantContain: []string{".tags"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("GET %s status = %v, want %v", tt.target, w.Code, tt.wantStatus)
+			}
+
+			body := w.Body.String()
+			for _, want := range tt.wantContain {
+				if !strings.Contains(body, want) {
+					t.Errorf("GET %s body doesn't contain %q", tt.target, want)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(body, missing) {
+					t.Errorf("GET %s body contains %q", tt.target, missing)
+				}
+			}
+		})
+	}
+}
+
+func TestSite_Sitemap(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseURL    string
+		tenantID   string
+		wantStatus int
+		wantLoc    string
+	}{
+		{
+			name:       "default tenant",
+			baseURL:    "https://example.com",
+			tenantID:   tenant.DefaultID,
+			wantStatus: http.StatusOK,
+			wantLoc:    "<loc>https://example.com/</loc>",
+		},
+		{
+			name:       "default tenant without base url nor host",
+			tenantID:   tenant.DefaultID,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "tenant host",
+			baseURL:    "https://example.com",
+			tenantID:   "acme",
+			wantStatus: http.StatusOK,
+			wantLoc:    "<loc>https://acme.example.com/</loc>",
+		},
+		{
+			name:       "tenant without host",
+			tenantID:   "initech",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(newFakeStore(1), fakeTenants{}, Config{BaseURL: tt.baseURL})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+			r.Host = "evil.example.com"
+			r = r.WithContext(tenant.NewContext(r.Context(), tt.tenantID))
+
+			w := httptest.NewRecorder()
+			s.Sitemap(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("GET /sitemap.xml status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if strings.Contains(w.Body.String(), r.Host) {
+				t.Errorf("GET /sitemap.xml body contains the request host %q", r.Host)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantLoc) {
+				t.Errorf("GET /sitemap.xml body doesn't contain %q", tt.wantLoc)
+			}
+		})
+	}
+}
+
+func TestSite_Caching(t *testing.T) {
+	r := newTestRouter(t, newFakeStore(1))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles/1", nil))
+
+	if got, want := w.Header().Get("Cache-Control"), "public, max-age=60"; got != want {
+		t.Errorf("GET /articles/1 Cache-Control = %q, want %q", got, want)
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("GET /articles/1 ETag is empty")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/articles/1", nil)
+	req.Header.Set("If-None-Match", etag)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("GET /articles/1 with ETag status = %v, want %v", w.Code, http.StatusNotModified)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("GET /articles/1 with ETag body = %q, want empty", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles/2", nil))
+
+	if got, want := w.Header().Get("Cache-Control"), "no-store"; got != want {
+		t.Errorf("GET /articles/2 Cache-Control = %q, want %q", got, want)
+	}
+	if etag := w.Header().Get("ETag"); etag != "" {
+		t.Errorf("GET /articles/2 ETag = %q, want empty", etag)
+	}
+}
@@ -0,0 +1,329 @@
+package tenancy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goodleby/golang-app/client"
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/model/tenant"
+)
+
+// Store persists the tenants.
+type Store interface {
+	SelectTenants(ctx context.Context) ([]tenant.Tenant, error)
+	InsertTenant(ctx context.Context, t tenant.Tenant) (*tenant.Tenant, error)
+	UpdateTenantStatus(ctx context.Context, id string, status tenant.Status) (*tenant.Tenant, error)
+}
+
+type Config struct {
+	// Fallback is the tenant of the requests that name none. If empty, such
+	// requests are rejected.
+	Fallback string
+	// ReloadInterval is how long the changes made by other replicas take to
+	// apply, e.g. a tenant suspended by another replica.
+	ReloadInterval time.Duration
+}
+
+// Registry resolves the tenant of requests and holds the keys of the tenants,
+// reloading them regularly. Registry is an app service.
+type Registry struct {
+	store  Store
+	config Config
+
+	mu      sync.RWMutex
+	tenants map[string]tenant.Tenant
+	hosts   map[string]string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New loads the tenants, the fallback one must exist.
+func New(ctx context.Context, store Store, config Config) (*Registry, error) {
+	r := &Registry{
+		store:  store,
+		config: config,
+		done:   make(chan struct{}),
+	}
+
+	err := r.Reload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Fallback != "" {
+		if _, ok := r.tenants[config.Fallback]; !ok {
+			return nil, fmt.Errorf("fallback tenant %q doesn't exist", config.Fallback)
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Registry) Start(ctx context.Context, errc chan<- error) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.config.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.Reload(ctx)
+			if err != nil {
+				// The previous tenants are served until the store is back.
+				slog.Error(fmt.Sprintf("Error reloading tenants: %v", err))
+			}
+		}
+	}
+}
+
+func (r *Registry) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("error stopping tenant registry: %v", ctx.Err())
+	}
+}
+
+// Reload replaces the tenants with the ones of the store.
+func (r *Registry) Reload(ctx context.Context) error {
+	loaded, err := r.store.SelectTenants(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading tenants: %v", err)
+	}
+
+	tenants := make(map[string]tenant.Tenant, len(loaded))
+	hosts := map[string]string{}
+	for _, t := range loaded {
+		tenants[t.ID] = t
+		for _, host := range t.Hosts {
+			hosts[host] = t.ID
+		}
+	}
+
+	r.mu.Lock()
+	r.tenants = tenants
+	r.hosts = hosts
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Resolve returns the tenant named by the header, else the one served at the
+// host, else the one claimed by the auth token, else the fallback one. It
+// fails for the unknown and suspended tenants.
+func (r *Registry) Resolve(header, host, claim string) (*tenant.Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id := header
+	if id == "" {
+		id = r.hosts[hostname(host)]
+	}
+	if id == "" {
+		id = claim
+	}
+	if id == "" {
+		id = r.config.Fallback
+	}
+	if id == "" {
+		return nil, &tenant.ErrUnresolved{}
+	}
+
+	return r.active(id)
+}
+
+// TenantKeys returns the keys of the tenant, see auth.TenantKeyring.
+func (r *Registry) TenantKeys(ctx context.Context, id string) (*tenant.Keys, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, err := r.active(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t.Keys, nil
+}
+
+// active returns a copy of the tenant if it is active. The read lock must be
+// held.
+func (r *Registry) active(id string) (*tenant.Tenant, error) {
+	t, ok := r.tenants[id]
+	if !ok {
+		return nil, &tenant.ErrUnknown{ID: id}
+	}
+
+	if t.Status == tenant.StatusSuspended {
+		return nil, &tenant.ErrSuspended{ID: id}
+	}
+
+	return &t, nil
+}
+
+// Tenants returns all the tenants sorted by ID.
+func (r *Registry) Tenants() []tenant.Tenant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenants := make([]tenant.Tenant, 0, len(r.tenants))
+	for _, id := range slices.Sorted(maps.Keys(r.tenants)) {
+		tenants = append(tenants, r.tenants[id])
+	}
+
+	return tenants
+}
+
+// Tenant returns the tenant, suspended or not.
+func (r *Registry) Tenant(id string) (*tenant.Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.tenants[id]
+	if !ok {
+		return nil, &tenant.ErrUnknown{ID: id}
+	}
+
+	return &t, nil
+}
+
+// Provision creates the tenant with a new signing secret and role keys. The
+// role keys are returned only this once, only their hashes are stored.
+func (r *Registry) Provision(ctx context.Context, payload tenant.Payload) (*tenant.Provisioned, error) {
+	err := payload.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	for _, host := range payload.Hosts {
+		if id, ok := r.hosts[host]; ok {
+			r.mu.RUnlock()
+			return nil, &client.ErrConflict{Err: fmt.Errorf("host %q is served by tenant %q", host, id)}
+		}
+	}
+	r.mu.RUnlock()
+
+	secret, err := randomBytes()
+	if err != nil {
+		return nil, fmt.Errorf("error generating signing secret: %v", err)
+	}
+
+	var roleKeys tenant.RoleKeys
+	for _, key := range []*string{&roleKeys.Admin, &roleKeys.Editor, &roleKeys.Viewer} {
+		b, err := randomBytes()
+		if err != nil {
+			return nil, fmt.Errorf("error generating role key: %v", err)
+		}
+		*key = base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	adminKeyHash := auth.HashRoleKey(roleKeys.Admin)
+	editorKeyHash := auth.HashRoleKey(roleKeys.Editor)
+	viewerKeyHash := auth.HashRoleKey(roleKeys.Viewer)
+
+	inserted, err := r.store.InsertTenant(ctx, tenant.Tenant{
+		ID:    payload.ID,
+		Name:  payload.Name,
+		Hosts: payload.Hosts,
+		Keys: tenant.Keys{
+			SigningSecret: secret,
+			AdminKeyHash:  &adminKeyHash,
+			EditorKeyHash: &editorKeyHash,
+			ViewerKeyHash: &viewerKeyHash,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.put(*inserted)
+
+	return &tenant.Provisioned{Tenant: *inserted, RoleKeys: roleKeys}, nil
+}
+
+// Suspend stops serving the tenant, its data is kept.
+func (r *Registry) Suspend(ctx context.Context, id string) (*tenant.Tenant, error) {
+	return r.setStatus(ctx, id, tenant.StatusSuspended)
+}
+
+// Resume serves the suspended tenant again.
+func (r *Registry) Resume(ctx context.Context, id string) (*tenant.Tenant, error) {
+	return r.setStatus(ctx, id, tenant.StatusActive)
+}
+
+func (r *Registry) setStatus(ctx context.Context, id string, status tenant.Status) (*tenant.Tenant, error) {
+	if id == tenant.DefaultID {
+		return nil, ErrDefaultTenant
+	}
+
+	updated, err := r.store.UpdateTenantStatus(ctx, id, status)
+	if err != nil {
+		return nil, err
+	}
+
+	r.put(*updated)
+
+	return updated, nil
+}
+
+// put applies a change made by this replica without waiting for a reload.
+func (r *Registry) put(t tenant.Tenant) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if old, ok := r.tenants[t.ID]; ok {
+		for _, host := range old.Hosts {
+			delete(r.hosts, host)
+		}
+	}
+
+	r.tenants[t.ID] = t
+	for _, host := range t.Hosts {
+		r.hosts[host] = t.ID
+	}
+}
+
+// ErrDefaultTenant is returned when suspending or resuming the default tenant,
+// which is always served.
+var ErrDefaultTenant = errors.New("the status of the default tenant can't change")
+
+// hostname strips the port of the Host header.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(host)
+}
+
+func randomBytes() ([]byte, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
@@ -0,0 +1,184 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/goodleby/golang-app/client/auth"
+	"github.com/goodleby/golang-app/model/tenant"
+)
+
+type fakeStore struct {
+	tenants []tenant.Tenant
+}
+
+func (s *fakeStore) SelectTenants(ctx context.Context) ([]tenant.Tenant, error) {
+	return s.tenants, nil
+}
+
+func (s *fakeStore) InsertTenant(ctx context.Context, t tenant.Tenant) (*tenant.Tenant, error) {
+	t.Status = tenant.StatusActive
+	s.tenants = append(s.tenants, t)
+	return &t, nil
+}
+
+func (s *fakeStore) UpdateTenantStatus(ctx context.Context, id string, status tenant.Status) (*tenant.Tenant, error) {
+	for i := range s.tenants {
+		if s.tenants[i].ID == id {
+			s.tenants[i].Status = status
+			t := s.tenants[i]
+			return &t, nil
+		}
+	}
+
+	return nil, &tenant.ErrUnknown{ID: id}
+}
+
+func newTestRegistry(t *testing.T, fallback string) *Registry {
+	t.Helper()
+
+	store := &fakeStore{tenants: []tenant.Tenant{
+		{ID: tenant.DefaultID, Status: tenant.StatusActive},
+		{ID: "acme", Hosts: []string{"acme.example.com"}, Status: tenant.StatusActive},
+		{ID: "globex", Hosts: []string{"globex.example.com"}, Status: tenant.StatusSuspended},
+	}}
+
+	r, err := New(context.Background(), store, Config{Fallback: fallback})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	return r
+}
+
+func TestRegistry_Resolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback string
+		header   string
+		host     string
+		claim    string
+		want     string
+		wantCode string
+	}{
+		{
+			name:   "header",
+			header: "acme",
+			host:   "other.example.com",
+			want:   "acme",
+		},
+		{
+			name: "host",
+			host: "acme.example.com",
+			want: "acme",
+		},
+		{
+			name: "host with port",
+			host: "ACME.example.com:8080",
+			want: "acme",
+		},
+		{
+			name:     "claim",
+			fallback: tenant.DefaultID,
+			host:     "other.example.com",
+			claim:    "acme",
+			want:     "acme",
+		},
+		{
+			name:     "fallback",
+			fallback: tenant.DefaultID,
+			host:     "other.example.com",
+			want:     tenant.DefaultID,
+		},
+		{
+			name:     "no fallback",
+			host:     "other.example.com",
+			wantCode: "tenant_required",
+		},
+		{
+			name:     "unknown",
+			header:   "initech",
+			wantCode: "unknown_tenant",
+		},
+		{
+			name:     "suspended",
+			host:     "globex.example.com",
+			wantCode: "tenant_suspended",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRegistry(t, tt.fallback)
+
+			got, err := r.Resolve(tt.header, tt.host, tt.claim)
+			if tt.wantCode != "" {
+				var codeErr interface{ Code() string }
+				if !errors.As(err, &codeErr) || codeErr.Code() != tt.wantCode {
+					t.Fatalf("Registry.Resolve() error = %v, want code %v", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Registry.Resolve() error = %v", err)
+			}
+
+			if got.ID != tt.want {
+				t.Errorf("Registry.Resolve() = %v, want %v", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_unknownFallback(t *testing.T) {
+	_, err := New(context.Background(), &fakeStore{}, Config{Fallback: tenant.DefaultID})
+	if err == nil {
+		t.Errorf("New() error = nil, want error")
+	}
+}
+
+func TestRegistry_Provision(t *testing.T) {
+	r := newTestRegistry(t, "")
+	ctx := context.Background()
+
+	_, err := r.Provision(ctx, tenant.Payload{ID: "initech", Name: "Initech", Hosts: []string{"acme.example.com"}})
+	if err == nil {
+		t.Fatalf("Registry.Provision() with a taken host error = nil, want error")
+	}
+
+	provisioned, err := r.Provision(ctx, tenant.Payload{ID: "initech", Name: "Initech", Hosts: []string{"initech.example.com"}})
+	if err != nil {
+		t.Fatalf("Registry.Provision() error = %v", err)
+	}
+
+	got, err := r.Resolve("", "initech.example.com", "")
+	if err != nil || got.ID != "initech" {
+		t.Fatalf("Registry.Resolve() of the provisioned tenant = %v, %v", got, err)
+	}
+
+	keys, err := r.TenantKeys(ctx, "initech")
+	if err != nil {
+		t.Fatalf("Registry.TenantKeys() error = %v", err)
+	}
+	if len(keys.SigningSecret) == 0 {
+		t.Errorf("Registry.TenantKeys() signing secret is empty")
+	}
+	if keys.EditorKeyHash == nil || *keys.EditorKeyHash != auth.HashRoleKey(provisioned.RoleKeys.Editor) {
+		t.Errorf("Registry.TenantKeys() editor key hash doesn't match the provisioned key")
+	}
+
+	_, err = r.Suspend(ctx, "initech")
+	if err != nil {
+		t.Fatalf("Registry.Suspend() error = %v", err)
+	}
+
+	_, err = r.TenantKeys(ctx, "initech")
+	if _, ok := err.(*tenant.ErrSuspended); !ok {
+		t.Errorf("Registry.TenantKeys() of a suspended tenant error = %v, want *tenant.ErrSuspended", err)
+	}
+
+	_, err = r.Suspend(ctx, tenant.DefaultID)
+	if err != ErrDefaultTenant {
+		t.Errorf("Registry.Suspend() of the default tenant error = %v, want %v", err, ErrDefaultTenant)
+	}
+}
@@ -28,11 +28,31 @@ func (s *Span) SetTag(key, value string) {
 	s.span.SetAttributes(attribute.String(key, value))
 }
 
+// TraceID returns the ID of the trace the span belongs to or an empty string
+// if the span isn't being recorded.
+func (s *Span) TraceID() string {
+	spanContext := s.span.SpanContext()
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+
+	return spanContext.TraceID().String()
+}
+
 func (s *Span) RecordError(err error) {
 	s.span.RecordError(err)
 	s.span.SetStatus(codes.Error, fmt.Sprintf("%v", err))
 }
 
+// RecordPanic records a recovered panic as an exception with the stack it was
+// raised at.
+func (s *Span) RecordPanic(value any, stack []byte) {
+	err := fmt.Errorf("panic: %v", value)
+
+	s.span.RecordError(err, trace.WithAttributes(attribute.String("exception.stacktrace", string(stack))))
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
 func StartSpan(ctx context.Context, name string) (context.Context, Span) {
 	var s Span
 
@@ -23,14 +23,19 @@ func (tt *TracedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	span.SetTag("http.url", r.URL.String())
 
 	res, err := tt.RoundTripper.RoundTrip(r)
+	if err != nil {
+		err = fmt.Errorf("error making request to %s: %v", r.URL, err)
+		span.RecordError(err)
+		return nil, err
+	}
 
 	span.SetTag("http.status_code", fmt.Sprint(res.StatusCode))
 
-	if err != nil || res.StatusCode >= 400 {
-		err = fmt.Errorf("error making request to %s, status code %d: %v", r.URL, res.StatusCode, err)
-		span.RecordError(err)
-		return nil, err
+	// The response is kept, callers may need the status or body of failed
+	// requests, e.g. the webhook delivery log.
+	if res.StatusCode >= 400 {
+		span.RecordError(fmt.Errorf("error making request to %s, status code %d", r.URL, res.StatusCode))
 	}
 
-	return res, err
+	return res, nil
 }
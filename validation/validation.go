@@ -0,0 +1,36 @@
+package validation
+
+import "strings"
+
+// FieldError is a violation of a single field, identified by its JSON path,
+// e.g. "scopes[1]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors collects every violation found while validating a value, so clients
+// can fix them all at once.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func (e *Errors) Add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+// Err returns the collected violations as an error or nil if there are none.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}